@@ -1,14 +1,19 @@
 package router
 
 import (
+	"time"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/swagger"
 	"github.com/minisource/go-common/logging"
 	"github.com/minisource/ticket/api/v1/handlers"
 	"github.com/minisource/ticket/config"
+	"github.com/minisource/ticket/internal/guest"
 	"github.com/minisource/ticket/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Router holds router dependencies
@@ -19,6 +24,15 @@ type Router struct {
 	ticketHandler *handlers.TicketHandler
 	adminHandler  *handlers.AdminHandler
 	healthHandler *handlers.HealthHandler
+	guestHandler  *handlers.GuestHandler
+	agentHandler  *handlers.AgentHandler
+	eventHandler  *handlers.EventHandler
+	viewHandler   *handlers.ViewHandler
+	reportHandler *handlers.ReportHandler
+	exportHandler *handlers.ExportHandler
+	importHandler *handlers.ImportHandler
+	guestTokens   *guest.TokenManager
+	rateLimits    middleware.RateLimitStore
 }
 
 // NewRouter creates a new router
@@ -28,11 +42,26 @@ func NewRouter(
 	ticketHandler *handlers.TicketHandler,
 	adminHandler *handlers.AdminHandler,
 	healthHandler *handlers.HealthHandler,
+	guestHandler *handlers.GuestHandler,
+	agentHandler *handlers.AgentHandler,
+	eventHandler *handlers.EventHandler,
+	viewHandler *handlers.ViewHandler,
+	reportHandler *handlers.ReportHandler,
+	exportHandler *handlers.ExportHandler,
+	importHandler *handlers.ImportHandler,
+	guestTokens *guest.TokenManager,
+	rateLimits middleware.RateLimitStore,
 ) *Router {
-	app := fiber.New(fiber.Config{
+	app := fiber.New(middleware.ClientIPConfig(fiber.Config{
 		AppName:      "Ticket Service",
 		ErrorHandler: customErrorHandler,
-	})
+	}, cfg.Server))
+
+	// Import uploads are the only large bodies; leave room for the multipart
+	// envelope around the file
+	if uploadLimit := cfg.Import.MaxSize + 1<<20; uploadLimit > fiber.DefaultBodyLimit {
+		middleware.AllowLargeBody(app, fiber.MethodPost, "/api/v1/admin/imports", int(uploadLimit))
+	}
 
 	return &Router{
 		app:           app,
@@ -41,6 +70,15 @@ func NewRouter(
 		ticketHandler: ticketHandler,
 		adminHandler:  adminHandler,
 		healthHandler: healthHandler,
+		guestHandler:  guestHandler,
+		agentHandler:  agentHandler,
+		eventHandler:  eventHandler,
+		viewHandler:   viewHandler,
+		reportHandler: reportHandler,
+		exportHandler: exportHandler,
+		importHandler: importHandler,
+		guestTokens:   guestTokens,
+		rateLimits:    rateLimits,
 	}
 }
 
@@ -48,19 +86,29 @@ func NewRouter(
 func (r *Router) Setup() *fiber.App {
 	// Global middleware
 	r.app.Use(recover.New())
+	r.app.Use(middleware.TracingMiddleware())
 	r.app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowMethods: "GET,POST,PUT,PATCH,DELETE,OPTIONS",
-		AllowHeaders: "Origin,Content-Type,Accept,Authorization,X-Tenant-ID,X-User-ID,X-Request-ID,Accept-Language",
+		AllowOrigins:  "*",
+		AllowMethods:  "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders:  "Origin,Content-Type,Accept,Authorization,X-Tenant-ID,X-Tenant-Timezone,X-User-ID,X-Request-ID,X-Guest-Token,Accept-Language,If-Match,traceparent,tracestate",
+		ExposeHeaders: "ETag",
 	}))
 	r.app.Use(middleware.RequestIDMiddleware())
 	r.app.Use(middleware.LoggingMiddleware(r.logger))
+	if r.config.Metrics.Enabled {
+		r.app.Use(middleware.MetricsMiddleware())
+	}
 
 	// Health check routes
 	r.app.Get("/health", r.healthHandler.Health)
 	r.app.Get("/ready", r.healthHandler.Ready)
 	r.app.Get("/live", r.healthHandler.Live)
 
+	// Prometheus metrics
+	if r.config.Metrics.Enabled {
+		r.app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
+	}
+
 	// Swagger documentation
 	r.app.Get("/swagger/*", swagger.HandlerDefault)
 
@@ -78,10 +126,19 @@ func (r *Router) Setup() *fiber.App {
 	public.Get("/categories", r.adminHandler.ListCategories)
 	public.Get("/categories/:id", r.adminHandler.GetCategory)
 
+	// Guest tickets (no account, magic-link access)
+	if r.config.Guest.Enabled {
+		r.setupGuestRoutes(api)
+	}
+
 	// Authenticated routes
 	authenticated := api.Group("")
 	authenticated.Use(middleware.AuthMiddleware(r.config))
 	authenticated.Use(middleware.TenantMiddleware())
+	if r.config.RateLimit.Enabled {
+		authenticated.Use(r.rateLimit("tenant", r.config.RateLimit.TenantPerMinute, time.Minute, middleware.TenantKey))
+		authenticated.Use(r.rateLimit("user", r.config.RateLimit.UserPerMinute, time.Minute, middleware.UserKey))
+	}
 
 	// Customer ticket routes
 	r.setupTicketRoutes(authenticated)
@@ -103,9 +160,13 @@ func (r *Router) Setup() *fiber.App {
 func (r *Router) setupTicketRoutes(group fiber.Router) {
 	tickets := group.Group("/tickets")
 
+	// Ticket creation gets a stricter per-user limit on top of the group limits
+	createLimit := r.rateLimit("ticket_create", r.config.Ticket.RateLimitPerMinute, time.Minute, middleware.RouteKey)
+
 	// Ticket CRUD
-	tickets.Post("", r.ticketHandler.CreateTicket)
+	tickets.Post("", createLimit, r.ticketHandler.CreateTicket)
 	tickets.Get("", r.ticketHandler.ListTickets)
+	tickets.Get("/search", r.ticketHandler.SearchTickets)
 	tickets.Get("/stats", r.ticketHandler.GetStats)
 	tickets.Get("/number/:number", r.ticketHandler.GetTicketByNumber)
 	tickets.Get("/:id", r.ticketHandler.GetTicket)
@@ -123,10 +184,33 @@ func (r *Router) setupTicketRoutes(group fiber.Router) {
 	// Ticket history
 	tickets.Get("/:id/history", r.ticketHandler.GetTicketHistory)
 
+	// Real-time events
+	if r.config.Realtime.Enabled {
+		tickets.Get("/:id/events", r.eventHandler.TicketEvents)
+		tickets.Post("/:id/typing", r.eventHandler.Typing)
+	}
+
 	// Customer tickets
 	group.Get("/customers/:customer_id/tickets", r.ticketHandler.GetCustomerTickets)
 }
 
+// setupGuestRoutes sets up guest ticket routes
+func (r *Router) setupGuestRoutes(api fiber.Router) {
+	guestTickets := api.Group("/guest/tickets")
+
+	// Submission is public but rate limited per client IP; the tenant header
+	// is the caller's to choose, so it can't be part of the key
+	limited := r.rateLimit("guest", r.config.Guest.RateLimitPerHour, time.Hour, middleware.IPKey)
+	guestTickets.Post("", limited, r.guestHandler.CreateTicket)
+	guestTickets.Post("/access-link", limited, r.guestHandler.ResendAccessLink)
+
+	// Token-authorized access to a single ticket
+	authorized := middleware.GuestTokenMiddleware(r.guestTokens)
+	guestTickets.Get("/:id", authorized, r.guestHandler.GetTicket)
+	guestTickets.Get("/:id/messages", authorized, r.guestHandler.GetMessages)
+	guestTickets.Post("/:id/messages", authorized, r.guestHandler.AddReply)
+}
+
 // setupAgentRoutes sets up agent-specific routes
 func (r *Router) setupAgentRoutes(group fiber.Router) {
 	tickets := group.Group("/tickets")
@@ -134,9 +218,37 @@ func (r *Router) setupAgentRoutes(group fiber.Router) {
 	// Agent ticket actions
 	tickets.Post("/:id/assign", r.ticketHandler.AssignTicket)
 	tickets.Post("/:id/transfer", r.ticketHandler.TransferTicket)
+	tickets.Post("/:id/reply", r.ticketHandler.AgentAddReply)
+
+	// Collision detection
+	tickets.Post("/:id/presence", r.ticketHandler.AgentUpdatePresence)
 
 	// Agent tickets
 	group.Get("/agents/:agent_id/tickets", r.ticketHandler.GetAgentTickets)
+
+	// Presence
+	group.Post("/agent/heartbeat", r.agentHandler.Heartbeat)
+
+	// Real-time events
+	if r.config.Realtime.Enabled {
+		group.Get("/agent/events", r.eventHandler.AgentEvents)
+	}
+
+	// Own performance
+	group.Get("/agent/scorecard", r.reportHandler.MyScorecard)
+
+	// Pull-based queue
+	group.Post("/agent/queue/next", r.ticketHandler.AgentClaimNextTicket)
+
+	// Saved views
+	views := group.Group("/agent/views")
+	views.Get("", r.viewHandler.ListViews)
+	views.Post("", r.viewHandler.CreateView)
+	views.Get("/counts", r.viewHandler.CountViews)
+	views.Get("/:id", r.viewHandler.GetView)
+	views.Patch("/:id", r.viewHandler.UpdateView)
+	views.Delete("/:id", r.viewHandler.DeleteView)
+	views.Get("/:id/tickets", r.viewHandler.ListViewTickets)
 }
 
 // setupAdminRoutes sets up admin routes
@@ -149,6 +261,9 @@ func (r *Router) setupAdminRoutes(admin fiber.Router) {
 	agents.Patch("/:id", r.adminHandler.UpdateAgent)
 	agents.Delete("/:id", r.adminHandler.DeleteAgent)
 	agents.Patch("/:id/status", r.adminHandler.UpdateAgentStatus)
+	agents.Put("/:id/schedule", r.adminHandler.UpdateAgentSchedule)
+	agents.Post("/:id/out-of-office", r.adminHandler.AddAgentOutOfOffice)
+	agents.Delete("/:id/out-of-office/:period_id", r.adminHandler.RemoveAgentOutOfOffice)
 
 	// Department management
 	departments := admin.Group("/departments")
@@ -193,12 +308,51 @@ func (r *Router) setupAdminRoutes(admin fiber.Router) {
 	bulk.Post("/bulk-transfer", r.adminHandler.BulkTransferDepartment)
 	bulk.Post("/bulk-delete", r.adminHandler.BulkDeleteTickets)
 
+	// Exports
+	bulk.Get("/export", r.exportHandler.ExportTickets)
+	exports := admin.Group("/exports")
+	exports.Get("/:id", r.exportHandler.GetExport)
+	exports.Get("/:id/download", r.exportHandler.DownloadExport)
+
+	// Imports
+	imports := admin.Group("/imports")
+	imports.Post("", r.importHandler.CreateImport)
+	imports.Get("", r.importHandler.ListImports)
+	imports.Get("/:id", r.importHandler.GetImport)
+	imports.Post("/:id/run", r.importHandler.RunImport)
+	imports.Post("/:id/resume", r.importHandler.ResumeImport)
+
 	// Dashboard
 	dashboard := admin.Group("/dashboard")
 	dashboard.Get("/stats", r.adminHandler.GetDashboardStats)
 	dashboard.Get("/sla-breached", r.adminHandler.GetSLABreachedTickets)
 	dashboard.Get("/due-soon", r.adminHandler.GetTicketsDueSoon)
 	dashboard.Get("/unassigned", r.adminHandler.GetUnassignedTickets)
+
+	// Reports
+	reports := admin.Group("/reports")
+	reports.Get("/tickets", r.reportHandler.TicketTrends)
+	reports.Get("/agents", r.reportHandler.AgentLeaderboard)
+	reports.Get("/agents/:agent_id", r.reportHandler.AgentScorecard)
+
+	// Stats maintenance
+	stats := admin.Group("/stats")
+	stats.Post("/reconcile", r.adminHandler.ReconcileStats)
+
+	// Search maintenance
+	search := admin.Group("/search")
+	search.Post("/rebuild", r.adminHandler.RebuildSearchIndex)
+}
+
+// rateLimit creates a rate limit middleware backed by the shared store
+func (r *Router) rateLimit(name string, max int, window time.Duration, keyFunc func(*fiber.Ctx) string) fiber.Handler {
+	return middleware.NewRateLimiter(middleware.RateLimitConfig{
+		Name:       name,
+		Max:        max,
+		Expiration: window,
+		KeyFunc:    keyFunc,
+		Store:      r.rateLimits,
+	}).Handler()
 }
 
 // customErrorHandler handles errors
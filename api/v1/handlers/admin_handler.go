@@ -16,6 +16,8 @@ type AdminHandler struct {
 	adminUsecase      *usecase.AdminUsecase
 	departmentUsecase *usecase.DepartmentUsecase
 	categoryUsecase   *usecase.CategoryUsecase
+	reconcileUsecase  *usecase.ReconcileUsecase
+	scheduleUsecase   *usecase.ScheduleUsecase
 	translator        *i18n.Translator
 }
 
@@ -24,11 +26,15 @@ func NewAdminHandler(
 	adminUsecase *usecase.AdminUsecase,
 	departmentUsecase *usecase.DepartmentUsecase,
 	categoryUsecase *usecase.CategoryUsecase,
+	reconcileUsecase *usecase.ReconcileUsecase,
+	scheduleUsecase *usecase.ScheduleUsecase,
 ) *AdminHandler {
 	return &AdminHandler{
 		adminUsecase:      adminUsecase,
 		departmentUsecase: departmentUsecase,
 		categoryUsecase:   categoryUsecase,
+		reconcileUsecase:  reconcileUsecase,
+		scheduleUsecase:   scheduleUsecase,
 		translator:        i18n.GetTranslator(),
 	}
 }
@@ -37,7 +43,7 @@ func NewAdminHandler(
 
 // CreateAgent creates a new agent
 func (h *AdminHandler) CreateAgent(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	if tenantID == "" {
 		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
@@ -58,7 +64,7 @@ func (h *AdminHandler) CreateAgent(c *fiber.Ctx) error {
 
 // GetAgent gets an agent by ID
 func (h *AdminHandler) GetAgent(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	agent, err := h.adminUsecase.GetAgent(ctx, id)
@@ -71,7 +77,7 @@ func (h *AdminHandler) GetAgent(c *fiber.Ctx) error {
 
 // UpdateAgent updates an agent
 func (h *AdminHandler) UpdateAgent(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	var req models.UpdateAgentRequest
@@ -89,7 +95,7 @@ func (h *AdminHandler) UpdateAgent(c *fiber.Ctx) error {
 
 // DeleteAgent deletes an agent
 func (h *AdminHandler) DeleteAgent(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	err := h.adminUsecase.DeleteAgent(ctx, id)
@@ -102,7 +108,7 @@ func (h *AdminHandler) DeleteAgent(c *fiber.Ctx) error {
 
 // ListAgents lists agents
 func (h *AdminHandler) ListAgents(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	if tenantID == "" {
 		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
@@ -120,7 +126,7 @@ func (h *AdminHandler) ListAgents(c *fiber.Ctx) error {
 
 // UpdateAgentStatus updates an agent's status
 func (h *AdminHandler) UpdateAgentStatus(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	userID := c.Params("id")
 
@@ -139,11 +145,60 @@ func (h *AdminHandler) UpdateAgentStatus(c *fiber.Ctx) error {
 	return response.OK(c, map[string]string{"message": h.translator.Translate(ctx, "agent.status_updated", nil)})
 }
 
+// UpdateAgentSchedule sets an agent's weekly shifts
+func (h *AdminHandler) UpdateAgentSchedule(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("id")
+
+	var req models.UpdateAgentScheduleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "INVALID_REQUEST", h.translator.Translate(ctx, "error.invalid_request_body", nil))
+	}
+
+	agent, err := h.scheduleUsecase.UpdateSchedule(ctx, id, req)
+	if err != nil {
+		return response.BadRequest(c, "UPDATE_FAILED", err.Error())
+	}
+
+	return response.OK(c, agent)
+}
+
+// AddAgentOutOfOffice schedules leave for an agent
+func (h *AdminHandler) AddAgentOutOfOffice(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("id")
+
+	var req models.CreateOutOfOfficeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "INVALID_REQUEST", h.translator.Translate(ctx, "error.invalid_request_body", nil))
+	}
+
+	agent, err := h.scheduleUsecase.AddOutOfOffice(ctx, id, req)
+	if err != nil {
+		return response.BadRequest(c, "CREATE_FAILED", err.Error())
+	}
+
+	return response.Created(c, agent)
+}
+
+// RemoveAgentOutOfOffice cancels an agent's leave period
+func (h *AdminHandler) RemoveAgentOutOfOffice(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("id")
+	periodID := c.Params("period_id")
+
+	if err := h.scheduleUsecase.RemoveOutOfOffice(ctx, id, periodID); err != nil {
+		return response.BadRequest(c, "DELETE_FAILED", err.Error())
+	}
+
+	return response.OK(c, map[string]string{"message": h.translator.Translate(ctx, "agent.updated", nil)})
+}
+
 // ===== Department Management =====
 
 // CreateDepartment creates a new department
 func (h *AdminHandler) CreateDepartment(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	if tenantID == "" {
 		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
@@ -164,7 +219,7 @@ func (h *AdminHandler) CreateDepartment(c *fiber.Ctx) error {
 
 // GetDepartment gets a department by ID
 func (h *AdminHandler) GetDepartment(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	department, err := h.departmentUsecase.GetDepartment(ctx, id)
@@ -177,7 +232,7 @@ func (h *AdminHandler) GetDepartment(c *fiber.Ctx) error {
 
 // UpdateDepartment updates a department
 func (h *AdminHandler) UpdateDepartment(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	var req models.UpdateDepartmentRequest
@@ -195,7 +250,7 @@ func (h *AdminHandler) UpdateDepartment(c *fiber.Ctx) error {
 
 // DeleteDepartment deletes a department
 func (h *AdminHandler) DeleteDepartment(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	err := h.departmentUsecase.DeleteDepartment(ctx, id)
@@ -208,7 +263,7 @@ func (h *AdminHandler) DeleteDepartment(c *fiber.Ctx) error {
 
 // ListDepartments lists departments
 func (h *AdminHandler) ListDepartments(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	if tenantID == "" {
 		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
@@ -226,7 +281,7 @@ func (h *AdminHandler) ListDepartments(c *fiber.Ctx) error {
 
 // AddAgentToDepartment adds an agent to a department
 func (h *AdminHandler) AddAgentToDepartment(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	var req struct {
@@ -246,7 +301,7 @@ func (h *AdminHandler) AddAgentToDepartment(c *fiber.Ctx) error {
 
 // RemoveAgentFromDepartment removes an agent from a department
 func (h *AdminHandler) RemoveAgentFromDepartment(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 	agentID := c.Params("agentId")
 
@@ -260,7 +315,7 @@ func (h *AdminHandler) RemoveAgentFromDepartment(c *fiber.Ctx) error {
 
 // GetDepartmentAgents gets all agents in a department
 func (h *AdminHandler) GetDepartmentAgents(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	id := c.Params("id")
 
@@ -276,7 +331,7 @@ func (h *AdminHandler) GetDepartmentAgents(c *fiber.Ctx) error {
 
 // CreateCategory creates a new category
 func (h *AdminHandler) CreateCategory(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	if tenantID == "" {
 		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
@@ -297,7 +352,7 @@ func (h *AdminHandler) CreateCategory(c *fiber.Ctx) error {
 
 // GetCategory gets a category by ID
 func (h *AdminHandler) GetCategory(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	category, err := h.categoryUsecase.GetCategory(ctx, id)
@@ -310,7 +365,7 @@ func (h *AdminHandler) GetCategory(c *fiber.Ctx) error {
 
 // UpdateCategory updates a category
 func (h *AdminHandler) UpdateCategory(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	var req models.UpdateCategoryRequest
@@ -328,7 +383,7 @@ func (h *AdminHandler) UpdateCategory(c *fiber.Ctx) error {
 
 // DeleteCategory deletes a category
 func (h *AdminHandler) DeleteCategory(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	err := h.categoryUsecase.DeleteCategory(ctx, id)
@@ -341,7 +396,7 @@ func (h *AdminHandler) DeleteCategory(c *fiber.Ctx) error {
 
 // ListCategories lists categories
 func (h *AdminHandler) ListCategories(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	if tenantID == "" {
 		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
@@ -361,7 +416,7 @@ func (h *AdminHandler) ListCategories(c *fiber.Ctx) error {
 
 // CreateSLAPolicy creates a new SLA policy
 func (h *AdminHandler) CreateSLAPolicy(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	if tenantID == "" {
 		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
@@ -382,7 +437,7 @@ func (h *AdminHandler) CreateSLAPolicy(c *fiber.Ctx) error {
 
 // GetSLAPolicy gets an SLA policy by ID
 func (h *AdminHandler) GetSLAPolicy(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	policy, err := h.adminUsecase.GetSLAPolicy(ctx, id)
@@ -395,7 +450,7 @@ func (h *AdminHandler) GetSLAPolicy(c *fiber.Ctx) error {
 
 // UpdateSLAPolicy updates an SLA policy
 func (h *AdminHandler) UpdateSLAPolicy(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	var req models.UpdateSLAPolicyRequest
@@ -413,7 +468,7 @@ func (h *AdminHandler) UpdateSLAPolicy(c *fiber.Ctx) error {
 
 // DeleteSLAPolicy deletes an SLA policy
 func (h *AdminHandler) DeleteSLAPolicy(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	err := h.adminUsecase.DeleteSLAPolicy(ctx, id)
@@ -426,7 +481,7 @@ func (h *AdminHandler) DeleteSLAPolicy(c *fiber.Ctx) error {
 
 // ListSLAPolicies lists SLA policies
 func (h *AdminHandler) ListSLAPolicies(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	if tenantID == "" {
 		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
@@ -444,7 +499,7 @@ func (h *AdminHandler) ListSLAPolicies(c *fiber.Ctx) error {
 
 // CreateCannedResponse creates a new canned response
 func (h *AdminHandler) CreateCannedResponse(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	userID := c.Get("X-User-ID")
 	if tenantID == "" {
@@ -466,7 +521,7 @@ func (h *AdminHandler) CreateCannedResponse(c *fiber.Ctx) error {
 
 // GetCannedResponse gets a canned response by ID
 func (h *AdminHandler) GetCannedResponse(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	cannedResp, err := h.adminUsecase.GetCannedResponse(ctx, id)
@@ -479,7 +534,7 @@ func (h *AdminHandler) GetCannedResponse(c *fiber.Ctx) error {
 
 // UpdateCannedResponse updates a canned response
 func (h *AdminHandler) UpdateCannedResponse(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	var req models.UpdateCannedResponseRequest
@@ -497,7 +552,7 @@ func (h *AdminHandler) UpdateCannedResponse(c *fiber.Ctx) error {
 
 // DeleteCannedResponse deletes a canned response
 func (h *AdminHandler) DeleteCannedResponse(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	err := h.adminUsecase.DeleteCannedResponse(ctx, id)
@@ -510,7 +565,7 @@ func (h *AdminHandler) DeleteCannedResponse(c *fiber.Ctx) error {
 
 // ListCannedResponses lists canned responses
 func (h *AdminHandler) ListCannedResponses(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	if tenantID == "" {
 		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
@@ -537,7 +592,7 @@ func (h *AdminHandler) ListCannedResponses(c *fiber.Ctx) error {
 
 // BulkAssignTickets assigns multiple tickets to an agent
 func (h *AdminHandler) BulkAssignTickets(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	userID := c.Get("X-User-ID")
 	userName := c.Get("X-User-Name")
@@ -563,7 +618,7 @@ func (h *AdminHandler) BulkAssignTickets(c *fiber.Ctx) error {
 
 // BulkChangeStatus changes status of multiple tickets
 func (h *AdminHandler) BulkChangeStatus(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	userID := c.Get("X-User-ID")
 	userName := c.Get("X-User-Name")
@@ -589,7 +644,7 @@ func (h *AdminHandler) BulkChangeStatus(c *fiber.Ctx) error {
 
 // BulkChangePriority changes priority of multiple tickets
 func (h *AdminHandler) BulkChangePriority(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	userID := c.Get("X-User-ID")
 	userName := c.Get("X-User-Name")
@@ -615,7 +670,7 @@ func (h *AdminHandler) BulkChangePriority(c *fiber.Ctx) error {
 
 // BulkTransferDepartment transfers multiple tickets to a department
 func (h *AdminHandler) BulkTransferDepartment(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	userID := c.Get("X-User-ID")
 	userName := c.Get("X-User-Name")
@@ -641,7 +696,7 @@ func (h *AdminHandler) BulkTransferDepartment(c *fiber.Ctx) error {
 
 // BulkDeleteTickets deletes multiple tickets
 func (h *AdminHandler) BulkDeleteTickets(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 
 	var req struct {
@@ -662,17 +717,56 @@ func (h *AdminHandler) BulkDeleteTickets(c *fiber.Ctx) error {
 	})
 }
 
+// ===== Search =====
+
+// RebuildSearchIndex reindexes the tenant's tickets and messages
+func (h *AdminHandler) RebuildSearchIndex(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+	if tenantID == "" {
+		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
+	}
+
+	report, err := h.adminUsecase.RebuildSearchIndex(ctx, tenantID)
+	if err != nil {
+		return response.InternalError(c, err.Error())
+	}
+
+	return response.OK(c, report)
+}
+
 // ===== Dashboard & Statistics =====
 
+// ReconcileStats recomputes agent and department counters for the tenant
+func (h *AdminHandler) ReconcileStats(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+	if tenantID == "" {
+		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
+	}
+
+	report, err := h.reconcileUsecase.ReconcileTenant(ctx, tenantID)
+	if err != nil {
+		return response.InternalError(c, err.Error())
+	}
+
+	return response.OK(c, report)
+}
+
 // GetDashboardStats gets dashboard statistics
 func (h *AdminHandler) GetDashboardStats(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	if tenantID == "" {
 		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
 	}
 
-	stats, err := h.adminUsecase.GetDashboardStats(ctx, tenantID)
+	filter, err := parseStatsFilter(c)
+	if err != nil {
+		return invalidFilter(c, h.translator, err)
+	}
+
+	stats, err := h.adminUsecase.GetDashboardStats(ctx, tenantID, filter)
 	if err != nil {
 		return response.InternalError(c, err.Error())
 	}
@@ -682,7 +776,7 @@ func (h *AdminHandler) GetDashboardStats(c *fiber.Ctx) error {
 
 // GetAgentStats gets agent statistics
 func (h *AdminHandler) GetAgentStats(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	agentID := c.Params("id")
 
@@ -696,7 +790,7 @@ func (h *AdminHandler) GetAgentStats(c *fiber.Ctx) error {
 
 // GetDepartmentStats gets department statistics
 func (h *AdminHandler) GetDepartmentStats(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	dept, err := h.adminUsecase.GetDepartmentStats(ctx, id)
@@ -709,7 +803,7 @@ func (h *AdminHandler) GetDepartmentStats(c *fiber.Ctx) error {
 
 // GetSLABreachedTickets gets tickets with breached SLA
 func (h *AdminHandler) GetSLABreachedTickets(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	if tenantID == "" {
 		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
@@ -743,7 +837,7 @@ func (h *AdminHandler) GetSLABreachedTickets(c *fiber.Ctx) error {
 
 // GetTicketsDueSoon gets tickets due soon
 func (h *AdminHandler) GetTicketsDueSoon(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	if tenantID == "" {
 		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
@@ -766,7 +860,7 @@ func (h *AdminHandler) GetTicketsDueSoon(c *fiber.Ctx) error {
 
 // GetUnassignedTickets gets unassigned tickets
 func (h *AdminHandler) GetUnassignedTickets(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	if tenantID == "" {
 		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
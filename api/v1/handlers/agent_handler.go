@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/minisource/go-common/i18n"
+	"github.com/minisource/go-common/response"
+	"github.com/minisource/ticket/internal/usecase"
+)
+
+// AgentHandler handles requests agents make about themselves
+type AgentHandler struct {
+	presenceUsecase *usecase.PresenceUsecase
+	translator      *i18n.Translator
+}
+
+// NewAgentHandler creates a new agent handler
+func NewAgentHandler(presenceUsecase *usecase.PresenceUsecase) *AgentHandler {
+	return &AgentHandler{
+		presenceUsecase: presenceUsecase,
+		translator:      i18n.GetTranslator(),
+	}
+}
+
+// Heartbeat records that the agent's UI is active
+// @Summary Agent heartbeat
+// @Description Marks the agent online and restores the status they had before going idle
+// @Tags Agent
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Success 200 {object} Response{data=models.Agent}
+// @Failure 404 {object} Response
+// @Router /api/v1/agent/heartbeat [post]
+func (h *AgentHandler) Heartbeat(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+	userID := c.Get("X-User-ID")
+
+	agent, err := h.presenceUsecase.Heartbeat(ctx, tenantID, userID)
+	if err != nil {
+		return response.InternalError(c, err.Error())
+	}
+	if agent == nil {
+		return response.NotFound(c, h.translator.Translate(ctx, "agent.not_found", nil))
+	}
+
+	return response.OK(c, agent)
+}
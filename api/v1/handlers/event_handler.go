@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/minisource/go-common/i18n"
+	"github.com/minisource/go-common/response"
+	"github.com/minisource/ticket/internal/middleware"
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/realtime"
+	"github.com/minisource/ticket/internal/usecase"
+)
+
+// EventHandler streams ticket events to consoles over Server-Sent Events
+type EventHandler struct {
+	ticketUsecase *usecase.TicketUsecase
+	hub           realtime.Hub
+	keepAlive     time.Duration
+	translator    *i18n.Translator
+}
+
+// NewEventHandler creates a new event handler; keepAlive is the interval
+// between comment frames that keep idle connections open through proxies
+func NewEventHandler(ticketUsecase *usecase.TicketUsecase, hub realtime.Hub, keepAlive time.Duration) *EventHandler {
+	return &EventHandler{
+		ticketUsecase: ticketUsecase,
+		hub:           hub,
+		keepAlive:     keepAlive,
+		translator:    i18n.GetTranslator(),
+	}
+}
+
+// TicketEvents streams events for a single ticket
+// @Summary Stream ticket events
+// @Description Server-Sent Events for one ticket: messages, status changes, assignment and typing. Customers may follow their own tickets; internal notes are only sent to agents.
+// @Tags Tickets
+// @Produce text/event-stream
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "Ticket ID"
+// @Success 200 {string} string "event stream"
+// @Failure 404 {object} Response
+// @Router /api/v1/tickets/{id}/events [get]
+func (h *EventHandler) TicketEvents(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+	userID := c.Get("X-User-ID")
+	isAgent := middleware.HasRole(c, middleware.AgentRoles...)
+
+	ticket, err := h.ticketUsecase.AuthorizeTicketAccess(ctx, c.Params("id"), tenantID, userID, isAgent)
+	if err != nil {
+		return response.NotFound(c, h.translator.Translate(ctx, "ticket.not_found", nil))
+	}
+
+	ticketID := ticket.ID.Hex()
+	return h.stream(c, tenantID, func(event realtime.Event) bool {
+		return event.TicketID == ticketID && (isAgent || !event.Private)
+	})
+}
+
+// AgentEvents streams events for every ticket in the tenant
+// @Summary Stream tenant ticket events
+// @Description Server-Sent Events for all tickets in the tenant, for agent consoles
+// @Tags Agent
+// @Produce text/event-stream
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Success 200 {string} string "event stream"
+// @Router /api/v1/agent/events [get]
+func (h *EventHandler) AgentEvents(c *fiber.Ctx) error {
+	return h.stream(c, c.Get("X-Tenant-ID"), func(realtime.Event) bool {
+		return true
+	})
+}
+
+// Typing broadcasts a typing indicator on a ticket
+// @Summary Send typing indicator
+// @Tags Tickets
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "Ticket ID"
+// @Param typing body models.TypingRequest true "Typing state"
+// @Success 200 {object} Response
+// @Failure 404 {object} Response
+// @Router /api/v1/tickets/{id}/typing [post]
+func (h *EventHandler) Typing(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+	userID := c.Get("X-User-ID")
+	userName := c.Get("X-User-Name")
+	isAgent := middleware.HasRole(c, middleware.AgentRoles...)
+
+	var req models.TypingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "INVALID_REQUEST", h.translator.Translate(ctx, "error.invalid_request_body", nil))
+	}
+
+	ticket, err := h.ticketUsecase.AuthorizeTicketAccess(ctx, c.Params("id"), tenantID, userID, isAgent)
+	if err != nil {
+		return response.NotFound(c, h.translator.Translate(ctx, "ticket.not_found", nil))
+	}
+
+	if err := h.ticketUsecase.SendTyping(ctx, ticket, req, userID, userName, isAgent); err != nil {
+		return response.InternalError(c, err.Error())
+	}
+
+	return response.OK(c, nil)
+}
+
+// stream writes the tenant's events that pass allow until the client
+// disconnects or the hub closes
+func (h *EventHandler) stream(c *fiber.Ctx, tenantID string, allow func(realtime.Event) bool) error {
+	sub := h.hub.Subscribe(tenantID)
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+
+		keepAlive := time.NewTicker(h.keepAlive)
+		defer keepAlive.Stop()
+
+		// Flush headers right away so clients see the stream open
+		fmt.Fprint(w, ": connected\n\n")
+		if w.Flush() != nil {
+			return
+		}
+
+		for {
+			select {
+			case event, ok := <-sub.Events():
+				if !ok {
+					return
+				}
+				if !allow(event) {
+					continue
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+
+			// A failed flush means the client went away
+			if w.Flush() != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
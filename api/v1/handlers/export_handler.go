@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/minisource/go-common/i18n"
+	"github.com/minisource/go-common/logging"
+	"github.com/minisource/go-common/response"
+	"github.com/minisource/ticket/internal/export"
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/schedule"
+	"github.com/minisource/ticket/internal/usecase"
+)
+
+// ExportHandler handles ticket export requests
+type ExportHandler struct {
+	exportUsecase *usecase.ExportUsecase
+	timezone      *time.Location
+	translator    *i18n.Translator
+	logger        logging.Logger
+}
+
+// NewExportHandler creates a new export handler. timezone is used for
+// tenants that don't send one.
+func NewExportHandler(exportUsecase *usecase.ExportUsecase, timezone string, logger logging.Logger) *ExportHandler {
+	return &ExportHandler{
+		exportUsecase: exportUsecase,
+		timezone:      schedule.Location(timezone),
+		translator:    i18n.GetTranslator(),
+		logger:        logger,
+	}
+}
+
+// ExportTickets exports tickets as a spreadsheet
+// @Summary Export tickets
+// @Description Exports every ticket matching the filters, with one cf.<key> column per custom field in use. Up to EXPORT_SYNC_LIMIT tickets stream in the response; larger exports, or any with async=true, are queued and answered with 202 and the export job, whose downloadUrl is set once it finishes.
+// @Tags Exports
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param X-User-ID header string false "User ID, for assignee:me in q"
+// @Param X-Tenant-Timezone header string false "Tenant IANA timezone"
+// @Param format query string false "csv (default) or xlsx"
+// @Param async query bool false "Always export in the background"
+// @Param timezone query string false "IANA timezone for times, overriding the tenant's"
+// @Param status query string false "Status filter (comma-separated)"
+// @Param priority query string false "Priority filter (comma-separated)"
+// @Param department_id query string false "Department ID"
+// @Param assigned_to query string false "Assignee user ID"
+// @Param created_from query string false "Created at or after (date or RFC 3339)"
+// @Param created_to query string false "Created at or before (date or RFC 3339)"
+// @Param q query string false "Ticket query; takes the same filters as the ticket list"
+// @Param sort_by query string false "Sort fields, e.g. priority:desc,created_at:asc"
+// @Success 200 {file} file
+// @Success 202 {object} Response{data=models.ExportJob}
+// @Failure 400 {object} Response
+// @Router /api/v1/admin/tickets/export [get]
+func (h *ExportHandler) ExportTickets(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	userID := c.Get("X-User-ID")
+
+	filter, err := parseTicketFilter(c)
+	if err != nil {
+		return invalidFilter(c, h.translator, err)
+	}
+
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		return h.invalidExport(c, err)
+	}
+	loc, err := requestLocation(c, h.timezone)
+	if err != nil {
+		return h.invalidExport(c, err)
+	}
+
+	background, err := h.exportUsecase.NeedsBackground(ctx, filter, userID)
+	if err != nil {
+		return listError(c, h.translator, err)
+	}
+
+	if background || c.QueryBool("async") {
+		job, err := h.exportUsecase.QueueTicketExport(ctx, filter, userID, format, loc)
+		if err != nil {
+			if errors.Is(err, usecase.ErrExportTooLarge) {
+				return response.BadRequest(c, "EXPORT_TOO_LARGE", h.translator.Translate(ctx, "export.too_large", nil))
+			}
+			return response.InternalError(c, err.Error())
+		}
+		return response.New().
+			Status(fiber.StatusAccepted).
+			Data(job).
+			Send(c)
+	}
+
+	setAttachment(c, format, "tickets-"+time.Now().In(loc).Format("20060102-150405"))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// Headers are already sent, so a failure can only cut the file short
+		rows, err := h.exportUsecase.WriteTickets(ctx, filter, userID, format, loc, w)
+		if err != nil {
+			h.logger.Error(logging.General, logging.Api, "Ticket export cut short", map[logging.ExtraKey]interface{}{
+				"tenant": filter.TenantID,
+				"filter": filter,
+				"rows":   rows,
+				"error":  err.Error(),
+			})
+		}
+		w.Flush()
+	})
+	return nil
+}
+
+// GetExport gets a background export
+// @Summary Get export
+// @Tags Exports
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "Export job ID"
+// @Success 200 {object} Response{data=models.ExportJob}
+// @Failure 404 {object} Response
+// @Router /api/v1/admin/exports/{id} [get]
+func (h *ExportHandler) GetExport(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+
+	job, err := h.exportUsecase.GetJob(ctx, tenantID, c.Params("id"))
+	if err != nil {
+		return h.exportError(c, err)
+	}
+
+	if job.Status == models.ExportDone {
+		job.DownloadURL = "/api/v1/admin/exports/" + job.ID.Hex() + "/download"
+	}
+	return response.OK(c, job)
+}
+
+// DownloadExport downloads the file of a finished background export
+// @Summary Download export
+// @Tags Exports
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "Export job ID"
+// @Success 200 {file} file
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Router /api/v1/admin/exports/{id}/download [get]
+func (h *ExportHandler) DownloadExport(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+
+	job, file, err := h.exportUsecase.OpenDownload(ctx, tenantID, c.Params("id"))
+	if err != nil {
+		return h.exportError(c, err)
+	}
+
+	c.Attachment(job.FileName)
+	c.Set(fiber.HeaderContentType, export.Format(job.Format).ContentType())
+	// The response closes the file once it is sent; it is read from GridFS,
+	// so any instance can serve it
+	return c.SendStream(file, int(job.Size))
+}
+
+// exportError maps export job errors to responses
+func (h *ExportHandler) exportError(c *fiber.Ctx, err error) error {
+	ctx := c.UserContext()
+
+	switch {
+	case errors.Is(err, usecase.ErrExportNotFound):
+		return response.NotFound(c, h.translator.Translate(ctx, "export.not_found", nil))
+	case errors.Is(err, usecase.ErrExportNotReady):
+		return response.New().
+			Status(fiber.StatusConflict).
+			Error("EXPORT_NOT_READY", h.translator.Translate(ctx, "export.not_ready", nil)).
+			Send(c)
+	}
+
+	return response.InternalError(c, err.Error())
+}
+
+// invalidExport reports export parameters that can't be used
+func (h *ExportHandler) invalidExport(c *fiber.Ctx, err error) error {
+	return response.BadRequest(c, "INVALID_EXPORT", h.translator.Translate(c.UserContext(), "error.invalid_export", map[string]interface{}{
+		"reason": err.Error(),
+	}))
+}
+
+// setAttachment makes the response a file download named name plus the
+// format's extension
+func setAttachment(c *fiber.Ctx, format export.Format, name string) {
+	c.Attachment(name + "." + string(format))
+	c.Set(fiber.HeaderContentType, format.ContentType())
+}
+
+// sendExport responds with rows as a spreadsheet file. It is meant for
+// reports, which are small enough to build in memory.
+func sendExport(c *fiber.Ctx, format export.Format, loc *time.Location, name string, header []interface{}, rows [][]interface{}) error {
+	var buf bytes.Buffer
+	w, err := export.NewWriter(&buf, format, loc)
+	if err != nil {
+		return response.InternalError(c, err.Error())
+	}
+	if err := export.WriteAll(w, header, rows); err != nil {
+		return response.InternalError(c, err.Error())
+	}
+
+	setAttachment(c, format, name)
+	return c.Send(buf.Bytes())
+}
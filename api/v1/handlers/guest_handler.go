@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/minisource/go-common/i18n"
+	"github.com/minisource/go-common/response"
+	"github.com/minisource/ticket/internal/guest"
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/usecase"
+)
+
+// GuestHandler handles guest ticket HTTP requests
+type GuestHandler struct {
+	guestUsecase *usecase.GuestUsecase
+	translator   *i18n.Translator
+}
+
+// NewGuestHandler creates a new guest handler
+func NewGuestHandler(guestUsecase *usecase.GuestUsecase) *GuestHandler {
+	return &GuestHandler{
+		guestUsecase: guestUsecase,
+		translator:   i18n.GetTranslator(),
+	}
+}
+
+// CreateTicket creates a ticket without an account
+// @Summary Create a guest ticket
+// @Description The access link is emailed to the customer; it is never part of the response.
+// @Tags Guest
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param ticket body models.CreateGuestTicketRequest true "Ticket data"
+// @Success 201 {object} Response{data=models.Ticket}
+// @Failure 400 {object} Response
+// @Failure 429 {object} Response
+// @Router /api/v1/guest/tickets [post]
+func (h *GuestHandler) CreateTicket(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	ip := c.IP()
+	userAgent := string(c.Request().Header.UserAgent())
+
+	var req models.CreateGuestTicketRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "INVALID_REQUEST", h.translator.Translate(ctx, "error.invalid_request_body", nil))
+	}
+
+	if req.TenantID == "" {
+		req.TenantID = c.Get("X-Tenant-ID")
+	}
+	if req.TenantID == "" {
+		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
+	}
+
+	ticket, err := h.guestUsecase.CreateTicket(ctx, req, ip, userAgent)
+	if err != nil {
+		return response.BadRequest(c, "CREATE_FAILED", err.Error())
+	}
+
+	return response.Created(c, ticket)
+}
+
+// ResendAccessLink emails a new access link for a guest ticket
+// @Summary Resend guest ticket access link
+// @Tags Guest
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param request body models.ResendGuestLinkRequest true "Ticket number and email"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Router /api/v1/guest/tickets/access-link [post]
+func (h *GuestHandler) ResendAccessLink(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	var req models.ResendGuestLinkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "INVALID_REQUEST", h.translator.Translate(ctx, "error.invalid_request_body", nil))
+	}
+
+	if req.TenantID == "" {
+		req.TenantID = c.Get("X-Tenant-ID")
+	}
+	if req.TenantID == "" {
+		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
+	}
+
+	if err := h.guestUsecase.ResendAccessLink(ctx, req); err != nil {
+		return response.InternalError(c, err.Error())
+	}
+
+	return response.OK(c, map[string]string{"message": h.translator.Translate(ctx, "guest.link_sent", nil)})
+}
+
+// GetTicket gets the ticket a guest token grants access to
+// @Summary Get a guest ticket
+// @Tags Guest
+// @Produce json
+// @Param X-Guest-Token header string true "Guest access token"
+// @Param id path string true "Ticket ID"
+// @Success 200 {object} Response{data=models.Ticket}
+// @Failure 401 {object} Response
+// @Failure 404 {object} Response
+// @Router /api/v1/guest/tickets/{id} [get]
+func (h *GuestHandler) GetTicket(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	claims := c.Locals("guest_claims").(*guest.Claims)
+
+	ticket, err := h.guestUsecase.GetTicket(ctx, claims)
+	if err != nil {
+		return response.NotFound(c, h.translator.Translate(ctx, "ticket.not_found", nil))
+	}
+
+	return response.OK(c, ticket)
+}
+
+// GetMessages gets public messages on a guest ticket
+// @Summary Get guest ticket messages
+// @Tags Guest
+// @Produce json
+// @Param X-Guest-Token header string true "Guest access token"
+// @Param id path string true "Ticket ID"
+// @Param cursor query string false "Keyset cursor from nextCursor; pass it empty for the first page to use cursors instead of page numbers"
+// @Param limit query int false "Page size with cursor (max 100)"
+// @Param include_total query bool false "Count all items with cursor"
+// @Param page query int false "Page number"
+// @Param per_page query int false "Items per page"
+// @Success 200 {object} Response{data=[]models.TicketMessage}
+// @Failure 401 {object} Response
+// @Router /api/v1/guest/tickets/{id}/messages [get]
+func (h *GuestHandler) GetMessages(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	claims := c.Locals("guest_claims").(*guest.Claims)
+
+	page := 1
+	perPage := 50
+	if p := c.Query("page"); p != "" {
+		if pVal, err := strconv.Atoi(p); err == nil {
+			page = pVal
+		}
+	}
+	if pp := c.Query("per_page"); pp != "" {
+		if ppVal, err := strconv.Atoi(pp); err == nil {
+			perPage = ppVal
+		}
+	}
+
+	if page, ok := cursorRequest(c, perPage); ok {
+		result, err := h.guestUsecase.GetMessagesAfter(ctx, claims, page)
+		if errors.Is(err, usecase.ErrInvalidCursor) {
+			return listError(c, h.translator, err)
+		}
+		if err != nil {
+			return response.NotFound(c, h.translator.Translate(ctx, "ticket.not_found", nil))
+		}
+		return response.OK(c, result)
+	}
+
+	messages, total, err := h.guestUsecase.GetMessages(ctx, claims, page, perPage)
+	if err != nil {
+		return response.NotFound(c, h.translator.Translate(ctx, "ticket.not_found", nil))
+	}
+
+	return response.OKWithPagination(c, messages, &response.Pagination{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: int((total + int64(perPage) - 1) / int64(perPage)),
+	})
+}
+
+// AddReply adds a reply to a guest ticket
+// @Summary Reply to a guest ticket
+// @Tags Guest
+// @Accept json
+// @Produce json
+// @Param X-Guest-Token header string true "Guest access token"
+// @Param id path string true "Ticket ID"
+// @Param message body models.CreateMessageRequest true "Message data"
+// @Success 201 {object} Response{data=models.TicketMessage}
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Router /api/v1/guest/tickets/{id}/messages [post]
+func (h *GuestHandler) AddReply(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	claims := c.Locals("guest_claims").(*guest.Claims)
+	ip := c.IP()
+	userAgent := string(c.Request().Header.UserAgent())
+
+	var req models.CreateMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "INVALID_REQUEST", h.translator.Translate(ctx, "error.invalid_request_body", nil))
+	}
+
+	message, err := h.guestUsecase.AddReply(ctx, claims, req, ip, userAgent)
+	if err != nil {
+		return response.BadRequest(c, "ADD_REPLY_FAILED", err.Error())
+	}
+
+	return response.Created(c, message)
+}
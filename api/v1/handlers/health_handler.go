@@ -1,16 +1,56 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/minisource/go-common/i18n"
 	"github.com/minisource/go-common/response"
+	"github.com/minisource/ticket/internal/cache"
 )
 
+// DependencyCheck checks a single dependency for readiness
+type DependencyCheck struct {
+	Name string
+	// Required dependencies make the service not ready when they fail;
+	// optional ones are only reported
+	Required bool
+	Check    func(ctx context.Context) error
+}
+
 // HealthHandler handles health check requests
-type HealthHandler struct{}
+type HealthHandler struct {
+	checks     []DependencyCheck
+	timeout    time.Duration
+	caches     []*cache.Cache
+	draining   atomic.Bool
+	translator *i18n.Translator
+}
 
-// NewHealthHandler creates a new health handler
-func NewHealthHandler() *HealthHandler {
-	return &HealthHandler{}
+// NewHealthHandler creates a new health handler; nil caches are skipped
+func NewHealthHandler(checks []DependencyCheck, timeout time.Duration, caches ...*cache.Cache) *HealthHandler {
+	h := &HealthHandler{
+		checks:     checks,
+		timeout:    timeout,
+		translator: i18n.GetTranslator(),
+	}
+	for _, c := range caches {
+		if c != nil {
+			h.caches = append(h.caches, c)
+		}
+	}
+	return h
+}
+
+// SetDraining marks the service as not ready so load balancers stop
+// routing to it while in-flight requests finish
+func (h *HealthHandler) SetDraining() {
+	h.draining.Store(true)
 }
 
 // Health returns service health status
@@ -20,23 +60,85 @@ func NewHealthHandler() *HealthHandler {
 // @Success 200 {object} HealthResponse
 // @Router /health [get]
 func (h *HealthHandler) Health(c *fiber.Ctx) error {
-	return response.OK(c, map[string]string{
-		"service": "ticket-service",
-		"status":  "healthy",
+	var stats []cache.Stats
+	for _, cc := range h.caches {
+		stats = append(stats, cc.Stats())
+	}
+
+	return response.OK(c, HealthResponse{
+		Service: "ticket-service",
+		Status:  "healthy",
+		Cache:   stats,
 	})
 }
 
 // Ready returns service readiness status
 // @Summary Readiness check
+// @Description Checks MongoDB and configured dependencies; returns 503 when a required one fails or the service is shutting down
 // @Tags Health
 // @Produce json
-// @Success 200 {object} HealthResponse
+// @Success 200 {object} ReadinessResponse
+// @Failure 503 {object} ReadinessResponse
 // @Router /ready [get]
 func (h *HealthHandler) Ready(c *fiber.Ctx) error {
-	return response.OK(c, map[string]string{
-		"service": "ticket-service",
-		"status":  "ready",
-	})
+	if h.draining.Load() {
+		return response.New().
+			Status(fiber.StatusServiceUnavailable).
+			Error("SERVICE_DRAINING", h.translator.Translate(c.UserContext(), "error.draining", nil)).
+			Data(ReadinessResponse{
+				Service: "ticket-service",
+				Status:  "draining",
+			}).
+			Send(c)
+	}
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), h.timeout)
+	defer cancel()
+
+	result := ReadinessResponse{
+		Service: "ticket-service",
+		Status:  "ready",
+		Checks:  make(map[string]DependencyStatus, len(h.checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func(check DependencyCheck) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := check.Check(ctx)
+			status := DependencyStatus{
+				Status:    "up",
+				Required:  check.Required,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				status.Status = "down"
+				status.Error = err.Error()
+			}
+
+			mu.Lock()
+			result.Checks[check.Name] = status
+			if err != nil && check.Required {
+				result.Status = "not_ready"
+			}
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	if result.Status != "ready" {
+		return response.New().
+			Status(fiber.StatusServiceUnavailable).
+			Error("SERVICE_NOT_READY", h.translator.Translate(c.UserContext(), "error.not_ready", nil)).
+			Data(result).
+			Send(c)
+	}
+
+	return response.OK(c, result)
 }
 
 // Live returns service liveness status
@@ -52,8 +154,45 @@ func (h *HealthHandler) Live(c *fiber.Ctx) error {
 	})
 }
 
+// HTTPDependencyCheck returns a check that expects a 2xx response from url
+func HTTPDependencyCheck(client *http.Client, url string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
 // HealthResponse represents health check response for swagger
 type HealthResponse struct {
-	Service string `json:"service"`
-	Status  string `json:"status"`
+	Service string        `json:"service"`
+	Status  string        `json:"status"`
+	Cache   []cache.Stats `json:"cache,omitempty"`
+}
+
+// ReadinessResponse represents readiness check response
+type ReadinessResponse struct {
+	Service string                      `json:"service"`
+	Status  string                      `json:"status"` // ready, not_ready or draining
+	Checks  map[string]DependencyStatus `json:"checks,omitempty"`
+}
+
+// DependencyStatus is the result of a single dependency check
+type DependencyStatus struct {
+	Status    string  `json:"status"` // up or down
+	Required  bool    `json:"required"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
 }
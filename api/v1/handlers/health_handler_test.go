@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReady checks readiness against required and optional dependency checks
+func TestReady(t *testing.T) {
+	up := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name   string
+		checks []DependencyCheck
+		status int
+	}{
+		{name: "no checks", status: fiber.StatusOK},
+		{name: "all up", checks: []DependencyCheck{
+			{Name: "mongodb", Required: true, Check: up},
+			{Name: "redis", Check: up},
+		}, status: fiber.StatusOK},
+		{name: "optional down", checks: []DependencyCheck{
+			{Name: "mongodb", Required: true, Check: up},
+			{Name: "redis", Check: down},
+		}, status: fiber.StatusOK},
+		{name: "required down", checks: []DependencyCheck{
+			{Name: "mongodb", Required: true, Check: down},
+			{Name: "redis", Check: up},
+		}, status: fiber.StatusServiceUnavailable},
+		{name: "required times out", checks: []DependencyCheck{
+			{Name: "mongodb", Required: true, Check: slow},
+		}, status: fiber.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/ready", NewHealthHandler(tt.checks, 50*time.Millisecond).Ready)
+
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/ready", nil))
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+// TestReadyDraining checks that a draining service is not ready without
+// checking its dependencies
+func TestReadyDraining(t *testing.T) {
+	var calls atomic.Int32
+	h := NewHealthHandler([]DependencyCheck{{
+		Name:     "mongodb",
+		Required: true,
+		Check: func(context.Context) error {
+			calls.Add(1)
+			return nil
+		},
+	}}, time.Second)
+
+	app := fiber.New()
+	app.Get("/ready", h.Ready)
+	app.Get("/live", h.Live)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/ready", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+
+	h.SetDraining()
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/ready", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// Liveness is unaffected so the process isn't restarted while draining
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/live", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
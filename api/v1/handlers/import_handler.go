@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/minisource/go-common/i18n"
+	"github.com/minisource/go-common/response"
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/schedule"
+	"github.com/minisource/ticket/internal/usecase"
+)
+
+// ImportHandler handles ticket import requests
+type ImportHandler struct {
+	importUsecase *usecase.ImportUsecase
+	timezone      *time.Location
+	translator    *i18n.Translator
+}
+
+// NewImportHandler creates a new import handler. timezone is used for
+// tenants that don't send one.
+func NewImportHandler(importUsecase *usecase.ImportUsecase, timezone string) *ImportHandler {
+	return &ImportHandler{
+		importUsecase: importUsecase,
+		timezone:      schedule.Location(timezone),
+		translator:    i18n.GetTranslator(),
+	}
+}
+
+// CreateImport uploads a file of tickets to import
+// @Summary Import tickets
+// @Description Queues an import of tickets, with their messages, from another helpdesk's CSV or JSON export. Agents are matched by email, and original times, statuses and, with keep_numbers, ticket numbers are kept. A dry run checks every record without writing anything; run it for real afterwards with POST /admin/imports/{id}/run. Tickets already imported, by their ID in the file, are skipped.
+// @Tags Imports
+// @Accept multipart/form-data
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param X-User-ID header string true "User ID"
+// @Param X-Tenant-Timezone header string false "Tenant IANA timezone"
+// @Param file formData file true "CSV or JSON file"
+// @Param format formData string false "csv or json; taken from the file extension by default"
+// @Param dry_run formData bool false "Only check the file"
+// @Param keep_numbers formData bool false "Keep the file's ticket numbers"
+// @Param timezone formData string false "IANA timezone of times without an offset, overriding the tenant's"
+// @Success 202 {object} Response{data=models.ImportJob}
+// @Failure 400 {object} Response
+// @Failure 503 {object} Response
+// @Router /api/v1/admin/imports [post]
+func (h *ImportHandler) CreateImport(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return h.invalidImport(c, errors.New("file is required"))
+	}
+	format, err := usecase.ParseImportFormat(c.FormValue("format"), header.Filename)
+	if err != nil {
+		return h.importError(c, err)
+	}
+	dryRun, err := formBool(c, "dry_run")
+	if err != nil {
+		return h.invalidImport(c, err)
+	}
+	keepNumbers, err := formBool(c, "keep_numbers")
+	if err != nil {
+		return h.invalidImport(c, err)
+	}
+	timezone := c.FormValue("timezone", c.Get("X-Tenant-Timezone"))
+	if timezone == "" {
+		timezone = h.timezone.String()
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return response.InternalError(c, err.Error())
+	}
+	defer file.Close()
+
+	job, err := h.importUsecase.Create(ctx, &models.ImportJob{
+		TenantID:      c.Get("X-Tenant-ID"),
+		RequestedBy:   c.Get("X-User-ID"),
+		RequestedName: c.Get("X-User-Name"),
+		FileName:      header.Filename,
+		Format:        format,
+		Timezone:      timezone,
+		DryRun:        dryRun,
+		KeepNumbers:   keepNumbers,
+	}, file)
+	if err != nil {
+		return h.importError(c, err)
+	}
+
+	return response.New().
+		Status(fiber.StatusAccepted).
+		Data(job).
+		Send(c)
+}
+
+// ListImports lists the latest imports
+// @Summary List imports
+// @Tags Imports
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Success 200 {object} Response{data=[]models.ImportJob}
+// @Router /api/v1/admin/imports [get]
+func (h *ImportHandler) ListImports(c *fiber.Ctx) error {
+	jobs, err := h.importUsecase.ListJobs(c.UserContext(), c.Get("X-Tenant-ID"))
+	if err != nil {
+		return response.InternalError(c, err.Error())
+	}
+
+	return response.OK(c, jobs)
+}
+
+// GetImport gets an import with its progress and validation report
+// @Summary Get import
+// @Tags Imports
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "Import job ID"
+// @Success 200 {object} Response{data=models.ImportJob}
+// @Failure 404 {object} Response
+// @Router /api/v1/admin/imports/{id} [get]
+func (h *ImportHandler) GetImport(c *fiber.Ctx) error {
+	job, err := h.importUsecase.GetJob(c.UserContext(), c.Get("X-Tenant-ID"), c.Params("id"))
+	if err != nil {
+		return h.importError(c, err)
+	}
+
+	return response.OK(c, job)
+}
+
+// RunImport imports the file of a finished dry run for real
+// @Summary Run a dry-run import
+// @Tags Imports
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "Import job ID"
+// @Success 202 {object} Response{data=models.ImportJob}
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Router /api/v1/admin/imports/{id}/run [post]
+func (h *ImportHandler) RunImport(c *fiber.Ctx) error {
+	job, err := h.importUsecase.Run(c.UserContext(), c.Get("X-Tenant-ID"), c.Params("id"))
+	if err != nil {
+		return h.importError(c, err)
+	}
+
+	return response.New().
+		Status(fiber.StatusAccepted).
+		Data(job).
+		Send(c)
+}
+
+// ResumeImport carries on a failed import after the last record it saved
+// @Summary Resume a failed import
+// @Tags Imports
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "Import job ID"
+// @Success 202 {object} Response{data=models.ImportJob}
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Router /api/v1/admin/imports/{id}/resume [post]
+func (h *ImportHandler) ResumeImport(c *fiber.Ctx) error {
+	job, err := h.importUsecase.Resume(c.UserContext(), c.Get("X-Tenant-ID"), c.Params("id"))
+	if err != nil {
+		return h.importError(c, err)
+	}
+
+	return response.New().
+		Status(fiber.StatusAccepted).
+		Data(job).
+		Send(c)
+}
+
+// importError maps import job errors to responses
+func (h *ImportHandler) importError(c *fiber.Ctx, err error) error {
+	ctx := c.UserContext()
+
+	switch {
+	case errors.Is(err, usecase.ErrImportNotFound):
+		return response.NotFound(c, h.translator.Translate(ctx, "import.not_found", nil))
+	case errors.Is(err, usecase.ErrImportInvalidState):
+		return response.New().
+			Status(fiber.StatusConflict).
+			Error("IMPORT_INVALID_STATE", h.translator.Translate(ctx, "import.invalid_state", nil)).
+			Send(c)
+	case errors.Is(err, usecase.ErrImportDisabled):
+		return response.New().
+			Status(fiber.StatusServiceUnavailable).
+			Error("IMPORT_DISABLED", h.translator.Translate(ctx, "import.disabled", nil)).
+			Send(c)
+	case errors.Is(err, usecase.ErrInvalidImport):
+		return h.invalidImport(c, errors.New(strings.TrimPrefix(err.Error(), usecase.ErrInvalidImport.Error()+": ")))
+	}
+
+	return response.InternalError(c, err.Error())
+}
+
+// invalidImport reports an upload that can't be imported
+func (h *ImportHandler) invalidImport(c *fiber.Ctx, err error) error {
+	return response.BadRequest(c, "INVALID_IMPORT", h.translator.Translate(c.UserContext(), "error.invalid_import", map[string]interface{}{
+		"reason": err.Error(),
+	}))
+}
+
+// formBool reads an optional boolean form field
+func formBool(c *fiber.Ctx, name string) (bool, error) {
+	value := c.FormValue(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.New(name + " must be true or false")
+	}
+	return b, nil
+}
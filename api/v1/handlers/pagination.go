@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/minisource/go-common/i18n"
+	"github.com/minisource/go-common/response"
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/query"
+	"github.com/minisource/ticket/internal/usecase"
+)
+
+// Largest page a cursor or page-numbered request may ask for
+const maxCursorLimit = 100
+
+// cursorRequest reads keyset pagination parameters. Passing cursor, empty
+// for the first page, switches a list from page numbers to cursors; limit
+// sets the page size and include_total=true adds a total count.
+func cursorRequest(c *fiber.Ctx, defaultLimit int) (models.CursorRequest, bool) {
+	if !c.Context().QueryArgs().Has("cursor") {
+		return models.CursorRequest{}, false
+	}
+
+	page := models.CursorRequest{
+		Cursor:       c.Query("cursor"),
+		Limit:        defaultLimit,
+		IncludeTotal: c.QueryBool("include_total"),
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if l, err := strconv.Atoi(limit); err == nil && l > 0 {
+			page.Limit = l
+		}
+	}
+	if page.Limit > maxCursorLimit {
+		page.Limit = maxCursorLimit
+	}
+
+	return page, true
+}
+
+// listError maps errors from filtered and paginated lists to responses
+func listError(c *fiber.Ctx, translator *i18n.Translator, err error) error {
+	ctx := c.UserContext()
+
+	var queryErr *query.Error
+	switch {
+	case errors.As(err, &queryErr):
+		return invalidQuery(c, translator, queryErr)
+	case errors.Is(err, usecase.ErrInvalidSort):
+		return invalidFilter(c, translator, err)
+	case errors.Is(err, usecase.ErrInvalidCursor):
+		return response.BadRequest(c, "INVALID_CURSOR", translator.Translate(ctx, "error.invalid_cursor", nil))
+	}
+
+	return response.InternalError(c, err.Error())
+}
+
+// invalidFilter reports ticket list parameters that can't be applied
+func invalidFilter(c *fiber.Ctx, translator *i18n.Translator, err error) error {
+	return response.BadRequest(c, "INVALID_FILTER", translator.Translate(c.UserContext(), "error.invalid_filter", map[string]interface{}{
+		"reason": err.Error(),
+	}))
+}
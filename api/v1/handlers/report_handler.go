@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/minisource/go-common/i18n"
+	"github.com/minisource/go-common/response"
+	"github.com/minisource/ticket/internal/export"
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/schedule"
+	"github.com/minisource/ticket/internal/usecase"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReportHandler handles reporting requests
+type ReportHandler struct {
+	reportUsecase *usecase.ReportUsecase
+	timezone      *time.Location
+	translator    *i18n.Translator
+}
+
+// NewReportHandler creates a new report handler. timezone is used for
+// tenants that don't send one.
+func NewReportHandler(reportUsecase *usecase.ReportUsecase, timezone string) *ReportHandler {
+	return &ReportHandler{
+		reportUsecase: reportUsecase,
+		timezone:      schedule.Location(timezone),
+		translator:    i18n.GetTranslator(),
+	}
+}
+
+// TicketTrends reports ticket metrics over time
+// @Summary Ticket trends
+// @Description Tickets created, resolved and open, first response and resolution percentiles (minutes), SLA compliance and CSAT per period, optionally split into one series per group. Periods start at midnight in the tenant timezone.
+// @Tags Reports
+// @Produce json
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param X-Tenant-Timezone header string false "Tenant IANA timezone"
+// @Param interval query string false "day (default), week or month"
+// @Param group_by query string false "department, agent, category, channel or priority"
+// @Param from query string false "Start date or RFC 3339 time (default 30 days, 12 weeks or 12 months back)"
+// @Param to query string false "End date (inclusive) or RFC 3339 time (default now)"
+// @Param timezone query string false "IANA timezone, overriding the tenant's"
+// @Param department_id query string false "Department ID"
+// @Param agent_id query string false "Assigned agent ID"
+// @Param format query string false "json (default), or csv or xlsx to download one row per series and period"
+// @Success 200 {object} Response{data=models.TicketTrendReport}
+// @Failure 400 {object} Response
+// @Router /api/v1/admin/reports/tickets [get]
+func (h *ReportHandler) TicketTrends(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+	if tenantID == "" {
+		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
+	}
+
+	format, file, err := reportFormat(c)
+	if err != nil {
+		return h.invalidReport(c, err)
+	}
+
+	req, err := h.parseReportRequest(c)
+	if err != nil {
+		return h.invalidReport(c, err)
+	}
+
+	report, err := h.reportUsecase.TicketTrends(ctx, tenantID, req)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidReport) {
+			return h.invalidReport(c, err)
+		}
+		return response.InternalError(c, err.Error())
+	}
+
+	if file {
+		name := "ticket-trends-" + report.From.In(req.Location).Format("20060102")
+		return sendExport(c, format, req.Location, name, export.TrendHeader(), export.TrendRows(report))
+	}
+	return response.OK(c, report)
+}
+
+// AgentLeaderboard ranks agents by a scorecard metric
+// @Summary Agent leaderboard
+// @Description Agent scorecards for a period, best first: replies, tickets handled and internal note ratio from messages; resolutions and reopen rate from status changes; resolved tickets, SLA compliance, median first response and ratings of assigned tickets
+// @Tags Reports
+// @Produce json
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param from query string false "Start date or RFC 3339 time (default 30 days back)"
+// @Param to query string false "End date (inclusive) or RFC 3339 time (default now)"
+// @Param timezone query string false "IANA timezone for dates, overriding the tenant's"
+// @Param department_id query string false "Only the department's agents"
+// @Param sort_by query string false "resolved (default), handled, first_response, sla_compliance, csat or reopen_rate"
+// @Param limit query int false "Top agents only"
+// @Param format query string false "json (default), or csv or xlsx to download one row per agent"
+// @Success 200 {object} Response{data=models.AgentScorecardReport}
+// @Failure 400 {object} Response
+// @Router /api/v1/admin/reports/agents [get]
+func (h *ReportHandler) AgentLeaderboard(c *fiber.Ctx) error {
+	return h.scorecards(c, "")
+}
+
+// AgentScorecard gets an agent's scorecard
+// @Summary Agent scorecard
+// @Tags Reports
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param agent_id path string true "Agent user ID"
+// @Param from query string false "Start date or RFC 3339 time (default 30 days back)"
+// @Param to query string false "End date (inclusive) or RFC 3339 time (default now)"
+// @Param timezone query string false "IANA timezone for dates, overriding the tenant's"
+// @Success 200 {object} Response{data=models.AgentScorecardReport}
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Router /api/v1/admin/reports/agents/{agent_id} [get]
+func (h *ReportHandler) AgentScorecard(c *fiber.Ctx) error {
+	return h.scorecards(c, c.Params("agent_id"))
+}
+
+// MyScorecard gets the calling agent's scorecard
+// @Summary Own agent scorecard
+// @Tags Reports
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param X-User-ID header string true "User ID"
+// @Param from query string false "Start date or RFC 3339 time (default 30 days back)"
+// @Param to query string false "End date (inclusive) or RFC 3339 time (default now)"
+// @Param timezone query string false "IANA timezone for dates, overriding the tenant's"
+// @Success 200 {object} Response{data=models.AgentScorecardReport}
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Router /api/v1/agent/scorecard [get]
+func (h *ReportHandler) MyScorecard(c *fiber.Ctx) error {
+	userID := c.Get("X-User-ID")
+	if userID == "" {
+		return response.BadRequest(c, "MISSING_USER", h.translator.Translate(c.UserContext(), "error.missing_user_id", nil))
+	}
+	return h.scorecards(c, userID)
+}
+
+// scorecards responds with the leaderboard, or with one agent's scorecard
+func (h *ReportHandler) scorecards(c *fiber.Ctx, agentID string) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+	if tenantID == "" {
+		return response.BadRequest(c, "TENANT_REQUIRED", h.translator.Translate(ctx, "error.tenant_required", nil))
+	}
+
+	format, file, err := reportFormat(c)
+	if err != nil {
+		return h.invalidReport(c, err)
+	}
+
+	req, err := h.parseScorecardRequest(c)
+	if err != nil {
+		return h.invalidReport(c, err)
+	}
+	req.AgentID = agentID
+
+	report, err := h.reportUsecase.AgentScorecards(ctx, tenantID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidReport):
+			return h.invalidReport(c, err)
+		case errors.Is(err, usecase.ErrAgentNotFound):
+			return response.NotFound(c, h.translator.Translate(ctx, "agent.not_found", nil))
+		}
+		return response.InternalError(c, err.Error())
+	}
+
+	if file {
+		name := "agent-scorecards-" + report.From.UTC().Format("20060102")
+		return sendExport(c, format, time.UTC, name, export.ScorecardHeader(), export.ScorecardRows(report))
+	}
+	return response.OK(c, report)
+}
+
+// reportFormat reads the format parameter. file is false for JSON, the
+// default; csv and xlsx download the report as a spreadsheet.
+func reportFormat(c *fiber.Ctx) (format export.Format, file bool, err error) {
+	name := c.Query("format")
+	if name == "" || name == "json" {
+		return "", false, nil
+	}
+	format, err = export.ParseFormat(name)
+	return format, err == nil, err
+}
+
+// parseReportRequest reads report parameters. Dates are days in the report
+// timezone, and a date as the end of the range includes that day.
+func (h *ReportHandler) parseReportRequest(c *fiber.Ctx) (models.ReportRequest, error) {
+	req := models.ReportRequest{
+		Interval: models.ReportInterval(c.Query("interval")),
+		GroupBy:  models.ReportGroupBy(c.Query("group_by")),
+		AgentID:  c.Query("agent_id"),
+	}
+
+	var err error
+	if req.Location, err = h.location(c); err != nil {
+		return req, err
+	}
+
+	if departmentID := c.Query("department_id"); departmentID != "" {
+		if !primitive.IsValidObjectID(departmentID) {
+			return req, fmt.Errorf("department_id %q is not a valid ID", departmentID)
+		}
+		req.DepartmentID = departmentID
+	}
+
+	if req.From, err = reportTime(c, "from", req.Location, false); err != nil {
+		return req, err
+	}
+	if req.To, err = reportTime(c, "to", req.Location, true); err != nil {
+		return req, err
+	}
+
+	return req, nil
+}
+
+// parseScorecardRequest reads scorecard parameters. Dates are days in the
+// report timezone, and a date as the end of the period includes that day.
+func (h *ReportHandler) parseScorecardRequest(c *fiber.Ctx) (models.ScorecardRequest, error) {
+	req := models.ScorecardRequest{
+		SortBy: models.ScorecardSort(c.Query("sort_by")),
+		Limit:  c.QueryInt("limit"),
+	}
+
+	loc, err := h.location(c)
+	if err != nil {
+		return req, err
+	}
+
+	if departmentID := c.Query("department_id"); departmentID != "" {
+		if !primitive.IsValidObjectID(departmentID) {
+			return req, fmt.Errorf("department_id %q is not a valid ID", departmentID)
+		}
+		req.DepartmentID = departmentID
+	}
+
+	if req.From, err = reportTime(c, "from", loc, false); err != nil {
+		return req, err
+	}
+	if req.To, err = reportTime(c, "to", loc, true); err != nil {
+		return req, err
+	}
+
+	return req, nil
+}
+
+// location returns the report timezone
+func (h *ReportHandler) location(c *fiber.Ctx) (*time.Location, error) {
+	return requestLocation(c, h.timezone)
+}
+
+// requestLocation returns the timezone parameter, the tenant's timezone from
+// the gateway or fallback, in that order
+func requestLocation(c *fiber.Ctx, fallback *time.Location) (*time.Location, error) {
+	name := c.Query("timezone", c.Get("X-Tenant-Timezone"))
+	if name == "" {
+		return fallback, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return loc, nil
+}
+
+// reportTime reads an optional RFC 3339 time or a date in loc. A date as the
+// exclusive end of a range moves to the next midnight to include the day.
+func reportTime(c *fiber.Ctx, name string, loc *time.Location, end bool) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be a date (2006-01-02) or RFC 3339 time", name)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// invalidReport reports report parameters that can't be used
+func (h *ReportHandler) invalidReport(c *fiber.Ctx, err error) error {
+	return response.BadRequest(c, "INVALID_REPORT", h.translator.Translate(c.UserContext(), "error.invalid_report", map[string]interface{}{
+		"reason": err.Error(),
+	}))
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/minisource/ticket/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Query parameters prefixed with this filter on custom field values, e.g. cf.plan=pro
+const customFieldParamPrefix = "cf."
+
+// parseTicketFilter reads ticket list filters from the query string.
+// Comma-separated parameters match any of their values.
+func parseTicketFilter(c *fiber.Ctx) (models.TicketFilter, error) {
+	filter := models.TicketFilter{
+		TenantID:     c.Get("X-Tenant-ID"),
+		Page:         1,
+		PerPage:      20,
+		AssignedToID: c.Query("assigned_to"),
+		CustomerID:   c.Query("customer_id"),
+		Search:       c.Query("search"),
+		Query:        c.Query("q"),
+		SortBy:       c.Query("sort_by"),
+		SortOrder:    c.Query("sort_order"),
+	}
+
+	if err := parsePage(c, &filter); err != nil {
+		return filter, err
+	}
+
+	for _, s := range splitParam(c.Query("status")) {
+		filter.Status = append(filter.Status, models.TicketStatus(s))
+	}
+	for _, p := range splitParam(c.Query("priority")) {
+		filter.Priority = append(filter.Priority, models.TicketPriority(p))
+	}
+	for _, t := range splitParam(c.Query("type")) {
+		filter.Type = append(filter.Type, models.TicketType(t))
+	}
+	for _, s := range splitParam(c.Query("source")) {
+		filter.Source = append(filter.Source, models.TicketSource(s))
+	}
+	filter.Tags = splitParam(c.Query("tags"))
+
+	if departmentID := c.Query("department_id"); departmentID != "" {
+		if !primitive.IsValidObjectID(departmentID) {
+			return filter, fmt.Errorf("department_id %q is not a valid ID", departmentID)
+		}
+		filter.DepartmentID = departmentID
+	}
+	if categoryID := c.Query("category_id"); categoryID != "" {
+		if !primitive.IsValidObjectID(categoryID) {
+			return filter, fmt.Errorf("category_id %q is not a valid ID", categoryID)
+		}
+		filter.CategoryID = categoryID
+	}
+
+	var err error
+	if filter.SLABreached, err = boolParam(c, "sla_breached"); err != nil {
+		return filter, err
+	}
+	if filter.Unassigned, err = boolParam(c, "unassigned"); err != nil {
+		return filter, err
+	}
+	if filter.HasRating, err = boolParam(c, "has_rating"); err != nil {
+		return filter, err
+	}
+
+	if filter.CreatedFrom, err = timeParam(c, "created_from", false); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = timeParam(c, "created_to", true); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedFrom, err = timeParam(c, "updated_from", false); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedTo, err = timeParam(c, "updated_to", true); err != nil {
+		return filter, err
+	}
+	if filter.DueBefore, err = timeParam(c, "due_before", false); err != nil {
+		return filter, err
+	}
+
+	for name, value := range c.Queries() {
+		if key, ok := strings.CutPrefix(name, customFieldParamPrefix); ok {
+			if key == "" || strings.ContainsAny(key, ".$") {
+				return filter, fmt.Errorf("%q is not a valid custom field", key)
+			}
+			if filter.CustomFields == nil {
+				filter.CustomFields = map[string]string{}
+			}
+			filter.CustomFields[key] = value
+		}
+	}
+
+	return filter, nil
+}
+
+// parsePage reads page and per_page into filter. per_page is clamped to
+// 1..maxCursorLimit, so page counts never divide by zero.
+func parsePage(c *fiber.Ctx, filter *models.TicketFilter) error {
+	if page := c.Query("page"); page != "" {
+		p, err := strconv.Atoi(page)
+		if err != nil || p < 1 {
+			return fmt.Errorf("page %q must be a number of at least 1", page)
+		}
+		filter.Page = p
+	}
+	if perPage := c.Query("per_page"); perPage != "" {
+		pp, err := strconv.Atoi(perPage)
+		if err != nil {
+			return fmt.Errorf("per_page %q is not a number", perPage)
+		}
+		filter.PerPage = min(max(pp, 1), maxCursorLimit)
+	}
+	return nil
+}
+
+// parseStatsFilter reads ticket statistics filters from the query string
+func parseStatsFilter(c *fiber.Ctx) (models.StatsFilter, error) {
+	filter := models.StatsFilter{AgentID: c.Query("agent_id")}
+
+	if departmentID := c.Query("department_id"); departmentID != "" {
+		if !primitive.IsValidObjectID(departmentID) {
+			return filter, fmt.Errorf("department_id %q is not a valid ID", departmentID)
+		}
+		filter.DepartmentID = departmentID
+	}
+
+	var err error
+	if filter.From, err = timeParam(c, "from", false); err != nil {
+		return filter, err
+	}
+	if filter.To, err = timeParam(c, "to", true); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// splitParam splits a comma-separated parameter, dropping empty values
+func splitParam(value string) []string {
+	if value == "" {
+		return nil
+	}
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// boolParam reads an optional boolean parameter
+func boolParam(c *fiber.Ctx, name string) (*bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false", name)
+	}
+	return &b, nil
+}
+
+// timeParam reads an optional RFC 3339 timestamp or calendar date. A date
+// used as the end of a range covers the whole day.
+func timeParam(c *fiber.Ctx, name string, endOfDay bool) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a date (2006-01-02) or RFC 3339 time", name)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return &t, nil
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/minisource/ticket/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseTicketFilterPage checks page bounds, per_page clamping and that
+// values that aren't numbers are rejected
+func TestParseTicketFilterPage(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		page    int
+		perPage int
+		wantErr bool
+	}{
+		{name: "defaults", query: "", page: 1, perPage: 20},
+		{name: "explicit", query: "page=3&per_page=50", page: 3, perPage: 50},
+		{name: "per page zero", query: "per_page=0", page: 1, perPage: 1},
+		{name: "per page negative", query: "per_page=-5", page: 1, perPage: 1},
+		{name: "per page too large", query: "per_page=5000", page: 1, perPage: maxCursorLimit},
+		{name: "page zero", query: "page=0", wantErr: true},
+		{name: "page negative", query: "page=-1", wantErr: true},
+		{name: "page not a number", query: "page=abc", wantErr: true},
+		{name: "per page not a number", query: "per_page=ten", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filter models.TicketFilter
+			var parseErr error
+
+			app := fiber.New()
+			app.Get("/tickets", func(c *fiber.Ctx) error {
+				filter, parseErr = parseTicketFilter(c)
+				return nil
+			})
+
+			_, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/tickets?"+tt.query, nil))
+			require.NoError(t, err)
+
+			if tt.wantErr {
+				assert.Error(t, parseErr)
+				return
+			}
+			require.NoError(t, parseErr)
+			assert.Equal(t, tt.page, filter.Page)
+			assert.Equal(t, tt.perPage, filter.PerPage)
+		})
+	}
+}
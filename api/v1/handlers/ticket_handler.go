@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/minisource/go-common/i18n"
 	"github.com/minisource/go-common/response"
+	"github.com/minisource/ticket/internal/middleware"
 	"github.com/minisource/ticket/internal/models"
 	"github.com/minisource/ticket/internal/usecase"
 )
@@ -37,7 +39,7 @@ func NewTicketHandler(ticketUsecase *usecase.TicketUsecase) *TicketHandler {
 // @Failure 400 {object} Response
 // @Router /api/v1/tickets [post]
 func (h *TicketHandler) CreateTicket(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	userID := c.Get("X-User-ID")
 	userName := c.Get("X-User-Name")
 	userEmail := c.Get("X-User-Email")
@@ -76,7 +78,7 @@ func (h *TicketHandler) CreateTicket(c *fiber.Ctx) error {
 // @Failure 404 {object} Response
 // @Router /api/v1/tickets/{id} [get]
 func (h *TicketHandler) GetTicket(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 
 	ticket, err := h.ticketUsecase.GetTicket(ctx, id)
@@ -84,7 +86,12 @@ func (h *TicketHandler) GetTicket(c *fiber.Ctx) error {
 		return response.NotFound(c, h.translator.Translate(ctx, "ticket.not_found", nil))
 	}
 
-	return response.OK(c, ticket)
+	// Agents see who else is on the ticket
+	if middleware.HasRole(c, middleware.AgentRoles...) {
+		ticket.Viewers, _ = h.ticketUsecase.ListViewers(ctx, ticket)
+	}
+
+	return h.respondTicket(c, ticket)
 }
 
 // GetTicketByNumber gets a ticket by number
@@ -97,7 +104,7 @@ func (h *TicketHandler) GetTicket(c *fiber.Ctx) error {
 // @Failure 404 {object} Response
 // @Router /api/v1/tickets/number/{number} [get]
 func (h *TicketHandler) GetTicketByNumber(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	number := c.Params("number")
 
@@ -106,75 +113,160 @@ func (h *TicketHandler) GetTicketByNumber(c *fiber.Ctx) error {
 		return response.NotFound(c, h.translator.Translate(ctx, "ticket.not_found", nil))
 	}
 
-	return response.OK(c, ticket)
+	return h.respondTicket(c, ticket)
 }
 
 // ListTickets lists tickets
 // @Summary List tickets
+// @Description Agents can list any tenant ticket; customers only see their own
 // @Tags Tickets
 // @Produce json
 // @Param X-Tenant-ID header string true "Tenant ID"
 // @Param status query string false "Status filter (comma-separated)"
 // @Param priority query string false "Priority filter (comma-separated)"
+// @Param type query string false "Type filter (comma-separated)"
+// @Param source query string false "Source filter (comma-separated)"
+// @Param department_id query string false "Department ID"
+// @Param category_id query string false "Category ID"
+// @Param assigned_to query string false "Assignee user ID"
+// @Param customer_id query string false "Customer user ID (agents only)"
+// @Param tags query string false "Tags, matching any (comma-separated)"
+// @Param sla_breached query bool false "SLA breached"
+// @Param unassigned query bool false "Unassigned"
+// @Param has_rating query bool false "Rated by the customer"
+// @Param created_from query string false "Created at or after (date or RFC 3339)"
+// @Param created_to query string false "Created at or before (date or RFC 3339)"
+// @Param updated_from query string false "Updated at or after (date or RFC 3339)"
+// @Param updated_to query string false "Updated at or before (date or RFC 3339)"
+// @Param due_before query string false "Resolution due before (date or RFC 3339)"
+// @Param search query string false "Full-text search"
+// @Param q query string false "Ticket query, e.g. status:open priority:>=high assignee:me created:>-7d -tag:spam; replaces search"
+// @Param sort_by query string false "Sort fields, e.g. priority:desc,created_at:asc"
+// @Param sort_order query string false "Direction for sort fields without one (asc or desc)"
+// @Param cursor query string false "Keyset cursor from nextCursor; pass it empty for the first page to use cursors instead of page numbers"
+// @Param limit query int false "Page size with cursor (max 100)"
+// @Param include_total query bool false "Count all matches with cursor"
 // @Param page query int false "Page number"
 // @Param per_page query int false "Items per page"
 // @Success 200 {object} Response{data=[]models.Ticket}
+// @Failure 400 {object} Response
 // @Router /api/v1/tickets [get]
 func (h *TicketHandler) ListTickets(c *fiber.Ctx) error {
-	ctx := c.Context()
-	tenantID := c.Get("X-Tenant-ID")
+	ctx := c.UserContext()
+	userID := c.Get("X-User-ID")
+
+	filter, err := parseTicketFilter(c)
+	if err != nil {
+		return invalidFilter(c, h.translator, err)
+	}
+
+	// Customers only ever list their own tickets
+	if !middleware.HasRole(c, middleware.AgentRoles...) {
+		if userID == "" {
+			return response.BadRequest(c, "MISSING_USER", h.translator.Translate(ctx, "error.missing_user_id", nil))
+		}
+		filter.CustomerID = userID
+	}
+
+	if page, ok := cursorRequest(c, filter.PerPage); ok {
+		result, err := h.ticketUsecase.ListTicketsAfter(ctx, filter, userID, page)
+		if err != nil {
+			return listError(c, h.translator, err)
+		}
+		return response.OK(c, result)
+	}
+
+	tickets, total, err := h.ticketUsecase.ListTickets(ctx, filter, userID)
+	if err != nil {
+		return listError(c, h.translator, err)
+	}
+
+	return response.OKWithPagination(c, tickets, &response.Pagination{
+		Page:       filter.Page,
+		PerPage:    filter.PerPage,
+		Total:      total,
+		TotalPages: int((total + int64(filter.PerPage) - 1) / int64(filter.PerPage)),
+	})
+}
+
+// SearchTickets searches tickets and their messages
+// @Summary Search tickets and messages
+// @Description Full-text search over ticket subjects, descriptions and message content, ranked by relevance, with status, priority and department facets. Customers only see their own tickets and never match internal notes.
+// @Tags Tickets
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param X-User-ID header string true "User ID"
+// @Param q query string true "Search query; prefix a word with - to exclude it, suffix it with * to match by prefix"
+// @Param fuzzy query bool false "Also match misspelled words (local backend only)"
+// @Param status query string false "Status filter (comma-separated)"
+// @Param priority query string false "Priority filter (comma-separated)"
+// @Param department_id query string false "Department filter (comma-separated)"
+// @Param page query int false "Page number"
+// @Param per_page query int false "Items per page"
+// @Success 200 {object} Response{data=models.TicketSearchResponse}
+// @Failure 400 {object} Response
+// @Router /api/v1/tickets/search [get]
+func (h *TicketHandler) SearchTickets(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	userID := c.Get("X-User-ID")
 
-	filter := models.TicketFilter{
-		TenantID: tenantID,
+	query := models.TicketSearchQuery{
+		TenantID: c.Get("X-Tenant-ID"),
+		Query:    strings.TrimSpace(c.Query("q")),
 		Page:     1,
 		PerPage:  20,
 	}
+	if query.Query == "" {
+		return response.BadRequest(c, "MISSING_QUERY", h.translator.Translate(ctx, "ticket.search_query_required", nil))
+	}
 
 	if page := c.Query("page"); page != "" {
 		if p, err := strconv.Atoi(page); err == nil {
-			filter.Page = p
+			query.Page = p
 		}
 	}
 	if perPage := c.Query("per_page"); perPage != "" {
 		if pp, err := strconv.Atoi(perPage); err == nil {
-			filter.PerPage = pp
+			query.PerPage = pp
 		}
 	}
+	query.Fuzzy = c.QueryBool("fuzzy")
 	if status := c.Query("status"); status != "" {
-		statuses := strings.Split(status, ",")
-		for _, s := range statuses {
-			filter.Status = append(filter.Status, models.TicketStatus(strings.TrimSpace(s)))
+		for _, s := range strings.Split(status, ",") {
+			query.Status = append(query.Status, models.TicketStatus(strings.TrimSpace(s)))
 		}
 	}
 	if priority := c.Query("priority"); priority != "" {
-		priorities := strings.Split(priority, ",")
-		for _, p := range priorities {
-			filter.Priority = append(filter.Priority, models.TicketPriority(strings.TrimSpace(p)))
+		for _, p := range strings.Split(priority, ",") {
+			query.Priority = append(query.Priority, models.TicketPriority(strings.TrimSpace(p)))
 		}
 	}
 	if departmentID := c.Query("department_id"); departmentID != "" {
-		filter.DepartmentID = departmentID
-	}
-	if categoryID := c.Query("category_id"); categoryID != "" {
-		filter.CategoryID = categoryID
-	}
-	if assignedToID := c.Query("assigned_to"); assignedToID != "" {
-		filter.AssignedToID = assignedToID
+		for _, d := range strings.Split(departmentID, ",") {
+			query.DepartmentID = append(query.DepartmentID, strings.TrimSpace(d))
+		}
 	}
-	if search := c.Query("search"); search != "" {
-		filter.Search = search
+
+	// Agents search every ticket including internal notes; customers only their own
+	if middleware.HasRole(c, middleware.AgentRoles...) {
+		query.IncludePrivate = true
+	} else {
+		if userID == "" {
+			return response.BadRequest(c, "MISSING_USER", h.translator.Translate(ctx, "error.missing_user_id", nil))
+		}
+		query.CustomerID = userID
 	}
 
-	tickets, total, err := h.ticketUsecase.ListTickets(ctx, filter)
+	results, total, err := h.ticketUsecase.SearchTickets(ctx, query)
 	if err != nil {
 		return response.InternalError(c, err.Error())
 	}
 
-	return response.OKWithPagination(c, tickets, &response.Pagination{
-		Page:       filter.Page,
-		PerPage:    filter.PerPage,
+	return response.OKWithPagination(c, results, &response.Pagination{
+		Page:       query.Page,
+		PerPage:    query.PerPage,
 		Total:      total,
-		TotalPages: int((total + int64(filter.PerPage) - 1) / int64(filter.PerPage)),
+		TotalPages: int((total + int64(query.PerPage) - 1) / int64(query.PerPage)),
 	})
 }
 
@@ -189,7 +281,7 @@ func (h *TicketHandler) ListTickets(c *fiber.Ctx) error {
 // @Success 200 {object} Response{data=[]models.Ticket}
 // @Router /api/v1/tickets/my [get]
 func (h *TicketHandler) GetMyTickets(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	userID := c.Get("X-User-ID")
 
@@ -226,13 +318,15 @@ func (h *TicketHandler) GetMyTickets(c *fiber.Ctx) error {
 // @Produce json
 // @Param X-Tenant-ID header string true "Tenant ID"
 // @Param X-User-ID header string true "User ID"
+// @Param If-Match header string false "Ticket version ETag from a previous read"
 // @Param id path string true "Ticket ID"
 // @Param ticket body models.UpdateTicketRequest true "Update data"
 // @Success 200 {object} Response{data=models.Ticket}
 // @Failure 400 {object} Response
+// @Failure 409 {object} Response
 // @Router /api/v1/tickets/{id} [patch]
 func (h *TicketHandler) UpdateTicket(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 	userID := c.Get("X-User-ID")
 	userName := c.Get("X-User-Name")
@@ -241,14 +335,24 @@ func (h *TicketHandler) UpdateTicket(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return response.BadRequest(c, "INVALID_REQUEST", h.translator.Translate(ctx, "error.invalid_request_body", nil))
 	}
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return response.BadRequest(c, "INVALID_IF_MATCH", h.translator.Translate(ctx, "error.invalid_if_match", nil))
+	}
+	if version != nil {
+		req.Version = version
+	}
 
 	// isAgent = false for customer routes
 	ticket, err := h.ticketUsecase.UpdateTicket(ctx, id, req, userID, userName, false)
+	if errors.Is(err, usecase.ErrConflict) {
+		return h.conflict(c)
+	}
 	if err != nil {
 		return response.BadRequest(c, "UPDATE_FAILED", err.Error())
 	}
 
-	return response.OK(c, ticket)
+	return h.respondTicket(c, ticket)
 }
 
 // AddReply adds a reply to a ticket
@@ -264,7 +368,7 @@ func (h *TicketHandler) UpdateTicket(c *fiber.Ctx) error {
 // @Failure 400 {object} Response
 // @Router /api/v1/tickets/{id}/reply [post]
 func (h *TicketHandler) AddReply(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	ticketID := c.Params("id")
 	userID := c.Get("X-User-ID")
 	userName := c.Get("X-User-Name")
@@ -278,6 +382,12 @@ func (h *TicketHandler) AddReply(c *fiber.Ctx) error {
 	}
 
 	message, err := h.ticketUsecase.AddReply(ctx, ticketID, req, userID, userName, userEmail, models.SenderCustomer, ip, userAgent)
+	if errors.Is(err, usecase.ErrNewerMessages) {
+		return response.New().
+			Status(fiber.StatusConflict).
+			Error("NEWER_MESSAGES", h.translator.Translate(ctx, "ticket.newer_messages", nil)).
+			Send(c)
+	}
 	if err != nil {
 		return response.BadRequest(c, "ADD_REPLY_FAILED", err.Error())
 	}
@@ -291,12 +401,15 @@ func (h *TicketHandler) AddReply(c *fiber.Ctx) error {
 // @Produce json
 // @Param X-Tenant-ID header string true "Tenant ID"
 // @Param id path string true "Ticket ID"
+// @Param cursor query string false "Keyset cursor from nextCursor; pass it empty for the first page to use cursors instead of page numbers"
+// @Param limit query int false "Page size with cursor (max 100)"
+// @Param include_total query bool false "Count all items with cursor"
 // @Param page query int false "Page number"
 // @Param per_page query int false "Items per page"
 // @Success 200 {object} Response{data=[]models.TicketMessage}
 // @Router /api/v1/tickets/{id}/messages [get]
 func (h *TicketHandler) GetTicketMessages(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	ticketID := c.Params("id")
 
 	page := 1
@@ -312,6 +425,14 @@ func (h *TicketHandler) GetTicketMessages(c *fiber.Ctx) error {
 		}
 	}
 
+	if page, ok := cursorRequest(c, perPage); ok {
+		result, err := h.ticketUsecase.GetTicketMessagesAfter(ctx, ticketID, false, page)
+		if err != nil {
+			return listError(c, h.translator, err)
+		}
+		return response.OK(c, result)
+	}
+
 	// includePrivate = false for customers
 	messages, total, err := h.ticketUsecase.GetTicketMessages(ctx, ticketID, false, page, perPage)
 	if err != nil {
@@ -332,12 +453,15 @@ func (h *TicketHandler) GetTicketMessages(c *fiber.Ctx) error {
 // @Produce json
 // @Param X-Tenant-ID header string true "Tenant ID"
 // @Param id path string true "Ticket ID"
+// @Param cursor query string false "Keyset cursor from nextCursor; pass it empty for the first page to use cursors instead of page numbers"
+// @Param limit query int false "Page size with cursor (max 100)"
+// @Param include_total query bool false "Count all items with cursor"
 // @Param page query int false "Page number"
 // @Param per_page query int false "Items per page"
 // @Success 200 {object} Response{data=[]models.TicketHistory}
 // @Router /api/v1/tickets/{id}/history [get]
 func (h *TicketHandler) GetTicketHistory(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	ticketID := c.Params("id")
 
 	page := 1
@@ -353,6 +477,14 @@ func (h *TicketHandler) GetTicketHistory(c *fiber.Ctx) error {
 		}
 	}
 
+	if page, ok := cursorRequest(c, perPage); ok {
+		result, err := h.ticketUsecase.GetTicketHistoryAfter(ctx, ticketID, page)
+		if err != nil {
+			return listError(c, h.translator, err)
+		}
+		return response.OK(c, result)
+	}
+
 	history, total, err := h.ticketUsecase.GetTicketHistory(ctx, ticketID, page, perPage)
 	if err != nil {
 		return response.InternalError(c, err.Error())
@@ -378,7 +510,7 @@ func (h *TicketHandler) GetTicketHistory(c *fiber.Ctx) error {
 // @Success 200 {object} Response{data=models.Ticket}
 // @Router /api/v1/tickets/{id}/status [patch]
 func (h *TicketHandler) ChangeStatus(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 	userID := c.Get("X-User-ID")
 	userName := c.Get("X-User-Name")
@@ -390,11 +522,14 @@ func (h *TicketHandler) ChangeStatus(c *fiber.Ctx) error {
 
 	// isAgent = false for customer routes
 	ticket, err := h.ticketUsecase.ChangeStatus(ctx, id, req, userID, userName, false)
+	if errors.Is(err, usecase.ErrConflict) {
+		return h.conflict(c)
+	}
 	if err != nil {
 		return response.BadRequest(c, "STATUS_CHANGE_FAILED", err.Error())
 	}
 
-	return response.OK(c, ticket)
+	return h.respondTicket(c, ticket)
 }
 
 // RateTicket rates a ticket
@@ -409,7 +544,7 @@ func (h *TicketHandler) ChangeStatus(c *fiber.Ctx) error {
 // @Success 200 {object} Response{data=models.Ticket}
 // @Router /api/v1/tickets/{id}/rate [post]
 func (h *TicketHandler) RateTicket(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 	userID := c.Get("X-User-ID")
 
@@ -419,11 +554,14 @@ func (h *TicketHandler) RateTicket(c *fiber.Ctx) error {
 	}
 
 	ticket, err := h.ticketUsecase.RateTicket(ctx, id, req, userID)
+	if errors.Is(err, usecase.ErrConflict) {
+		return h.conflict(c)
+	}
 	if err != nil {
 		return response.BadRequest(c, "RATE_FAILED", err.Error())
 	}
 
-	return response.OK(c, ticket)
+	return h.respondTicket(c, ticket)
 }
 
 // ===== Agent-specific endpoints =====
@@ -438,7 +576,7 @@ func (h *TicketHandler) RateTicket(c *fiber.Ctx) error {
 // @Success 201 {object} Response{data=models.TicketMessage}
 // @Router /api/v1/agent/tickets/{id}/reply [post]
 func (h *TicketHandler) AgentAddReply(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	ticketID := c.Params("id")
 	userID := c.Get("X-User-ID")
 	userName := c.Get("X-User-Name")
@@ -452,6 +590,12 @@ func (h *TicketHandler) AgentAddReply(c *fiber.Ctx) error {
 	}
 
 	message, err := h.ticketUsecase.AddReply(ctx, ticketID, req, userID, userName, userEmail, models.SenderAgent, ip, userAgent)
+	if errors.Is(err, usecase.ErrNewerMessages) {
+		return response.New().
+			Status(fiber.StatusConflict).
+			Error("NEWER_MESSAGES", h.translator.Translate(ctx, "ticket.newer_messages", nil)).
+			Send(c)
+	}
 	if err != nil {
 		return response.BadRequest(c, "ADD_REPLY_FAILED", err.Error())
 	}
@@ -469,7 +613,7 @@ func (h *TicketHandler) AgentAddReply(c *fiber.Ctx) error {
 // @Success 200 {object} Response{data=models.Ticket}
 // @Router /api/v1/agent/tickets/{id}/status [patch]
 func (h *TicketHandler) AgentChangeStatus(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 	userID := c.Get("X-User-ID")
 	userName := c.Get("X-User-Name")
@@ -481,11 +625,14 @@ func (h *TicketHandler) AgentChangeStatus(c *fiber.Ctx) error {
 
 	// isAgent = true for agent routes
 	ticket, err := h.ticketUsecase.ChangeStatus(ctx, id, req, userID, userName, true)
+	if errors.Is(err, usecase.ErrConflict) {
+		return h.conflict(c)
+	}
 	if err != nil {
 		return response.BadRequest(c, "STATUS_CHANGE_FAILED", err.Error())
 	}
 
-	return response.OK(c, ticket)
+	return h.respondTicket(c, ticket)
 }
 
 // AgentUpdateTicket updates a ticket as an agent
@@ -493,12 +640,14 @@ func (h *TicketHandler) AgentChangeStatus(c *fiber.Ctx) error {
 // @Tags Agent
 // @Accept json
 // @Produce json
+// @Param If-Match header string false "Ticket version ETag from a previous read"
 // @Param id path string true "Ticket ID"
 // @Param ticket body models.UpdateTicketRequest true "Update data"
 // @Success 200 {object} Response{data=models.Ticket}
+// @Failure 409 {object} Response
 // @Router /api/v1/agent/tickets/{id} [patch]
 func (h *TicketHandler) AgentUpdateTicket(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 	userID := c.Get("X-User-ID")
 	userName := c.Get("X-User-Name")
@@ -507,14 +656,24 @@ func (h *TicketHandler) AgentUpdateTicket(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return response.BadRequest(c, "INVALID_REQUEST", h.translator.Translate(ctx, "error.invalid_request_body", nil))
 	}
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return response.BadRequest(c, "INVALID_IF_MATCH", h.translator.Translate(ctx, "error.invalid_if_match", nil))
+	}
+	if version != nil {
+		req.Version = version
+	}
 
 	// isAgent = true for agent routes
 	ticket, err := h.ticketUsecase.UpdateTicket(ctx, id, req, userID, userName, true)
+	if errors.Is(err, usecase.ErrConflict) {
+		return h.conflict(c)
+	}
 	if err != nil {
 		return response.BadRequest(c, "UPDATE_FAILED", err.Error())
 	}
 
-	return response.OK(c, ticket)
+	return h.respondTicket(c, ticket)
 }
 
 // AgentAssignTicket assigns a ticket to an agent
@@ -527,7 +686,7 @@ func (h *TicketHandler) AgentUpdateTicket(c *fiber.Ctx) error {
 // @Success 200 {object} Response{data=models.Ticket}
 // @Router /api/v1/agent/tickets/{id}/assign [post]
 func (h *TicketHandler) AgentAssignTicket(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 	userID := c.Get("X-User-ID")
 	userName := c.Get("X-User-Name")
@@ -538,11 +697,81 @@ func (h *TicketHandler) AgentAssignTicket(c *fiber.Ctx) error {
 	}
 
 	ticket, err := h.ticketUsecase.AssignTicket(ctx, id, req, userID, userName)
+	if errors.Is(err, usecase.ErrConflict) {
+		return h.conflict(c)
+	}
 	if err != nil {
 		return response.BadRequest(c, "ASSIGN_FAILED", err.Error())
 	}
 
-	return response.OK(c, ticket)
+	return h.respondTicket(c, ticket)
+}
+
+// AgentClaimNextTicket claims the next ticket from the agent's queue
+// @Summary Claim next ticket from queue
+// @Description Atomically assigns the highest-priority unassigned ticket from the agent's departments
+// @Tags Agent
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Success 200 {object} Response{data=models.Ticket}
+// @Failure 404 {object} Response
+// @Router /api/v1/agent/queue/next [post]
+func (h *TicketHandler) AgentClaimNextTicket(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+	userID := c.Get("X-User-ID")
+	userName := c.Get("X-User-Name")
+
+	ticket, err := h.ticketUsecase.ClaimNextTicket(ctx, tenantID, userID, userName)
+	if err != nil {
+		return response.BadRequest(c, "CLAIM_FAILED", err.Error())
+	}
+	if ticket == nil {
+		return response.NotFound(c, h.translator.Translate(ctx, "ticket.queue_empty", nil))
+	}
+
+	return h.respondTicket(c, ticket)
+}
+
+// AgentUpdatePresence records the agent viewing, replying to or leaving a ticket
+// @Summary Update ticket presence
+// @Description Refresh while the ticket is open; entries expire after the configured TTL. Other agents are notified on the event stream.
+// @Tags Agent
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "Ticket ID"
+// @Param presence body models.TicketPresenceRequest true "Activity"
+// @Success 200 {object} Response{data=[]models.TicketViewer}
+// @Failure 404 {object} Response
+// @Router /api/v1/tickets/{id}/presence [post]
+func (h *TicketHandler) AgentUpdatePresence(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+	userID := c.Get("X-User-ID")
+	userName := c.Get("X-User-Name")
+
+	var req models.TicketPresenceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "INVALID_REQUEST", h.translator.Translate(ctx, "error.invalid_request_body", nil))
+	}
+	switch req.Activity {
+	case models.ViewerViewing, models.ViewerReplying, models.ViewerLeft:
+	default:
+		return response.BadRequest(c, "INVALID_ACTIVITY", h.translator.Translate(ctx, "error.invalid_request_body", nil))
+	}
+
+	ticket, err := h.ticketUsecase.AuthorizeTicketAccess(ctx, c.Params("id"), tenantID, userID, true)
+	if err != nil {
+		return response.NotFound(c, h.translator.Translate(ctx, "ticket.not_found", nil))
+	}
+
+	viewers, err := h.ticketUsecase.UpdatePresence(ctx, ticket, req.Activity, userID, userName)
+	if err != nil {
+		return response.InternalError(c, err.Error())
+	}
+
+	return response.OK(c, viewers)
 }
 
 // AgentTransferTicket transfers a ticket to a department
@@ -555,7 +784,7 @@ func (h *TicketHandler) AgentAssignTicket(c *fiber.Ctx) error {
 // @Success 200 {object} Response{data=models.Ticket}
 // @Router /api/v1/agent/tickets/{id}/transfer [post]
 func (h *TicketHandler) AgentTransferTicket(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 	userID := c.Get("X-User-ID")
 	userName := c.Get("X-User-Name")
@@ -566,11 +795,14 @@ func (h *TicketHandler) AgentTransferTicket(c *fiber.Ctx) error {
 	}
 
 	ticket, err := h.ticketUsecase.TransferTicket(ctx, id, req, userID, userName)
+	if errors.Is(err, usecase.ErrConflict) {
+		return h.conflict(c)
+	}
 	if err != nil {
 		return response.BadRequest(c, "TRANSFER_FAILED", err.Error())
 	}
 
-	return response.OK(c, ticket)
+	return h.respondTicket(c, ticket)
 }
 
 // AgentGetMessages gets messages including private notes
@@ -578,12 +810,15 @@ func (h *TicketHandler) AgentTransferTicket(c *fiber.Ctx) error {
 // @Tags Agent
 // @Produce json
 // @Param id path string true "Ticket ID"
+// @Param cursor query string false "Keyset cursor from nextCursor; pass it empty for the first page to use cursors instead of page numbers"
+// @Param limit query int false "Page size with cursor (max 100)"
+// @Param include_total query bool false "Count all items with cursor"
 // @Param page query int false "Page number"
 // @Param per_page query int false "Items per page"
 // @Success 200 {object} Response{data=[]models.TicketMessage}
 // @Router /api/v1/agent/tickets/{id}/messages [get]
 func (h *TicketHandler) AgentGetMessages(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	ticketID := c.Params("id")
 
 	page := 1
@@ -599,6 +834,14 @@ func (h *TicketHandler) AgentGetMessages(c *fiber.Ctx) error {
 		}
 	}
 
+	if page, ok := cursorRequest(c, perPage); ok {
+		result, err := h.ticketUsecase.GetTicketMessagesAfter(ctx, ticketID, true, page)
+		if err != nil {
+			return listError(c, h.translator, err)
+		}
+		return response.OK(c, result)
+	}
+
 	// includePrivate = true for agents
 	messages, total, err := h.ticketUsecase.GetTicketMessages(ctx, ticketID, true, page, perPage)
 	if err != nil {
@@ -622,7 +865,7 @@ func (h *TicketHandler) AgentGetMessages(c *fiber.Ctx) error {
 // @Success 200 {object} Response{data=[]models.Ticket}
 // @Router /api/v1/agent/tickets/my [get]
 func (h *TicketHandler) AgentGetMyTickets(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	userID := c.Get("X-User-ID")
 
@@ -654,16 +897,35 @@ func (h *TicketHandler) AgentGetMyTickets(c *fiber.Ctx) error {
 
 // GetStats gets ticket statistics
 // @Summary Get ticket statistics
+// @Description Customers only get statistics for their own tickets
 // @Tags Tickets
 // @Produce json
 // @Param X-Tenant-ID header string true "Tenant ID"
+// @Param from query string false "Created at or after (date or RFC 3339)"
+// @Param to query string false "Created at or before (date or RFC 3339)"
+// @Param department_id query string false "Department ID"
+// @Param agent_id query string false "Assigned agent ID"
 // @Success 200 {object} Response{data=models.TicketStats}
+// @Failure 400 {object} Response
 // @Router /api/v1/tickets/stats [get]
 func (h *TicketHandler) GetStats(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
+	userID := c.Get("X-User-ID")
 
-	stats, err := h.ticketUsecase.GetStats(ctx, tenantID)
+	filter, err := parseStatsFilter(c)
+	if err != nil {
+		return invalidFilter(c, h.translator, err)
+	}
+
+	if !middleware.HasRole(c, middleware.AgentRoles...) {
+		if userID == "" {
+			return response.BadRequest(c, "MISSING_USER", h.translator.Translate(ctx, "error.missing_user_id", nil))
+		}
+		filter.CustomerID = userID
+	}
+
+	stats, err := h.ticketUsecase.GetStats(ctx, tenantID, filter)
 	if err != nil {
 		return response.InternalError(c, err.Error())
 	}
@@ -681,7 +943,7 @@ func (h *TicketHandler) GetStats(c *fiber.Ctx) error {
 // @Success 200 {object} Response
 // @Router /api/v1/tickets/{id} [delete]
 func (h *TicketHandler) DeleteTicket(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	id := c.Params("id")
 	userID := c.Get("X-User-ID")
 
@@ -703,7 +965,7 @@ func (h *TicketHandler) DeleteTicket(c *fiber.Ctx) error {
 // @Success 200 {object} Response{data=[]models.Ticket}
 // @Router /api/v1/customers/{customer_id}/tickets [get]
 func (h *TicketHandler) GetCustomerTickets(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	customerID := c.Params("customer_id")
 
@@ -744,7 +1006,7 @@ func (h *TicketHandler) GetCustomerTickets(c *fiber.Ctx) error {
 // @Success 200 {object} Response{data=[]models.Ticket}
 // @Router /api/v1/agents/{agent_id}/tickets [get]
 func (h *TicketHandler) GetAgentTickets(c *fiber.Ctx) error {
-	ctx := c.Context()
+	ctx := c.UserContext()
 	tenantID := c.Get("X-Tenant-ID")
 	agentID := c.Params("agent_id")
 
@@ -774,6 +1036,36 @@ func (h *TicketHandler) GetAgentTickets(c *fiber.Ctx) error {
 	})
 }
 
+// respondTicket sends the ticket with its version as the ETag
+func (h *TicketHandler) respondTicket(c *fiber.Ctx, ticket *models.Ticket) error {
+	c.Set(fiber.HeaderETag, `"`+strconv.FormatInt(ticket.Version, 10)+`"`)
+	return response.OK(c, ticket)
+}
+
+// conflict reports that the ticket changed since the client read it
+func (h *TicketHandler) conflict(c *fiber.Ctx) error {
+	return response.New().
+		Status(fiber.StatusConflict).
+		Error("VERSION_CONFLICT", h.translator.Translate(c.UserContext(), "ticket.version_conflict", nil)).
+		Send(c)
+}
+
+// ifMatchVersion reads the ticket version from If-Match. It returns nil when
+// the header is absent or "*", and ok false when it isn't a version ETag.
+func ifMatchVersion(c *fiber.Ctx) (version *int64, ok bool) {
+	value := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if value == "" || value == "*" {
+		return nil, true
+	}
+
+	value = strings.Trim(strings.TrimPrefix(value, "W/"), `"`)
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &parsed, true
+}
+
 // AssignTicket assigns a ticket to an agent (used by router)
 // Redirects to AgentAssignTicket
 func (h *TicketHandler) AssignTicket(c *fiber.Ctx) error {
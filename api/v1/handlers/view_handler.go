@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/minisource/go-common/i18n"
+	"github.com/minisource/go-common/response"
+	"github.com/minisource/ticket/internal/middleware"
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/query"
+	"github.com/minisource/ticket/internal/usecase"
+)
+
+// ViewHandler handles saved ticket view requests
+type ViewHandler struct {
+	viewUsecase *usecase.ViewUsecase
+	translator  *i18n.Translator
+}
+
+// NewViewHandler creates a new view handler
+func NewViewHandler(viewUsecase *usecase.ViewUsecase) *ViewHandler {
+	return &ViewHandler{
+		viewUsecase: viewUsecase,
+		translator:  i18n.GetTranslator(),
+	}
+}
+
+// CreateView saves a ticket view
+// @Summary Create saved view
+// @Description Saves a ticket query with column and sort preferences. Only admins and supervisors can create shared views.
+// @Tags Views
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param request body models.CreateSavedViewRequest true "View"
+// @Success 201 {object} Response{data=models.SavedView}
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Router /api/v1/agent/views [post]
+func (h *ViewHandler) CreateView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+	userID := c.Get("X-User-ID")
+	userName := c.Get("X-User-Name")
+
+	var req models.CreateSavedViewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "INVALID_REQUEST", h.translator.Translate(ctx, "error.invalid_request_body", nil))
+	}
+
+	view, err := h.viewUsecase.CreateView(ctx, tenantID, userID, userName, canShareViews(c), req)
+	if err != nil {
+		return h.viewError(c, err, "CREATE_FAILED")
+	}
+
+	return response.Created(c, view)
+}
+
+// ListViews lists the user's views and the tenant's shared views
+// @Summary List saved views
+// @Tags Views
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Success 200 {object} Response{data=[]models.SavedView}
+// @Router /api/v1/agent/views [get]
+func (h *ViewHandler) ListViews(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+	userID := c.Get("X-User-ID")
+
+	views, err := h.viewUsecase.ListViews(ctx, tenantID, userID)
+	if err != nil {
+		return response.InternalError(c, err.Error())
+	}
+
+	return response.OK(c, views)
+}
+
+// CountViews counts the tickets in each visible view
+// @Summary Saved view counts
+// @Description Ticket counts for view badges. Counting stops at 1000, flagged by capped.
+// @Tags Views
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Success 200 {object} Response{data=[]models.ViewCount}
+// @Router /api/v1/agent/views/counts [get]
+func (h *ViewHandler) CountViews(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+	userID := c.Get("X-User-ID")
+
+	counts, err := h.viewUsecase.CountViews(ctx, tenantID, userID)
+	if err != nil {
+		return response.InternalError(c, err.Error())
+	}
+
+	return response.OK(c, counts)
+}
+
+// GetView gets a saved view
+// @Summary Get saved view
+// @Tags Views
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "View ID"
+// @Success 200 {object} Response{data=models.SavedView}
+// @Failure 404 {object} Response
+// @Router /api/v1/agent/views/{id} [get]
+func (h *ViewHandler) GetView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+	userID := c.Get("X-User-ID")
+
+	view, err := h.viewUsecase.GetView(ctx, tenantID, userID, c.Params("id"))
+	if err != nil {
+		return h.viewError(c, err, "GET_FAILED")
+	}
+
+	return response.OK(c, view)
+}
+
+// UpdateView updates a saved view
+// @Summary Update saved view
+// @Description Owners can change their own views; shared views can only be changed by admins and supervisors
+// @Tags Views
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "View ID"
+// @Param request body models.UpdateSavedViewRequest true "Changes"
+// @Success 200 {object} Response{data=models.SavedView}
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Router /api/v1/agent/views/{id} [patch]
+func (h *ViewHandler) UpdateView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+	userID := c.Get("X-User-ID")
+
+	var req models.UpdateSavedViewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "INVALID_REQUEST", h.translator.Translate(ctx, "error.invalid_request_body", nil))
+	}
+
+	view, err := h.viewUsecase.UpdateView(ctx, tenantID, userID, canShareViews(c), c.Params("id"), req)
+	if err != nil {
+		return h.viewError(c, err, "UPDATE_FAILED")
+	}
+
+	return response.OK(c, view)
+}
+
+// DeleteView deletes a saved view
+// @Summary Delete saved view
+// @Tags Views
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "View ID"
+// @Success 200 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Router /api/v1/agent/views/{id} [delete]
+func (h *ViewHandler) DeleteView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+	userID := c.Get("X-User-ID")
+
+	if err := h.viewUsecase.DeleteView(ctx, tenantID, userID, canShareViews(c), c.Params("id")); err != nil {
+		return h.viewError(c, err, "DELETE_FAILED")
+	}
+
+	return response.OK(c, map[string]string{"message": h.translator.Translate(ctx, "view.deleted", nil)})
+}
+
+// ListViewTickets lists the tickets in a saved view
+// @Summary List saved view tickets
+// @Description Runs the view's query with its sort, which sort_by and sort_order override
+// @Tags Views
+// @Produce json
+// @Param X-Tenant-ID header string true "Tenant ID"
+// @Param id path string true "View ID"
+// @Param sort_by query string false "Sort field"
+// @Param sort_order query string false "asc or desc"
+// @Param cursor query string false "Keyset cursor from nextCursor; pass it empty for the first page to use cursors instead of page numbers"
+// @Param limit query int false "Page size with cursor (max 100)"
+// @Param include_total query bool false "Count all items with cursor"
+// @Param page query int false "Page number"
+// @Param per_page query int false "Items per page"
+// @Success 200 {object} Response{data=[]models.Ticket}
+// @Failure 404 {object} Response
+// @Router /api/v1/agent/views/{id}/tickets [get]
+func (h *ViewHandler) ListViewTickets(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantID := c.Get("X-Tenant-ID")
+	userID := c.Get("X-User-ID")
+
+	filter := models.TicketFilter{
+		Page:      1,
+		PerPage:   20,
+		SortBy:    c.Query("sort_by"),
+		SortOrder: c.Query("sort_order"),
+	}
+	if err := parsePage(c, &filter); err != nil {
+		return invalidFilter(c, h.translator, err)
+	}
+
+	if page, ok := cursorRequest(c, filter.PerPage); ok {
+		result, err := h.viewUsecase.ListViewTicketsAfter(ctx, tenantID, userID, c.Params("id"), filter, page)
+		if err != nil {
+			return h.viewError(c, err, "LIST_FAILED")
+		}
+		return response.OK(c, result)
+	}
+
+	tickets, total, err := h.viewUsecase.ListViewTickets(ctx, tenantID, userID, c.Params("id"), filter)
+	if err != nil {
+		return h.viewError(c, err, "LIST_FAILED")
+	}
+
+	return response.OKWithPagination(c, tickets, &response.Pagination{
+		Page:       filter.Page,
+		PerPage:    filter.PerPage,
+		Total:      total,
+		TotalPages: int((total + int64(filter.PerPage) - 1) / int64(filter.PerPage)),
+	})
+}
+
+// viewError maps view usecase errors to responses
+func (h *ViewHandler) viewError(c *fiber.Ctx, err error, code string) error {
+	ctx := c.UserContext()
+
+	var queryErr *query.Error
+	switch {
+	case errors.Is(err, usecase.ErrViewNotFound):
+		return response.NotFound(c, h.translator.Translate(ctx, "view.not_found", nil))
+	case errors.Is(err, usecase.ErrViewForbidden):
+		return response.New().
+			Status(fiber.StatusForbidden).
+			Error("VIEW_FORBIDDEN", h.translator.Translate(ctx, "view.forbidden", nil)).
+			Send(c)
+	case errors.As(err, &queryErr), errors.Is(err, usecase.ErrInvalidSort), errors.Is(err, usecase.ErrInvalidCursor):
+		return listError(c, h.translator, err)
+	}
+
+	return response.BadRequest(c, code, err.Error())
+}
+
+// invalidQuery reports a ticket query that doesn't compile
+func invalidQuery(c *fiber.Ctx, translator *i18n.Translator, err *query.Error) error {
+	return response.BadRequest(c, "INVALID_QUERY", translator.Translate(c.UserContext(), "error.invalid_query", map[string]interface{}{
+		"reason": err.Error(),
+	}))
+}
+
+// canShareViews reports whether the user may create and edit shared views
+func canShareViews(c *fiber.Ctx) bool {
+	return middleware.HasRole(c, "admin", "supervisor")
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/minisource/go-common/i18n"
 	"github.com/minisource/go-common/logging"
@@ -14,9 +17,21 @@ import (
 	"github.com/minisource/ticket/api/v1/handlers"
 	"github.com/minisource/ticket/config"
 	_ "github.com/minisource/ticket/docs" // Swagger docs
+	"github.com/minisource/ticket/internal/assignment"
+	"github.com/minisource/ticket/internal/cache"
+	"github.com/minisource/ticket/internal/collision"
 	"github.com/minisource/ticket/internal/database"
+	"github.com/minisource/ticket/internal/guest"
+	"github.com/minisource/ticket/internal/metrics"
+	"github.com/minisource/ticket/internal/middleware"
+	"github.com/minisource/ticket/internal/notifier"
+	"github.com/minisource/ticket/internal/realtime"
 	"github.com/minisource/ticket/internal/repository"
+	"github.com/minisource/ticket/internal/search"
+	"github.com/minisource/ticket/internal/tracing"
 	"github.com/minisource/ticket/internal/usecase"
+	"github.com/minisource/ticket/internal/worker"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // @title Ticket Service API
@@ -45,6 +60,8 @@ import (
 // @tag.description Agent operations for handling tickets
 // @tag.name Admin
 // @tag.description Administrative operations for ticket system
+// @tag.name Guest
+// @tag.description Ticket access for customers without an account
 
 func main() {
 	// Load configuration
@@ -68,6 +85,14 @@ func main() {
 		logger.Warn(logging.General, logging.Startup, "Failed to load locales, using defaults", nil)
 	}
 
+	// Initialize tracing before MongoDB so the driver monitor uses the provider
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Fatal(logging.General, logging.Startup, "Failed to initialize tracing", map[logging.ExtraKey]interface{}{
+			"error": err.Error(),
+		})
+	}
+
 	// Initialize MongoDB
 	db, err := database.NewMongoDB(cfg.MongoDB)
 	if err != nil {
@@ -92,15 +117,115 @@ func main() {
 
 	logger.Info(logging.General, logging.Startup, "MongoDB connected successfully", nil)
 
+	// Readiness checks; optional ones are reported but don't fail /ready
+	healthChecks := []handlers.DependencyCheck{
+		{Name: "mongodb", Required: true, Check: db.Ping},
+	}
+
+	// Initialize Redis; it backs rate limits, the lookup cache, event fan-out and ticket viewers across instances
+	var rateLimits middleware.RateLimitStore
+	var cacheStore cache.Store = cache.NewMemoryStore()
+	var hub realtime.Hub = realtime.NewMemoryHub(cfg.Realtime.Buffer)
+	var viewerStore collision.Store = collision.NewMemoryStore()
+	if cfg.Redis.Enabled {
+		redisClient, err := database.NewRedis(cfg.Redis)
+		if err != nil {
+			logger.Fatal(logging.General, logging.Startup, "Failed to connect to Redis", map[logging.ExtraKey]interface{}{
+				"error": err.Error(),
+			})
+		}
+		defer func() {
+			if err := redisClient.Close(); err != nil {
+				logger.Error(logging.General, logging.Startup, "Failed to close Redis", map[logging.ExtraKey]interface{}{
+					"error": err.Error(),
+				})
+			}
+		}()
+
+		rateLimits = middleware.NewRedisRateLimitStore(redisClient)
+		if cfg.Realtime.Enabled {
+			hub = realtime.NewRedisHub(redisClient, cfg.Realtime.Buffer)
+		}
+		viewerStore = collision.NewRedisStore(redisClient)
+
+		cacheStore = cache.NewRedisStore(redisClient)
+
+		// Rate limits fail open and the cache falls back to MongoDB, so Redis is optional
+		healthChecks = append(healthChecks, handlers.DependencyCheck{
+			Name: "redis",
+			Check: func(ctx context.Context) error {
+				return redisClient.Ping(ctx).Err()
+			},
+		})
+
+		logger.Info(logging.General, logging.Startup, "Redis connected successfully", nil)
+	} else {
+		rateLimits = middleware.NewMemoryRateLimitStore()
+	}
+	defer rateLimits.Close()
+
+	// Without Redis the lookup cache is per instance; REDIS_CACHE_TTL bounds how stale it gets
+	departmentCache := cache.New(cacheStore, "departments", cfg.Redis.CacheTTL)
+	categoryCache := cache.New(cacheStore, "categories", cfg.Redis.CacheTTL)
+	slaCache := cache.New(cacheStore, "sla_policies", cfg.Redis.CacheTTL)
+
+	if cfg.Health.CheckAuth {
+		healthChecks = append(healthChecks, handlers.DependencyCheck{
+			Name:  "auth",
+			Check: handlers.HTTPDependencyCheck(http.DefaultClient, strings.TrimRight(cfg.Auth.ServiceURL, "/")+"/health"),
+		})
+	}
+
 	// Initialize repositories
 	ticketRepo := repository.NewTicketRepository(db)
 	messageRepo := repository.NewMessageRepository(db)
 	historyRepo := repository.NewHistoryRepository(db)
-	departmentRepo := repository.NewDepartmentRepository(db)
-	categoryRepo := repository.NewCategoryRepository(db)
+	departmentRepo := repository.NewDepartmentRepository(db).WithCache(departmentCache)
+	categoryRepo := repository.NewCategoryRepository(db).WithCache(categoryCache)
 	agentRepo := repository.NewAgentRepository(db)
-	slaRepo := repository.NewSLAPolicyRepository(db)
+	if cfg.Presence.Enabled {
+		agentRepo.WithStaleAfter(cfg.Presence.AwayAfter)
+	}
+	slaRepo := repository.NewSLAPolicyRepository(db).WithCache(slaCache)
 	cannedRepo := repository.NewCannedResponseRepository(db)
+	viewRepo := repository.NewSavedViewRepository(db)
+	reportRepo := repository.NewReportRepository(db)
+	exportRepo := repository.NewExportRepository(db)
+	exportFiles := repository.NewFileRepository(db, database.BucketExports)
+	importRepo := repository.NewImportRepository(db)
+	importFiles := repository.NewFileRepository(db, database.BucketImports)
+	leaseRepo := repository.NewLeaseRepository(db)
+
+	// Search index; the local backend is kept in sync by the usecases as tickets and messages change
+	var searchIndex search.Index = search.NewMongoIndex(ticketRepo, messageRepo)
+	if cfg.Search.Backend == search.BackendLocal {
+		// Each instance would only see its own changes
+		if cfg.Redis.Enabled {
+			logger.Fatal(logging.General, logging.Startup, "The local search backend can't run with Redis enabled; use SEARCH_BACKEND=mongo across instances", nil)
+		}
+		localIndex, err := search.OpenLocalIndex(cfg.Search.IndexPath)
+		if err != nil {
+			logger.Fatal(logging.General, logging.Startup, "Failed to open search index", map[logging.ExtraKey]interface{}{
+				"error": err.Error(),
+			})
+		}
+		searchIndex = localIndex
+	}
+	defer func() {
+		if err := searchIndex.Close(); err != nil {
+			logger.Error(logging.General, logging.Startup, "Failed to close search index", map[logging.ExtraKey]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}()
+
+	// Register business and cache metrics; they are read on each scrape
+	if cfg.Metrics.Enabled {
+		prometheus.MustRegister(
+			metrics.NewBusinessCollector(ticketRepo, agentRepo, cfg.Metrics.ScrapeTimeout),
+			metrics.NewCacheCollector(departmentCache, categoryCache, slaCache),
+		)
+	}
 
 	// Initialize usecases
 	ticketUsecase := usecase.NewTicketUsecase(
@@ -111,6 +236,10 @@ func main() {
 		categoryRepo,
 		agentRepo,
 		slaRepo,
+		assignment.NewAssigner(departmentRepo),
+		hub,
+		collision.NewTracker(viewerStore, cfg.Collision.TTL),
+		searchIndex,
 		cfg,
 	)
 
@@ -136,16 +265,73 @@ func main() {
 		agentRepo,
 		slaRepo,
 		cannedRepo,
+		searchIndex,
 		cfg,
 	)
 
+	reconcileUsecase := usecase.NewReconcileUsecase(
+		ticketRepo,
+		departmentRepo,
+		agentRepo,
+		leaseRepo,
+	)
+
+	scheduleUsecase := usecase.NewScheduleUsecase(
+		agentRepo,
+		ticketRepo,
+		historyRepo,
+	)
+
+	presenceUsecase := usecase.NewPresenceUsecase(
+		agentRepo,
+		cfg.Presence.AwayAfter,
+		cfg.Presence.OfflineAfter,
+	)
+
+	guestTokens := guest.NewTokenManager(cfg.Guest.TokenSecret, cfg.Guest.TokenTTL)
+	if cfg.Guest.Enabled && cfg.Guest.TokenSecret == "" {
+		logger.Fatal(logging.General, logging.Startup, "GUEST_TOKEN_SECRET is required when guest tickets are enabled", nil)
+	}
+
+	guestUsecase := usecase.NewGuestUsecase(
+		ticketUsecase,
+		ticketRepo,
+		guestTokens,
+		notifier.NewClient(cfg.Notifier),
+		cfg,
+	)
+
+	viewUsecase := usecase.NewViewUsecase(viewRepo, ticketRepo)
+	reportUsecase := usecase.NewReportUsecase(reportRepo, agentRepo)
+	exportUsecase := usecase.NewExportUsecase(ticketRepo, exportRepo, exportFiles, cfg.Export)
+	importUsecase := usecase.NewImportUsecase(
+		ticketRepo,
+		messageRepo,
+		historyRepo,
+		agentRepo,
+		departmentRepo,
+		categoryRepo,
+		importRepo,
+		importFiles,
+		reconcileUsecase,
+		searchIndex,
+		cfg.Import,
+	)
+
 	// Initialize handlers
 	ticketHandler := handlers.NewTicketHandler(ticketUsecase)
-	adminHandler := handlers.NewAdminHandler(adminUsecase, departmentUsecase, categoryUsecase)
-	healthHandler := handlers.NewHealthHandler()
+	adminHandler := handlers.NewAdminHandler(adminUsecase, departmentUsecase, categoryUsecase, reconcileUsecase, scheduleUsecase)
+	healthHandler := handlers.NewHealthHandler(healthChecks, cfg.Health.CheckTimeout, departmentCache, categoryCache, slaCache)
+	guestHandler := handlers.NewGuestHandler(guestUsecase)
+	agentHandler := handlers.NewAgentHandler(presenceUsecase)
+	eventHandler := handlers.NewEventHandler(ticketUsecase, hub, cfg.Realtime.KeepAlive)
+	viewHandler := handlers.NewViewHandler(viewUsecase)
+	reportHandler := handlers.NewReportHandler(reportUsecase, cfg.Report.Timezone)
+	exportHandler := handlers.NewExportHandler(exportUsecase, cfg.Report.Timezone, logger)
+	importHandler := handlers.NewImportHandler(importUsecase, cfg.Report.Timezone)
 
 	// Initialize router
-	r := router.NewRouter(cfg, logger, ticketHandler, adminHandler, healthHandler)
+	r := router.NewRouter(cfg, logger, ticketHandler, adminHandler, healthHandler, guestHandler, agentHandler, eventHandler, viewHandler, reportHandler, exportHandler, importHandler, guestTokens, rateLimits)
 	app := r.Setup()
 
 	// Start server in goroutine
@@ -157,6 +343,29 @@ func main() {
 		}
 	}()
 
+	// Background workers stop when the server shuts down
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	if cfg.Reconcile.Enabled {
+		worker.NewReconciler(reconcileUsecase, cfg.Reconcile.Interval, cfg.Reconcile.Timeout, logger).Start(workerCtx)
+	}
+	if cfg.Schedule.Enabled {
+		worker.NewShiftScheduler(scheduleUsecase, cfg.Schedule.Interval, logger).Start(workerCtx)
+	}
+	if cfg.Search.Backend == search.BackendLocal {
+		worker.NewSearchFlusher(searchIndex, cfg.Search.FlushInterval, logger).Start(workerCtx)
+	}
+	if cfg.Presence.Enabled {
+		worker.NewPresenceSweeper(presenceUsecase, cfg.Presence.SweepInterval, logger).Start(workerCtx)
+	}
+	if cfg.Export.Enabled {
+		worker.NewExporter(exportUsecase, cfg.Export.Interval, logger).Start(workerCtx)
+	}
+	if cfg.Import.Enabled {
+		worker.NewImporter(importUsecase, cfg.Import.Interval, logger).Start(workerCtx)
+	}
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -164,6 +373,14 @@ func main() {
 
 	logger.Info(logging.General, logging.Startup, "Shutting down server...", nil)
 
+	// Report not-ready first so load balancers drain traffic before we stop accepting it
+	healthHandler.SetDraining()
+	stopWorkers()
+
+	// End open event streams so they don't hold up shutdown
+	_ = hub.Close()
+	time.Sleep(cfg.Server.DrainDelay)
+
 	// Graceful shutdown
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer shutdownCancel()
@@ -174,5 +391,12 @@ func main() {
 		})
 	}
 
+	// Flush pending spans
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error(logging.General, logging.Startup, "Failed to flush traces", map[logging.ExtraKey]interface{}{
+			"error": err.Error(),
+		})
+	}
+
 	logger.Info(logging.General, logging.Startup, "Server exited", nil)
 }
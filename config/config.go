@@ -11,14 +11,28 @@ import (
 
 // Config holds all configuration for the ticket service
 type Config struct {
-	Server   ServerConfig
-	MongoDB  MongoDBConfig
-	Redis    RedisConfig
-	Auth     AuthConfig
-	Notifier NotifierConfig
-	SLA      SLAConfig
-	Ticket   TicketConfig
-	Logging  LoggingConfig
+	Server    ServerConfig
+	MongoDB   MongoDBConfig
+	Redis     RedisConfig
+	Auth      AuthConfig
+	Notifier  NotifierConfig
+	SLA       SLAConfig
+	Ticket    TicketConfig
+	Guest     GuestConfig
+	RateLimit RateLimitConfig
+	Reconcile ReconcileConfig
+	Schedule  ScheduleConfig
+	Presence  PresenceConfig
+	Realtime  RealtimeConfig
+	Collision CollisionConfig
+	Search    SearchConfig
+	Report    ReportConfig
+	Export    ExportConfig
+	Import    ImportConfig
+	Health    HealthConfig
+	Metrics   MetricsConfig
+	Tracing   TracingConfig
+	Logging   LoggingConfig
 }
 
 // ServerConfig holds server configuration
@@ -28,6 +42,9 @@ type ServerConfig struct {
 	ReadTimeout     time.Duration
 	WriteTimeout    time.Duration
 	ShutdownTimeout time.Duration
+	DrainDelay      time.Duration // Time to report not-ready before shutting down
+	ProxyHeader     string        // Header a proxy sets to the client IP, e.g. X-Real-IP; empty uses the peer address
+	TrustedProxies  []string      // Proxy IPs or CIDRs whose ProxyHeader is believed; empty trusts every peer
 }
 
 // MongoDBConfig holds MongoDB configuration
@@ -41,10 +58,12 @@ type MongoDBConfig struct {
 
 // RedisConfig holds Redis configuration for caching
 type RedisConfig struct {
+	Enabled  bool
 	Host     string
 	Port     int
 	Password string
 	DB       int
+	CacheTTL time.Duration
 }
 
 // AuthConfig holds auth service configuration
@@ -89,6 +108,105 @@ type TicketConfig struct {
 	RateLimitPerMinute      int
 }
 
+// GuestConfig holds anonymous guest ticket configuration
+type GuestConfig struct {
+	Enabled          bool
+	TokenSecret      string
+	TokenTTL         time.Duration
+	PortalURL        string
+	RateLimitPerHour int
+	MaxLinks         int
+}
+
+// RateLimitConfig holds request rate limit configuration
+type RateLimitConfig struct {
+	Enabled         bool
+	TenantPerMinute int
+	UserPerMinute   int
+}
+
+// ReconcileConfig holds the counter reconciliation worker configuration
+type ReconcileConfig struct {
+	Enabled  bool
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+// ScheduleConfig holds the agent shift scheduler configuration
+type ScheduleConfig struct {
+	Enabled  bool
+	Interval time.Duration // How often shift boundaries and leave are checked
+}
+
+// PresenceConfig holds agent heartbeat and idle sweeper configuration
+type PresenceConfig struct {
+	Enabled       bool
+	AwayAfter     time.Duration // Idle time before an agent is marked away and skipped by auto-assignment
+	OfflineAfter  time.Duration // Idle time before an agent is marked offline
+	SweepInterval time.Duration
+}
+
+// RealtimeConfig holds event streaming configuration.
+// Events go over Redis pub/sub when Redis is enabled, otherwise in process.
+type RealtimeConfig struct {
+	Enabled   bool
+	KeepAlive time.Duration // Interval between keep-alive comments on idle streams
+	Buffer    int           // Events queued per connection before dropping
+}
+
+// CollisionConfig holds ticket viewer tracking configuration
+type CollisionConfig struct {
+	TTL time.Duration // How long a viewing/replying entry lasts without a refresh
+}
+
+// SearchConfig holds ticket search configuration
+type SearchConfig struct {
+	Backend       string        // mongo, or local for the embedded index
+	IndexPath     string        // Snapshot file of the local index
+	FlushInterval time.Duration // How often local index changes are written to disk
+}
+
+// ReportConfig holds reporting configuration
+type ReportConfig struct {
+	Timezone string // Default for tenants that don't send X-Tenant-Timezone
+}
+
+// ExportConfig holds ticket export configuration
+type ExportConfig struct {
+	Enabled   bool          // Runs the worker for exports too large to stream in the request
+	SyncLimit int           // Exports of more tickets than this run in the background
+	Interval  time.Duration // How often the worker checks for queued exports
+	Retention time.Duration // How long finished exports can be downloaded
+}
+
+// ImportConfig holds ticket import configuration
+type ImportConfig struct {
+	Enabled  bool          // Runs the worker that imports uploaded files
+	MaxSize  int64         // Largest file accepted, in bytes
+	Interval time.Duration // How often the worker checks for queued imports
+}
+
+// HealthConfig holds readiness check configuration
+type HealthConfig struct {
+	CheckTimeout time.Duration
+	CheckAuth    bool // Report auth service reachability on /ready
+}
+
+// MetricsConfig holds Prometheus metrics configuration
+type MetricsConfig struct {
+	Enabled       bool
+	ScrapeTimeout time.Duration
+}
+
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	Enabled     bool
+	ServiceName string
+	Endpoint    string // OTLP/HTTP collector host:port
+	Insecure    bool
+	SampleRatio float64
+}
+
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
 	Level  string
@@ -106,6 +224,9 @@ func Load() (*Config, error) {
 			ReadTimeout:     getDuration("SERVER_READ_TIMEOUT", 30*time.Second),
 			WriteTimeout:    getDuration("SERVER_WRITE_TIMEOUT", 30*time.Second),
 			ShutdownTimeout: getDuration("SERVER_SHUTDOWN_TIMEOUT", 30*time.Second),
+			DrainDelay:      getDuration("SERVER_DRAIN_DELAY", 5*time.Second),
+			ProxyHeader:     getEnv("SERVER_PROXY_HEADER", ""),
+			TrustedProxies:  getEnvAsSlice("SERVER_TRUSTED_PROXIES", nil),
 		},
 		MongoDB: MongoDBConfig{
 			URI:             getEnv("MONGODB_URI", "mongodb://localhost:27017"),
@@ -115,10 +236,12 @@ func Load() (*Config, error) {
 			MaxConnIdleTime: getDuration("MONGODB_MAX_CONN_IDLE_TIME", 30*time.Minute),
 		},
 		Redis: RedisConfig{
+			Enabled:  getEnvAsBool("REDIS_ENABLED", false),
 			Host:     getEnv("REDIS_HOST", "localhost"),
 			Port:     getEnvAsInt("REDIS_PORT", 6379),
 			Password: getEnv("REDIS_PASSWORD", ""),
 			DB:       getEnvAsInt("REDIS_DB", 3),
+			CacheTTL: getDuration("REDIS_CACHE_TTL", 5*time.Minute),
 		},
 		Auth: AuthConfig{
 			ServiceURL:        getEnv("AUTH_SERVICE_URL", "http://localhost:5001"),
@@ -154,6 +277,76 @@ func Load() (*Config, error) {
 			AllowCustomerClose:      getEnvAsBool("TICKET_ALLOW_CUSTOMER_CLOSE", true),
 			RateLimitPerMinute:      getEnvAsInt("TICKET_RATE_LIMIT_PER_MINUTE", 10),
 		},
+		Guest: GuestConfig{
+			Enabled:          getEnvAsBool("GUEST_TICKETS_ENABLED", false),
+			TokenSecret:      getEnv("GUEST_TOKEN_SECRET", ""),
+			TokenTTL:         getDuration("GUEST_TOKEN_TTL", 30*24*time.Hour),
+			PortalURL:        getEnv("GUEST_PORTAL_URL", "http://localhost:3000/support/tickets"),
+			RateLimitPerHour: getEnvAsInt("GUEST_RATE_LIMIT_PER_HOUR", 5),
+			MaxLinks:         getEnvAsInt("GUEST_MAX_LINKS", 3),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:         getEnvAsBool("RATE_LIMIT_ENABLED", true),
+			TenantPerMinute: getEnvAsInt("RATE_LIMIT_TENANT_PER_MINUTE", 1000),
+			UserPerMinute:   getEnvAsInt("RATE_LIMIT_USER_PER_MINUTE", 120),
+		},
+		Reconcile: ReconcileConfig{
+			Enabled:  getEnvAsBool("RECONCILE_ENABLED", true),
+			Interval: getDuration("RECONCILE_INTERVAL", time.Hour),
+			Timeout:  getDuration("RECONCILE_TIMEOUT", 5*time.Minute),
+		},
+		Schedule: ScheduleConfig{
+			Enabled:  getEnvAsBool("SCHEDULE_ENABLED", true),
+			Interval: getDuration("SCHEDULE_INTERVAL", time.Minute),
+		},
+		Presence: PresenceConfig{
+			Enabled:       getEnvAsBool("PRESENCE_ENABLED", true),
+			AwayAfter:     getDuration("PRESENCE_AWAY_AFTER", 5*time.Minute),
+			OfflineAfter:  getDuration("PRESENCE_OFFLINE_AFTER", 30*time.Minute),
+			SweepInterval: getDuration("PRESENCE_SWEEP_INTERVAL", time.Minute),
+		},
+		Realtime: RealtimeConfig{
+			Enabled:   getEnvAsBool("REALTIME_ENABLED", true),
+			KeepAlive: getDuration("REALTIME_KEEPALIVE", 25*time.Second),
+			Buffer:    getEnvAsInt("REALTIME_BUFFER", 64),
+		},
+		Collision: CollisionConfig{
+			TTL: getDuration("COLLISION_TTL", 30*time.Second),
+		},
+		Search: SearchConfig{
+			Backend:       getEnv("SEARCH_BACKEND", "mongo"),
+			IndexPath:     getEnv("SEARCH_INDEX_PATH", "data/search.idx"),
+			FlushInterval: getDuration("SEARCH_FLUSH_INTERVAL", 30*time.Second),
+		},
+		Report: ReportConfig{
+			Timezone: getEnv("REPORT_TIMEZONE", "UTC"),
+		},
+		Export: ExportConfig{
+			Enabled:   getEnvAsBool("EXPORT_ENABLED", true),
+			SyncLimit: getEnvAsInt("EXPORT_SYNC_LIMIT", 5000),
+			Interval:  getDuration("EXPORT_INTERVAL", 5*time.Second),
+			Retention: getDuration("EXPORT_RETENTION", 24*time.Hour),
+		},
+		Import: ImportConfig{
+			Enabled:  getEnvAsBool("IMPORT_ENABLED", true),
+			MaxSize:  int64(getEnvAsInt("IMPORT_MAX_SIZE_MB", 100)) << 20,
+			Interval: getDuration("IMPORT_INTERVAL", 5*time.Second),
+		},
+		Health: HealthConfig{
+			CheckTimeout: getDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+			CheckAuth:    getEnvAsBool("HEALTH_CHECK_AUTH", false),
+		},
+		Metrics: MetricsConfig{
+			Enabled:       getEnvAsBool("METRICS_ENABLED", true),
+			ScrapeTimeout: getDuration("METRICS_SCRAPE_TIMEOUT", 5*time.Second),
+		},
+		Tracing: TracingConfig{
+			Enabled:     getEnvAsBool("TRACING_ENABLED", false),
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "ticket-service"),
+			Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4318"),
+			Insecure:    getEnvAsBool("OTEL_EXPORTER_OTLP_INSECURE", true),
+			SampleRatio: getEnvAsFloat("TRACING_SAMPLE_RATIO", 1.0),
+		},
 		Logging: LoggingConfig{
 			Level:  getEnv("LOG_LEVEL", "info"),
 			Format: getEnv("LOG_FORMAT", "json"),
@@ -188,6 +381,15 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvAsSlice(key string, defaultValue []string) []string {
 	if value := os.Getenv(key); value != "" {
 		return strings.Split(value, ",")
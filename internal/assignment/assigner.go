@@ -0,0 +1,115 @@
+package assignment
+
+import (
+	"context"
+	"strings"
+
+	"github.com/minisource/ticket/internal/models"
+)
+
+// Assigner chooses an agent for a ticket using the department's strategy
+type Assigner struct {
+	strategies map[string]Strategy
+	fallback   Strategy
+}
+
+// NewAssigner creates an assigner with the round-robin, least-busy and random strategies
+func NewAssigner(cursors CursorStore) *Assigner {
+	return &Assigner{
+		strategies: map[string]Strategy{
+			models.AutoAssignRoundRobin: NewRoundRobin(cursors),
+			models.AutoAssignLeastBusy:  LeastBusy{},
+			models.AutoAssignRandom:     Random{},
+		},
+		fallback: LeastBusy{},
+	}
+}
+
+// IsValidStrategy reports whether name is a known strategy
+func IsValidStrategy(name string) bool {
+	switch name {
+	case models.AutoAssignRoundRobin, models.AutoAssignLeastBusy, models.AutoAssignRandom:
+		return true
+	}
+	return false
+}
+
+// Pick narrows agents to the best skill and language match for the ticket, then
+// applies the department's strategy. It returns nil when there are no agents.
+func (a *Assigner) Pick(ctx context.Context, department *models.Department, ticket *models.Ticket, agents []models.Agent) (*models.Agent, error) {
+	candidates := Match(ticket, agents)
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	strategy := a.fallback
+	key := "tenant:" + ticket.TenantID
+	if department != nil {
+		if s, ok := a.strategies[department.AutoAssignType]; ok {
+			strategy = s
+		}
+		key = department.ID.Hex()
+	}
+
+	return strategy.Pick(ctx, key, candidates)
+}
+
+// Match returns the agents best suited to the ticket. Agents speaking the
+// ticket's language are preferred, then those with the most skills matching the
+// ticket's tags and category. Matching only narrows the list; when nobody
+// matches, all agents remain candidates.
+func Match(ticket *models.Ticket, agents []models.Agent) []models.Agent {
+	if ticket.Language != "" {
+		var speakers []models.Agent
+		for _, agent := range agents {
+			if containsFold(agent.Languages, ticket.Language) {
+				speakers = append(speakers, agent)
+			}
+		}
+		if len(speakers) > 0 {
+			agents = speakers
+		}
+	}
+
+	wanted := make([]string, 0, len(ticket.Tags)+1)
+	wanted = append(wanted, ticket.Tags...)
+	if ticket.CategoryName != "" {
+		wanted = append(wanted, ticket.CategoryName)
+	}
+	if len(wanted) == 0 {
+		return agents
+	}
+
+	var best []models.Agent
+	bestScore := 0
+	for _, agent := range agents {
+		score := 0
+		for _, skill := range wanted {
+			if containsFold(agent.Skills, skill) {
+				score++
+			}
+		}
+
+		switch {
+		case score > bestScore:
+			best = []models.Agent{agent}
+			bestScore = score
+		case score == bestScore && score > 0:
+			best = append(best, agent)
+		}
+	}
+
+	if bestScore == 0 {
+		return agents
+	}
+	return best
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
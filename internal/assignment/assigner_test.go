@@ -0,0 +1,152 @@
+package assignment
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// agent builds a test agent with a fixed, ordered ID
+func agent(id byte, name string, current, max int) models.Agent {
+	var oid primitive.ObjectID
+	oid[len(oid)-1] = id
+	return models.Agent{ID: oid, Name: name, CurrentTickets: current, MaxTickets: max}
+}
+
+func names(agents []models.Agent) []string {
+	result := make([]string, len(agents))
+	for i, a := range agents {
+		result[i] = a.Name
+	}
+	return result
+}
+
+// fakeCursors hands out sequence numbers per key like the Mongo counter
+type fakeCursors struct {
+	next map[string]int64
+	err  error
+}
+
+func (f *fakeCursors) NextAssignmentCursor(_ context.Context, key string) (int64, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	f.next[key]++
+	return f.next[key], nil
+}
+
+func TestMatch(t *testing.T) {
+	ana := agent(1, "ana", 0, 5)
+	ana.Languages = []string{"en", "fa"}
+	ana.Skills = []string{"Billing", "refunds"}
+	ben := agent(2, "ben", 0, 5)
+	ben.Languages = []string{"en"}
+	ben.Skills = []string{"billing"}
+	cy := agent(3, "cy", 0, 5)
+	cy.Languages = []string{"de"}
+	cy.Skills = []string{"hardware", "billing", "refunds"}
+	agents := []models.Agent{ana, ben, cy}
+
+	tests := []struct {
+		name   string
+		ticket models.Ticket
+		want   []string
+	}{
+		{name: "nothing to match", ticket: models.Ticket{}, want: []string{"ana", "ben", "cy"}},
+		{name: "language narrows", ticket: models.Ticket{Language: "EN"}, want: []string{"ana", "ben"}},
+		{name: "unknown language keeps everyone", ticket: models.Ticket{Language: "ja"}, want: []string{"ana", "ben", "cy"}},
+		{name: "most skills win", ticket: models.Ticket{Tags: []string{"billing", "refunds"}}, want: []string{"ana", "cy"}},
+		{name: "category counts as a skill", ticket: models.Ticket{Tags: []string{"hardware"}, CategoryName: "Refunds"}, want: []string{"cy"}},
+		{name: "language before skills", ticket: models.Ticket{Language: "en", Tags: []string{"hardware", "billing"}}, want: []string{"ana", "ben"}},
+		{name: "no skill matches keeps everyone", ticket: models.Ticket{Tags: []string{"shipping"}}, want: []string{"ana", "ben", "cy"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, names(Match(&tt.ticket, agents)))
+		})
+	}
+}
+
+func TestLeastBusy(t *testing.T) {
+	tests := []struct {
+		name   string
+		agents []models.Agent
+		want   string
+	}{
+		{name: "single", agents: []models.Agent{agent(1, "ana", 3, 5)}, want: "ana"},
+		{name: "fewest tickets", agents: []models.Agent{agent(1, "ana", 3, 5), agent(2, "ben", 1, 2), agent(3, "cy", 2, 10)}, want: "ben"},
+		{name: "tie broken by capacity", agents: []models.Agent{agent(1, "ana", 2, 4), agent(2, "ben", 2, 10)}, want: "ben"},
+		{name: "no limit counts as full", agents: []models.Agent{agent(1, "ana", 2, 0), agent(2, "ben", 2, 3)}, want: "ben"},
+		{name: "full tie keeps the first", agents: []models.Agent{agent(1, "ana", 1, 5), agent(2, "ben", 1, 5)}, want: "ana"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			picked, err := LeastBusy{}.Pick(t.Context(), "", tt.agents)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, picked.Name)
+		})
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	cursors := &fakeCursors{next: map[string]int64{}}
+	rr := NewRoundRobin(cursors)
+
+	// Candidate order doesn't matter; the rotation follows agent IDs
+	candidates := []models.Agent{agent(3, "cy", 0, 5), agent(1, "ana", 0, 5), agent(2, "ben", 0, 5)}
+
+	var picked []string
+	for i := 0; i < 4; i++ {
+		a, err := rr.Pick(t.Context(), "dept-1", candidates)
+		require.NoError(t, err)
+		picked = append(picked, a.Name)
+	}
+	assert.Equal(t, []string{"ana", "ben", "cy", "ana"}, picked)
+
+	// Each key rotates on its own
+	a, err := rr.Pick(t.Context(), "dept-2", candidates)
+	require.NoError(t, err)
+	assert.Equal(t, "ana", a.Name)
+
+	cursors.err = errors.New("mongo down")
+	_, err = rr.Pick(t.Context(), "dept-1", candidates)
+	assert.Error(t, err)
+}
+
+func TestAssignerPick(t *testing.T) {
+	busy := agent(1, "ana", 4, 5)
+	idle := agent(2, "ben", 0, 5)
+	agents := []models.Agent{busy, idle}
+	ticket := &models.Ticket{TenantID: "tenant-1"}
+
+	tests := []struct {
+		name       string
+		department *models.Department
+		want       string
+	}{
+		{name: "no department uses least busy", want: "ben"},
+		{name: "unknown strategy uses least busy", department: &models.Department{ID: primitive.NewObjectID(), AutoAssignType: "manual"}, want: "ben"},
+		{name: "round robin", department: &models.Department{ID: primitive.NewObjectID(), AutoAssignType: models.AutoAssignRoundRobin}, want: "ana"},
+		{name: "least busy", department: &models.Department{ID: primitive.NewObjectID(), AutoAssignType: models.AutoAssignLeastBusy}, want: "ben"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assigner := NewAssigner(&fakeCursors{next: map[string]int64{}})
+			picked, err := assigner.Pick(t.Context(), tt.department, ticket, agents)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, picked.Name)
+		})
+	}
+
+	picked, err := NewAssigner(&fakeCursors{next: map[string]int64{}}).Pick(t.Context(), nil, ticket, nil)
+	require.NoError(t, err)
+	assert.Nil(t, picked)
+
+	assert.True(t, IsValidStrategy(models.AutoAssignRandom))
+	assert.False(t, IsValidStrategy("manual"))
+}
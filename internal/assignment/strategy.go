@@ -0,0 +1,79 @@
+package assignment
+
+import (
+	"context"
+	"math/rand/v2"
+	"sort"
+
+	"github.com/minisource/ticket/internal/models"
+)
+
+// Strategy picks an agent from a non-empty list of candidates
+type Strategy interface {
+	Pick(ctx context.Context, key string, candidates []models.Agent) (*models.Agent, error)
+}
+
+// CursorStore hands out increasing sequence numbers per key
+type CursorStore interface {
+	NextAssignmentCursor(ctx context.Context, key string) (int64, error)
+}
+
+// LeastBusy picks the agent with the fewest open tickets,
+// breaking ties by the lowest share of capacity in use
+type LeastBusy struct{}
+
+// Pick implements Strategy
+func (LeastBusy) Pick(_ context.Context, _ string, candidates []models.Agent) (*models.Agent, error) {
+	best := &candidates[0]
+	for i := 1; i < len(candidates); i++ {
+		agent := &candidates[i]
+		if agent.CurrentTickets < best.CurrentTickets ||
+			(agent.CurrentTickets == best.CurrentTickets && loadRatio(agent) < loadRatio(best)) {
+			best = agent
+		}
+	}
+	return best, nil
+}
+
+// Random picks a uniformly random agent
+type Random struct{}
+
+// Pick implements Strategy
+func (Random) Pick(_ context.Context, _ string, candidates []models.Agent) (*models.Agent, error) {
+	return &candidates[rand.IntN(len(candidates))], nil
+}
+
+// RoundRobin rotates through agents using a cursor persisted per key,
+// so the rotation is shared by all instances
+type RoundRobin struct {
+	cursors CursorStore
+}
+
+// NewRoundRobin creates a new round-robin strategy
+func NewRoundRobin(cursors CursorStore) *RoundRobin {
+	return &RoundRobin{cursors: cursors}
+}
+
+// Pick implements Strategy
+func (s *RoundRobin) Pick(ctx context.Context, key string, candidates []models.Agent) (*models.Agent, error) {
+	cursor, err := s.cursors.NextAssignmentCursor(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rotate over a stable order so the cursor means the same thing on every call
+	sorted := make([]models.Agent, len(candidates))
+	copy(sorted, candidates)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID.Hex() < sorted[j].ID.Hex()
+	})
+
+	return &sorted[int((cursor-1)%int64(len(sorted)))], nil
+}
+
+func loadRatio(agent *models.Agent) float64 {
+	if agent.MaxTickets <= 0 {
+		return 1
+	}
+	return float64(agent.CurrentTickets) / float64(agent.MaxTickets)
+}
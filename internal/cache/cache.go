@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Store is a key-value store for cached entries
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Stats holds cache hit and miss counts
+type Stats struct {
+	Name   string `json:"name"`
+	Hits   int64  `json:"hits"`
+	Misses int64  `json:"misses"`
+}
+
+// Cache is a read-through cache for documents in a single collection.
+// A nil Cache is valid and never hits, so repositories work without one.
+type Cache struct {
+	name   string
+	store  Store
+	ttl    time.Duration
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// New creates a new cache; name namespaces keys and labels stats
+func New(store Store, name string, ttl time.Duration) *Cache {
+	return &Cache{
+		name:  name,
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// Get decodes the cached value for key into v and reports whether it was found.
+// Store errors are treated as misses so the caller falls back to the database.
+func (c *Cache) Get(ctx context.Context, key string, v interface{}) bool {
+	if c == nil {
+		return false
+	}
+
+	data, found, err := c.store.Get(ctx, c.key(key))
+	if err != nil || !found || bson.Unmarshal(data, v) != nil {
+		c.misses.Add(1)
+		return false
+	}
+
+	c.hits.Add(1)
+	return true
+}
+
+// Set caches v under key
+func (c *Cache) Set(ctx context.Context, key string, v interface{}) {
+	if c == nil {
+		return
+	}
+
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return
+	}
+	_ = c.store.Set(ctx, c.key(key), data, c.ttl)
+}
+
+// Delete invalidates the given keys
+func (c *Cache) Delete(ctx context.Context, keys ...string) {
+	if c == nil || len(keys) == 0 {
+		return
+	}
+
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.key(key)
+	}
+	_ = c.store.Delete(ctx, prefixed...)
+}
+
+// Stats returns hit and miss counts since startup
+func (c *Cache) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+
+	return Stats{
+		Name:   c.name,
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+}
+
+func (c *Cache) key(key string) string {
+	return "ticket:cache:" + c.name + ":" + key
+}
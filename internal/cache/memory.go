@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often Set drops expired entries
+const sweepInterval = time.Minute
+
+// MemoryStore keeps entries in process memory. It is the store used when
+// Redis is disabled; it isn't shared between instances, so invalidations stay
+// local and other instances see changes once their entries expire.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	entries   map[string]memoryEntry
+	nextSweep time.Time
+}
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates a new in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries:   make(map[string]memoryEntry),
+		nextSweep: time.Now().Add(sweepInterval),
+	}
+}
+
+// Get implements Store
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.RLock()
+	entry, exists := s.entries[key]
+	s.mu.RUnlock()
+
+	if !exists || time.Now().After(entry.expiresAt) {
+		return nil, false, nil
+	}
+
+	return entry.value, true, nil
+}
+
+// Set implements Store
+func (s *MemoryStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Expired entries are only dropped once per interval so Set stays cheap
+	now := time.Now()
+	if now.After(s.nextSweep) {
+		for k, entry := range s.entries {
+			if now.After(entry.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.nextSweep = now.Add(sweepInterval)
+	}
+
+	s.entries[key] = memoryEntry{
+		value:     value,
+		expiresAt: now.Add(ttl),
+	}
+	return nil
+}
+
+// Delete implements Store
+func (s *MemoryStore) Delete(_ context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	return nil
+}
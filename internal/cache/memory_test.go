@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMemoryStoreExpiry checks that expired entries miss and are swept on a later Set
+func TestMemoryStoreExpiry(t *testing.T) {
+	ctx := t.Context()
+	store := NewMemoryStore()
+
+	require.NoError(t, store.Set(ctx, "a", []byte("1"), time.Millisecond))
+	require.NoError(t, store.Set(ctx, "b", []byte("2"), time.Hour))
+	time.Sleep(5 * time.Millisecond)
+
+	_, found, err := store.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	value, found, err := store.Get(ctx, "b")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("2"), value)
+
+	// The sweep only runs once its interval has passed
+	require.NoError(t, store.Set(ctx, "c", []byte("3"), time.Hour))
+	assert.Len(t, store.entries, 3)
+
+	store.nextSweep = time.Now().Add(-time.Second)
+	require.NoError(t, store.Set(ctx, "c", []byte("3"), time.Hour))
+	assert.Len(t, store.entries, 2)
+	assert.NotContains(t, store.entries, "a")
+}
+
+// TestCacheInvalidation checks read-through hits and Delete through a Cache
+func TestCacheInvalidation(t *testing.T) {
+	ctx := t.Context()
+	c := New(NewMemoryStore(), "things", time.Hour)
+
+	type thing struct {
+		Name string `bson:"name"`
+	}
+
+	var got thing
+	assert.False(t, c.Get(ctx, "1", &got))
+	c.Set(ctx, "1", &thing{Name: "one"})
+	assert.True(t, c.Get(ctx, "1", &got))
+	assert.Equal(t, "one", got.Name)
+
+	c.Delete(ctx, "1")
+	assert.False(t, c.Get(ctx, "1", &got))
+	assert.Equal(t, Stats{Name: "things", Hits: 1, Misses: 2}, c.Stats())
+
+	var nilCache *Cache
+	nilCache.Set(ctx, "1", &thing{Name: "one"})
+	assert.False(t, nilCache.Get(ctx, "1", &got))
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps entries in Redis so invalidations reach every instance
+type RedisStore struct {
+	client redis.Cmdable
+}
+
+// NewRedisStore creates a new Redis-backed store
+func NewRedisStore(client redis.Cmdable) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Get implements Store
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, err := s.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to get cache entry: %w", err)
+	}
+
+	return data, true, nil
+}
+
+// Set implements Store
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := s.client.Set(ctx, key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to set cache entry: %w", err)
+	}
+	return nil
+}
+
+// Delete implements Store
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	if err := s.client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("failed to delete cache entries: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package collision
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+)
+
+// Store keeps the agents on each ticket
+type Store interface {
+	// Set adds or replaces the viewer's entry under key
+	Set(ctx context.Context, key string, viewer models.TicketViewer) error
+	// Remove drops the user's entry under key
+	Remove(ctx context.Context, key, userID string) error
+	// List returns the unexpired entries under key
+	List(ctx context.Context, key string) ([]models.TicketViewer, error)
+}
+
+// Tracker records which agents are viewing or replying to a ticket so
+// consoles can warn before two agents answer the same customer
+type Tracker struct {
+	store Store
+	ttl   time.Duration
+}
+
+// NewTracker creates a new tracker; entries expire ttl after their last refresh
+func NewTracker(store Store, ttl time.Duration) *Tracker {
+	return &Tracker{
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// Touch records the user's activity on a ticket, or removes them when the
+// activity is left. It returns the ticket's viewers and whether the set of
+// viewers or their activities changed.
+func (t *Tracker) Touch(ctx context.Context, tenantID, ticketID, userID, userName string, activity models.ViewerActivity) ([]models.TicketViewer, bool, error) {
+	key := t.key(tenantID, ticketID)
+
+	before, err := t.store.List(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	previous := activityOf(before, userID)
+
+	if activity == models.ViewerLeft {
+		if err := t.store.Remove(ctx, key, userID); err != nil {
+			return nil, false, err
+		}
+	} else {
+		err := t.store.Set(ctx, key, models.TicketViewer{
+			UserID:    userID,
+			UserName:  userName,
+			Activity:  activity,
+			ExpiresAt: time.Now().Add(t.ttl),
+		})
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	viewers, err := t.Viewers(ctx, tenantID, ticketID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	changed := previous != activity && !(previous == "" && activity == models.ViewerLeft)
+	return viewers, changed, nil
+}
+
+// Viewers returns the agents currently on a ticket, ordered by name
+func (t *Tracker) Viewers(ctx context.Context, tenantID, ticketID string) ([]models.TicketViewer, error) {
+	viewers, err := t.store.List(ctx, t.key(tenantID, ticketID))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(viewers, func(i, j int) bool { return viewers[i].UserName < viewers[j].UserName })
+	return viewers, nil
+}
+
+func (t *Tracker) key(tenantID, ticketID string) string {
+	return "ticket:viewers:" + tenantID + ":" + ticketID
+}
+
+// activityOf returns the user's activity among viewers, or "" if absent
+func activityOf(viewers []models.TicketViewer, userID string) models.ViewerActivity {
+	for _, v := range viewers {
+		if v.UserID == userID {
+			return v.Activity
+		}
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+package collision
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCollisionTracker checks viewer tracking against the in-memory store
+func TestCollisionTracker(t *testing.T) {
+	ctx := context.Background()
+	tracker := NewTracker(NewMemoryStore(), 50*time.Millisecond)
+
+	t.Run("Reports Changes Only", func(t *testing.T) {
+		viewers, changed, err := tracker.Touch(ctx, "tenant", "ticket", "a1", "Alice", models.ViewerViewing)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Len(t, viewers, 1)
+
+		_, changed, err = tracker.Touch(ctx, "tenant", "ticket", "a1", "Alice", models.ViewerViewing)
+		require.NoError(t, err)
+		assert.False(t, changed)
+
+		viewers, changed, err = tracker.Touch(ctx, "tenant", "ticket", "a2", "Bob", models.ViewerReplying)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		require.Len(t, viewers, 2)
+		assert.Equal(t, "Alice", viewers[0].UserName)
+		assert.Equal(t, models.ViewerReplying, viewers[1].Activity)
+	})
+
+	t.Run("Leave Removes Viewer", func(t *testing.T) {
+		viewers, changed, err := tracker.Touch(ctx, "tenant", "ticket", "a2", "Bob", models.ViewerLeft)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Len(t, viewers, 1)
+
+		_, changed, err = tracker.Touch(ctx, "tenant", "ticket", "a2", "Bob", models.ViewerLeft)
+		require.NoError(t, err)
+		assert.False(t, changed)
+	})
+
+	t.Run("Entries Expire", func(t *testing.T) {
+		time.Sleep(60 * time.Millisecond)
+
+		viewers, err := tracker.Viewers(ctx, "tenant", "ticket")
+		require.NoError(t, err)
+		assert.Empty(t, viewers)
+	})
+}
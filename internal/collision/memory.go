@@ -0,0 +1,68 @@
+package collision
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+)
+
+// MemoryStore keeps viewers in process memory.
+// Agents on other instances aren't visible; use it for tests and single-node setups.
+type MemoryStore struct {
+	mu      sync.Mutex
+	viewers map[string]map[string]models.TicketViewer
+}
+
+// NewMemoryStore creates a new in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		viewers: make(map[string]map[string]models.TicketViewer),
+	}
+}
+
+// Set implements Store
+func (s *MemoryStore) Set(_ context.Context, key string, viewer models.TicketViewer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.viewers[key] == nil {
+		s.viewers[key] = make(map[string]models.TicketViewer)
+	}
+	s.viewers[key][viewer.UserID] = viewer
+	return nil
+}
+
+// Remove implements Store
+func (s *MemoryStore) Remove(_ context.Context, key, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.viewers[key], userID)
+	if len(s.viewers[key]) == 0 {
+		delete(s.viewers, key)
+	}
+	return nil
+}
+
+// List implements Store
+func (s *MemoryStore) List(_ context.Context, key string) ([]models.TicketViewer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	viewers := make([]models.TicketViewer, 0, len(s.viewers[key]))
+	for userID, viewer := range s.viewers[key] {
+		if now.After(viewer.ExpiresAt) {
+			delete(s.viewers[key], userID)
+			continue
+		}
+		viewers = append(viewers, viewer)
+	}
+	if len(s.viewers[key]) == 0 {
+		delete(s.viewers, key)
+	}
+
+	return viewers, nil
+}
@@ -0,0 +1,73 @@
+package collision
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps viewers in a Redis hash per ticket so every instance
+// sees the same agents
+type RedisStore struct {
+	client redis.Cmdable
+}
+
+// NewRedisStore creates a new Redis-backed store
+func NewRedisStore(client redis.Cmdable) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Set implements Store
+func (s *RedisStore) Set(ctx context.Context, key string, viewer models.TicketViewer) error {
+	data, err := json.Marshal(viewer)
+	if err != nil {
+		return fmt.Errorf("failed to encode viewer: %w", err)
+	}
+
+	// Every entry shares the TTL, so the hash can expire with its newest entry
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, key, viewer.UserID, data)
+	pipe.ExpireAt(ctx, key, viewer.ExpiresAt)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set viewer: %w", err)
+	}
+	return nil
+}
+
+// Remove implements Store
+func (s *RedisStore) Remove(ctx context.Context, key, userID string) error {
+	if err := s.client.HDel(ctx, key, userID).Err(); err != nil {
+		return fmt.Errorf("failed to remove viewer: %w", err)
+	}
+	return nil
+}
+
+// List implements Store
+func (s *RedisStore) List(ctx context.Context, key string) ([]models.TicketViewer, error) {
+	entries, err := s.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list viewers: %w", err)
+	}
+
+	now := time.Now()
+	viewers := make([]models.TicketViewer, 0, len(entries))
+	var expired []string
+	for userID, data := range entries {
+		var viewer models.TicketViewer
+		if err := json.Unmarshal([]byte(data), &viewer); err != nil || now.After(viewer.ExpiresAt) {
+			expired = append(expired, userID)
+			continue
+		}
+		viewers = append(viewers, viewer)
+	}
+
+	if len(expired) > 0 {
+		_ = s.client.HDel(ctx, key, expired...).Err()
+	}
+
+	return viewers, nil
+}
@@ -9,6 +9,8 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 // Collections
@@ -23,6 +25,17 @@ const (
 	CollectionCannedResponses = "canned_responses"
 	CollectionTicketHistory   = "ticket_history"
 	CollectionTicketCounters  = "ticket_counters"
+	CollectionAssignCursors   = "assignment_cursors"
+	CollectionSavedViews      = "saved_views"
+	CollectionExportJobs      = "export_jobs"
+	CollectionImportJobs      = "import_jobs"
+	CollectionLeases          = "leases"
+)
+
+// GridFS buckets
+const (
+	BucketExports = "exports"
+	BucketImports = "imports"
 )
 
 // MongoDB holds the MongoDB client and database
@@ -41,7 +54,8 @@ func NewMongoDB(cfg config.MongoDBConfig) (*MongoDB, error) {
 		ApplyURI(cfg.URI).
 		SetMaxPoolSize(cfg.MaxPoolSize).
 		SetMinPoolSize(cfg.MinPoolSize).
-		SetMaxConnIdleTime(cfg.MaxConnIdleTime)
+		SetMaxConnIdleTime(cfg.MaxConnIdleTime).
+		SetMonitor(otelmongo.NewMonitor()) // Command spans under the caller's span
 
 	// Connect
 	client, err := mongo.Connect(ctx, clientOptions)
@@ -74,6 +88,11 @@ func (m *MongoDB) Close(ctx context.Context) error {
 	return m.Client.Disconnect(ctx)
 }
 
+// Ping checks that the primary is reachable
+func (m *MongoDB) Ping(ctx context.Context) error {
+	return m.Client.Ping(ctx, readpref.Primary())
+}
+
 // Collection returns a collection by name
 func (m *MongoDB) Collection(name string) *mongo.Collection {
 	return m.Database.Collection(name)
@@ -90,6 +109,15 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 			},
 			Options: options.Index().SetUnique(true),
 		},
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "import.external_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"import.external_id": bson.M{"$exists": true},
+			}),
+		},
 		{
 			Keys: bson.D{
 				{Key: "tenant_id", Value: 1},
@@ -193,6 +221,20 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 				{Key: "sender_id", Value: 1},
 			},
 		},
+		// Agent scorecards
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "sender_type", Value: 1},
+				{Key: "created_at", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "content", Value: "text"},
+			},
+			Options: options.Index().SetName("message_text_search"),
+		},
 	}
 
 	if _, err := m.Collection(CollectionMessages).Indexes().CreateMany(ctx, messageIndexes); err != nil {
@@ -300,6 +342,14 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 				{Key: "ticket_id", Value: 1},
 			},
 		},
+		// Agent scorecards
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "action", Value: 1},
+				{Key: "created_at", Value: 1},
+			},
+		},
 	}
 
 	if _, err := m.Collection(CollectionTicketHistory).Indexes().CreateMany(ctx, historyIndexes); err != nil {
@@ -347,6 +397,27 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		return fmt.Errorf("failed to create canned response indexes: %w", err)
 	}
 
+	// Saved view indexes
+	viewIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "owner_id", Value: 1},
+				{Key: "name", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "shared", Value: 1},
+			},
+		},
+	}
+
+	if _, err := m.Collection(CollectionSavedViews).Indexes().CreateMany(ctx, viewIndexes); err != nil {
+		return fmt.Errorf("failed to create saved view indexes: %w", err)
+	}
+
 	// Team indexes
 	teamIndexes := []mongo.IndexModel{
 		{
@@ -362,5 +433,42 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		return fmt.Errorf("failed to create team indexes: %w", err)
 	}
 
+	// Export job indexes
+	exportIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "created_at", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{{Key: "expires_at", Value: 1}},
+		},
+	}
+
+	if _, err := m.Collection(CollectionExportJobs).Indexes().CreateMany(ctx, exportIndexes); err != nil {
+		return fmt.Errorf("failed to create export job indexes: %w", err)
+	}
+
+	// Import job indexes
+	importIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "created_at", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		},
+	}
+
+	if _, err := m.Collection(CollectionImportJobs).Indexes().CreateMany(ctx, importIndexes); err != nil {
+		return fmt.Errorf("failed to create import job indexes: %w", err)
+	}
+
 	return nil
 }
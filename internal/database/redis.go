@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/minisource/ticket/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// NewRedis creates a new Redis client and verifies the connection
+func NewRedis(cfg config.RedisConfig) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to ping Redis: %w", err)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,47 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"time"
+)
+
+// csvWriter writes UTF-8 CSV with a byte order mark, which spreadsheet apps
+// need to read non-Latin text correctly
+type csvWriter struct {
+	w   *csv.Writer
+	loc *time.Location
+}
+
+func newCSVWriter(w io.Writer, loc *time.Location) (*csvWriter, error) {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: csv.NewWriter(w), loc: loc}, nil
+}
+
+func (c *csvWriter) Write(row []interface{}) error {
+	record := make([]string, len(row))
+	for n, cell := range row {
+		text, _ := cellText(cell, c.loc)
+		if _, isString := cell.(string); isString {
+			text = defuse(text)
+		}
+		record[n] = text
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// defuse stops spreadsheet apps from running text that looks like a formula
+func defuse(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
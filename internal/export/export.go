@@ -0,0 +1,106 @@
+// Package export writes tabular data as CSV or XLSX spreadsheets.
+//
+// Rows are written one at a time, so exports of any size stream in constant
+// memory. Cells may be strings, integers, floats, booleans or times; nil
+// pointers are written as empty cells.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Format is a spreadsheet file format
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+)
+
+// ErrUnknownFormat is returned for formats other than csv and xlsx
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// ParseFormat reads a format name, defaulting to CSV
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case "", FormatCSV:
+		return FormatCSV, nil
+	case FormatXLSX:
+		return FormatXLSX, nil
+	}
+	return "", fmt.Errorf("%w %q", ErrUnknownFormat, name)
+}
+
+// ContentType is the MIME type of files in the format
+func (f Format) ContentType() string {
+	if f == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Writer writes rows of cells. The first row written is the header.
+type Writer interface {
+	Write(row []interface{}) error
+	// Close completes the file. It doesn't close the underlying writer.
+	Close() error
+}
+
+// NewWriter creates a writer for the format. Times are written as wall clock
+// times in loc.
+func NewWriter(w io.Writer, format Format, loc *time.Location) (Writer, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, loc)
+	case FormatXLSX:
+		return newXLSXWriter(w, loc)
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+}
+
+// cellText formats a cell as text; ok is false for empty cells
+func cellText(cell interface{}, loc *time.Location) (text string, ok bool) {
+	switch v := cell.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case *float64:
+		if v == nil {
+			return "", false
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64), true
+	case *int:
+		if v == nil {
+			return "", false
+		}
+		return strconv.Itoa(*v), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case time.Time:
+		if v.IsZero() {
+			return "", false
+		}
+		return v.In(loc).Format(time.RFC3339), true
+	case *time.Time:
+		if v == nil || v.IsZero() {
+			return "", false
+		}
+		return v.In(loc).Format(time.RFC3339), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
@@ -0,0 +1,88 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExportWriters checks the cells both spreadsheet formats produce
+func TestExportWriters(t *testing.T) {
+	tehran, err := time.LoadLocation("Asia/Tehran")
+	require.NoError(t, err)
+
+	at := time.Date(2025, 3, 2, 20, 30, 0, 0, time.UTC) // Midnight in Tehran
+	var missing *float64
+	header := []interface{}{"subject", "rating", "avg", "created_at"}
+	rows := [][]interface{}{
+		{"=HYPERLINK(\"x\")", 5, missing, at},
+		{"سلام, \"world\"", int64(3), 4.5, &at},
+	}
+
+	// CSV starts with a byte order mark and defuses formulas
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, FormatCSV, tehran)
+	require.NoError(t, err)
+	require.NoError(t, WriteAll(w, header, rows))
+
+	require.True(t, strings.HasPrefix(buf.String(), "\ufeff"))
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\ufeff"))).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"subject", "rating", "avg", "created_at"},
+		{"'=HYPERLINK(\"x\")", "5", "", "2025-03-03T00:00:00+03:30"},
+		{"سلام, \"world\"", "3", "4.5", "2025-03-03T00:00:00+03:30"},
+	}, records)
+
+	// XLSX is a zip of a workbook with one sheet; times are local date serials
+	buf.Reset()
+	w, err = NewWriter(&buf, FormatXLSX, tehran)
+	require.NoError(t, err)
+	require.NoError(t, WriteAll(w, header, rows))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	parts := map[string]*zip.File{}
+	for _, f := range archive.File {
+		parts[f.Name] = f
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		assert.Contains(t, parts, name)
+	}
+	require.Contains(t, parts, "xl/worksheets/sheet1.xml")
+
+	f, err := parts["xl/worksheets/sheet1.xml"].Open()
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	require.NoError(t, xml.Unmarshal(data, &sheet))
+	require.Len(t, sheet.Rows, 3)
+
+	first := sheet.Rows[1].Cells
+	require.Len(t, first, 4)
+	assert.Equal(t, "inlineStr", first[0].Type)
+	assert.Equal(t, "=HYPERLINK(\"x\")", first[0].Inline) // Inline strings are never formulas
+	assert.Equal(t, "5", first[1].Value)
+	assert.Empty(t, first[2].Value)
+	assert.Equal(t, "45719", first[3].Value) // 2025-03-03 00:00
+	assert.Equal(t, "سلام, \"world\"", sheet.Rows[2].Cells[0].Inline)
+}
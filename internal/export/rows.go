@@ -0,0 +1,140 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/minisource/ticket/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// customFieldPrefix prefixes custom field columns, keeping them apart from
+// the fixed ticket columns
+const customFieldPrefix = "cf."
+
+// ticketColumns are the fixed columns of ticket exports
+var ticketColumns = []string{
+	"id", "ticket_number", "subject", "status", "priority", "type", "source",
+	"customer_id", "customer_name", "customer_email",
+	"department", "category", "subcategory", "assigned_to_id", "assigned_to", "team",
+	"tags", "sla_breached", "first_response_due", "resolution_due", "first_response_at",
+	"message_count", "reopen_count", "escalation_level", "satisfaction_rating",
+	"created_at", "updated_at", "resolved_at", "closed_at",
+}
+
+// TicketHeader is the header row of a ticket export with a column for each
+// custom field key
+func TicketHeader(customKeys []string) []interface{} {
+	row := make([]interface{}, 0, len(ticketColumns)+len(customKeys))
+	for _, column := range ticketColumns {
+		row = append(row, column)
+	}
+	for _, key := range customKeys {
+		row = append(row, customFieldPrefix+key)
+	}
+	return row
+}
+
+// TicketRow is a ticket's row, matching TicketHeader
+func TicketRow(t *models.Ticket, customKeys []string) []interface{} {
+	row := []interface{}{
+		t.ID.Hex(), t.TicketNumber, t.Subject, string(t.Status), string(t.Priority), string(t.Type), string(t.Source),
+		t.CustomerID, t.CustomerName, t.CustomerEmail,
+		t.DepartmentName, t.CategoryName, t.SubcategoryName, t.AssignedToID, t.AssignedToName, t.TeamName,
+		strings.Join(t.Tags, ", "), t.SLABreached, t.FirstResponseDue, t.ResolutionDue, t.FirstResponsedAt,
+		t.MessageCount, t.ReopenCount, t.EscalationLevel, t.SatisfactionRating,
+		t.CreatedAt, t.UpdatedAt, t.ResolvedAt, t.ClosedAt,
+	}
+	for _, key := range customKeys {
+		row = append(row, customFieldCell(t.CustomFields[key]))
+	}
+	return row
+}
+
+// customFieldCell converts a decoded custom field value to a cell. Lists are
+// joined and documents are written as JSON.
+func customFieldCell(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, string, bool, int64, float64:
+		return v
+	case int32:
+		return int64(v)
+	case int:
+		return int64(v)
+	case primitive.DateTime:
+		return v.Time()
+	case primitive.A:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, fmt.Sprint(customFieldCell(item)))
+		}
+		return strings.Join(parts, ", ")
+	default:
+		if data, err := json.Marshal(v); err == nil {
+			return string(data)
+		}
+		return fmt.Sprint(v)
+	}
+}
+
+// TrendHeader is the header row of a ticket trend export
+func TrendHeader() []interface{} {
+	return []interface{}{
+		"group", "group_name", "period_start", "created", "resolved", "backlog",
+		"first_response_count", "first_response_p50", "first_response_p90", "first_response_p95",
+		"resolution_count", "resolution_p50", "resolution_p90", "resolution_p95",
+		"sla_compliance", "csat", "avg_rating", "ratings",
+	}
+}
+
+// TrendRows are the rows of a ticket trend report, one per series and period
+func TrendRows(report *models.TicketTrendReport) [][]interface{} {
+	var rows [][]interface{}
+	for _, series := range report.Series {
+		for _, p := range series.Periods {
+			rows = append(rows, []interface{}{
+				series.Key, series.Name, p.Start, p.Created, p.Resolved, p.Backlog,
+				p.FirstResponse.Count, p.FirstResponse.P50, p.FirstResponse.P90, p.FirstResponse.P95,
+				p.Resolution.Count, p.Resolution.P50, p.Resolution.P90, p.Resolution.P95,
+				p.SLACompliance, p.CSAT, p.AvgRating, p.Ratings,
+			})
+		}
+	}
+	return rows
+}
+
+// ScorecardHeader is the header row of an agent scorecard export
+func ScorecardHeader() []interface{} {
+	return []interface{}{
+		"rank", "user_id", "name", "handled", "replies", "internal_notes", "internal_note_ratio",
+		"resolutions", "reopened", "reopen_rate", "resolved", "sla_compliance", "first_response_median",
+		"ratings_1", "ratings_2", "ratings_3", "ratings_4", "ratings_5", "csat", "avg_rating",
+	}
+}
+
+// ScorecardRows are the rows of an agent scorecard report, one per agent
+func ScorecardRows(report *models.AgentScorecardReport) [][]interface{} {
+	rows := make([][]interface{}, 0, len(report.Agents))
+	for _, a := range report.Agents {
+		rows = append(rows, []interface{}{
+			a.Rank, a.UserID, a.Name, a.Handled, a.Replies, a.InternalNotes, a.InternalNoteRatio,
+			a.Resolutions, a.Reopened, a.ReopenRate, a.Resolved, a.SLACompliance, a.FirstResponseMedian,
+			a.Ratings[0], a.Ratings[1], a.Ratings[2], a.Ratings[3], a.Ratings[4], a.CSAT, a.AvgRating,
+		})
+	}
+	return rows
+}
+
+// WriteAll writes a header and rows and completes the file
+func WriteAll(w Writer, header []interface{}, rows [][]interface{}) error {
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
@@ -0,0 +1,143 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+// xlsxWriter writes a single sheet workbook. Rows are streamed into the sheet
+// part; the other parts are fixed. The header row is bold and times use a
+// date format.
+type xlsxWriter struct {
+	zip    *zip.Writer
+	sheet  *bufio.Writer
+	loc    *time.Location
+	header bool
+}
+
+// Cell styles, indexes into cellXfs of xlsxStyles
+const (
+	styleDate   = 1
+	styleHeader = 2
+)
+
+// excelEpoch is day zero of spreadsheet date serials
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`
+
+const xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Export" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`
+
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`
+
+const xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+const xlsxSheetEnd = `</sheetData></worksheet>`
+
+func newXLSXWriter(w io.Writer, loc *time.Location) (*xlsxWriter, error) {
+	x := &xlsxWriter{zip: zip.NewWriter(w), loc: loc, header: true}
+
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		f, err := x.zip.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	// The sheet is the last part, so it stays open for rows until Close
+	f, err := x.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	x.sheet = bufio.NewWriter(f)
+	if _, err := x.sheet.WriteString(xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *xlsxWriter) Write(row []interface{}) error {
+	x.sheet.WriteString("<row>")
+	for _, cell := range row {
+		x.writeCell(cell)
+	}
+	_, err := x.sheet.WriteString("</row>")
+	x.header = false
+	return err
+}
+
+func (x *xlsxWriter) writeCell(cell interface{}) {
+	switch v := cell.(type) {
+	case time.Time:
+		if !v.IsZero() {
+			x.writeDate(v)
+			return
+		}
+	case *time.Time:
+		if v != nil && !v.IsZero() {
+			x.writeDate(*v)
+			return
+		}
+	case int, int64, float64, *float64, *int:
+		if text, ok := cellText(v, x.loc); ok && !x.header {
+			x.sheet.WriteString(`<c><v>` + text + `</v></c>`)
+			return
+		}
+	}
+
+	text, ok := cellText(cell, x.loc)
+	if !ok {
+		x.sheet.WriteString("<c/>")
+		return
+	}
+	if x.header {
+		x.sheet.WriteString(`<c t="inlineStr" s="` + strconv.Itoa(styleHeader) + `"><is><t xml:space="preserve">`)
+	} else {
+		x.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+	}
+	xml.EscapeText(x.sheet, []byte(text))
+	x.sheet.WriteString(`</t></is></c>`)
+}
+
+// writeDate writes the wall clock time in the writer's location as a date
+// serial, the number of days since the spreadsheet epoch
+func (x *xlsxWriter) writeDate(t time.Time) {
+	local := t.In(x.loc)
+	wall := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), 0, time.UTC)
+	serial := wall.Sub(excelEpoch).Seconds() / 86400
+	x.sheet.WriteString(`<c s="` + strconv.Itoa(styleDate) + `"><v>` + strconv.FormatFloat(serial, 'f', -1, 64) + `</v></c>`)
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := x.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
@@ -0,0 +1,93 @@
+package guest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidToken is returned when a token is malformed or its signature does not match
+	ErrInvalidToken = errors.New("invalid guest access token")
+	// ErrTokenExpired is returned when a token is past its expiry
+	ErrTokenExpired = errors.New("guest access token expired")
+)
+
+// Claims identifies the single ticket a guest token grants access to
+type Claims struct {
+	TicketID  string `json:"tid"`
+	TenantID  string `json:"tnt"`
+	Email     string `json:"eml"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// TokenManager issues and verifies signed guest access tokens
+type TokenManager struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewTokenManager creates a new token manager
+func NewTokenManager(secret string, ttl time.Duration) *TokenManager {
+	return &TokenManager{
+		secret: []byte(secret),
+		ttl:    ttl,
+	}
+}
+
+// Issue creates a token for a ticket, returning the token and its expiry
+func (m *TokenManager) Issue(ticketID, tenantID, email string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(m.ttl)
+	claims := Claims{
+		TicketID:  ticketID,
+		TenantID:  tenantID,
+		Email:     strings.ToLower(email),
+		ExpiresAt: expiresAt.Unix(),
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + m.sign(encoded), expiresAt, nil
+}
+
+// Verify checks the token signature and expiry and returns its claims
+func (m *TokenManager) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidToken
+	}
+
+	if !hmac.Equal([]byte(parts[1]), []byte(m.sign(parts[0]))) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if time.Now().Unix() > claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+
+	return &claims, nil
+}
+
+func (m *TokenManager) sign(payload string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
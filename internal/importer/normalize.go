@@ -0,0 +1,355 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+)
+
+// Ticket is a record checked and converted to ticket values
+type Ticket struct {
+	ExternalID    string
+	TicketNumber  string
+	Subject       string
+	Description   string
+	Status        models.TicketStatus
+	Priority      models.TicketPriority
+	Type          models.TicketType
+	Source        models.TicketSource
+	CustomerID    string
+	CustomerName  string
+	CustomerEmail string
+	AssigneeEmail string
+	Department    string
+	Category      string
+	Tags          []string
+	CustomFields  map[string]interface{}
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	ResolvedAt    *time.Time
+	ClosedAt      *time.Time
+	Rating        *int
+	Attachments   []models.Attachment
+	Messages      []TicketMessage // Oldest first
+}
+
+// TicketMessage is a message checked and converted to message values
+type TicketMessage struct {
+	Body        string
+	AuthorEmail string
+	AuthorName  string
+	AuthorType  models.SenderType // Empty when the file doesn't say
+	Internal    bool
+	CreatedAt   time.Time
+	Attachments []models.Attachment
+}
+
+// statusNames maps status names, including other helpdesks', to statuses
+var statusNames = map[string]models.TicketStatus{
+	"open":        models.StatusOpen,
+	"new":         models.StatusOpen,
+	"in_progress": models.StatusInProgress,
+	"pending":     models.StatusPending,
+	"waiting":     models.StatusPending,
+	"on_hold":     models.StatusOnHold,
+	"hold":        models.StatusOnHold,
+	"resolved":    models.StatusResolved,
+	"solved":      models.StatusResolved,
+	"closed":      models.StatusClosed,
+	"reopened":    models.StatusReopened,
+	"escalated":   models.StatusEscalated,
+	"cancelled":   models.StatusCancelled,
+	"canceled":    models.StatusCancelled,
+}
+
+// priorityNames maps priority names to priorities
+var priorityNames = map[string]models.TicketPriority{
+	"low":      models.PriorityLow,
+	"medium":   models.PriorityMedium,
+	"normal":   models.PriorityMedium,
+	"high":     models.PriorityHigh,
+	"urgent":   models.PriorityUrgent,
+	"critical": models.PriorityCritical,
+}
+
+var ticketTypes = map[models.TicketType]bool{
+	models.TypeQuestion: true, models.TypeIncident: true, models.TypeProblem: true, models.TypeFeature: true,
+	models.TypeBug: true, models.TypeTask: true, models.TypeComplaint: true, models.TypeFeedback: true,
+}
+
+var ticketSources = map[models.TicketSource]bool{
+	models.SourceWeb: true, models.SourceEmail: true, models.SourceAPI: true, models.SourcePhone: true,
+	models.SourceChat: true, models.SourceMobile: true, models.SourceInternal: true,
+}
+
+// timeLayouts are the accepted time formats besides Unix seconds. Layouts
+// without an offset are read in the import timezone.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Normalize checks a record and converts it to ticket values. It returns
+// nil when the record has errors; issues lists them along with warnings.
+func Normalize(rec *Record, loc *time.Location) (*Ticket, []models.ImportIssue) {
+	n := normalizer{loc: loc}
+	t := &Ticket{
+		ExternalID:    rec.ID,
+		TicketNumber:  rec.TicketNumber,
+		Subject:       rec.Subject,
+		Description:   rec.Description,
+		CustomerID:    rec.CustomerID,
+		CustomerName:  rec.CustomerName,
+		CustomerEmail: strings.ToLower(rec.CustomerEmail),
+		AssigneeEmail: strings.ToLower(rec.AssigneeEmail),
+		Department:    rec.Department,
+		Category:      rec.Category,
+		Tags:          rec.Tags,
+		CustomFields:  customFields(rec.CustomFields),
+		Status:        models.StatusOpen,
+		Priority:      models.PriorityMedium,
+		Type:          models.TypeQuestion,
+		Source:        models.SourceEmail,
+	}
+
+	if t.Subject == "" {
+		n.fail("subject", "subject is required")
+	}
+	if t.CustomerID == "" && t.CustomerEmail == "" {
+		n.fail("customer_email", "customer email or ID is required")
+	}
+	if t.CustomerName == "" {
+		t.CustomerName = t.CustomerEmail
+	}
+
+	if rec.Status != "" {
+		status, ok := statusNames[name(rec.Status)]
+		if !ok {
+			n.fail("status", fmt.Sprintf("unknown status %q", rec.Status))
+		}
+		t.Status = status
+	}
+	if rec.Priority != "" {
+		priority, ok := priorityNames[name(rec.Priority)]
+		if !ok {
+			n.fail("priority", fmt.Sprintf("unknown priority %q", rec.Priority))
+		}
+		t.Priority = priority
+	}
+	if rec.Type != "" {
+		if t.Type = models.TicketType(name(rec.Type)); !ticketTypes[t.Type] {
+			n.warn("type", fmt.Sprintf("unknown type %q; imported as a question", rec.Type))
+			t.Type = models.TypeQuestion
+		}
+	}
+	if rec.Source != "" {
+		if t.Source = models.TicketSource(name(rec.Source)); !ticketSources[t.Source] {
+			n.warn("source", fmt.Sprintf("unknown source %q; imported as email", rec.Source))
+			t.Source = models.SourceEmail
+		}
+	}
+
+	if rec.Rating != "" {
+		rating, err := strconv.Atoi(rec.Rating)
+		if err != nil || rating < 1 || rating > 5 {
+			n.fail("satisfaction_rating", fmt.Sprintf("rating %q is not 1 to 5", rec.Rating))
+		} else {
+			t.Rating = &rating
+		}
+	}
+
+	if created := n.time("created_at", rec.CreatedAt); created != nil {
+		t.CreatedAt = *created
+	} else if rec.CreatedAt == "" {
+		n.fail("created_at", "created time is required")
+	}
+	t.ResolvedAt = n.time("resolved_at", rec.ResolvedAt)
+	t.ClosedAt = n.time("closed_at", rec.ClosedAt)
+	updated := n.time("updated_at", rec.UpdatedAt)
+
+	t.Attachments = n.attachments("attachments", rec.Attachments)
+	for i, m := range rec.Messages {
+		field := fmt.Sprintf("messages[%d]", i)
+		msg := TicketMessage{
+			Body:        m.Body,
+			AuthorEmail: strings.ToLower(m.AuthorEmail),
+			AuthorName:  m.AuthorName,
+			Attachments: n.attachments(field+".attachments", m.Attachments),
+		}
+		if m.AuthorType != "" {
+			switch msg.AuthorType = models.SenderType(name(m.AuthorType)); msg.AuthorType {
+			case models.SenderCustomer, models.SenderAgent, models.SenderSystem:
+			case "requester", "end_user", "user":
+				msg.AuthorType = models.SenderCustomer
+			default:
+				n.warn(field+".author_type", fmt.Sprintf("unknown author type %q", m.AuthorType))
+				msg.AuthorType = ""
+			}
+		}
+		if m.Internal != "" {
+			internal, err := strconv.ParseBool(m.Internal)
+			if err != nil {
+				n.fail(field+".internal", fmt.Sprintf("%q is not true or false", m.Internal))
+			}
+			msg.Internal = internal
+		}
+		if created := n.time(field+".created_at", m.CreatedAt); created != nil {
+			msg.CreatedAt = *created
+		} else if m.CreatedAt == "" {
+			n.warn(field+".created_at", "no time; dated with the ticket")
+			msg.CreatedAt = t.CreatedAt
+		}
+		if msg.Body == "" && len(msg.Attachments) == 0 {
+			n.warn(field, "empty message skipped")
+			continue
+		}
+		t.Messages = append(t.Messages, msg)
+	}
+	sort.SliceStable(t.Messages, func(i, j int) bool {
+		return t.Messages[i].CreatedAt.Before(t.Messages[j].CreatedAt)
+	})
+
+	if t.Description == "" && len(t.Messages) > 0 {
+		t.Description = t.Messages[0].Body
+	}
+
+	// Without an update time the ticket was last touched by its latest event
+	if updated != nil {
+		t.UpdatedAt = *updated
+	} else {
+		t.UpdatedAt = t.CreatedAt
+		for _, at := range []*time.Time{t.ResolvedAt, t.ClosedAt} {
+			if at != nil && at.After(t.UpdatedAt) {
+				t.UpdatedAt = *at
+			}
+		}
+		if len(t.Messages) > 0 && t.Messages[len(t.Messages)-1].CreatedAt.After(t.UpdatedAt) {
+			t.UpdatedAt = t.Messages[len(t.Messages)-1].CreatedAt
+		}
+	}
+
+	if !t.CreatedAt.IsZero() {
+		if t.ResolvedAt != nil && t.ResolvedAt.Before(t.CreatedAt) {
+			n.fail("resolved_at", "resolved before it was created")
+		}
+		if t.ClosedAt != nil && t.ClosedAt.Before(t.CreatedAt) {
+			n.fail("closed_at", "closed before it was created")
+		}
+	}
+
+	// Resolved and closed tickets need the time they got there
+	switch t.Status {
+	case models.StatusResolved:
+		if t.ResolvedAt == nil {
+			t.ResolvedAt = &t.UpdatedAt
+		}
+	case models.StatusClosed:
+		if t.ClosedAt == nil {
+			t.ClosedAt = &t.UpdatedAt
+		}
+	}
+
+	if n.failed {
+		return nil, n.issues
+	}
+	return t, n.issues
+}
+
+// normalizer collects the issues of one record
+type normalizer struct {
+	loc    *time.Location
+	issues []models.ImportIssue
+	failed bool
+}
+
+func (n *normalizer) fail(field, message string) {
+	n.issues = append(n.issues, models.ImportIssue{Field: field, Message: message})
+	n.failed = true
+}
+
+func (n *normalizer) warn(field, message string) {
+	n.issues = append(n.issues, models.ImportIssue{Field: field, Message: message, Warning: true})
+}
+
+// time parses an optional time, failing the record when it is malformed
+func (n *normalizer) time(field, value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	t, err := ParseTime(value, n.loc)
+	if err != nil {
+		n.fail(field, err.Error())
+		return nil
+	}
+	return &t
+}
+
+// attachments keeps the attachments with absolute http(s) URLs
+func (n *normalizer) attachments(field string, in []Attachment) []models.Attachment {
+	var out []models.Attachment
+	for _, a := range in {
+		u, err := url.Parse(a.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			n.warn(field, fmt.Sprintf("attachment %q is not an http(s) URL; skipped", a.URL))
+			continue
+		}
+		name := a.Name
+		if name == "" {
+			name = path.Base(u.Path)
+		}
+		out = append(out, models.Attachment{
+			Name:     name,
+			URL:      a.URL,
+			Size:     a.Size,
+			MimeType: a.MimeType,
+		})
+	}
+	return out
+}
+
+// ParseTime reads an RFC 3339 time, a date and time with or without an
+// offset, a date, or Unix seconds. Times without an offset are in loc.
+func ParseTime(value string, loc *time.Location) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a time", value)
+}
+
+// name normalizes an enum value: "In Progress" reads as in_progress
+func name(value string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "_", "-", "_").Replace(strings.TrimSpace(value)))
+}
+
+// customFields turns JSON numbers into integers or floats, so they are
+// stored and filtered as numbers
+func customFields(fields map[string]interface{}) map[string]interface{} {
+	for key, value := range fields {
+		number, ok := value.(json.Number)
+		if !ok {
+			continue
+		}
+		if i, err := number.Int64(); err == nil {
+			fields[key] = i
+		} else if f, err := number.Float64(); err == nil {
+			fields[key] = f
+		}
+	}
+	return fields
+}
@@ -0,0 +1,447 @@
+// Package importer reads tickets exported from other helpdesks.
+//
+// Files are CSV or JSON. Readers return one Record per ticket, with values as
+// written in the file, and Normalize checks and converts a record into
+// ticket values. Looking up agents, departments and existing tickets is left
+// to the caller.
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/minisource/ticket/internal/models"
+)
+
+// Record is one ticket read from an import file, with its messages
+type Record struct {
+	ID            string // In the source helpdesk; repeat imports skip records already imported by ID
+	TicketNumber  string
+	Subject       string
+	Description   string
+	Status        string
+	Priority      string
+	Type          string
+	Source        string
+	CustomerID    string
+	CustomerName  string
+	CustomerEmail string
+	AssigneeEmail string
+	Department    string // Name or slug
+	Category      string // Name
+	Tags          []string
+	CustomFields  map[string]interface{}
+	CreatedAt     string
+	UpdatedAt     string
+	ResolvedAt    string
+	ClosedAt      string
+	Rating        string
+	Attachments   []Attachment
+	Messages      []Message
+}
+
+// Message is a reply or note on a record
+type Message struct {
+	Body        string
+	AuthorEmail string
+	AuthorName  string
+	AuthorType  string // customer, agent or system; guessed from the email when empty
+	Internal    string
+	CreatedAt   string
+	Attachments []Attachment
+}
+
+// Attachment is a file linked by URL. In JSON it may be just the URL.
+type Attachment struct {
+	URL      string `json:"url"`
+	Name     string `json:"name"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+}
+
+// UnmarshalJSON accepts an attachment object or a URL string
+func (a *Attachment) UnmarshalJSON(data []byte) error {
+	var url string
+	if err := json.Unmarshal(data, &url); err == nil {
+		*a = Attachment{URL: url}
+		return nil
+	}
+	type plain Attachment
+	return json.Unmarshal(data, (*plain)(a))
+}
+
+// Reader reads records one at a time, returning io.EOF after the last
+type Reader interface {
+	// Next reads the next record. A *RecordError means only that record was
+	// unreadable; reading can go on.
+	Next() (*Record, error)
+}
+
+// RecordError is a record that couldn't be read
+type RecordError struct {
+	Err error
+}
+
+func (e *RecordError) Error() string { return e.Err.Error() }
+
+func (e *RecordError) Unwrap() error { return e.Err }
+
+// NewReader creates a reader for the format
+func NewReader(r io.Reader, format models.ImportFormat) (Reader, error) {
+	switch format {
+	case models.ImportCSV:
+		return newCSVReader(r)
+	case models.ImportJSON:
+		return newJSONReader(r)
+	}
+	return nil, fmt.Errorf("unknown import format %q", format)
+}
+
+// csvColumnAliases maps column names used by other exports, including this
+// service's own, to the import column names
+var csvColumnAliases = map[string]string{
+	"ticket_id":         "id",
+	"external_id":       "id",
+	"number":            "ticket_number",
+	"title":             "subject",
+	"body":              "description",
+	"requester_id":      "customer_id",
+	"requester_name":    "customer_name",
+	"requester_email":   "customer_email",
+	"assignee":          "assignee_email",
+	"assigned_to_email": "assignee_email",
+	"group":             "department",
+	"rating":            "satisfaction_rating",
+	"solved_at":         "resolved_at",
+}
+
+// csvReader reads one ticket per row. Consecutive rows with the same id are
+// one ticket: the first row has the ticket columns and every row can add a
+// message through the message_* columns.
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+	pending []string // Row read ahead that starts the next record
+	err     error
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	c := &csvReader{r: csv.NewReader(r), columns: map[string]int{}}
+	c.r.FieldsPerRecord = -1
+
+	header, err := c.r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("file is empty")
+		}
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.TrimSpace(name)
+		if !strings.HasPrefix(name, "cf.") {
+			name = strings.ToLower(strings.NewReplacer(" ", "_", "-", "_").Replace(name))
+		}
+		if alias, ok := csvColumnAliases[name]; ok {
+			name = alias
+		}
+		if _, taken := c.columns[name]; !taken {
+			c.columns[name] = i
+		}
+	}
+	if _, ok := c.columns["subject"]; !ok {
+		return nil, errors.New("header has no subject column")
+	}
+
+	return c, nil
+}
+
+func (c *csvReader) Next() (*Record, error) {
+	row := c.pending
+	c.pending = nil
+	if row == nil {
+		if err := c.err; err != nil {
+			c.err = nil
+			return nil, err
+		}
+		var err error
+		if row, err = c.r.Read(); err != nil {
+			return nil, c.readError(err)
+		}
+	}
+
+	rec := c.record(row)
+	c.addMessage(rec, row)
+
+	// Further rows of the same ticket only add messages
+	for rec.ID != "" {
+		next, err := c.r.Read()
+		if err != nil {
+			c.err = c.readError(err)
+			break
+		}
+		if c.get(next, "id") != rec.ID {
+			c.pending = next
+			break
+		}
+		c.addMessage(rec, next)
+	}
+
+	return rec, nil
+}
+
+func (c *csvReader) readError(err error) error {
+	if err == io.EOF {
+		return io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		// The reader carries on at the next line
+		return &RecordError{Err: err}
+	}
+	return fmt.Errorf("failed to read CSV: %w", err)
+}
+
+func (c *csvReader) get(row []string, column string) string {
+	if i, ok := c.columns[column]; ok && i < len(row) {
+		return strings.TrimSpace(row[i])
+	}
+	return ""
+}
+
+func (c *csvReader) record(row []string) *Record {
+	rec := &Record{
+		ID:            c.get(row, "id"),
+		TicketNumber:  c.get(row, "ticket_number"),
+		Subject:       c.get(row, "subject"),
+		Description:   c.get(row, "description"),
+		Status:        c.get(row, "status"),
+		Priority:      c.get(row, "priority"),
+		Type:          c.get(row, "type"),
+		Source:        c.get(row, "source"),
+		CustomerID:    c.get(row, "customer_id"),
+		CustomerName:  c.get(row, "customer_name"),
+		CustomerEmail: c.get(row, "customer_email"),
+		AssigneeEmail: c.get(row, "assignee_email"),
+		Department:    c.get(row, "department"),
+		Category:      c.get(row, "category"),
+		CreatedAt:     c.get(row, "created_at"),
+		UpdatedAt:     c.get(row, "updated_at"),
+		ResolvedAt:    c.get(row, "resolved_at"),
+		ClosedAt:      c.get(row, "closed_at"),
+		Rating:        c.get(row, "satisfaction_rating"),
+		Attachments:   splitAttachments(c.get(row, "attachments")),
+	}
+
+	for _, tag := range strings.FieldsFunc(c.get(row, "tags"), func(r rune) bool { return r == ',' || r == ';' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			rec.Tags = append(rec.Tags, tag)
+		}
+	}
+
+	for column, i := range c.columns {
+		if key := strings.TrimPrefix(column, "cf."); key != column && i < len(row) && row[i] != "" {
+			if rec.CustomFields == nil {
+				rec.CustomFields = map[string]interface{}{}
+			}
+			rec.CustomFields[key] = row[i]
+		}
+	}
+
+	return rec
+}
+
+func (c *csvReader) addMessage(rec *Record, row []string) {
+	msg := Message{
+		Body:        c.get(row, "message_body"),
+		AuthorEmail: c.get(row, "message_author_email"),
+		AuthorName:  c.get(row, "message_author_name"),
+		AuthorType:  c.get(row, "message_author_type"),
+		Internal:    c.get(row, "message_internal"),
+		CreatedAt:   c.get(row, "message_created_at"),
+		Attachments: splitAttachments(c.get(row, "message_attachments")),
+	}
+	if msg.Body != "" || len(msg.Attachments) > 0 {
+		rec.Messages = append(rec.Messages, msg)
+	}
+}
+
+// splitAttachments reads attachment URLs separated by whitespace
+func splitAttachments(value string) []Attachment {
+	var attachments []Attachment
+	for _, url := range strings.Fields(value) {
+		attachments = append(attachments, Attachment{URL: url})
+	}
+	return attachments
+}
+
+// jsonReader reads an array of tickets, or an object with the array under
+// "tickets", one element at a time
+type jsonReader struct {
+	dec *json.Decoder
+}
+
+// text decodes a JSON string, number or boolean as text
+type text string
+
+func (t *text) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case nil:
+		*t = ""
+	case string:
+		*t = text(v)
+	case float64, bool:
+		*t = text(strings.TrimSpace(string(data)))
+	default:
+		return fmt.Errorf("expected a string, number or boolean, got %s", data)
+	}
+	return nil
+}
+
+type jsonCustomer struct {
+	ID    text `json:"id"`
+	Name  text `json:"name"`
+	Email text `json:"email"`
+}
+
+type jsonMessage struct {
+	Body        text         `json:"body"`
+	Content     text         `json:"content"`
+	AuthorEmail text         `json:"authorEmail"`
+	AuthorName  text         `json:"authorName"`
+	AuthorType  text         `json:"authorType"`
+	Internal    text         `json:"internal"`
+	CreatedAt   text         `json:"createdAt"`
+	Attachments []Attachment `json:"attachments"`
+}
+
+type jsonTicket struct {
+	ID            text                   `json:"id"`
+	TicketNumber  text                   `json:"ticketNumber"`
+	Subject       text                   `json:"subject"`
+	Description   text                   `json:"description"`
+	Status        text                   `json:"status"`
+	Priority      text                   `json:"priority"`
+	Type          text                   `json:"type"`
+	Source        text                   `json:"source"`
+	Customer      jsonCustomer           `json:"customer"`
+	AssigneeEmail text                   `json:"assigneeEmail"`
+	Department    text                   `json:"department"`
+	Category      text                   `json:"category"`
+	Tags          []string               `json:"tags"`
+	CustomFields  map[string]interface{} `json:"customFields"`
+	CreatedAt     text                   `json:"createdAt"`
+	UpdatedAt     text                   `json:"updatedAt"`
+	ResolvedAt    text                   `json:"resolvedAt"`
+	ClosedAt      text                   `json:"closedAt"`
+	Rating        text                   `json:"satisfactionRating"`
+	Attachments   []Attachment           `json:"attachments"`
+	Messages      []jsonMessage          `json:"messages"`
+}
+
+func newJSONReader(r io.Reader) (*jsonReader, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	token, err := dec.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON: %w", err)
+	}
+
+	if token == json.Delim('{') {
+		// Skip to the tickets array
+		for {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read JSON: %w", err)
+			}
+			if key == json.Delim('}') {
+				return nil, errors.New(`object has no "tickets" array`)
+			}
+			if key == "tickets" {
+				break
+			}
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, fmt.Errorf("failed to read JSON: %w", err)
+			}
+		}
+		if token, err = dec.Token(); err != nil {
+			return nil, fmt.Errorf("failed to read JSON: %w", err)
+		}
+	}
+
+	if token != json.Delim('[') {
+		return nil, errors.New("expected an array of tickets")
+	}
+	return &jsonReader{dec: dec}, nil
+}
+
+func (j *jsonReader) Next() (*Record, error) {
+	if !j.dec.More() {
+		return nil, io.EOF
+	}
+
+	var t jsonTicket
+	if err := j.dec.Decode(&t); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("failed to read JSON: %w", err)
+		}
+		// The decoder has read past the whole value, so the next one is fine
+		return nil, &RecordError{Err: err}
+	}
+
+	rec := &Record{
+		ID:            string(t.ID),
+		TicketNumber:  string(t.TicketNumber),
+		Subject:       string(t.Subject),
+		Description:   string(t.Description),
+		Status:        string(t.Status),
+		Priority:      string(t.Priority),
+		Type:          string(t.Type),
+		Source:        string(t.Source),
+		CustomerID:    string(t.Customer.ID),
+		CustomerName:  string(t.Customer.Name),
+		CustomerEmail: string(t.Customer.Email),
+		AssigneeEmail: string(t.AssigneeEmail),
+		Department:    string(t.Department),
+		Category:      string(t.Category),
+		Tags:          t.Tags,
+		CustomFields:  t.CustomFields,
+		CreatedAt:     string(t.CreatedAt),
+		UpdatedAt:     string(t.UpdatedAt),
+		ResolvedAt:    string(t.ResolvedAt),
+		ClosedAt:      string(t.ClosedAt),
+		Rating:        string(t.Rating),
+		Attachments:   t.Attachments,
+	}
+	for _, m := range t.Messages {
+		body := m.Body
+		if body == "" {
+			body = m.Content
+		}
+		rec.Messages = append(rec.Messages, Message{
+			Body:        string(body),
+			AuthorEmail: string(m.AuthorEmail),
+			AuthorName:  string(m.AuthorName),
+			AuthorType:  string(m.AuthorType),
+			Internal:    string(m.Internal),
+			CreatedAt:   string(m.CreatedAt),
+			Attachments: m.Attachments,
+		})
+	}
+
+	return rec, nil
+}
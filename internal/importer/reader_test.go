@@ -0,0 +1,130 @@
+package importer
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readAll reads every record of an import file
+func readAll(t *testing.T, format models.ImportFormat, data string) []*Record {
+	r, err := NewReader(strings.NewReader(data), format)
+	require.NoError(t, err)
+
+	var records []*Record
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return records
+		}
+		require.NoError(t, err)
+		records = append(records, rec)
+	}
+}
+
+// TestImportCSV checks that rows sharing an id become one ticket with its
+// messages, and that other helpdesks' column names are understood
+func TestImportCSV(t *testing.T) {
+	data := "\ufeffTicket ID,Title,Status,Requester Email,Assignee,Created At,Solved At,cf.Plan,message_body,message_author_email,message_created_at\n" +
+		"z-1,Printer on fire,Solved,ann@example.com,bob@example.com,2024-05-01 09:00,2024-05-02 10:00,gold,It is on fire,ann@example.com,2024-05-01 09:00\n" +
+		"z-1,,,,,,,,Put it out,bob@example.com,2024-05-01 09:30\n" +
+		"z-2,Hello,New,cy@example.com,,2024-05-03T08:00:00Z,,,,,\n"
+
+	records := readAll(t, models.ImportCSV, data)
+	require.Len(t, records, 2)
+
+	first := records[0]
+	assert.Equal(t, "z-1", first.ID)
+	assert.Equal(t, "Printer on fire", first.Subject)
+	assert.Equal(t, "ann@example.com", first.CustomerEmail)
+	assert.Equal(t, "bob@example.com", first.AssigneeEmail)
+	assert.Equal(t, map[string]interface{}{"Plan": "gold"}, first.CustomFields)
+	require.Len(t, first.Messages, 2)
+	assert.Equal(t, "Put it out", first.Messages[1].Body)
+
+	tehran, err := time.LoadLocation("Asia/Tehran")
+	require.NoError(t, err)
+
+	ticket, issues := Normalize(first, tehran)
+	require.NotNil(t, ticket)
+	assert.Empty(t, issues)
+	assert.Equal(t, models.StatusResolved, ticket.Status)
+	assert.Equal(t, time.Date(2024, 5, 1, 5, 30, 0, 0, time.UTC), ticket.CreatedAt.UTC()) // 09:00 in Tehran
+	require.NotNil(t, ticket.ResolvedAt)
+	assert.Equal(t, *ticket.ResolvedAt, ticket.UpdatedAt)
+	assert.Equal(t, "It is on fire", ticket.Description)
+
+	second, issues := Normalize(records[1], tehran)
+	require.NotNil(t, second)
+	assert.Empty(t, issues)
+	assert.Equal(t, models.StatusOpen, second.Status)
+}
+
+// TestImportJSON checks JSON files and the validation report of bad records
+func TestImportJSON(t *testing.T) {
+	data := `{"exportedAt": "2024-06-01", "tickets": [
+		{"id": 17, "subject": "Refund", "status": "closed", "priority": "normal", "type": "gossip",
+		 "customer": {"email": "Dee@Example.com"}, "createdAt": 1717200000,
+		 "customFields": {"orderId": 42},
+		 "attachments": ["https://files.example.com/a/receipt.pdf", "file:///etc/passwd"],
+		 "messages": [{"content": "Later", "createdAt": "2024-06-01T02:00:00Z", "internal": true},
+		              {"body": "Earlier", "createdAt": "2024-06-01T01:00:00Z"}]},
+		{"id": "18", "subject": "", "status": "limbo", "createdAt": "yesterday"}
+	]}`
+
+	records := readAll(t, models.ImportJSON, data)
+	require.Len(t, records, 2)
+	assert.Equal(t, "17", records[0].ID)
+
+	ticket, issues := Normalize(records[0], time.UTC)
+	require.NotNil(t, ticket)
+	assert.Equal(t, "dee@example.com", ticket.CustomerEmail)
+	assert.Equal(t, models.PriorityMedium, ticket.Priority)
+	assert.Equal(t, models.TypeQuestion, ticket.Type)
+	assert.Equal(t, int64(42), ticket.CustomFields["orderId"])
+	require.Len(t, ticket.Attachments, 1)
+	assert.Equal(t, "receipt.pdf", ticket.Attachments[0].Name)
+	require.Len(t, ticket.Messages, 2)
+	assert.Equal(t, "Earlier", ticket.Messages[0].Body)
+	assert.True(t, ticket.Messages[1].Internal)
+	require.NotNil(t, ticket.ClosedAt)
+	assert.Equal(t, time.Date(2024, 6, 1, 2, 0, 0, 0, time.UTC), *ticket.ClosedAt)
+
+	// Unknown types and unusable attachments are warnings
+	require.Len(t, issues, 2)
+	for _, issue := range issues {
+		assert.True(t, issue.Warning)
+	}
+
+	bad, issues := Normalize(records[1], time.UTC)
+	assert.Nil(t, bad)
+	fields := map[string]bool{}
+	for _, issue := range issues {
+		assert.False(t, issue.Warning)
+		fields[issue.Field] = true
+	}
+	assert.Equal(t, map[string]bool{"subject": true, "customer_email": true, "status": true, "created_at": true}, fields)
+}
+
+// TestImportReaderErrors checks files that can't be imported at all
+func TestImportReaderErrors(t *testing.T) {
+	_, err := NewReader(strings.NewReader("id,name\n1,x\n"), models.ImportCSV)
+	assert.Error(t, err)
+
+	_, err = NewReader(strings.NewReader(`{"data": []}`), models.ImportJSON)
+	assert.Error(t, err)
+
+	r, err := NewReader(strings.NewReader(`[{"subject": ["not", "text"]}, {"subject": "ok"}]`), models.ImportJSON)
+	require.NoError(t, err)
+	_, err = r.Next()
+	var recErr *RecordError
+	assert.ErrorAs(t, err, &recErr)
+	rec, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "ok", rec.Subject)
+}
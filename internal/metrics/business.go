@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TicketCounter supplies the ticket counts behind the business gauges
+type TicketCounter interface {
+	CountOpenByDepartment(ctx context.Context) ([]models.TicketCount, error)
+	CountSLABreached(ctx context.Context) ([]models.TicketCount, error)
+	CountUnassigned(ctx context.Context) ([]models.TicketCount, error)
+}
+
+// AgentLoadSource supplies current agent workloads
+type AgentLoadSource interface {
+	ListLoad(ctx context.Context) ([]models.AgentLoad, error)
+}
+
+// BusinessCollector queries MongoDB on each scrape so gauges are never stale
+// and nothing runs between scrapes.
+type BusinessCollector struct {
+	tickets TicketCounter
+	agents  AgentLoadSource
+	timeout time.Duration
+
+	openTickets       *prometheus.Desc
+	slaBreached       *prometheus.Desc
+	unassignedTickets *prometheus.Desc
+	agentLoad         *prometheus.Desc
+	agentCapacity     *prometheus.Desc
+	scrapeErrors      *prometheus.Desc
+}
+
+// NewBusinessCollector creates a new business metrics collector
+func NewBusinessCollector(tickets TicketCounter, agents AgentLoadSource, timeout time.Duration) *BusinessCollector {
+	return &BusinessCollector{
+		tickets: tickets,
+		agents:  agents,
+		timeout: timeout,
+		openTickets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "open_tickets"),
+			"Open tickets by tenant and department.",
+			[]string{"tenant_id", "department_id"}, nil,
+		),
+		slaBreached: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "sla_breached_tickets"),
+			"Open tickets with a breached SLA by tenant.",
+			[]string{"tenant_id"}, nil,
+		),
+		unassignedTickets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "unassigned_tickets"),
+			"Open tickets without an assignee by tenant.",
+			[]string{"tenant_id"}, nil,
+		),
+		agentLoad: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "agent_current_tickets"),
+			"Tickets currently assigned to each agent.",
+			[]string{"tenant_id", "agent_id"}, nil,
+		),
+		agentCapacity: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "agent_max_tickets"),
+			"Maximum concurrent tickets for each agent.",
+			[]string{"tenant_id", "agent_id"}, nil,
+		),
+		scrapeErrors: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "business_scrape_errors"),
+			"Business metric queries that failed during this scrape.",
+			nil, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *BusinessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.openTickets
+	ch <- c.slaBreached
+	ch <- c.unassignedTickets
+	ch <- c.agentLoad
+	ch <- c.agentCapacity
+	ch <- c.scrapeErrors
+}
+
+// Collect implements prometheus.Collector
+func (c *BusinessCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	errorCount := 0
+
+	if counts, err := c.tickets.CountOpenByDepartment(ctx); err != nil {
+		errorCount++
+	} else {
+		for _, count := range counts {
+			ch <- prometheus.MustNewConstMetric(c.openTickets, prometheus.GaugeValue, float64(count.Count), count.TenantID, count.DepartmentID)
+		}
+	}
+
+	if counts, err := c.tickets.CountSLABreached(ctx); err != nil {
+		errorCount++
+	} else {
+		for _, count := range counts {
+			ch <- prometheus.MustNewConstMetric(c.slaBreached, prometheus.GaugeValue, float64(count.Count), count.TenantID)
+		}
+	}
+
+	if counts, err := c.tickets.CountUnassigned(ctx); err != nil {
+		errorCount++
+	} else {
+		for _, count := range counts {
+			ch <- prometheus.MustNewConstMetric(c.unassignedTickets, prometheus.GaugeValue, float64(count.Count), count.TenantID)
+		}
+	}
+
+	if loads, err := c.agents.ListLoad(ctx); err != nil {
+		errorCount++
+	} else {
+		for _, load := range loads {
+			ch <- prometheus.MustNewConstMetric(c.agentLoad, prometheus.GaugeValue, float64(load.CurrentTickets), load.TenantID, load.AgentID)
+			ch <- prometheus.MustNewConstMetric(c.agentCapacity, prometheus.GaugeValue, float64(load.MaxTickets), load.TenantID, load.AgentID)
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.scrapeErrors, prometheus.GaugeValue, float64(errorCount))
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"github.com/minisource/ticket/internal/cache"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CacheCollector exports hit and miss counts for lookup caches
+type CacheCollector struct {
+	caches   []*cache.Cache
+	requests *prometheus.Desc
+}
+
+// NewCacheCollector creates a new cache metrics collector; nil caches are skipped
+func NewCacheCollector(caches ...*cache.Cache) *CacheCollector {
+	c := &CacheCollector{
+		requests: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cache", "requests_total"),
+			"Cache lookups by cache and result.",
+			[]string{"cache", "result"}, nil,
+		),
+	}
+	for _, cc := range caches {
+		if cc != nil {
+			c.caches = append(c.caches, cc)
+		}
+	}
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *CacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.requests
+}
+
+// Collect implements prometheus.Collector
+func (c *CacheCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, cc := range c.caches {
+		stats := cc.Stats()
+		ch <- prometheus.MustNewConstMetric(c.requests, prometheus.CounterValue, float64(stats.Hits), stats.Name, "hit")
+		ch <- prometheus.MustNewConstMetric(c.requests, prometheus.CounterValue, float64(stats.Misses), stats.Name, "miss")
+	}
+}
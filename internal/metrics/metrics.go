@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "ticket"
+
+var (
+	// HTTPRequestDuration tracks request latency by route and status
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// MongoOperationDuration tracks repository method latency
+	MongoOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "mongo",
+		Name:      "operation_duration_seconds",
+		Help:      "MongoDB operation latency by repository and method.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"repository", "method"})
+
+	// TicketsCreated counts created tickets
+	TicketsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tickets_created_total",
+		Help:      "Tickets created by tenant and source.",
+	}, []string{"tenant_id", "source"})
+
+	// TicketsResolved counts resolved tickets
+	TicketsResolved = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tickets_resolved_total",
+		Help:      "Tickets resolved by tenant.",
+	}, []string{"tenant_id"})
+)
+
+// ObserveMongo starts timing a repository method; call the returned func when it finishes.
+//
+//	defer metrics.ObserveMongo("ticket", "GetByID")()
+func ObserveMongo(repository, method string) func() {
+	start := time.Now()
+	return func() {
+		MongoOperationDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+	}
+}
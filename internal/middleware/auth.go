@@ -108,22 +108,30 @@ func RequireRole(roles ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		ctx := c.Context()
 
-		userRoles, ok := c.Locals("roles").([]string)
-		if !ok {
-			return response.Forbidden(c, translator.Translate(ctx, "error.forbidden", nil))
+		if HasRole(c, roles...) {
+			return c.Next()
 		}
 
-		// Check if user has any of the required roles
-		for _, requiredRole := range roles {
-			for _, userRole := range userRoles {
-				if userRole == requiredRole {
-					return c.Next()
-				}
+		return response.Forbidden(c, translator.Translate(ctx, "error.forbidden", nil))
+	}
+}
+
+// HasRole reports whether the authenticated user has any of the given roles
+func HasRole(c *fiber.Ctx, roles ...string) bool {
+	userRoles, ok := c.Locals("roles").([]string)
+	if !ok {
+		return false
+	}
+
+	for _, requiredRole := range roles {
+		for _, userRole := range userRoles {
+			if userRole == requiredRole {
+				return true
 			}
 		}
-
-		return response.Forbidden(c, translator.Translate(ctx, "error.forbidden", nil))
 	}
+
+	return false
 }
 
 // RequirePermission creates middleware that requires specific permissions
@@ -156,9 +164,12 @@ func RequirePermission(permissions ...string) fiber.Handler {
 	}
 }
 
+// AgentRoles are the roles allowed on agent routes
+var AgentRoles = []string{"agent", "admin", "supervisor"}
+
 // AgentMiddleware creates middleware that checks if user is an agent
 func AgentMiddleware() fiber.Handler {
-	return RequireRole("agent", "admin", "supervisor")
+	return RequireRole(AgentRoles...)
 }
 
 // AdminMiddleware creates middleware that checks if user is an admin
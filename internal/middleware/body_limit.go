@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// AllowLargeBody raises the body limit to limit for requests to method and
+// path alone; every other route keeps the app's BodyLimit. The body is read
+// before routing, so this is set on the server rather than as a handler.
+// Multipart files of a known length are spooled to temp files, so large
+// uploads aren't held in memory.
+func AllowLargeBody(app *fiber.App, method, path string, limit int) {
+	path = strings.TrimSuffix(path, "/")
+
+	app.Server().HeaderReceived = func(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+		uri, _, _ := bytes.Cut(header.RequestURI(), []byte("?"))
+		if string(header.Method()) == method && strings.EqualFold(strings.TrimSuffix(string(uri), "/"), path) {
+			return fasthttp.RequestConfig{MaxRequestBodySize: limit}
+		}
+		return fasthttp.RequestConfig{}
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// TestAllowLargeBody checks that only the chosen route takes bodies over the
+// app's limit, and only up to its own
+func TestAllowLargeBody(t *testing.T) {
+	app := fiber.New(fiber.Config{BodyLimit: 1 << 10})
+	AllowLargeBody(app, fiber.MethodPost, "/admin/imports", 4<<10)
+
+	ok := func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+	app.Post("/admin/imports", ok)
+	app.Put("/admin/imports", ok)
+	app.Post("/tickets", ok)
+
+	send := func(method, target string, size int) int {
+		req := httptest.NewRequest(method, target, bytes.NewReader(make([]byte, size)))
+		resp, err := app.Test(req)
+		if errors.Is(err, fasthttp.ErrBodyTooLarge) {
+			// app.Test reports the rejection instead of the 413 it sends
+			return fiber.StatusRequestEntityTooLarge
+		}
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, fiber.StatusNoContent, send(fiber.MethodPost, "/admin/imports", 3<<10))
+	assert.Equal(t, fiber.StatusNoContent, send(fiber.MethodPost, "/admin/imports/?dry_run=true", 3<<10))
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, send(fiber.MethodPost, "/admin/imports", 5<<10))
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, send(fiber.MethodPut, "/admin/imports", 3<<10))
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, send(fiber.MethodPost, "/tickets", 3<<10))
+	assert.Equal(t, fiber.StatusNoContent, send(fiber.MethodPost, "/tickets", 1<<9))
+}
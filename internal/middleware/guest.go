@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/minisource/go-common/i18n"
+	"github.com/minisource/go-common/response"
+	"github.com/minisource/ticket/internal/guest"
+)
+
+// GuestTokenMiddleware authorizes access to a single ticket with a guest access token.
+// The token is only read from the X-Guest-Token header, never the URL, so it
+// stays out of access logs and Referer headers.
+func GuestTokenMiddleware(tokens *guest.TokenManager) fiber.Handler {
+	translator := i18n.GetTranslator()
+
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+
+		token := c.Get("X-Guest-Token")
+		if token == "" {
+			return response.Unauthorized(c, translator.Translate(ctx, "error.unauthorized", nil))
+		}
+
+		claims, err := tokens.Verify(token)
+		if err != nil {
+			return response.Unauthorized(c, translator.Translate(ctx, "guest.invalid_token", nil))
+		}
+
+		// A token only grants access to the ticket it was issued for
+		if id := c.Params("id"); id != "" && id != claims.TicketID {
+			return response.Forbidden(c, translator.Translate(ctx, "error.forbidden", nil))
+		}
+
+		c.Locals("guest_claims", claims)
+		c.Locals("tenant_id", claims.TenantID)
+		c.Request().Header.Set("X-Tenant-ID", claims.TenantID)
+
+		return c.Next()
+	}
+}
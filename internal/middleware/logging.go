@@ -5,6 +5,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/minisource/go-common/logging"
+	"github.com/minisource/ticket/internal/tracing"
 )
 
 // LoggingMiddleware creates logging middleware
@@ -32,6 +33,7 @@ func LoggingMiddleware(logger logging.Logger) fiber.Handler {
 			"tenant_id":  c.Get("X-Tenant-ID"),
 			"user_id":    c.Get("X-User-ID"),
 			"request_id": c.Get("X-Request-ID"),
+			"trace_id":   tracing.TraceID(c.UserContext()),
 		}
 
 		if err != nil {
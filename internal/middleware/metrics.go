@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/minisource/ticket/internal/metrics"
+)
+
+// MetricsMiddleware records request latency by route template and status
+func MetricsMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		middleware := c.Route()
+
+		// Process request
+		err := c.Next()
+
+		// The error handler sets the final status after middleware returns
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+
+		// Use the route template, not the path, to keep label cardinality bounded.
+		// When no handler matched, the current route is still this middleware's.
+		route := c.Route().Path
+		if c.Route() == middleware {
+			route = "unmatched"
+		}
+
+		metrics.HTTPRequestDuration.
+			WithLabelValues(c.Method(), route, strconv.Itoa(status)).
+			Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/minisource/ticket/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requestCount returns how many requests were observed with the given labels
+func requestCount(t *testing.T, method, route, status string) uint64 {
+	var m dto.Metric
+	observer := metrics.HTTPRequestDuration.WithLabelValues(method, route, status)
+	require.NoError(t, observer.(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+// TestMetricsMiddlewareLabels checks that requests are labelled by route
+// template and final status, including errors returned by handlers
+func TestMetricsMiddlewareLabels(t *testing.T) {
+	app := fiber.New()
+	app.Use(MetricsMiddleware())
+	app.Get("/tickets/:id", func(c *fiber.Ctx) error {
+		if c.Params("id") == "missing" {
+			return fiber.ErrNotFound
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Post("/tickets", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		route  string
+		status string
+	}{
+		{name: "route template", method: fiber.MethodGet, path: "/tickets/42", route: "/tickets/:id", status: "200"},
+		{name: "handler status", method: fiber.MethodPost, path: "/tickets", route: "/tickets", status: "201"},
+		{name: "fiber error", method: fiber.MethodGet, path: "/tickets/missing", route: "/tickets/:id", status: "404"},
+		{name: "unmatched", method: fiber.MethodGet, path: "/nowhere/7", route: "unmatched", status: "404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := requestCount(t, tt.method, tt.route, tt.status)
+
+			resp, err := app.Test(httptest.NewRequest(tt.method, tt.path, nil))
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, strconv.Itoa(resp.StatusCode))
+
+			assert.Equal(t, before+1, requestCount(t, tt.method, tt.route, tt.status))
+		})
+	}
+
+	// The raw path never becomes a label
+	assert.Zero(t, requestCount(t, fiber.MethodGet, "/tickets/42", "200"))
+}
@@ -1,37 +1,103 @@
 package middleware
 
 import (
+	"context"
+	"math"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/minisource/go-common/i18n"
 	"github.com/minisource/go-common/response"
+	"github.com/minisource/ticket/config"
 )
 
+// RateLimitStore counts requests per key in fixed time windows
+type RateLimitStore interface {
+	// Increment records a hit for key and returns the hit count in the
+	// current window along with the time the window resets
+	Increment(ctx context.Context, key string, window time.Duration) (int, time.Time, error)
+
+	// Close releases the store
+	Close() error
+}
+
 // RateLimitConfig holds rate limit configuration
 type RateLimitConfig struct {
+	Name       string        // Policy name, used to namespace keys
 	Max        int           // Maximum number of requests
 	Expiration time.Duration // Time window
 	KeyFunc    func(*fiber.Ctx) string
+	Store      RateLimitStore // Defaults to an in-memory store
+}
+
+// RateLimiter enforces a single rate limit policy
+type RateLimiter struct {
+	config RateLimitConfig
 }
 
-// DefaultRateLimitConfig returns default rate limit config
-func DefaultRateLimitConfig() RateLimitConfig {
-	return RateLimitConfig{
-		Max:        100,
-		Expiration: time.Minute,
-		KeyFunc: func(c *fiber.Ctx) string {
+// NewRateLimiter creates a new rate limiter
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	if config.Store == nil {
+		config.Store = NewMemoryRateLimitStore()
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = func(c *fiber.Ctx) string {
 			return c.IP()
-		},
+		}
 	}
+
+	return &RateLimiter{config: config}
 }
 
-// RateLimiter holds rate limit state
-type RateLimiter struct {
-	mu      sync.RWMutex
+// Handler returns the rate limit middleware handler
+func (rl *RateLimiter) Handler() fiber.Handler {
+	translator := i18n.GetTranslator()
+
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		key := "ratelimit:" + rl.config.Name + ":" + rl.config.KeyFunc(c)
+
+		count, resetAt, err := rl.config.Store.Increment(ctx, key, rl.config.Expiration)
+		if err != nil {
+			// Fail open so a store outage doesn't take the API down
+			return c.Next()
+		}
+
+		remaining := rl.config.Max - count
+		if remaining < 0 {
+			remaining = 0
+		}
+		resetSeconds := int(math.Ceil(time.Until(resetAt).Seconds()))
+		if resetSeconds < 0 {
+			resetSeconds = 0
+		}
+
+		// Set rate limit headers
+		c.Set("X-RateLimit-Limit", strconv.Itoa(rl.config.Max))
+		c.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Set("X-RateLimit-Reset", strconv.Itoa(resetSeconds))
+
+		if count > rl.config.Max {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(resetSeconds))
+			return response.New().
+				Status(fiber.StatusTooManyRequests).
+				Error("RATE_LIMIT_EXCEEDED", translator.Translate(ctx, "error.rate_limit_exceeded", nil)).
+				Send(c)
+		}
+
+		return c.Next()
+	}
+}
+
+// MemoryRateLimitStore keeps counters in process memory.
+// Limits are per instance, so use it for single-node deployments and tests.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
 	entries map[string]*rateLimitEntry
-	config  RateLimitConfig
+	stop    chan struct{}
+	once    sync.Once
 }
 
 type rateLimitEntry struct {
@@ -39,97 +105,110 @@ type rateLimitEntry struct {
 	expiresAt time.Time
 }
 
-// NewRateLimiter creates a new rate limiter
-func NewRateLimiter(config RateLimitConfig) *RateLimiter {
-	rl := &RateLimiter{
+// NewMemoryRateLimitStore creates a new in-memory rate limit store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	s := &MemoryRateLimitStore{
 		entries: make(map[string]*rateLimitEntry),
-		config:  config,
+		stop:    make(chan struct{}),
 	}
 
 	// Start cleanup goroutine
-	go rl.cleanup()
+	go s.cleanup()
 
-	return rl
+	return s
 }
 
-// cleanup removes expired entries
-func (rl *RateLimiter) cleanup() {
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
+// Increment implements RateLimitStore
+func (s *MemoryRateLimitStore) Increment(_ context.Context, key string, window time.Duration) (int, time.Time, error) {
+	now := time.Now()
 
-	for range ticker.C {
-		now := time.Now()
-		rl.mu.Lock()
-		for key, entry := range rl.entries {
-			if now.After(entry.expiresAt) {
-				delete(rl.entries, key)
-			}
-		}
-		rl.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.entries[key]
+	if !exists || now.After(entry.expiresAt) {
+		entry = &rateLimitEntry{expiresAt: now.Add(window)}
+		s.entries[key] = entry
 	}
-}
+	entry.count++
 
-// Handler returns the rate limit middleware handler
-func (rl *RateLimiter) Handler() fiber.Handler {
-	translator := i18n.GetTranslator()
+	return entry.count, entry.expiresAt, nil
+}
 
-	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
-		key := rl.config.KeyFunc(c)
-		now := time.Now()
+// Close stops the cleanup goroutine
+func (s *MemoryRateLimitStore) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	return nil
+}
 
-		rl.mu.Lock()
-		entry, exists := rl.entries[key]
+// cleanup removes expired entries until the store is closed
+func (s *MemoryRateLimitStore) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
 
-		if !exists || now.After(entry.expiresAt) {
-			rl.entries[key] = &rateLimitEntry{
-				count:     1,
-				expiresAt: now.Add(rl.config.Expiration),
-			}
-			rl.mu.Unlock()
-			return c.Next()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
 		}
 
-		if entry.count >= rl.config.Max {
-			rl.mu.Unlock()
-			return response.New().
-				Status(fiber.StatusTooManyRequests).
-				Error("RATE_LIMIT_EXCEEDED", translator.Translate(ctx, "error.rate_limit_exceeded", nil)).
-				Send(c)
+		now := time.Now()
+		s.mu.Lock()
+		for key, entry := range s.entries {
+			if now.After(entry.expiresAt) {
+				delete(s.entries, key)
+			}
 		}
+		s.mu.Unlock()
+	}
+}
 
-		entry.count++
-		rl.mu.Unlock()
+// Key functions
 
-		// Set rate limit headers
-		c.Set("X-RateLimit-Limit", string(rune(rl.config.Max)))
-		c.Set("X-RateLimit-Remaining", string(rune(rl.config.Max-entry.count)))
+// TenantKey limits by the tenant TenantMiddleware resolved, falling back to
+// client IP. Use it after TenantMiddleware.
+func TenantKey(c *fiber.Ctx) string {
+	if tenantID, ok := c.Locals("tenant_id").(string); ok && tenantID != "" {
+		return tenantID
+	}
+	return c.IP()
+}
 
-		return c.Next()
+// UserKey limits by the authenticated user, falling back to client IP. The
+// user comes from the validated token, never from request headers, so
+// switching tenants or user headers doesn't start a fresh window.
+func UserKey(c *fiber.Ctx) string {
+	if userID, ok := c.Locals("user_id").(string); ok && userID != "" {
+		return userID
 	}
+	return c.IP()
 }
 
-// RateLimitMiddleware creates a rate limit middleware with default config
-func RateLimitMiddleware() fiber.Handler {
-	config := DefaultRateLimitConfig()
-	limiter := NewRateLimiter(config)
-	return limiter.Handler()
+// IPKey limits by client IP alone, for unauthenticated endpoints where any
+// other header is chosen by the caller. Behind a proxy, ClientIPConfig makes
+// this the client's address rather than the proxy's.
+func IPKey(c *fiber.Ctx) string {
+	return c.IP()
 }
 
-// RateLimitMiddlewareWithConfig creates a rate limit middleware with custom config
-func RateLimitMiddlewareWithConfig(max int, expiration time.Duration) fiber.Handler {
-	config := RateLimitConfig{
-		Max:        max,
-		Expiration: expiration,
-		KeyFunc: func(c *fiber.Ctx) string {
-			// Use tenant + IP for rate limiting
-			tenantID := c.Get("X-Tenant-ID")
-			if tenantID != "" {
-				return tenantID + ":" + c.IP()
-			}
-			return c.IP()
-		},
+// ClientIPConfig sets app up to read client IPs from the header a trusted
+// proxy sets, when one is configured
+func ClientIPConfig(app fiber.Config, cfg config.ServerConfig) fiber.Config {
+	if cfg.ProxyHeader == "" {
+		return app
 	}
-	limiter := NewRateLimiter(config)
-	return limiter.Handler()
+
+	app.ProxyHeader = cfg.ProxyHeader
+	app.EnableIPValidation = true
+	if len(cfg.TrustedProxies) > 0 {
+		app.EnableTrustedProxyCheck = true
+		app.TrustedProxies = cfg.TrustedProxies
+	}
+	return app
+}
+
+// RouteKey limits by user and the matched route
+func RouteKey(c *fiber.Ctx) string {
+	return UserKey(c) + ":" + c.Method() + ":" + c.Route().Path
 }
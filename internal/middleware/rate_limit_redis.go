@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// incrementScript increments a counter and starts its window on the first hit,
+// returning the count and the remaining window in milliseconds
+var incrementScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// RedisRateLimitStore keeps counters in Redis so limits are shared across instances.
+// It works with any server speaking the Redis protocol and supporting EVAL.
+type RedisRateLimitStore struct {
+	client redis.Scripter
+}
+
+// NewRedisRateLimitStore creates a new Redis-backed rate limit store
+func NewRedisRateLimitStore(client redis.Scripter) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client}
+}
+
+// Increment implements RateLimitStore
+func (s *RedisRateLimitStore) Increment(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	result, err := incrementScript.Run(ctx, s.client, []string{key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to increment rate limit counter: %w", err)
+	}
+	if len(result) != 2 {
+		return 0, time.Time{}, fmt.Errorf("unexpected rate limit script result: %v", result)
+	}
+
+	return int(result[0]), time.Now().Add(time.Duration(result[1]) * time.Millisecond), nil
+}
+
+// Close is a no-op; the Redis client is closed by its owner
+func (s *RedisRateLimitStore) Close() error {
+	return nil
+}
@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/minisource/ticket/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRateLimiterHeaders checks the limit headers on allowed requests and
+// Retry-After once the limit is reached, per key
+func TestRateLimiterHeaders(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	t.Cleanup(func() { _ = store.Close() })
+
+	limiter := NewRateLimiter(RateLimitConfig{
+		Name:       "test",
+		Max:        2,
+		Expiration: time.Minute,
+		KeyFunc:    TenantKey,
+		Store:      store,
+	})
+
+	app := fiber.New()
+	app.Get("/", TenantMiddleware(), limiter.Handler(), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	send := func(tenantID string) *http.Response {
+		req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		req.Header.Set("X-Tenant-ID", tenantID)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	for i, remaining := range []string{"1", "0"} {
+		resp := send("acme")
+		assert.Equal(t, fiber.StatusNoContent, resp.StatusCode, "request %d", i+1)
+		assert.Equal(t, "2", resp.Header.Get("X-RateLimit-Limit"))
+		assert.Equal(t, remaining, resp.Header.Get("X-RateLimit-Remaining"))
+		assert.Empty(t, resp.Header.Get(fiber.HeaderRetryAfter))
+	}
+
+	resp := send("acme")
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "0", resp.Header.Get("X-RateLimit-Remaining"))
+	retryAfter, err := strconv.Atoi(resp.Header.Get(fiber.HeaderRetryAfter))
+	require.NoError(t, err)
+	assert.InDelta(t, 60, retryAfter, 1)
+	assert.Equal(t, resp.Header.Get("X-RateLimit-Reset"), resp.Header.Get(fiber.HeaderRetryAfter))
+
+	// Other keys have their own window
+	resp = send("globex")
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("X-RateLimit-Remaining"))
+}
+
+// TestMemoryRateLimitStoreWindow checks that counts start over once the
+// window has passed
+func TestMemoryRateLimitStoreWindow(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	t.Cleanup(func() { _ = store.Close() })
+
+	count, resetAt, err := store.Increment(t.Context(), "k", 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.WithinDuration(t, time.Now().Add(50*time.Millisecond), resetAt, 20*time.Millisecond)
+
+	count, _, err = store.Increment(t.Context(), "k", 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	time.Sleep(60 * time.Millisecond)
+	count, _, err = store.Increment(t.Context(), "k", 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+// TestIPKeyBehindProxy checks that guests behind a proxy get their own
+// buckets, and that the header is ignored from peers that aren't trusted
+func TestIPKeyBehindProxy(t *testing.T) {
+	newApp := func(cfg config.ServerConfig) *fiber.App {
+		store := NewMemoryRateLimitStore()
+		t.Cleanup(func() { _ = store.Close() })
+
+		limiter := NewRateLimiter(RateLimitConfig{
+			Name:       "guest",
+			Max:        1,
+			Expiration: time.Minute,
+			KeyFunc:    IPKey,
+			Store:      store,
+		})
+
+		app := fiber.New(ClientIPConfig(fiber.Config{}, cfg))
+		app.Post("/guest/tickets", limiter.Handler(), func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusNoContent)
+		})
+		return app
+	}
+	send := func(app *fiber.App, clientIP string) int {
+		req := httptest.NewRequest(fiber.MethodPost, "/guest/tickets", nil)
+		req.Header.Set("X-Real-IP", clientIP)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	// app.Test connects from 0.0.0.0, which plays the proxy here
+	t.Run("Trusted Proxy", func(t *testing.T) {
+		app := newApp(config.ServerConfig{ProxyHeader: "X-Real-IP", TrustedProxies: []string{"0.0.0.0"}})
+
+		assert.Equal(t, fiber.StatusNoContent, send(app, "203.0.113.1"))
+		assert.Equal(t, fiber.StatusTooManyRequests, send(app, "203.0.113.1"))
+		assert.Equal(t, fiber.StatusNoContent, send(app, "203.0.113.2"))
+	})
+
+	t.Run("Untrusted Peer", func(t *testing.T) {
+		app := newApp(config.ServerConfig{ProxyHeader: "X-Real-IP", TrustedProxies: []string{"10.0.0.1"}})
+
+		assert.Equal(t, fiber.StatusNoContent, send(app, "203.0.113.1"))
+		assert.Equal(t, fiber.StatusTooManyRequests, send(app, "203.0.113.2"))
+	})
+
+	t.Run("No Proxy Header", func(t *testing.T) {
+		app := newApp(config.ServerConfig{})
+
+		assert.Equal(t, fiber.StatusNoContent, send(app, "203.0.113.1"))
+		assert.Equal(t, fiber.StatusTooManyRequests, send(app, "203.0.113.2"))
+	})
+}
+
+// TestUserKeyIgnoresHeaders checks that user limits follow the authenticated
+// user, whatever tenant or user headers the client sends
+func TestUserKeyIgnoresHeaders(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	t.Cleanup(func() { _ = store.Close() })
+
+	limiter := NewRateLimiter(RateLimitConfig{
+		Name:       "user",
+		Max:        1,
+		Expiration: time.Minute,
+		KeyFunc:    RouteKey,
+		Store:      store,
+	})
+
+	app := fiber.New()
+	authenticate := func(c *fiber.Ctx) error {
+		if userID := c.Get("Authorization"); userID != "" {
+			c.Locals("user_id", userID)
+		}
+		return c.Next()
+	}
+	app.Post("/tickets", authenticate, limiter.Handler(), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	send := func(userID, tenantID, claimedUserID string) int {
+		req := httptest.NewRequest(fiber.MethodPost, "/tickets", nil)
+		req.Header.Set("Authorization", userID)
+		req.Header.Set("X-Tenant-ID", tenantID)
+		req.Header.Set("X-User-ID", claimedUserID)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, fiber.StatusNoContent, send("alice", "acme", "alice"))
+	assert.Equal(t, fiber.StatusTooManyRequests, send("alice", "globex", "alice"))
+	assert.Equal(t, fiber.StatusTooManyRequests, send("alice", "acme", "mallory"))
+	assert.Equal(t, fiber.StatusNoContent, send("bob", "acme", "bob"))
+}
+
+// TestMemoryRateLimitStoreClose checks that Close can be called more than once
+func TestMemoryRateLimitStoreClose(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	require.NoError(t, store.Close())
+	require.NoError(t, store.Close())
+}
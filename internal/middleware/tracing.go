@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/minisource/ticket/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware starts a server span for each request, continuing any W3C
+// trace context in the incoming headers. The span context is stored as the
+// request's user context, so handlers must pass c.UserContext() downstream.
+func TracingMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Derive from the request context so request-scoped values stay reachable
+		ctx := otel.GetTextMapPropagator().Extract(c.Context(), headerCarrier{c: c})
+
+		ctx, span := tracing.Start(ctx, c.Method()+" "+c.Path(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Method()),
+				semconv.URLPath(c.Path()),
+				semconv.ClientAddress(c.IP()),
+			),
+		)
+		defer span.End()
+
+		c.SetUserContext(ctx)
+		middleware := c.Route()
+
+		// Process request
+		err := c.Next()
+
+		// Name the span after the route template once routing is done; the
+		// route is still this middleware's when no handler matched
+		if route := c.Route(); route != middleware {
+			span.SetName(c.Method() + " " + route.Path)
+			span.SetAttributes(semconv.HTTPRoute(route.Path))
+		}
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			span.RecordError(err)
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+		}
+
+		return err
+	}
+}
+
+// headerCarrier adapts fiber request headers to a propagation.TextMapCarrier
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	headers := h.c.GetReqHeaders()
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	return keys
+}
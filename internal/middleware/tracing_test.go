@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/minisource/go-common/logging"
+	"github.com/minisource/ticket/config"
+	"github.com/minisource/ticket/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordingLogger keeps the extra fields of every entry it logs
+type recordingLogger struct {
+	logging.Logger
+	entries []map[logging.ExtraKey]interface{}
+}
+
+func (l *recordingLogger) Info(_ logging.Category, _ logging.SubCategory, _ string, extra map[logging.ExtraKey]interface{}) {
+	l.entries = append(l.entries, extra)
+}
+
+func (l *recordingLogger) Warn(_ logging.Category, _ logging.SubCategory, _ string, extra map[logging.ExtraKey]interface{}) {
+	l.entries = append(l.entries, extra)
+}
+
+func (l *recordingLogger) Error(_ logging.Category, _ logging.SubCategory, _ string, extra map[logging.ExtraKey]interface{}) {
+	l.entries = append(l.entries, extra)
+}
+
+// TestTraceIDInLogs checks that the request log carries the trace ID of the
+// incoming traceparent header, or of the new trace when there is none
+func TestTraceIDInLogs(t *testing.T) {
+	_, err := tracing.Init(t.Context(), config.TracingConfig{})
+	require.NoError(t, err)
+
+	spans := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	logger := &recordingLogger{}
+	app := fiber.New()
+	app.Use(TracingMiddleware())
+	app.Use(LoggingMiddleware(logger))
+	app.Get("/tickets/:id", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(fiber.MethodGet, "/tickets/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	_, err = app.Test(req)
+	require.NoError(t, err)
+
+	require.Len(t, logger.entries, 1)
+	assert.Equal(t, traceID, logger.entries[0]["trace_id"])
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	assert.Equal(t, traceID, ended[0].SpanContext().TraceID().String())
+	assert.Equal(t, "GET /tickets/:id", ended[0].Name())
+
+	// Without a header a new trace is started, and unmatched paths keep the raw name
+	_, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/nowhere", nil))
+	require.NoError(t, err)
+
+	require.Len(t, logger.entries, 2)
+	ended = spans.Ended()
+	require.Len(t, ended, 2)
+	assert.Equal(t, ended[1].SpanContext().TraceID().String(), logger.entries[1]["trace_id"])
+	assert.NotEqual(t, traceID, logger.entries[1]["trace_id"])
+	assert.Equal(t, "GET /nowhere", ended[1].Name())
+}
@@ -6,6 +6,13 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Auto-assign strategies
+const (
+	AutoAssignRoundRobin = "round_robin"
+	AutoAssignLeastBusy  = "least_busy"
+	AutoAssignRandom     = "random"
+)
+
 // Department represents a support department
 type Department struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
@@ -55,6 +62,12 @@ type Department struct {
 	Order int    `bson:"order" json:"order"`
 }
 
+// AssignmentCursor tracks the round-robin position for a department
+type AssignmentCursor struct {
+	ID       string `bson:"_id" json:"id"` // department ID, or tenant key for tickets without one
+	Sequence int64  `bson:"sequence" json:"sequence"`
+}
+
 // BusinessHours represents working hours configuration
 type BusinessHours struct {
 	Enabled  bool          `bson:"enabled" json:"enabled"`
@@ -20,6 +20,7 @@ type CreateTicketRequest struct {
 	Source       TicketSource           `json:"source,omitempty"`
 	DepartmentID string                 `json:"departmentId,omitempty"`
 	CategoryID   string                 `json:"categoryId,omitempty"`
+	Language     string                 `json:"language,omitempty"`
 	Tags         []string               `json:"tags,omitempty"`
 	CustomFields map[string]interface{} `json:"customFields,omitempty"`
 	Attachments  []AttachmentInput      `json:"attachments,omitempty"`
@@ -38,6 +39,9 @@ type UpdateTicketRequest struct {
 	Tags         []string               `json:"tags,omitempty"`
 	CustomFields map[string]interface{} `json:"customFields,omitempty"`
 	CCEmails     []string               `json:"ccEmails,omitempty"`
+
+	// Version the client last saw; the update fails if the ticket has moved on
+	Version *int64 `json:"version,omitempty"`
 }
 
 // ChangeStatusRequest represents a request to change ticket status
@@ -52,6 +56,17 @@ type AssignTicketRequest struct {
 	Comment    string `json:"comment,omitempty"`
 }
 
+// TypingRequest represents a typing indicator update
+type TypingRequest struct {
+	Typing  bool `json:"typing"`
+	Private bool `json:"private,omitempty"` // Agent is drafting an internal note
+}
+
+// TicketPresenceRequest represents an agent's activity on a ticket
+type TicketPresenceRequest struct {
+	Activity ViewerActivity `json:"activity" validate:"required,oneof=viewing replying left"`
+}
+
 // TransferTicketRequest represents a request to transfer a ticket
 type TransferTicketRequest struct {
 	DepartmentID string `json:"departmentId" validate:"required"`
@@ -98,6 +113,28 @@ type AttachmentInput struct {
 	MimeType string `json:"mimeType"`
 }
 
+// CreateGuestTicketRequest represents a ticket submitted without an account
+type CreateGuestTicketRequest struct {
+	TenantID     string            `json:"tenantId,omitempty"`
+	Name         string            `json:"name" validate:"required,min=2,max=100"`
+	Email        string            `json:"email" validate:"required,email"`
+	Phone        string            `json:"phone,omitempty"`
+	Subject      string            `json:"subject" validate:"required,min=5,max=200"`
+	Description  string            `json:"description" validate:"required,min=10,max=10000"`
+	Type         TicketType        `json:"type,omitempty"`
+	DepartmentID string            `json:"departmentId,omitempty"`
+	CategoryID   string            `json:"categoryId,omitempty"`
+	Attachments  []AttachmentInput `json:"attachments,omitempty"`
+	Website      string            `json:"website,omitempty"` // Honeypot, must be left empty
+}
+
+// ResendGuestLinkRequest represents a request to email a fresh access link
+type ResendGuestLinkRequest struct {
+	TenantID     string `json:"tenantId,omitempty"`
+	Email        string `json:"email" validate:"required,email"`
+	TicketNumber string `json:"ticketNumber" validate:"required"`
+}
+
 // ========================
 // Message DTOs
 // ========================
@@ -108,6 +145,9 @@ type CreateMessageRequest struct {
 	Type        MessageType       `json:"type,omitempty"`
 	IsPrivate   bool              `json:"isPrivate,omitempty"`
 	Attachments []AttachmentInput `json:"attachments,omitempty"`
+
+	// Rejects the reply if another message arrived after this one
+	LastSeenMessageID string `json:"lastSeenMessageId,omitempty"`
 }
 
 // UpdateMessageRequest represents a request to update a message
@@ -221,6 +261,22 @@ type UpdateAgentStatusRequest struct {
 	Status AgentStatus `json:"status" validate:"required"`
 }
 
+// UpdateAgentScheduleRequest represents a request to set an agent's shifts
+type UpdateAgentScheduleRequest struct {
+	Enabled  bool          `json:"enabled"`
+	Timezone *string       `json:"timezone,omitempty"`
+	Shifts   []DaySchedule `json:"shifts"`
+}
+
+// CreateOutOfOfficeRequest represents a request to schedule agent leave
+type CreateOutOfOfficeRequest struct {
+	Start        time.Time `json:"start" validate:"required"`
+	End          time.Time `json:"end" validate:"required"`
+	Reason       string    `json:"reason,omitempty"`
+	DelegateID   string    `json:"delegateId,omitempty"`
+	TicketAction string    `json:"ticketAction,omitempty"` // hold (default), reassign
+}
+
 // ========================
 // SLA DTOs
 // ========================
@@ -272,30 +328,110 @@ type UpdateCannedResponseRequest struct {
 	IsActive *bool    `json:"isActive,omitempty"`
 }
 
+// ========================
+// Saved View DTOs
+// ========================
+
+// CreateSavedViewRequest represents a request to save a ticket view
+type CreateSavedViewRequest struct {
+	Name      string   `json:"name" validate:"required"`
+	Query     string   `json:"query"`
+	Columns   []string `json:"columns,omitempty"`
+	SortBy    string   `json:"sortBy,omitempty"`
+	SortOrder string   `json:"sortOrder,omitempty"`
+	Shared    bool     `json:"shared,omitempty"`
+}
+
+// UpdateSavedViewRequest represents a request to update a saved view
+type UpdateSavedViewRequest struct {
+	Name      *string  `json:"name,omitempty"`
+	Query     *string  `json:"query,omitempty"`
+	Columns   []string `json:"columns,omitempty"`
+	SortBy    *string  `json:"sortBy,omitempty"`
+	SortOrder *string  `json:"sortOrder,omitempty"`
+	Shared    *bool    `json:"shared,omitempty"`
+}
+
+// ViewCount is the number of tickets in a saved view, for list badges.
+// Counting stops at a cap, reported by Capped.
+type ViewCount struct {
+	ViewID primitive.ObjectID `json:"viewId"`
+	Name   string             `json:"name"`
+	Count  int64              `json:"count"`
+	Capped bool               `json:"capped,omitempty"`
+}
+
 // ========================
 // Filter/List DTOs
 // ========================
 
 // TicketFilter represents filter options for listing tickets
 type TicketFilter struct {
-	TenantID     string           `query:"tenantId"`
-	Status       []TicketStatus   `query:"status"`
-	Priority     []TicketPriority `query:"priority"`
-	Type         []TicketType     `query:"type"`
-	DepartmentID string           `query:"departmentId"`
-	CategoryID   string           `query:"categoryId"`
-	AssignedToID string           `query:"assignedToId"`
-	CustomerID   string           `query:"customerId"`
-	Tags         []string         `query:"tags"`
-	SLABreached  *bool            `query:"slaBreached"`
-	Unassigned   *bool            `query:"unassigned"`
-	Search       string           `query:"search"`
-	CreatedFrom  *time.Time       `query:"createdFrom"`
-	CreatedTo    *time.Time       `query:"createdTo"`
-	SortBy       string           `query:"sortBy"`
-	SortOrder    string           `query:"sortOrder"`
-	Page         int              `query:"page"`
-	PerPage      int              `query:"perPage"`
+	TenantID     string            `query:"tenantId"`
+	Status       []TicketStatus    `query:"status"`
+	Priority     []TicketPriority  `query:"priority"`
+	Type         []TicketType      `query:"type"`
+	Source       []TicketSource    `query:"source"`
+	DepartmentID string            `query:"departmentId"`
+	CategoryID   string            `query:"categoryId"`
+	AssignedToID string            `query:"assignedToId"`
+	CustomerID   string            `query:"customerId"`
+	Tags         []string          `query:"tags"`
+	SLABreached  *bool             `query:"slaBreached"`
+	Unassigned   *bool             `query:"unassigned"`
+	HasRating    *bool             `query:"hasRating"`
+	Search       string            `query:"search"`
+	Query        string            `query:"q"` // Ticket query language; see internal/query
+	CreatedFrom  *time.Time        `query:"createdFrom"`
+	CreatedTo    *time.Time        `query:"createdTo"`
+	UpdatedFrom  *time.Time        `query:"updatedFrom"`
+	UpdatedTo    *time.Time        `query:"updatedTo"`
+	DueBefore    *time.Time        `query:"dueBefore"`    // Resolution due before this time
+	CustomFields map[string]string `query:"customFields"` // Custom field values; numbers and booleans match their typed values too
+	SortBy       string            `query:"sortBy"`       // Comma-separated fields, each optionally suffixed with :asc or :desc
+	SortOrder    string            `query:"sortOrder"`    // Direction for fields without one; defaults to desc
+	Page         int               `query:"page"`
+	PerPage      int               `query:"perPage"`
+}
+
+// TicketSearchQuery represents a full-text search over tickets and their messages
+type TicketSearchQuery struct {
+	TenantID       string
+	Query          string
+	CustomerID     string // Restricts results to this customer's tickets when set
+	IncludePrivate bool   // Searches internal notes
+	Fuzzy          bool   // Also matches misspellings; backends without support ignore it
+	Status         []TicketStatus
+	Priority       []TicketPriority
+	DepartmentID   []string
+	Page           int
+	PerPage        int
+}
+
+// TicketTextMatch is a ticket matched by a text search with its relevance score
+type TicketTextMatch struct {
+	Ticket `bson:",inline"`
+	Score  float64 `bson:"score"`
+}
+
+// MessageTextMatch is a message matched by a text search with its relevance score
+type MessageTextMatch struct {
+	TicketMessage `bson:",inline"`
+	Score         float64 `bson:"score"`
+}
+
+// CursorRequest asks for one page of a keyset-paginated list
+type CursorRequest struct {
+	Cursor       string // From the previous page's NextCursor; empty for the first page
+	Limit        int
+	IncludeTotal bool // Counting every match is slow on large lists, so it's opt-in
+}
+
+// CursorPage is one page of a keyset-paginated list
+type CursorPage[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"` // Empty on the last page
+	Total      *int64 `json:"total,omitempty"`
 }
 
 // ========================
@@ -353,6 +489,52 @@ type TicketListResponse struct {
 	TotalPages int              `json:"totalPages"`
 }
 
+// SearchHighlight is a snippet of a matched field. Text is HTML-escaped and
+// matched terms are wrapped in <mark> tags.
+type SearchHighlight struct {
+	Field   string `json:"field"` // subject, description, message
+	Snippet string `json:"snippet"`
+}
+
+// TicketSearchResult is a ticket matched by a search, ranked by relevance
+type TicketSearchResult struct {
+	Ticket     *Ticket           `json:"ticket"`
+	Score      float64           `json:"score"`
+	Highlights []SearchHighlight `json:"highlights"`
+	Message    *TicketMessage    `json:"message,omitempty"` // Best matching message
+}
+
+// FacetCount is the number of matching tickets with a given field value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// TicketSearchResponse is a page of search results with facet counts over
+// all matching tickets, keyed by status, priority and department
+type TicketSearchResponse struct {
+	Results []TicketSearchResult    `json:"results"`
+	Facets  map[string][]FacetCount `json:"facets"`
+}
+
+// SearchRebuildReport summarizes a search index rebuild
+type SearchRebuildReport struct {
+	Backend    string    `json:"backend"`
+	Tickets    int       `json:"tickets"`
+	Messages   int       `json:"messages"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+// StatsFilter narrows ticket statistics. Zero values don't filter.
+type StatsFilter struct {
+	From         *time.Time // Created at or after
+	To           *time.Time // Created at or before
+	DepartmentID string
+	AgentID      string // Assignee
+	CustomerID   string
+}
+
 // TicketStats represents ticket statistics
 type TicketStats struct {
 	TotalTickets      int64            `json:"totalTickets"`
@@ -369,3 +551,57 @@ type TicketStats struct {
 	AvgResolutionTime int64            `json:"avgResolutionTime"` // in minutes
 	AvgSatisfaction   float64          `json:"avgSatisfaction"`
 }
+
+// TicketCount is a ticket count for a tenant and, optionally, a department
+type TicketCount struct {
+	TenantID     string `bson:"tenant_id" json:"tenantId"`
+	DepartmentID string `bson:"department_id,omitempty" json:"departmentId,omitempty"`
+	Count        int64  `bson:"count" json:"count"`
+}
+
+// AgentLoad is an agent's current workload
+type AgentLoad struct {
+	TenantID       string `bson:"tenant_id" json:"tenantId"`
+	AgentID        string `bson:"agent_id" json:"agentId"`
+	CurrentTickets int    `bson:"current_tickets" json:"currentTickets"`
+	MaxTickets     int    `bson:"max_tickets" json:"maxTickets"`
+}
+
+// AgentTicketStats are an agent's counters recomputed from the tickets collection
+type AgentTicketStats struct {
+	UserID           string `bson:"_id" json:"userId"`
+	CurrentTickets   int    `bson:"current_tickets" json:"currentTickets"`
+	TicketsToday     int    `bson:"tickets_today" json:"ticketsToday"`
+	TicketsThisWeek  int    `bson:"tickets_this_week" json:"ticketsThisWeek"`
+	TicketsThisMonth int    `bson:"tickets_this_month" json:"ticketsThisMonth"`
+	AvgResponseTime  int64  `bson:"avg_response_time" json:"avgResponseTime"` // in minutes
+}
+
+// DepartmentTicketStats are a department's counters recomputed from the tickets collection
+type DepartmentTicketStats struct {
+	DepartmentID    primitive.ObjectID `bson:"_id" json:"departmentId"`
+	OpenTickets     int                `bson:"open_tickets" json:"openTickets"`
+	TotalTickets    int                `bson:"total_tickets" json:"totalTickets"`
+	AvgResponseTime int64              `bson:"avg_response_time" json:"avgResponseTime"` // in minutes
+}
+
+// StatCorrection records a counter that reconciliation changed
+type StatCorrection struct {
+	Entity   string `json:"entity"` // agent, department
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Field    string `json:"field"`
+	OldValue int64  `json:"oldValue"`
+	NewValue int64  `json:"newValue"`
+}
+
+// ReconcileReport summarizes a reconciliation run for a tenant
+type ReconcileReport struct {
+	TenantID           string           `json:"tenantId"`
+	AgentsChecked      int              `json:"agentsChecked"`
+	DepartmentsChecked int              `json:"departmentsChecked"`
+	Corrections        []StatCorrection `json:"corrections"`
+	Skipped            int              `json:"skipped"` // Changed concurrently; picked up by the next run
+	StartedAt          time.Time        `json:"startedAt"`
+	FinishedAt         time.Time        `json:"finishedAt"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExportStatus is the state of a background export
+type ExportStatus string
+
+const (
+	ExportPending ExportStatus = "pending"
+	ExportRunning ExportStatus = "running"
+	ExportDone    ExportStatus = "done"
+	ExportFailed  ExportStatus = "failed"
+)
+
+// ExportKind is what an export contains
+type ExportKind string
+
+const (
+	ExportTickets ExportKind = "tickets"
+)
+
+// ExportJob is an export too large to stream in the request, written to a
+// file by the export worker. The file is deleted once the job expires.
+type ExportJob struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID    string             `bson:"tenant_id" json:"tenantId"`
+	RequestedBy string             `bson:"requested_by" json:"requestedBy"` // User ID; "me" in the ticket query means this user
+	Kind        ExportKind         `bson:"kind" json:"kind"`
+	Format      string             `bson:"format" json:"format"`
+	Filter      TicketFilter       `bson:"filter" json:"-"`
+	Timezone    string             `bson:"timezone,omitempty" json:"timezone,omitempty"` // Times are written in this zone
+	Status      ExportStatus       `bson:"status" json:"status"`
+	Rows        int64              `bson:"rows" json:"rows"`
+	FileName    string             `bson:"file_name,omitempty" json:"fileName,omitempty"`
+	FileID      primitive.ObjectID `bson:"file_id,omitempty" json:"-"` // In the exports GridFS bucket
+	Size        int64              `bson:"size,omitempty" json:"size,omitempty"`
+	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
+	DownloadURL string             `bson:"-" json:"downloadUrl,omitempty"` // Set once done
+	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
+	StartedAt   *time.Time         `bson:"started_at,omitempty" json:"startedAt,omitempty"`
+	FinishedAt  *time.Time         `bson:"finished_at,omitempty" json:"finishedAt,omitempty"`
+	ExpiresAt   time.Time          `bson:"expires_at" json:"expiresAt"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ImportStatus is the state of a ticket import
+type ImportStatus string
+
+const (
+	ImportPending ImportStatus = "pending"
+	ImportRunning ImportStatus = "running"
+	ImportDone    ImportStatus = "done"
+	ImportFailed  ImportStatus = "failed" // Resumable from the last saved record
+)
+
+// ImportFormat is the file format of a ticket import
+type ImportFormat string
+
+const (
+	ImportCSV  ImportFormat = "csv"
+	ImportJSON ImportFormat = "json"
+)
+
+// MaxImportIssues caps the issues stored on an import job; the counts keep
+// going past it
+const MaxImportIssues = 500
+
+// ImportJob imports tickets with their messages from another helpdesk's
+// export. A dry run validates every record without writing anything.
+type ImportJob struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID      string             `bson:"tenant_id" json:"tenantId"`
+	RequestedBy   string             `bson:"requested_by" json:"requestedBy"`
+	RequestedName string             `bson:"requested_name,omitempty" json:"requestedName,omitempty"`
+	FileName      string             `bson:"file_name" json:"fileName"`  // As uploaded
+	FileID        primitive.ObjectID `bson:"file_id,omitempty" json:"-"` // In the imports GridFS bucket
+	Format        ImportFormat       `bson:"format" json:"format"`
+	Timezone      string             `bson:"timezone" json:"timezone"` // For times without an offset
+	DryRun        bool               `bson:"dry_run" json:"dryRun"`
+	KeepNumbers   bool               `bson:"keep_numbers" json:"keepNumbers"` // Keep the file's ticket numbers instead of allocating new ones
+	Status        ImportStatus       `bson:"status" json:"status"`
+
+	// Progress; Records is where a resumed job picks up
+	Records  int64         `bson:"records" json:"records"`   // Records read
+	Imported int64         `bson:"imported" json:"imported"` // Tickets created, or that would be in a dry run
+	Skipped  int64         `bson:"skipped" json:"skipped"`   // Imported before, by ID
+	Invalid  int64         `bson:"invalid" json:"invalid"`   // Records with errors, not imported
+	Warnings int64         `bson:"warnings" json:"warnings"`
+	Issues   []ImportIssue `bson:"issues,omitempty" json:"issues,omitempty"` // The first MaxImportIssues
+	Error    string        `bson:"error,omitempty" json:"error,omitempty"`   // Why the job failed
+
+	CreatedAt   time.Time  `bson:"created_at" json:"createdAt"`
+	StartedAt   *time.Time `bson:"started_at,omitempty" json:"startedAt,omitempty"`
+	HeartbeatAt *time.Time `bson:"heartbeat_at,omitempty" json:"-"` // Last saved progress; jobs that go quiet are taken over
+	FinishedAt  *time.Time `bson:"finished_at,omitempty" json:"finishedAt,omitempty"`
+}
+
+// ImportIssue is a problem with one record. Errors stop the record from
+// being imported; warnings don't.
+type ImportIssue struct {
+	Record     int64  `bson:"record" json:"record"` // From 1, in file order
+	ExternalID string `bson:"external_id,omitempty" json:"externalId,omitempty"`
+	Field      string `bson:"field,omitempty" json:"field,omitempty"`
+	Message    string `bson:"message" json:"message"`
+	Warning    bool   `bson:"warning,omitempty" json:"warning,omitempty"`
+}
+
+// TicketImport records where an imported ticket came from
+type TicketImport struct {
+	JobID      primitive.ObjectID `bson:"job_id" json:"jobId"`
+	ExternalID string             `bson:"external_id" json:"externalId"` // ID in the source helpdesk
+	Record     int64              `bson:"record" json:"record"`          // Position in the import file
+	ImportedAt time.Time          `bson:"imported_at" json:"importedAt"`
+}
@@ -0,0 +1,133 @@
+package models
+
+import "time"
+
+// ReportInterval is the length of a report period
+type ReportInterval string
+
+const (
+	IntervalDay   ReportInterval = "day"
+	IntervalWeek  ReportInterval = "week" // Starts on Monday
+	IntervalMonth ReportInterval = "month"
+)
+
+// ReportGroupBy is the ticket dimension a report is split by
+type ReportGroupBy string
+
+const (
+	GroupByNone       ReportGroupBy = ""
+	GroupByDepartment ReportGroupBy = "department"
+	GroupByAgent      ReportGroupBy = "agent"
+	GroupByCategory   ReportGroupBy = "category"
+	GroupByChannel    ReportGroupBy = "channel"
+	GroupByPriority   ReportGroupBy = "priority"
+)
+
+// ReportRequest selects the periods, grouping and tickets of a report
+type ReportRequest struct {
+	From         time.Time
+	To           time.Time // Exclusive
+	Interval     ReportInterval
+	GroupBy      ReportGroupBy
+	Location     *time.Location // Periods start at midnight here
+	DepartmentID string
+	AgentID      string
+}
+
+// TicketTrendReport is a ticket time series
+type TicketTrendReport struct {
+	From     time.Time      `json:"from"`
+	To       time.Time      `json:"to"`
+	Interval ReportInterval `json:"interval"`
+	GroupBy  ReportGroupBy  `json:"groupBy,omitempty"`
+	Timezone string         `json:"timezone"`
+	Series   []ReportSeries `json:"series"`
+}
+
+// ReportSeries is the time series of one group, or of all tickets when the
+// report isn't grouped
+type ReportSeries struct {
+	Key     string         `json:"key,omitempty"` // Group value; "none" for tickets without one
+	Name    string         `json:"name,omitempty"`
+	Periods []ReportPeriod `json:"periods"`
+}
+
+// ReportPeriod holds the metrics of one period. Tickets count towards the
+// period they were created, resolved or rated in, respectively.
+type ReportPeriod struct {
+	Start         time.Time           `json:"start"`
+	Created       int64               `json:"created"`
+	Resolved      int64               `json:"resolved"`
+	Backlog       int64               `json:"backlog"`                 // Open at the end of the period
+	FirstResponse DurationPercentiles `json:"firstResponse"`           // Of tickets created in the period, in minutes
+	Resolution    DurationPercentiles `json:"resolution"`              // Of tickets resolved in the period, in minutes
+	SLACompliance *float64            `json:"slaCompliance,omitempty"` // Share of resolved tickets without an SLA breach
+	CSAT          *float64            `json:"csat,omitempty"`          // Share of ratings of 4 or 5
+	AvgRating     *float64            `json:"avgRating,omitempty"`
+	Ratings       int64               `json:"ratings"`
+}
+
+// DurationPercentiles summarizes durations in minutes
+type DurationPercentiles struct {
+	Count int64    `json:"count"`
+	P50   *float64 `json:"p50,omitempty"`
+	P90   *float64 `json:"p90,omitempty"`
+	P95   *float64 `json:"p95,omitempty"`
+}
+
+// ScorecardSort is the metric a leaderboard is ranked by
+type ScorecardSort string
+
+const (
+	ScorecardByResolved      ScorecardSort = "resolved"
+	ScorecardByHandled       ScorecardSort = "handled"
+	ScorecardByFirstResponse ScorecardSort = "first_response" // Fastest first
+	ScorecardBySLACompliance ScorecardSort = "sla_compliance"
+	ScorecardByCSAT          ScorecardSort = "csat"
+	ScorecardByReopenRate    ScorecardSort = "reopen_rate" // Lowest first
+)
+
+// ScorecardRequest selects the period and agents of scorecards
+type ScorecardRequest struct {
+	From         time.Time
+	To           time.Time // Exclusive
+	DepartmentID string    // Only the department's agents
+	AgentID      string    // User ID of a single agent
+	SortBy       ScorecardSort
+	Limit        int
+}
+
+// AgentScorecard is an agent's performance over a period
+type AgentScorecard struct {
+	UserID  string `json:"userId"`
+	AgentID string `json:"agentId,omitempty"`
+	Name    string `json:"name"`
+	Rank    int    `json:"rank,omitempty"`
+
+	// From the agent's messages in the period
+	Handled           int64    `json:"handled"` // Tickets the agent replied to
+	Replies           int64    `json:"replies"`
+	InternalNotes     int64    `json:"internalNotes"`
+	InternalNoteRatio *float64 `json:"internalNoteRatio,omitempty"` // Share of the agent's messages that are internal notes
+
+	// From the agent's status changes in the period
+	Resolutions int64    `json:"resolutions"` // Times the agent resolved a ticket
+	Reopened    int64    `json:"reopened"`    // Of those, reopened afterwards
+	ReopenRate  *float64 `json:"reopenRate,omitempty"`
+
+	// From tickets assigned to the agent
+	Resolved            int64    `json:"resolved"`                      // Resolved in the period
+	SLACompliance       *float64 `json:"slaCompliance,omitempty"`       // Share of those without an SLA breach
+	FirstResponseMedian *float64 `json:"firstResponseMedian,omitempty"` // Of tickets created in the period, in minutes
+	Ratings             [5]int64 `json:"ratings"`                       // Ratings given in the period, from 1 to 5 stars
+	CSAT                *float64 `json:"csat,omitempty"`                // Share of ratings of 4 or 5
+	AvgRating           *float64 `json:"avgRating,omitempty"`
+}
+
+// AgentScorecardReport ranks agents' scorecards
+type AgentScorecardReport struct {
+	From   time.Time        `json:"from"`
+	To     time.Time        `json:"to"`
+	SortBy ScorecardSort    `json:"sortBy"`
+	Agents []AgentScorecard `json:"agents"`
+}
@@ -66,6 +66,10 @@ type Agent struct {
 	IsOnline     bool        `bson:"is_online" json:"isOnline"`
 	LastActiveAt *time.Time  `bson:"last_active_at,omitempty" json:"lastActiveAt,omitempty"`
 
+	// Presence; set when the idle sweeper changes status, cleared on heartbeat
+	PresenceStatus  AgentStatus `bson:"presence_status,omitempty" json:"presenceStatus,omitempty"`
+	PresenceRestore AgentStatus `bson:"presence_restore,omitempty" json:"-"` // Status to return to on the next heartbeat
+
 	// Capacity
 	MaxTickets       int `bson:"max_tickets" json:"maxTickets"`
 	CurrentTickets   int `bson:"current_tickets" json:"currentTickets"`
@@ -85,6 +89,12 @@ type Agent struct {
 	// Preferences
 	Preferences AgentPreferences `bson:"preferences,omitempty" json:"preferences,omitempty"`
 
+	// Schedule; shift times are in Preferences.Timezone
+	ScheduleEnabled bool          `bson:"schedule_enabled" json:"scheduleEnabled"`
+	Shifts          []DaySchedule `bson:"shifts,omitempty" json:"shifts,omitempty"`
+	OutOfOffice     []OutOfOffice `bson:"out_of_office,omitempty" json:"outOfOffice,omitempty"`
+	ScheduleStatus  AgentStatus   `bson:"schedule_status,omitempty" json:"scheduleStatus,omitempty"` // Last status applied by the scheduler
+
 	// Timestamps
 	CreatedAt time.Time  `bson:"created_at" json:"createdAt"`
 	UpdatedAt time.Time  `bson:"updated_at" json:"updatedAt"`
@@ -107,13 +117,32 @@ const (
 type AgentStatus string
 
 const (
-	AgentStatusAvailable AgentStatus = "available"
-	AgentStatusBusy      AgentStatus = "busy"
-	AgentStatusAway      AgentStatus = "away"
-	AgentStatusOffline   AgentStatus = "offline"
-	AgentStatusOnBreak   AgentStatus = "on_break"
+	AgentStatusAvailable   AgentStatus = "available"
+	AgentStatusBusy        AgentStatus = "busy"
+	AgentStatusAway        AgentStatus = "away"
+	AgentStatusOffline     AgentStatus = "offline"
+	AgentStatusOnBreak     AgentStatus = "on_break"
+	AgentStatusOutOfOffice AgentStatus = "out_of_office"
 )
 
+// Out-of-office ticket handling
+const (
+	LeaveTicketsHold     = "hold"     // Keep tickets assigned until the agent returns
+	LeaveTicketsReassign = "reassign" // Move open tickets to the delegate, or back to the queue
+)
+
+// OutOfOffice is a leave period during which the agent receives no tickets
+type OutOfOffice struct {
+	ID           primitive.ObjectID `bson:"id" json:"id"`
+	Start        time.Time          `bson:"start" json:"start"`
+	End          time.Time          `bson:"end" json:"end"`
+	Reason       string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	DelegateID   string             `bson:"delegate_id,omitempty" json:"delegateId,omitempty"` // User ID of the covering agent
+	DelegateName string             `bson:"delegate_name,omitempty" json:"delegateName,omitempty"`
+	TicketAction string             `bson:"ticket_action" json:"ticketAction"` // hold, reassign
+	Applied      bool               `bson:"applied" json:"applied"`            // Ticket action has run
+}
+
 // AgentPreferences represents agent preferences
 type AgentPreferences struct {
 	EmailNotifications   bool   `bson:"email_notifications" json:"emailNotifications"`
@@ -64,6 +64,7 @@ type Ticket struct {
 	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	TenantID     string             `bson:"tenant_id" json:"tenantId"`
 	TicketNumber string             `bson:"ticket_number" json:"ticketNumber"` // Human-readable ticket number
+	Version      int64              `bson:"version" json:"version"`            // Bumped on every update, for optimistic concurrency
 
 	// Basic Info
 	Subject     string         `bson:"subject" json:"subject"`
@@ -72,6 +73,7 @@ type Ticket struct {
 	Status      TicketStatus   `bson:"status" json:"status"`
 	Priority    TicketPriority `bson:"priority" json:"priority"`
 	Source      TicketSource   `bson:"source" json:"source"`
+	Language    string         `bson:"language,omitempty" json:"language,omitempty"` // Customer's language, used to match agents
 
 	// Customer Info
 	CustomerID     string `bson:"customer_id" json:"customerId"`
@@ -79,6 +81,7 @@ type Ticket struct {
 	CustomerEmail  string `bson:"customer_email" json:"customerEmail"`
 	CustomerPhone  string `bson:"customer_phone,omitempty" json:"customerPhone,omitempty"`
 	CustomerAvatar string `bson:"customer_avatar,omitempty" json:"customerAvatar,omitempty"`
+	IsGuest        bool   `bson:"is_guest" json:"isGuest"` // Submitted without an account
 
 	// Organization
 	DepartmentID    *primitive.ObjectID `bson:"department_id,omitempty" json:"departmentId,omitempty"`
@@ -136,6 +139,7 @@ type Ticket struct {
 	IPAddress string                 `bson:"ip_address,omitempty" json:"-"`
 	UserAgent string                 `bson:"user_agent,omitempty" json:"-"`
 	Metadata  map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	Import    *TicketImport          `bson:"import,omitempty" json:"import,omitempty"` // Set on tickets migrated from another helpdesk
 
 	// Timestamps
 	CreatedAt  time.Time  `bson:"created_at" json:"createdAt"`
@@ -150,6 +154,27 @@ type Ticket struct {
 	LastActivityAt      time.Time  `bson:"last_activity_at" json:"lastActivityAt"`
 	LastCustomerReplyAt *time.Time `bson:"last_customer_reply_at,omitempty" json:"lastCustomerReplyAt,omitempty"`
 	LastAgentReplyAt    *time.Time `bson:"last_agent_reply_at,omitempty" json:"lastAgentReplyAt,omitempty"`
+
+	// Agents currently on the ticket; not stored, filled in for agents
+	Viewers []TicketViewer `bson:"-" json:"viewers,omitempty"`
+}
+
+// ViewerActivity is what an agent is doing on a ticket
+type ViewerActivity string
+
+const (
+	ViewerViewing  ViewerActivity = "viewing"
+	ViewerReplying ViewerActivity = "replying"
+	ViewerLeft     ViewerActivity = "left"
+)
+
+// TicketViewer is an agent with the ticket open. Entries expire unless
+// the agent's console refreshes them.
+type TicketViewer struct {
+	UserID    string         `json:"userId"`
+	UserName  string         `json:"userName"`
+	Activity  ViewerActivity `json:"activity"`
+	ExpiresAt time.Time      `json:"expiresAt"`
 }
 
 // Attachment represents a file attached to a ticket or message
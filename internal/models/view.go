@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SavedView is a named ticket list query with display preferences. Views are
+// private to their owner unless shared with the whole tenant.
+type SavedView struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID  string             `bson:"tenant_id" json:"tenantId"`
+	OwnerID   string             `bson:"owner_id" json:"ownerId"`
+	OwnerName string             `bson:"owner_name,omitempty" json:"ownerName,omitempty"`
+	Name      string             `bson:"name" json:"name"`
+	Query     string             `bson:"query" json:"query"`                         // Ticket query language, e.g. status:open assignee:me
+	Columns   []string           `bson:"columns,omitempty" json:"columns,omitempty"` // Ticket list columns, in display order
+	SortBy    string             `bson:"sort_by,omitempty" json:"sortBy,omitempty"`
+	SortOrder string             `bson:"sort_order,omitempty" json:"sortOrder,omitempty"`
+	Shared    bool               `bson:"shared" json:"shared"` // Visible to every agent in the tenant
+	CreatedAt time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updatedAt"`
+}
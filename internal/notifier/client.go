@@ -0,0 +1,75 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/minisource/ticket/config"
+)
+
+// EmailRequest represents an email sent through the notifier service
+type EmailRequest struct {
+	TenantID string                 `json:"tenantId"`
+	To       []string               `json:"to"`
+	Subject  string                 `json:"subject"`
+	Body     string                 `json:"body"`
+	Template string                 `json:"template,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+}
+
+// Client sends notifications through the notifier service
+type Client struct {
+	baseURL      string
+	clientID     string
+	clientSecret string
+	enabled      bool
+	httpClient   *http.Client
+}
+
+// NewClient creates a new notifier client
+func NewClient(cfg config.NotifierConfig) *Client {
+	return &Client{
+		baseURL:      strings.TrimRight(cfg.ServiceURL, "/"),
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		enabled:      cfg.Enabled,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SendEmail sends an email; it is a no-op when the notifier is disabled
+func (c *Client) SendEmail(ctx context.Context, req EmailRequest) error {
+	if !c.enabled {
+		return nil
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to encode email request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/notifications/email", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build email request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-Tenant-ID", req.TenantID)
+	httpReq.SetBasicAuth(c.clientID, c.clientSecret)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("notifier returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+// Package pagination implements opaque keyset cursors for MongoDB lists.
+//
+// A cursor holds the sort key values of the last item on a page. The next
+// page starts strictly after those values, so items inserted or removed
+// between requests never shift the remaining pages the way skip/limit does.
+// Sorts must end with _id to make every position unique.
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrInvalidCursor is returned for cursors that are malformed or were issued
+// for a different sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursor is the encoded form of a position
+type cursor struct {
+	Sort   string `bson:"s"` // Sort the cursor was issued for
+	Values bson.A `bson:"v"` // Sort key values of the last item, in sort order
+}
+
+// Encode returns the cursor positioned after doc, which must hold every key in sort
+func Encode(sort bson.D, doc bson.Raw) (string, error) {
+	values := make(bson.A, len(sort))
+	for n, e := range sort {
+		value, err := doc.LookupErr(e.Key)
+		if err != nil {
+			// Missing fields sort as null
+			continue
+		}
+		var v interface{}
+		if err := value.Unmarshal(&v); err != nil {
+			return "", fmt.Errorf("failed to read cursor field %s: %w", e.Key, err)
+		}
+		values[n] = v
+	}
+
+	data, err := bson.Marshal(cursor{Sort: signature(sort), Values: values})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// After decodes a cursor and returns the filter matching items that come
+// after it in sort order. An empty cursor matches everything.
+func After(token string, sort bson.D) (bson.M, error) {
+	if token == "" {
+		return bson.M{}, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := bson.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != signature(sort) || len(c.Values) != len(sort) {
+		return nil, ErrInvalidCursor
+	}
+
+	// Items after the cursor tie with it on the first n keys and are past it
+	// on key n, for some n
+	var branches bson.A
+	for n, e := range sort {
+		past, ok := pastValue(e.Key, e.Value, c.Values[n])
+		if !ok {
+			continue
+		}
+		branch := bson.A{}
+		for m := 0; m < n; m++ {
+			branch = append(branch, bson.M{sort[m].Key: c.Values[m]})
+		}
+		branches = append(branches, bson.M{"$and": append(branch, past)})
+	}
+
+	if len(branches) == 0 {
+		// Nothing can come after the cursor
+		return bson.M{"_id": bson.M{"$exists": false}}, nil
+	}
+	return bson.M{"$or": branches}, nil
+}
+
+// pastValue matches values of key that sort after value. MongoDB sorts null
+// and missing values before everything else. ok is false when nothing can
+// sort after value.
+func pastValue(key string, dir interface{}, value interface{}) (bson.M, bool) {
+	ascending := dir == 1
+	switch {
+	case ascending && value == nil:
+		return bson.M{key: bson.M{"$ne": nil}}, true
+	case ascending:
+		return bson.M{key: bson.M{"$gt": value}}, true
+	case value == nil:
+		return nil, false
+	default:
+		return bson.M{"$or": bson.A{
+			bson.M{key: bson.M{"$lt": value}},
+			bson.M{key: nil},
+		}}, true
+	}
+}
+
+// signature identifies a sort so cursors can't be replayed against another
+func signature(sort bson.D) string {
+	parts := make([]string, len(sort))
+	for n, e := range sort {
+		parts[n] = fmt.Sprintf("%s:%v", e.Key, e.Value)
+	}
+	return strings.Join(parts, ",")
+}
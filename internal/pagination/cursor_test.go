@@ -0,0 +1,63 @@
+package pagination
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestKeysetCursor checks cursor round trips and the filters they produce
+func TestKeysetCursor(t *testing.T) {
+	sort := bson.D{{Key: "resolved_at", Value: -1}, {Key: "_id", Value: -1}}
+
+	filter, err := After("", sort)
+	require.NoError(t, err)
+	assert.Empty(t, filter)
+
+	id := primitive.NewObjectID()
+	resolved := time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC)
+	doc, err := bson.Marshal(bson.M{"_id": id, "resolved_at": resolved, "subject": "x"})
+	require.NoError(t, err)
+
+	token, err := Encode(sort, doc)
+	require.NoError(t, err)
+
+	// Descending: earlier values, then nulls, then ties broken by _id
+	filter, err = After(token, sort)
+	require.NoError(t, err)
+	at := primitive.NewDateTimeFromTime(resolved)
+	assert.Equal(t, bson.M{"$or": bson.A{
+		bson.M{"$and": bson.A{bson.M{"$or": bson.A{
+			bson.M{"resolved_at": bson.M{"$lt": at}},
+			bson.M{"resolved_at": nil},
+		}}}},
+		bson.M{"$and": bson.A{
+			bson.M{"resolved_at": at},
+			bson.M{"$or": bson.A{
+				bson.M{"_id": bson.M{"$lt": id}},
+				bson.M{"_id": nil},
+			}},
+		}},
+	}}, filter)
+
+	// A missing sort field is null; ascending, everything non-null comes after it
+	asc := bson.D{{Key: "resolved_at", Value: 1}, {Key: "_id", Value: 1}}
+	doc, err = bson.Marshal(bson.M{"_id": id})
+	require.NoError(t, err)
+	token, err = Encode(asc, doc)
+	require.NoError(t, err)
+	filter, err = After(token, asc)
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"resolved_at": bson.M{"$ne": nil}}, filter["$or"].(bson.A)[0].(bson.M)["$and"].(bson.A)[0])
+
+	// Cursors only work with the sort they were issued for
+	_, err = After(token, sort)
+	assert.True(t, errors.Is(err, ErrInvalidCursor))
+	_, err = After("not a cursor!", sort)
+	assert.True(t, errors.Is(err, ErrInvalidCursor))
+}
@@ -0,0 +1,288 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// kind is how a field's values are parsed and compared
+type kind int
+
+const (
+	kindString kind = iota
+	kindEnum
+	kindPriority
+	kindUser
+	kindObjectID
+	kindDate
+	kindFlag
+)
+
+// field is a queryable ticket field
+type field struct {
+	path   string
+	kind   kind
+	values []string // Allowed values for enums
+}
+
+// fields by query name
+var fields = map[string]field{
+	"status":     {path: "status", kind: kindEnum, values: enumValues(models.StatusOpen, models.StatusInProgress, models.StatusPending, models.StatusOnHold, models.StatusResolved, models.StatusClosed, models.StatusReopened, models.StatusEscalated, models.StatusCancelled)},
+	"priority":   {path: "priority", kind: kindPriority},
+	"type":       {path: "type", kind: kindEnum, values: enumValues(models.TypeQuestion, models.TypeIncident, models.TypeProblem, models.TypeFeature, models.TypeBug, models.TypeTask, models.TypeComplaint, models.TypeFeedback)},
+	"source":     {path: "source", kind: kindEnum, values: enumValues(models.SourceWeb, models.SourceEmail, models.SourceAPI, models.SourcePhone, models.SourceChat, models.SourceMobile, models.SourceInternal)},
+	"assignee":   {path: "assigned_to_id", kind: kindUser},
+	"customer":   {path: "customer_id", kind: kindUser},
+	"department": {path: "department_id", kind: kindObjectID},
+	"category":   {path: "category_id", kind: kindObjectID},
+	"tag":        {path: "tags", kind: kindString},
+	"language":   {path: "language", kind: kindString},
+	"number":     {path: "ticket_number", kind: kindString},
+	"created":    {path: "created_at", kind: kindDate},
+	"updated":    {path: "updated_at", kind: kindDate},
+	"resolved":   {path: "resolved_at", kind: kindDate},
+	"activity":   {path: "last_activity_at", kind: kindDate},
+	"due":        {path: "resolution_due", kind: kindDate},
+	"is":         {kind: kindFlag},
+}
+
+// flags are the values of is:, as the filter that matches them and the one
+// that matches their negation
+var flags = map[string][2]bson.M{
+	"unassigned": {
+		{"assigned_to_id": bson.M{"$in": bson.A{"", nil}}},
+		{"assigned_to_id": bson.M{"$nin": bson.A{"", nil}}},
+	},
+	"breached": {
+		{"sla_breached": true},
+		{"sla_breached": bson.M{"$ne": true}},
+	},
+	"guest": {
+		{"is_guest": true},
+		{"is_guest": bson.M{"$ne": true}},
+	},
+	"rated": {
+		{"satisfaction_rating": bson.M{"$exists": true}},
+		{"satisfaction_rating": bson.M{"$exists": false}},
+	},
+}
+
+// compile turns a term on this field into a filter
+func (f field) compile(tok token, qc Context) (bson.M, error) {
+	switch f.kind {
+	case kindFlag:
+		return f.compileFlag(tok)
+	case kindPriority:
+		return f.compilePriority(tok)
+	case kindDate:
+		return f.compileDate(tok, qc)
+	}
+
+	if tok.op != opEq && tok.op != opNe {
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("%q only supports equality", tok.field)}
+	}
+
+	values := make(bson.A, 0, len(tok.values))
+	for _, v := range tok.values {
+		converted, err := f.convert(v, tok, qc)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, converted...)
+	}
+
+	return membership(f.path, values, tok.negated != (tok.op == opNe)), nil
+}
+
+// convert parses one value; "none" may stand for several stored values
+func (f field) convert(value string, tok token, qc Context) (bson.A, error) {
+	switch f.kind {
+	case kindEnum:
+		v := strings.ToLower(value)
+		for _, allowed := range f.values {
+			if v == allowed {
+				return bson.A{v}, nil
+			}
+		}
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("%q is not a valid %s", value, tok.field)}
+	case kindUser:
+		switch strings.ToLower(value) {
+		case "me":
+			return bson.A{qc.UserID}, nil
+		case "none":
+			return bson.A{"", nil}, nil
+		}
+		return bson.A{value}, nil
+	case kindObjectID:
+		if strings.EqualFold(value, "none") {
+			return bson.A{nil}, nil
+		}
+		id, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("%q is not a valid %s ID", value, tok.field)}
+		}
+		return bson.A{id}, nil
+	default:
+		return bson.A{value}, nil
+	}
+}
+
+func (f field) compileFlag(tok token) (bson.M, error) {
+	if tok.op != opEq && tok.op != opNe || len(tok.values) != 1 {
+		return nil, &Error{Pos: tok.pos, Msg: "is: takes a single flag"}
+	}
+	flag, ok := flags[strings.ToLower(tok.values[0])]
+	if !ok {
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("unknown flag %q", tok.values[0])}
+	}
+	if tok.negated != (tok.op == opNe) {
+		return flag[1], nil
+	}
+	return flag[0], nil
+}
+
+// compilePriority expands comparisons into the set of priorities they cover
+func (f field) compilePriority(tok token) (bson.M, error) {
+	rank := func(value string) (int, error) {
+		for n, p := range priorityOrder {
+			if strings.EqualFold(value, string(p)) {
+				return n, nil
+			}
+		}
+		return 0, &Error{Pos: tok.pos, Msg: fmt.Sprintf("%q is not a valid priority", value)}
+	}
+
+	var values bson.A
+	switch tok.op {
+	case opEq, opNe:
+		for _, v := range tok.values {
+			n, err := rank(v)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, string(priorityOrder[n]))
+		}
+	default:
+		if len(tok.values) != 1 {
+			return nil, &Error{Pos: tok.pos, Msg: "comparisons take a single value"}
+		}
+		n, err := rank(tok.values[0])
+		if err != nil {
+			return nil, err
+		}
+		for m, p := range priorityOrder {
+			if compare(m, n, tok.op) {
+				values = append(values, string(p))
+			}
+		}
+	}
+
+	return membership(f.path, values, tok.negated != (tok.op == opNe)), nil
+}
+
+// compileDate compares against a point in time. Equality on a calendar date
+// matches the whole day.
+func (f field) compileDate(tok token, qc Context) (bson.M, error) {
+	if len(tok.values) != 1 {
+		return nil, &Error{Pos: tok.pos, Msg: "dates take a single value"}
+	}
+
+	at, day, err := parseDate(tok.values[0], qc.Now)
+	if err != nil {
+		return nil, &Error{Pos: tok.pos, Msg: err.Error()}
+	}
+
+	var cond bson.M
+	switch tok.op {
+	case opGt:
+		cond = bson.M{"$gt": at}
+	case opGe:
+		cond = bson.M{"$gte": at}
+	case opLt:
+		cond = bson.M{"$lt": at}
+	case opLe:
+		cond = bson.M{"$lte": at}
+	default:
+		if day {
+			cond = bson.M{"$gte": at, "$lt": at.AddDate(0, 0, 1)}
+		} else {
+			cond = bson.M{"$gte": at}
+		}
+	}
+
+	if tok.negated != (tok.op == opNe) {
+		return bson.M{f.path: bson.M{"$not": cond}}, nil
+	}
+	return bson.M{f.path: cond}, nil
+}
+
+// parseDate reads "today", a relative offset such as -7d, a calendar date or
+// an RFC 3339 timestamp. day reports whether the value names a whole day.
+func parseDate(value string, now time.Time) (at time.Time, day bool, err error) {
+	if strings.EqualFold(value, "today") {
+		y, m, d := now.UTC().Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), true, nil
+	}
+
+	if len(value) >= 3 && (value[0] == '-' || value[0] == '+') {
+		n, err := strconv.Atoi(value[1 : len(value)-1])
+		if err == nil {
+			units := map[byte]time.Duration{'m': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+			if unit, ok := units[value[len(value)-1]]; ok {
+				offset := time.Duration(n) * unit
+				if value[0] == '-' {
+					offset = -offset
+				}
+				return now.Add(offset), false, nil
+			}
+		}
+	}
+
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+
+	return time.Time{}, false, fmt.Errorf("%q is not a valid date", value)
+}
+
+func compare(a, b int, op string) bool {
+	switch op {
+	case opGt:
+		return a > b
+	case opGe:
+		return a >= b
+	case opLt:
+		return a < b
+	case opLe:
+		return a <= b
+	}
+	return a == b
+}
+
+// membership matches path against any of values, or none of them
+func membership(path string, values bson.A, negated bool) bson.M {
+	if negated {
+		return bson.M{path: bson.M{"$nin": values}}
+	}
+	if len(values) == 1 && values[0] != nil {
+		return bson.M{path: values[0]}
+	}
+	return bson.M{path: bson.M{"$in": values}}
+}
+
+func enumValues[T ~string](values ...T) []string {
+	result := make([]string, len(values))
+	for n, v := range values {
+		result[n] = string(v)
+	}
+	return result
+}
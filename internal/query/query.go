@@ -0,0 +1,223 @@
+// Package query compiles the ticket list query language into MongoDB filters.
+//
+// A query is a list of space-separated terms that must all match:
+//
+//	status:open,pending      any of the listed values
+//	priority:>=high          comparisons on priorities and dates
+//	assignee:me              the user running the query; "none" for unset
+//	created:>-7d             relative (m, h, d, w) or absolute dates
+//	-tag:spam                a leading - negates a term
+//	is:unassigned            flags: unassigned, breached, guest, rated
+//	printer "paper jam"      anything else is full-text search
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Context resolves values that depend on who runs a query and when
+type Context struct {
+	UserID string    // Substituted for "me"
+	Now    time.Time // Relative dates count back from here
+}
+
+// Error is a problem with a query, at a byte offset into it
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s", e.Pos+1, e.Msg)
+}
+
+// Comparison operators
+const (
+	opEq = "="
+	opNe = "!="
+	opGt = ">"
+	opGe = ">="
+	opLt = "<"
+	opLe = "<="
+)
+
+// Parse compiles a query into a MongoDB filter. An empty query matches
+// everything.
+func Parse(input string, qc Context) (bson.M, error) {
+	if qc.Now.IsZero() {
+		qc.Now = time.Now()
+	}
+
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var clauses []bson.M
+	var text []string
+	for _, tok := range tokens {
+		if tok.field == "" {
+			text = append(text, tok.textSearch())
+			continue
+		}
+
+		f, ok := fields[tok.field]
+		if !ok {
+			return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("unknown field %q", tok.field)}
+		}
+		clause, err := f.compile(tok, qc)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+
+	if len(text) > 0 {
+		clauses = append(clauses, bson.M{"$text": bson.M{"$search": strings.Join(text, " ")}})
+	}
+
+	switch len(clauses) {
+	case 0:
+		return bson.M{}, nil
+	case 1:
+		return clauses[0], nil
+	default:
+		return bson.M{"$and": clauses}, nil
+	}
+}
+
+// token is one term of a query
+type token struct {
+	pos     int
+	negated bool
+	field   string   // Empty for free text
+	op      string   // Comparison operator; opEq when none is given
+	values  []string // Comma-separated values, or the free text
+	quoted  bool
+}
+
+// textSearch renders a free-text token for MongoDB's $search syntax
+func (t token) textSearch() string {
+	value := t.values[0]
+	if t.quoted {
+		value = `"` + value + `"`
+	}
+	if t.negated {
+		value = "-" + value
+	}
+	return value
+}
+
+// tokenize splits a query into terms, honoring double quotes
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+
+	i := 0
+	for i < len(input) {
+		if input[i] == ' ' || input[i] == '\t' {
+			i++
+			continue
+		}
+
+		tok := token{pos: i, op: opEq}
+		if input[i] == '-' && i+1 < len(input) && input[i+1] != ' ' {
+			tok.negated = true
+			i++
+		}
+
+		// A field name runs up to a colon, as long as no quote or space comes first
+		start := i
+		for i < len(input) && isFieldChar(input[i]) {
+			i++
+		}
+		if i < len(input) && input[i] == ':' && i > start {
+			tok.field = strings.ToLower(input[start:i])
+			i++
+			tok.op, i = readOperator(input, i)
+		} else {
+			i = start
+		}
+
+		value, quoted, next, err := readValue(input, i)
+		if err != nil {
+			return nil, err
+		}
+		i = next
+		tok.quoted = quoted
+
+		if tok.field == "" {
+			if value == "" {
+				continue
+			}
+			tok.values = []string{value}
+		} else {
+			if value == "" {
+				return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("missing value for %q", tok.field)}
+			}
+			if quoted {
+				tok.values = []string{value}
+			} else {
+				tok.values = splitValues(value)
+			}
+		}
+
+		tokens = append(tokens, tok)
+	}
+
+	return tokens, nil
+}
+
+func isFieldChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+// readOperator reads an optional comparison operator at i
+func readOperator(input string, i int) (string, int) {
+	for _, op := range []string{opGe, opLe, opNe, opGt, opLt, opEq} {
+		if strings.HasPrefix(input[i:], op) {
+			return op, i + len(op)
+		}
+	}
+	return opEq, i
+}
+
+// readValue reads a bare word or a double-quoted string at i
+func readValue(input string, i int) (string, bool, int, error) {
+	if i < len(input) && input[i] == '"' {
+		end := strings.IndexByte(input[i+1:], '"')
+		if end < 0 {
+			return "", false, 0, &Error{Pos: i, Msg: "unterminated quote"}
+		}
+		return input[i+1 : i+1+end], true, i + end + 2, nil
+	}
+
+	start := i
+	for i < len(input) && input[i] != ' ' && input[i] != '\t' {
+		i++
+	}
+	return input[start:i], false, i, nil
+}
+
+func splitValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// priorityOrder ranks priorities for comparisons
+var priorityOrder = []models.TicketPriority{
+	models.PriorityLow,
+	models.PriorityMedium,
+	models.PriorityHigh,
+	models.PriorityUrgent,
+	models.PriorityCritical,
+}
@@ -0,0 +1,71 @@
+package query
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestQueryParse checks how the ticket query language compiles to MongoDB filters
+func TestQueryParse(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	qc := Context{UserID: "agent-1", Now: now}
+
+	filter, err := Parse("", qc)
+	require.NoError(t, err)
+	assert.Empty(t, filter)
+
+	filter, err = Parse("status:open,pending", qc)
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"status": bson.M{"$in": bson.A{"open", "pending"}}}, filter)
+
+	// Priority comparisons expand to the priorities they cover
+	filter, err = Parse("priority:>=urgent", qc)
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"priority": bson.M{"$in": bson.A{"urgent", "critical"}}}, filter)
+
+	filter, err = Parse(`assignee:me tag:billing created:>-7d -tag:spam printer "paper jam"`, qc)
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"$and": []bson.M{
+		{"assigned_to_id": "agent-1"},
+		{"tags": "billing"},
+		{"created_at": bson.M{"$gt": now.Add(-7 * 24 * time.Hour)}},
+		{"tags": bson.M{"$nin": bson.A{"spam"}}},
+		{"$text": bson.M{"$search": `printer "paper jam"`}},
+	}}, filter)
+
+	// A calendar date matches the whole day
+	filter, err = Parse("resolved:2025-03-01", qc)
+	require.NoError(t, err)
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, bson.M{"resolved_at": bson.M{"$gte": day, "$lt": day.AddDate(0, 0, 1)}}, filter)
+
+	dept := primitive.NewObjectID()
+	filter, err = Parse("-is:unassigned department:"+dept.Hex(), qc)
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"$and": []bson.M{
+		{"assigned_to_id": bson.M{"$nin": bson.A{"", nil}}},
+		{"department_id": dept},
+	}}, filter)
+
+	// Errors point at the offending term
+	for input, pos := range map[string]int{
+		"status:bogus":         0,
+		"status:open colour:x": 12,
+		"priority:>=extreme":   0,
+		"created:yesterdayish": 0,
+		`tag:"unterminated`:    4,
+		"tag:>billing":         0,
+		"department:not-an-id": 0,
+	} {
+		_, err := Parse(input, qc)
+		var queryErr *Error
+		require.True(t, errors.As(err, &queryErr), input)
+		assert.Equal(t, pos, queryErr.Pos, input)
+	}
+}
@@ -0,0 +1,80 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType identifies what happened to a ticket
+type EventType string
+
+const (
+	EventTicketCreated     EventType = "ticket.created"
+	EventTicketUpdated     EventType = "ticket.updated"
+	EventStatusChanged     EventType = "ticket.status_changed"
+	EventTicketAssigned    EventType = "ticket.assigned"
+	EventTicketTransferred EventType = "ticket.transferred"
+	EventMessageCreated    EventType = "message.created"
+	EventTyping            EventType = "typing"
+	EventViewersChanged    EventType = "ticket.viewers"
+)
+
+// Event is a ticket change pushed to connected consoles
+type Event struct {
+	ID        string          `json:"id"`
+	Type      EventType       `json:"type"`
+	TenantID  string          `json:"tenantId"`
+	TicketID  string          `json:"ticketId"`
+	ActorID   string          `json:"actorId,omitempty"`
+	ActorName string          `json:"actorName,omitempty"`
+	Private   bool            `json:"private,omitempty"` // Only delivered to agents
+	Data      json.RawMessage `json:"data,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// NewEvent creates an event with data encoded as JSON
+func NewEvent(eventType EventType, tenantID, ticketID, actorID, actorName string, data interface{}) Event {
+	event := Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		TenantID:  tenantID,
+		TicketID:  ticketID,
+		ActorID:   actorID,
+		ActorName: actorName,
+		CreatedAt: time.Now(),
+	}
+	if data != nil {
+		event.Data, _ = json.Marshal(data)
+	}
+	return event
+}
+
+// Hub fans events out to subscribers of the event's tenant
+type Hub interface {
+	// Publish delivers event to every subscriber of its tenant
+	Publish(ctx context.Context, event Event) error
+	// Subscribe receives the tenant's events until the subscription is closed
+	Subscribe(tenantID string) *Subscription
+	// Close ends all subscriptions
+	Close() error
+}
+
+// Subscription is a subscriber's buffered event stream.
+// Events are dropped rather than blocking publishers when the buffer is full.
+type Subscription struct {
+	events chan Event
+	cancel func()
+}
+
+// Events returns the event channel; it is closed when the subscription ends
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	s.cancel()
+}
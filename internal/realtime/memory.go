@@ -0,0 +1,102 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryHub fans events out within the process.
+// Events published on other instances never arrive; use it for tests and
+// single-node setups.
+type MemoryHub struct {
+	mu          sync.RWMutex
+	buffer      int
+	subscribers map[string]map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewMemoryHub creates a new in-process hub; buffer is each subscriber's queue size
+func NewMemoryHub(buffer int) *MemoryHub {
+	return &MemoryHub{
+		buffer:      buffer,
+		subscribers: make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+// Publish implements Hub
+func (h *MemoryHub) Publish(_ context.Context, event Event) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subscribers[event.TenantID] {
+		select {
+		case sub.events <- event:
+		default:
+			// Slow subscriber; drop rather than stall the publisher
+		}
+	}
+	return nil
+}
+
+// Subscribe implements Hub
+func (h *MemoryHub) Subscribe(tenantID string) *Subscription {
+	sub := &Subscription{events: make(chan Event, h.buffer)}
+	sub.cancel = func() { h.remove(tenantID, sub) }
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(sub.events)
+		return sub
+	}
+	if h.subscribers[tenantID] == nil {
+		h.subscribers[tenantID] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[tenantID][sub] = struct{}{}
+
+	return sub
+}
+
+// Close implements Hub
+func (h *MemoryHub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, subs := range h.subscribers {
+		for sub := range subs {
+			close(sub.events)
+		}
+	}
+	h.subscribers = make(map[string]map[*Subscription]struct{})
+	h.closed = true
+	return nil
+}
+
+// Subscribers returns the number of open subscriptions
+func (h *MemoryHub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	count := 0
+	for _, subs := range h.subscribers {
+		count += len(subs)
+	}
+	return count
+}
+
+// remove unregisters sub; it is safe to call more than once
+func (h *MemoryHub) remove(tenantID string, sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs := h.subscribers[tenantID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subscribers, tenantID)
+	}
+	close(sub.events)
+}
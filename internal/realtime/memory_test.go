@@ -0,0 +1,74 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receive waits briefly for the next event on sub
+func receive(t *testing.T, sub *Subscription) (Event, bool) {
+	t.Helper()
+
+	select {
+	case event, ok := <-sub.Events():
+		return event, ok
+	case <-time.After(100 * time.Millisecond):
+		return Event{}, false
+	}
+}
+
+// TestMemoryHubFanOut checks tenant-scoped delivery through the in-process hub
+func TestMemoryHubFanOut(t *testing.T) {
+	ctx := context.Background()
+	hub := NewMemoryHub(8)
+	t.Cleanup(func() { _ = hub.Close() })
+
+	first := hub.Subscribe("tenant-a")
+	second := hub.Subscribe("tenant-a")
+	other := hub.Subscribe("tenant-b")
+
+	event := NewEvent(EventMessageCreated, "tenant-a", "ticket-1", "user-1", "User", map[string]string{"content": "hi"})
+	require.NoError(t, hub.Publish(ctx, event))
+
+	t.Run("Delivers To Every Tenant Subscriber", func(t *testing.T) {
+		for _, sub := range []*Subscription{first, second} {
+			got, ok := receive(t, sub)
+			require.True(t, ok)
+			assert.Equal(t, event.ID, got.ID)
+			assert.JSONEq(t, `{"content":"hi"}`, string(got.Data))
+		}
+	})
+
+	t.Run("Isolates Tenants", func(t *testing.T) {
+		_, ok := receive(t, other)
+		assert.False(t, ok)
+	})
+
+	t.Run("Close Ends Subscription", func(t *testing.T) {
+		first.Close()
+		first.Close()
+
+		_, ok := <-first.Events()
+		assert.False(t, ok)
+		assert.Equal(t, 2, hub.Subscribers())
+	})
+
+	t.Run("Drops Events For Full Buffers", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			require.NoError(t, hub.Publish(ctx, NewEvent(EventTyping, "tenant-a", "ticket-1", "", "", nil)))
+		}
+		assert.Len(t, second.Events(), 8)
+	})
+
+	t.Run("Hub Close Ends Streams", func(t *testing.T) {
+		require.NoError(t, hub.Close())
+
+		_, ok := <-other.Events()
+		assert.False(t, ok)
+		assert.Equal(t, 0, hub.Subscribers())
+	})
+}
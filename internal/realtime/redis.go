@@ -0,0 +1,66 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const channelPrefix = "ticket:events:"
+
+// RedisHub publishes events over Redis pub/sub so subscribers on every
+// instance receive them; each instance fans out locally
+type RedisHub struct {
+	client redis.UniversalClient
+	pubsub *redis.PubSub
+	local  *MemoryHub
+}
+
+// NewRedisHub creates a new Redis-backed hub and starts receiving events
+func NewRedisHub(client redis.UniversalClient, buffer int) *RedisHub {
+	h := &RedisHub{
+		client: client,
+		pubsub: client.PSubscribe(context.Background(), channelPrefix+"*"),
+		local:  NewMemoryHub(buffer),
+	}
+	go h.receive()
+	return h
+}
+
+// Publish implements Hub
+func (h *RedisHub) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	if err := h.client.Publish(ctx, channelPrefix+event.TenantID, data).Err(); err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+	return nil
+}
+
+// Subscribe implements Hub
+func (h *RedisHub) Subscribe(tenantID string) *Subscription {
+	return h.local.Subscribe(tenantID)
+}
+
+// Close implements Hub
+func (h *RedisHub) Close() error {
+	err := h.pubsub.Close()
+	_ = h.local.Close()
+	return err
+}
+
+// receive delivers events from Redis to local subscribers until Close
+func (h *RedisHub) receive() {
+	for msg := range h.pubsub.Channel() {
+		var event Event
+		if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+			continue
+		}
+		_ = h.local.Publish(context.Background(), event)
+	}
+}
@@ -0,0 +1,50 @@
+// Package report splits time ranges into reporting periods.
+//
+// Periods start at local midnight in the report's timezone, weeks on Monday
+// and months on the 1st, matching MongoDB's $dateTrunc with the same
+// timezone and startOfWeek, so aggregation results line up with Periods.
+package report
+
+import (
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+)
+
+// Truncate returns the start of the period containing t
+func Truncate(t time.Time, interval models.ReportInterval, loc *time.Location) time.Time {
+	t = t.In(loc)
+	y, m, d := t.Date()
+	switch interval {
+	case models.IntervalMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, loc)
+	case models.IntervalWeek:
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+}
+
+// Next returns the start of the period after the one starting at start.
+// Days and weeks follow the calendar, so they can be 23 or 25 hours long
+// around daylight saving changes.
+func Next(start time.Time, interval models.ReportInterval, loc *time.Location) time.Time {
+	y, m, d := start.In(loc).Date()
+	switch interval {
+	case models.IntervalMonth:
+		return time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+	case models.IntervalWeek:
+		return time.Date(y, m, d+7, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	}
+}
+
+// Periods lists the starts of the periods overlapping [from, to)
+func Periods(from, to time.Time, interval models.ReportInterval, loc *time.Location) []time.Time {
+	var periods []time.Time
+	for p := Truncate(from, interval, loc); p.Before(to); p = Next(p, interval, loc) {
+		periods = append(periods, p)
+	}
+	return periods
+}
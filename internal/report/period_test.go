@@ -0,0 +1,41 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReportPeriods checks that periods start at local midnight in the
+// report timezone
+func TestReportPeriods(t *testing.T) {
+	tehran, err := time.LoadLocation("Asia/Tehran")
+	require.NoError(t, err)
+
+	// 22:00 UTC on a Sunday is already Monday in Tehran
+	at := time.Date(2025, 3, 2, 22, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 3, 3, 0, 0, 0, 0, tehran), Truncate(at, models.IntervalDay, tehran))
+	assert.Equal(t, time.Date(2025, 3, 3, 0, 0, 0, 0, tehran), Truncate(at, models.IntervalWeek, tehran))
+	assert.Equal(t, time.Date(2025, 2, 24, 0, 0, 0, 0, time.UTC), Truncate(at, models.IntervalWeek, time.UTC))
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, tehran), Truncate(at, models.IntervalMonth, tehran))
+
+	// Days follow the calendar across daylight saving changes
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	from := time.Date(2025, 3, 29, 12, 0, 0, 0, berlin)
+	periods := Periods(from, time.Date(2025, 3, 31, 0, 0, 1, 0, berlin), models.IntervalDay, berlin)
+	require.Len(t, periods, 3)
+	assert.Equal(t, time.Date(2025, 3, 29, 0, 0, 0, 0, berlin), periods[0])
+	assert.Equal(t, 24*time.Hour, periods[1].Sub(periods[0]))
+	assert.Equal(t, 23*time.Hour, periods[2].Sub(periods[1]))
+
+	months := Periods(time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), models.IntervalMonth, time.UTC)
+	assert.Equal(t, []time.Time{
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+	}, months)
+}
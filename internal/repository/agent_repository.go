@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/minisource/ticket/internal/cache"
 	"github.com/minisource/ticket/internal/database"
+	"github.com/minisource/ticket/internal/metrics"
 	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/tracing"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -15,7 +18,8 @@ import (
 
 // AgentRepository handles agent database operations
 type AgentRepository struct {
-	db *database.MongoDB
+	db         *database.MongoDB
+	staleAfter time.Duration
 }
 
 // NewAgentRepository creates a new agent repository
@@ -23,8 +27,19 @@ func NewAgentRepository(db *database.MongoDB) *AgentRepository {
 	return &AgentRepository{db: db}
 }
 
+// WithStaleAfter excludes agents idle for longer than d from GetAvailable.
+// Agents that have never sent a heartbeat count as idle.
+func (r *AgentRepository) WithStaleAfter(d time.Duration) *AgentRepository {
+	r.staleAfter = d
+	return r
+}
+
 // Create creates a new agent
 func (r *AgentRepository) Create(ctx context.Context, agent *models.Agent) error {
+	ctx, span := tracing.Start(ctx, "AgentRepository.Create")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "Create")()
+
 	agent.CreatedAt = time.Now()
 	agent.UpdatedAt = time.Now()
 	agent.IsActive = true
@@ -40,6 +55,10 @@ func (r *AgentRepository) Create(ctx context.Context, agent *models.Agent) error
 
 // GetByID gets an agent by ID
 func (r *AgentRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "AgentRepository.GetByID")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "GetByID")()
+
 	var agent models.Agent
 	err := r.db.Collection(database.CollectionAgents).FindOne(ctx, bson.M{
 		"_id":        id,
@@ -58,6 +77,10 @@ func (r *AgentRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*
 
 // GetByUserID gets an agent by user ID
 func (r *AgentRepository) GetByUserID(ctx context.Context, tenantID, userID string) (*models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "AgentRepository.GetByUserID")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "GetByUserID")()
+
 	var agent models.Agent
 	err := r.db.Collection(database.CollectionAgents).FindOne(ctx, bson.M{
 		"tenant_id":  tenantID,
@@ -77,6 +100,10 @@ func (r *AgentRepository) GetByUserID(ctx context.Context, tenantID, userID stri
 
 // GetByEmail gets an agent by email
 func (r *AgentRepository) GetByEmail(ctx context.Context, tenantID, email string) (*models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "AgentRepository.GetByEmail")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "GetByEmail")()
+
 	var agent models.Agent
 	err := r.db.Collection(database.CollectionAgents).FindOne(ctx, bson.M{
 		"tenant_id":  tenantID,
@@ -96,6 +123,10 @@ func (r *AgentRepository) GetByEmail(ctx context.Context, tenantID, email string
 
 // Update updates an agent
 func (r *AgentRepository) Update(ctx context.Context, agent *models.Agent) error {
+	ctx, span := tracing.Start(ctx, "AgentRepository.Update")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "Update")()
+
 	agent.UpdatedAt = time.Now()
 
 	_, err := r.db.Collection(database.CollectionAgents).UpdateOne(
@@ -112,6 +143,10 @@ func (r *AgentRepository) Update(ctx context.Context, agent *models.Agent) error
 
 // Delete soft deletes an agent
 func (r *AgentRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, span := tracing.Start(ctx, "AgentRepository.Delete")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "Delete")()
+
 	now := time.Now()
 	_, err := r.db.Collection(database.CollectionAgents).UpdateOne(
 		ctx,
@@ -132,6 +167,10 @@ func (r *AgentRepository) Delete(ctx context.Context, id primitive.ObjectID) err
 
 // List lists agents
 func (r *AgentRepository) List(ctx context.Context, tenantID string, activeOnly bool) ([]models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "AgentRepository.List")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "List")()
+
 	query := bson.M{
 		"tenant_id":  tenantID,
 		"is_deleted": false,
@@ -159,6 +198,10 @@ func (r *AgentRepository) List(ctx context.Context, tenantID string, activeOnly
 
 // GetByDepartmentID gets agents for a department
 func (r *AgentRepository) GetByDepartmentID(ctx context.Context, tenantID string, departmentID primitive.ObjectID) ([]models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "AgentRepository.GetByDepartmentID")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "GetByDepartmentID")()
+
 	query := bson.M{
 		"tenant_id":      tenantID,
 		"department_ids": departmentID,
@@ -184,6 +227,10 @@ func (r *AgentRepository) GetByDepartmentID(ctx context.Context, tenantID string
 
 // GetAvailable gets available agents (for auto-assign)
 func (r *AgentRepository) GetAvailable(ctx context.Context, tenantID string, departmentID *primitive.ObjectID) ([]models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "AgentRepository.GetAvailable")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "GetAvailable")()
+
 	query := bson.M{
 		"tenant_id":  tenantID,
 		"is_deleted": false,
@@ -198,6 +245,11 @@ func (r *AgentRepository) GetAvailable(ctx context.Context, tenantID string, dep
 		query["department_ids"] = *departmentID
 	}
 
+	if r.staleAfter > 0 {
+		// Agents that never sent a heartbeat or set a status don't match
+		query["last_active_at"] = bson.M{"$gte": time.Now().Add(-r.staleAfter)}
+	}
+
 	opts := options.Find().
 		SetSort(bson.D{{Key: "current_tickets", Value: 1}}) // Least busy first
 
@@ -217,6 +269,10 @@ func (r *AgentRepository) GetAvailable(ctx context.Context, tenantID string, dep
 
 // UpdateStatus updates agent status
 func (r *AgentRepository) UpdateStatus(ctx context.Context, id primitive.ObjectID, status models.AgentStatus) error {
+	ctx, span := tracing.Start(ctx, "AgentRepository.UpdateStatus")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "UpdateStatus")()
+
 	update := bson.M{
 		"$set": bson.M{
 			"status":         status,
@@ -224,14 +280,84 @@ func (r *AgentRepository) UpdateStatus(ctx context.Context, id primitive.ObjectI
 			"last_active_at": time.Now(),
 			"updated_at":     time.Now(),
 		},
+		// A manual change overrides whatever the idle sweeper set
+		"$unset": bson.M{"presence_status": "", "presence_restore": ""},
 	}
 
 	_, err := r.db.Collection(database.CollectionAgents).UpdateOne(ctx, bson.M{"_id": id}, update)
 	return err
 }
 
+// Heartbeat records agent activity and, if the idle sweeper changed the
+// agent's status and nothing else has since, restores the previous status
+func (r *AgentRepository) Heartbeat(ctx context.Context, tenantID, userID string) (*models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "AgentRepository.Heartbeat")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "Heartbeat")()
+
+	now := time.Now()
+	filter := bson.M{"tenant_id": tenantID, "user_id": userID, "is_deleted": false}
+	restored := bson.M{"$eq": bson.A{"$status", "$presence_status"}}
+
+	update := bson.A{bson.M{"$set": bson.M{
+		"is_online":        true,
+		"last_active_at":   now,
+		"updated_at":       now,
+		"status":           bson.M{"$cond": bson.A{restored, bson.M{"$ifNull": bson.A{"$presence_restore", models.AgentStatusAvailable}}, "$status"}},
+		"presence_status":  "$$REMOVE",
+		"presence_restore": "$$REMOVE",
+	}}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var agent models.Agent
+	err := r.db.Collection(database.CollectionAgents).FindOneAndUpdate(ctx, filter, update, opts).Decode(&agent)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+
+	return &agent, nil
+}
+
+// MarkIdle moves online agents in one of the given statuses whose last
+// activity is before cutoff to status, remembering the status to restore.
+// It returns the number of agents changed.
+func (r *AgentRepository) MarkIdle(ctx context.Context, cutoff time.Time, from []models.AgentStatus, status models.AgentStatus) (int64, error) {
+	ctx, span := tracing.Start(ctx, "AgentRepository.MarkIdle")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "MarkIdle")()
+
+	filter := bson.M{
+		"is_deleted":     false,
+		"is_online":      true,
+		"status":         bson.M{"$in": from},
+		"last_active_at": bson.M{"$lt": cutoff},
+	}
+
+	update := bson.A{bson.M{"$set": bson.M{
+		"presence_restore": bson.M{"$ifNull": bson.A{"$presence_restore", "$status"}},
+		"presence_status":  status,
+		"status":           status,
+		"is_online":        status != models.AgentStatusOffline,
+		"updated_at":       time.Now(),
+	}}}
+
+	result, err := r.db.Collection(database.CollectionAgents).UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark idle agents: %w", err)
+	}
+
+	return result.ModifiedCount, nil
+}
+
 // IncrementTicketCount increments the current ticket count
 func (r *AgentRepository) IncrementTicketCount(ctx context.Context, id primitive.ObjectID) error {
+	ctx, span := tracing.Start(ctx, "AgentRepository.IncrementTicketCount")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "IncrementTicketCount")()
+
 	_, err := r.db.Collection(database.CollectionAgents).UpdateOne(
 		ctx,
 		bson.M{"_id": id},
@@ -243,8 +369,61 @@ func (r *AgentRepository) IncrementTicketCount(ctx context.Context, id primitive
 	return err
 }
 
+// ReserveTicketSlot atomically takes one ticket slot if the agent is below
+// capacity. It returns nil when the agent is full or does not exist.
+func (r *AgentRepository) ReserveTicketSlot(ctx context.Context, id primitive.ObjectID) (*models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "AgentRepository.ReserveTicketSlot")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "ReserveTicketSlot")()
+
+	filter := bson.M{
+		"_id":        id,
+		"is_deleted": false,
+		"$expr":      bson.M{"$lt": bson.A{"$current_tickets", "$max_tickets"}},
+	}
+	update := bson.M{
+		"$inc": bson.M{"current_tickets": 1, "tickets_today": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var agent models.Agent
+	err := r.db.Collection(database.CollectionAgents).FindOneAndUpdate(ctx, filter, update, opts).Decode(&agent)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to reserve ticket slot: %w", err)
+	}
+
+	return &agent, nil
+}
+
+// ReleaseTicketSlot undoes a ReserveTicketSlot whose assignment was not saved.
+// tickets_today stops at zero, since the daily reset may have run in between.
+func (r *AgentRepository) ReleaseTicketSlot(ctx context.Context, id primitive.ObjectID) error {
+	ctx, span := tracing.Start(ctx, "AgentRepository.ReleaseTicketSlot")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "ReleaseTicketSlot")()
+
+	_, err := r.db.Collection(database.CollectionAgents).UpdateOne(
+		ctx,
+		bson.M{"_id": id, "current_tickets": bson.M{"$gt": 0}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"current_tickets": bson.M{"$subtract": bson.A{"$current_tickets", 1}},
+			"tickets_today":   bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{"$tickets_today", 1}}}},
+			"updated_at":      time.Now(),
+		}}}},
+	)
+	return err
+}
+
 // DecrementTicketCount decrements the current ticket count
 func (r *AgentRepository) DecrementTicketCount(ctx context.Context, id primitive.ObjectID) error {
+	ctx, span := tracing.Start(ctx, "AgentRepository.DecrementTicketCount")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "DecrementTicketCount")()
+
 	_, err := r.db.Collection(database.CollectionAgents).UpdateOne(
 		ctx,
 		bson.M{"_id": id, "current_tickets": bson.M{"$gt": 0}},
@@ -256,8 +435,155 @@ func (r *AgentRepository) DecrementTicketCount(ctx context.Context, id primitive
 	return err
 }
 
+// SetCounters replaces counter fields that still hold their old values
+func (r *AgentRepository) SetCounters(ctx context.Context, id primitive.ObjectID, oldValues, newValues map[string]int64) (bool, error) {
+	ctx, span := tracing.Start(ctx, "AgentRepository.SetCounters")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "SetCounters")()
+
+	return setCounters(ctx, r.db.Collection(database.CollectionAgents), id, oldValues, newValues)
+}
+
+// ResetCounters zeroes the given counter fields on every agent
+func (r *AgentRepository) ResetCounters(ctx context.Context, fields ...string) error {
+	ctx, span := tracing.Start(ctx, "AgentRepository.ResetCounters")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "ResetCounters")()
+
+	set := bson.M{"updated_at": time.Now()}
+	for _, field := range fields {
+		set[field] = 0
+	}
+
+	_, err := r.db.Collection(database.CollectionAgents).UpdateMany(ctx, bson.M{"is_deleted": false}, bson.M{"$set": set})
+	if err != nil {
+		return fmt.Errorf("failed to reset agent counters: %w", err)
+	}
+	return nil
+}
+
+// ListTenantIDs lists tenants that have agents
+func (r *AgentRepository) ListTenantIDs(ctx context.Context) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "AgentRepository.ListTenantIDs")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "ListTenantIDs")()
+
+	return distinctTenants(ctx, r.db.Collection(database.CollectionAgents))
+}
+
+// ListScheduled lists active agents across tenants that have a schedule,
+// out-of-office periods, or a status previously set by the scheduler
+func (r *AgentRepository) ListScheduled(ctx context.Context) ([]models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "AgentRepository.ListScheduled")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "ListScheduled")()
+
+	cursor, err := r.db.Collection(database.CollectionAgents).Find(ctx, bson.M{
+		"is_deleted": false,
+		"is_active":  true,
+		"$or": bson.A{
+			bson.M{"schedule_enabled": true},
+			bson.M{"out_of_office.0": bson.M{"$exists": true}},
+			bson.M{"schedule_status": bson.M{"$nin": bson.A{"", nil}}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled agents: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var agents []models.Agent
+	if err := cursor.All(ctx, &agents); err != nil {
+		return nil, fmt.Errorf("failed to decode agents: %w", err)
+	}
+
+	return agents, nil
+}
+
+// ApplyScheduleStatus sets the agent's status if the scheduler's last applied
+// status is still previous, so only one instance applies each transition
+func (r *AgentRepository) ApplyScheduleStatus(ctx context.Context, id primitive.ObjectID, previous, status models.AgentStatus) (bool, error) {
+	ctx, span := tracing.Start(ctx, "AgentRepository.ApplyScheduleStatus")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "ApplyScheduleStatus")()
+
+	filter := bson.M{"_id": id, "schedule_status": previous}
+	if previous == "" {
+		filter["schedule_status"] = bson.M{"$in": bson.A{"", nil}}
+	}
+
+	result, err := r.db.Collection(database.CollectionAgents).UpdateOne(ctx, filter, bson.M{
+		"$set": bson.M{
+			"status":          status,
+			"schedule_status": status,
+			"updated_at":      time.Now(),
+		},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to apply schedule status: %w", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// AddOutOfOffice adds a leave period to the agent
+func (r *AgentRepository) AddOutOfOffice(ctx context.Context, id primitive.ObjectID, period models.OutOfOffice) error {
+	ctx, span := tracing.Start(ctx, "AgentRepository.AddOutOfOffice")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "AddOutOfOffice")()
+
+	_, err := r.db.Collection(database.CollectionAgents).UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$push": bson.M{"out_of_office": period},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add out-of-office period: %w", err)
+	}
+	return nil
+}
+
+// RemoveOutOfOffice removes a leave period from the agent
+func (r *AgentRepository) RemoveOutOfOffice(ctx context.Context, id, periodID primitive.ObjectID) error {
+	ctx, span := tracing.Start(ctx, "AgentRepository.RemoveOutOfOffice")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "RemoveOutOfOffice")()
+
+	_, err := r.db.Collection(database.CollectionAgents).UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$pull": bson.M{"out_of_office": bson.M{"id": periodID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove out-of-office period: %w", err)
+	}
+	return nil
+}
+
+// MarkLeaveApplied flags a leave period's ticket action as done. It reports
+// false if another instance already applied it.
+func (r *AgentRepository) MarkLeaveApplied(ctx context.Context, id, periodID primitive.ObjectID) (bool, error) {
+	ctx, span := tracing.Start(ctx, "AgentRepository.MarkLeaveApplied")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "MarkLeaveApplied")()
+
+	result, err := r.db.Collection(database.CollectionAgents).UpdateOne(ctx, bson.M{
+		"_id":           id,
+		"out_of_office": bson.M{"$elemMatch": bson.M{"id": periodID, "applied": false}},
+	}, bson.M{
+		"$set": bson.M{"out_of_office.$.applied": true, "updated_at": time.Now()},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to mark leave applied: %w", err)
+	}
+
+	return result.ModifiedCount > 0, nil
+}
+
 // IncrementResolved increments the total resolved count
 func (r *AgentRepository) IncrementResolved(ctx context.Context, id primitive.ObjectID) error {
+	ctx, span := tracing.Start(ctx, "AgentRepository.IncrementResolved")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "IncrementResolved")()
+
 	_, err := r.db.Collection(database.CollectionAgents).UpdateOne(
 		ctx,
 		bson.M{"_id": id},
@@ -269,9 +595,63 @@ func (r *AgentRepository) IncrementResolved(ctx context.Context, id primitive.Ob
 	return err
 }
 
+// SetAvgRating sets the agent's average satisfaction rating
+func (r *AgentRepository) SetAvgRating(ctx context.Context, id primitive.ObjectID, avg float64) error {
+	ctx, span := tracing.Start(ctx, "AgentRepository.SetAvgRating")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "SetAvgRating")()
+
+	_, err := r.db.Collection(database.CollectionAgents).UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"avg_rating": avg, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// ListLoad lists current workloads of active agents across all tenants
+func (r *AgentRepository) ListLoad(ctx context.Context) ([]models.AgentLoad, error) {
+	ctx, span := tracing.Start(ctx, "AgentRepository.ListLoad")
+	defer span.End()
+	defer metrics.ObserveMongo("agent", "ListLoad")()
+
+	opts := options.Find().SetProjection(bson.M{
+		"tenant_id":       1,
+		"current_tickets": 1,
+		"max_tickets":     1,
+	})
+
+	cursor, err := r.db.Collection(database.CollectionAgents).Find(ctx, bson.M{
+		"is_deleted": false,
+		"is_active":  true,
+	}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agent load: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var agents []models.Agent
+	if err := cursor.All(ctx, &agents); err != nil {
+		return nil, fmt.Errorf("failed to decode agents: %w", err)
+	}
+
+	loads := make([]models.AgentLoad, 0, len(agents))
+	for _, agent := range agents {
+		loads = append(loads, models.AgentLoad{
+			TenantID:       agent.TenantID,
+			AgentID:        agent.ID.Hex(),
+			CurrentTickets: agent.CurrentTickets,
+			MaxTickets:     agent.MaxTickets,
+		})
+	}
+
+	return loads, nil
+}
+
 // SLAPolicyRepository handles SLA policy database operations
 type SLAPolicyRepository struct {
-	db *database.MongoDB
+	db    *database.MongoDB
+	cache *cache.Cache
 }
 
 // NewSLAPolicyRepository creates a new SLA policy repository
@@ -279,8 +659,18 @@ func NewSLAPolicyRepository(db *database.MongoDB) *SLAPolicyRepository {
 	return &SLAPolicyRepository{db: db}
 }
 
+// WithCache puts a read-through cache in front of GetByID and GetDefault
+func (r *SLAPolicyRepository) WithCache(c *cache.Cache) *SLAPolicyRepository {
+	r.cache = c
+	return r
+}
+
 // Create creates a new SLA policy
 func (r *SLAPolicyRepository) Create(ctx context.Context, policy *models.SLAPolicy) error {
+	ctx, span := tracing.Start(ctx, "SLAPolicyRepository.Create")
+	defer span.End()
+	defer metrics.ObserveMongo("sla_policy", "Create")()
+
 	policy.CreatedAt = time.Now()
 	policy.UpdatedAt = time.Now()
 	policy.IsActive = true
@@ -291,12 +681,21 @@ func (r *SLAPolicyRepository) Create(ctx context.Context, policy *models.SLAPoli
 	}
 
 	policy.ID = result.InsertedID.(primitive.ObjectID)
+	r.cache.Delete(ctx, slaDefaultCacheKey(policy.TenantID))
 	return nil
 }
 
 // GetByID gets an SLA policy by ID
 func (r *SLAPolicyRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.SLAPolicy, error) {
+	ctx, span := tracing.Start(ctx, "SLAPolicyRepository.GetByID")
+	defer span.End()
+	defer metrics.ObserveMongo("sla_policy", "GetByID")()
+
 	var policy models.SLAPolicy
+	if r.cache.Get(ctx, id.Hex(), &policy) {
+		return &policy, nil
+	}
+
 	err := r.db.Collection(database.CollectionSLAPolicies).FindOne(ctx, bson.M{"_id": id}).Decode(&policy)
 
 	if err != nil {
@@ -306,12 +705,21 @@ func (r *SLAPolicyRepository) GetByID(ctx context.Context, id primitive.ObjectID
 		return nil, fmt.Errorf("failed to get SLA policy: %w", err)
 	}
 
+	r.cache.Set(ctx, id.Hex(), &policy)
 	return &policy, nil
 }
 
 // GetDefault gets the default SLA policy for a tenant
 func (r *SLAPolicyRepository) GetDefault(ctx context.Context, tenantID string) (*models.SLAPolicy, error) {
+	ctx, span := tracing.Start(ctx, "SLAPolicyRepository.GetDefault")
+	defer span.End()
+	defer metrics.ObserveMongo("sla_policy", "GetDefault")()
+
 	var policy models.SLAPolicy
+	if r.cache.Get(ctx, slaDefaultCacheKey(tenantID), &policy) {
+		return &policy, nil
+	}
+
 	err := r.db.Collection(database.CollectionSLAPolicies).FindOne(ctx, bson.M{
 		"tenant_id":  tenantID,
 		"is_default": true,
@@ -325,11 +733,16 @@ func (r *SLAPolicyRepository) GetDefault(ctx context.Context, tenantID string) (
 		return nil, fmt.Errorf("failed to get default SLA policy: %w", err)
 	}
 
+	r.cache.Set(ctx, slaDefaultCacheKey(tenantID), &policy)
 	return &policy, nil
 }
 
 // Update updates an SLA policy
 func (r *SLAPolicyRepository) Update(ctx context.Context, policy *models.SLAPolicy) error {
+	ctx, span := tracing.Start(ctx, "SLAPolicyRepository.Update")
+	defer span.End()
+	defer metrics.ObserveMongo("sla_policy", "Update")()
+
 	policy.UpdatedAt = time.Now()
 
 	_, err := r.db.Collection(database.CollectionSLAPolicies).UpdateOne(
@@ -341,20 +754,36 @@ func (r *SLAPolicyRepository) Update(ctx context.Context, policy *models.SLAPoli
 		return fmt.Errorf("failed to update SLA policy: %w", err)
 	}
 
+	// Any update may change which policy is the tenant default
+	r.cache.Delete(ctx, policy.ID.Hex(), slaDefaultCacheKey(policy.TenantID))
 	return nil
 }
 
 // Delete deletes an SLA policy
 func (r *SLAPolicyRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
-	_, err := r.db.Collection(database.CollectionSLAPolicies).DeleteOne(ctx, bson.M{"_id": id})
+	ctx, span := tracing.Start(ctx, "SLAPolicyRepository.Delete")
+	defer span.End()
+	defer metrics.ObserveMongo("sla_policy", "Delete")()
+
+	var policy models.SLAPolicy
+	err := r.db.Collection(database.CollectionSLAPolicies).FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&policy)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
 		return fmt.Errorf("failed to delete SLA policy: %w", err)
 	}
+
+	r.cache.Delete(ctx, id.Hex(), slaDefaultCacheKey(policy.TenantID))
 	return nil
 }
 
 // List lists SLA policies
 func (r *SLAPolicyRepository) List(ctx context.Context, tenantID string) ([]models.SLAPolicy, error) {
+	ctx, span := tracing.Start(ctx, "SLAPolicyRepository.List")
+	defer span.End()
+	defer metrics.ObserveMongo("sla_policy", "List")()
+
 	query := bson.M{"tenant_id": tenantID}
 
 	cursor, err := r.db.Collection(database.CollectionSLAPolicies).Find(ctx, query)
@@ -371,6 +800,10 @@ func (r *SLAPolicyRepository) List(ctx context.Context, tenantID string) ([]mode
 	return policies, nil
 }
 
+func slaDefaultCacheKey(tenantID string) string {
+	return "default:" + tenantID
+}
+
 // CannedResponseRepository handles canned response database operations
 type CannedResponseRepository struct {
 	db *database.MongoDB
@@ -383,6 +816,10 @@ func NewCannedResponseRepository(db *database.MongoDB) *CannedResponseRepository
 
 // Create creates a new canned response
 func (r *CannedResponseRepository) Create(ctx context.Context, response *models.CannedResponse) error {
+	ctx, span := tracing.Start(ctx, "CannedResponseRepository.Create")
+	defer span.End()
+	defer metrics.ObserveMongo("canned_response", "Create")()
+
 	response.CreatedAt = time.Now()
 	response.UpdatedAt = time.Now()
 	response.IsActive = true
@@ -398,6 +835,10 @@ func (r *CannedResponseRepository) Create(ctx context.Context, response *models.
 
 // GetByID gets a canned response by ID
 func (r *CannedResponseRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.CannedResponse, error) {
+	ctx, span := tracing.Start(ctx, "CannedResponseRepository.GetByID")
+	defer span.End()
+	defer metrics.ObserveMongo("canned_response", "GetByID")()
+
 	var response models.CannedResponse
 	err := r.db.Collection(database.CollectionCannedResponses).FindOne(ctx, bson.M{"_id": id}).Decode(&response)
 
@@ -413,6 +854,10 @@ func (r *CannedResponseRepository) GetByID(ctx context.Context, id primitive.Obj
 
 // Update updates a canned response
 func (r *CannedResponseRepository) Update(ctx context.Context, response *models.CannedResponse) error {
+	ctx, span := tracing.Start(ctx, "CannedResponseRepository.Update")
+	defer span.End()
+	defer metrics.ObserveMongo("canned_response", "Update")()
+
 	response.UpdatedAt = time.Now()
 
 	_, err := r.db.Collection(database.CollectionCannedResponses).UpdateOne(
@@ -429,6 +874,10 @@ func (r *CannedResponseRepository) Update(ctx context.Context, response *models.
 
 // Delete deletes a canned response
 func (r *CannedResponseRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, span := tracing.Start(ctx, "CannedResponseRepository.Delete")
+	defer span.End()
+	defer metrics.ObserveMongo("canned_response", "Delete")()
+
 	_, err := r.db.Collection(database.CollectionCannedResponses).DeleteOne(ctx, bson.M{"_id": id})
 	if err != nil {
 		return fmt.Errorf("failed to delete canned response: %w", err)
@@ -438,6 +887,10 @@ func (r *CannedResponseRepository) Delete(ctx context.Context, id primitive.Obje
 
 // List lists canned responses
 func (r *CannedResponseRepository) List(ctx context.Context, tenantID string, departmentID *primitive.ObjectID, globalOnly bool) ([]models.CannedResponse, error) {
+	ctx, span := tracing.Start(ctx, "CannedResponseRepository.List")
+	defer span.End()
+	defer metrics.ObserveMongo("canned_response", "List")()
+
 	query := bson.M{
 		"tenant_id": tenantID,
 		"is_active": true,
@@ -470,6 +923,10 @@ func (r *CannedResponseRepository) List(ctx context.Context, tenantID string, de
 
 // GetByShortcut gets a canned response by shortcut
 func (r *CannedResponseRepository) GetByShortcut(ctx context.Context, tenantID, shortcut string) (*models.CannedResponse, error) {
+	ctx, span := tracing.Start(ctx, "CannedResponseRepository.GetByShortcut")
+	defer span.End()
+	defer metrics.ObserveMongo("canned_response", "GetByShortcut")()
+
 	var response models.CannedResponse
 	err := r.db.Collection(database.CollectionCannedResponses).FindOne(ctx, bson.M{
 		"tenant_id": tenantID,
@@ -489,6 +946,10 @@ func (r *CannedResponseRepository) GetByShortcut(ctx context.Context, tenantID,
 
 // IncrementUsage increments the usage count
 func (r *CannedResponseRepository) IncrementUsage(ctx context.Context, id primitive.ObjectID) error {
+	ctx, span := tracing.Start(ctx, "CannedResponseRepository.IncrementUsage")
+	defer span.End()
+	defer metrics.ObserveMongo("canned_response", "IncrementUsage")()
+
 	_, err := r.db.Collection(database.CollectionCannedResponses).UpdateOne(
 		ctx,
 		bson.M{"_id": id},
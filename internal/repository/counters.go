@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// setCounters sets counter fields only if they still hold the old values, so
+// a reconciliation never overwrites an increment that landed after it read.
+// It reports whether the document was updated.
+func setCounters(ctx context.Context, coll *mongo.Collection, id primitive.ObjectID, oldValues, newValues map[string]int64) (bool, error) {
+	filter := bson.M{"_id": id}
+	for field, value := range oldValues {
+		if value == 0 {
+			// Zero counters may be omitted from the document
+			filter[field] = bson.M{"$in": bson.A{0, nil}}
+		} else {
+			filter[field] = value
+		}
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	for field, value := range newValues {
+		set[field] = value
+	}
+
+	result, err := coll.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return false, fmt.Errorf("failed to set counters: %w", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// distinctTenants lists the tenant IDs with live documents in a collection
+func distinctTenants(ctx context.Context, coll *mongo.Collection) ([]string, error) {
+	values, err := coll.Distinct(ctx, "tenant_id", bson.M{"is_deleted": false})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	tenants := make([]string, 0, len(values))
+	for _, value := range values {
+		if tenantID, ok := value.(string); ok && tenantID != "" {
+			tenants = append(tenants, tenantID)
+		}
+	}
+
+	return tenants, nil
+}
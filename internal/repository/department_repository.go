@@ -6,8 +6,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/minisource/ticket/internal/cache"
 	"github.com/minisource/ticket/internal/database"
+	"github.com/minisource/ticket/internal/metrics"
 	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/tracing"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -16,7 +19,8 @@ import (
 
 // DepartmentRepository handles department database operations
 type DepartmentRepository struct {
-	db *database.MongoDB
+	db    *database.MongoDB
+	cache *cache.Cache
 }
 
 // NewDepartmentRepository creates a new department repository
@@ -24,8 +28,18 @@ func NewDepartmentRepository(db *database.MongoDB) *DepartmentRepository {
 	return &DepartmentRepository{db: db}
 }
 
+// WithCache puts a read-through cache in front of GetByID
+func (r *DepartmentRepository) WithCache(c *cache.Cache) *DepartmentRepository {
+	r.cache = c
+	return r
+}
+
 // Create creates a new department
 func (r *DepartmentRepository) Create(ctx context.Context, department *models.Department) error {
+	ctx, span := tracing.Start(ctx, "DepartmentRepository.Create")
+	defer span.End()
+	defer metrics.ObserveMongo("department", "Create")()
+
 	department.CreatedAt = time.Now()
 	department.UpdatedAt = time.Now()
 	department.Slug = r.generateSlug(department.Name)
@@ -41,7 +55,15 @@ func (r *DepartmentRepository) Create(ctx context.Context, department *models.De
 
 // GetByID gets a department by ID
 func (r *DepartmentRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Department, error) {
+	ctx, span := tracing.Start(ctx, "DepartmentRepository.GetByID")
+	defer span.End()
+	defer metrics.ObserveMongo("department", "GetByID")()
+
 	var department models.Department
+	if r.cache.Get(ctx, id.Hex(), &department) {
+		return &department, nil
+	}
+
 	err := r.db.Collection(database.CollectionDepartments).FindOne(ctx, bson.M{
 		"_id":        id,
 		"is_deleted": false,
@@ -54,11 +76,16 @@ func (r *DepartmentRepository) GetByID(ctx context.Context, id primitive.ObjectI
 		return nil, fmt.Errorf("failed to get department: %w", err)
 	}
 
+	r.cache.Set(ctx, id.Hex(), &department)
 	return &department, nil
 }
 
 // GetBySlug gets a department by slug
 func (r *DepartmentRepository) GetBySlug(ctx context.Context, tenantID, slug string) (*models.Department, error) {
+	ctx, span := tracing.Start(ctx, "DepartmentRepository.GetBySlug")
+	defer span.End()
+	defer metrics.ObserveMongo("department", "GetBySlug")()
+
 	var department models.Department
 	err := r.db.Collection(database.CollectionDepartments).FindOne(ctx, bson.M{
 		"tenant_id":  tenantID,
@@ -78,6 +105,10 @@ func (r *DepartmentRepository) GetBySlug(ctx context.Context, tenantID, slug str
 
 // Update updates a department
 func (r *DepartmentRepository) Update(ctx context.Context, department *models.Department) error {
+	ctx, span := tracing.Start(ctx, "DepartmentRepository.Update")
+	defer span.End()
+	defer metrics.ObserveMongo("department", "Update")()
+
 	department.UpdatedAt = time.Now()
 
 	_, err := r.db.Collection(database.CollectionDepartments).UpdateOne(
@@ -89,11 +120,16 @@ func (r *DepartmentRepository) Update(ctx context.Context, department *models.De
 		return fmt.Errorf("failed to update department: %w", err)
 	}
 
+	r.cache.Delete(ctx, department.ID.Hex())
 	return nil
 }
 
 // Delete soft deletes a department
 func (r *DepartmentRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, span := tracing.Start(ctx, "DepartmentRepository.Delete")
+	defer span.End()
+	defer metrics.ObserveMongo("department", "Delete")()
+
 	now := time.Now()
 	_, err := r.db.Collection(database.CollectionDepartments).UpdateOne(
 		ctx,
@@ -108,11 +144,16 @@ func (r *DepartmentRepository) Delete(ctx context.Context, id primitive.ObjectID
 		return fmt.Errorf("failed to delete department: %w", err)
 	}
 
+	r.cache.Delete(ctx, id.Hex())
 	return nil
 }
 
 // List lists departments
 func (r *DepartmentRepository) List(ctx context.Context, tenantID string, activeOnly bool) ([]models.Department, error) {
+	ctx, span := tracing.Start(ctx, "DepartmentRepository.List")
+	defer span.End()
+	defer metrics.ObserveMongo("department", "List")()
+
 	query := bson.M{
 		"tenant_id":  tenantID,
 		"is_deleted": false,
@@ -140,6 +181,10 @@ func (r *DepartmentRepository) List(ctx context.Context, tenantID string, active
 
 // AddAgent adds an agent to a department
 func (r *DepartmentRepository) AddAgent(ctx context.Context, departmentID primitive.ObjectID, agentID string) error {
+	ctx, span := tracing.Start(ctx, "DepartmentRepository.AddAgent")
+	defer span.End()
+	defer metrics.ObserveMongo("department", "AddAgent")()
+
 	_, err := r.db.Collection(database.CollectionDepartments).UpdateOne(
 		ctx,
 		bson.M{"_id": departmentID},
@@ -148,11 +193,16 @@ func (r *DepartmentRepository) AddAgent(ctx context.Context, departmentID primit
 			"$set":      bson.M{"updated_at": time.Now()},
 		},
 	)
+	r.cache.Delete(ctx, departmentID.Hex())
 	return err
 }
 
 // RemoveAgent removes an agent from a department
 func (r *DepartmentRepository) RemoveAgent(ctx context.Context, departmentID primitive.ObjectID, agentID string) error {
+	ctx, span := tracing.Start(ctx, "DepartmentRepository.RemoveAgent")
+	defer span.End()
+	defer metrics.ObserveMongo("department", "RemoveAgent")()
+
 	_, err := r.db.Collection(database.CollectionDepartments).UpdateOne(
 		ctx,
 		bson.M{"_id": departmentID},
@@ -161,11 +211,18 @@ func (r *DepartmentRepository) RemoveAgent(ctx context.Context, departmentID pri
 			"$set":  bson.M{"updated_at": time.Now()},
 		},
 	)
+	r.cache.Delete(ctx, departmentID.Hex())
 	return err
 }
 
-// IncrementTicketCount increments the ticket count
+// IncrementTicketCount increments the ticket count.
+// Counters change on every ticket, so the cached department is left alone and
+// its counts may lag by up to the cache TTL.
 func (r *DepartmentRepository) IncrementTicketCount(ctx context.Context, departmentID primitive.ObjectID, isOpen bool) error {
+	ctx, span := tracing.Start(ctx, "DepartmentRepository.IncrementTicketCount")
+	defer span.End()
+	defer metrics.ObserveMongo("department", "IncrementTicketCount")()
+
 	update := bson.M{
 		"$inc": bson.M{"total_tickets": 1},
 		"$set": bson.M{"updated_at": time.Now()},
@@ -181,6 +238,10 @@ func (r *DepartmentRepository) IncrementTicketCount(ctx context.Context, departm
 
 // DecrementOpenTickets decrements the open ticket count
 func (r *DepartmentRepository) DecrementOpenTickets(ctx context.Context, departmentID primitive.ObjectID) error {
+	ctx, span := tracing.Start(ctx, "DepartmentRepository.DecrementOpenTickets")
+	defer span.End()
+	defer metrics.ObserveMongo("department", "DecrementOpenTickets")()
+
 	_, err := r.db.Collection(database.CollectionDepartments).UpdateOne(
 		ctx,
 		bson.M{"_id": departmentID, "open_tickets": bson.M{"$gt": 0}},
@@ -192,6 +253,49 @@ func (r *DepartmentRepository) DecrementOpenTickets(ctx context.Context, departm
 	return err
 }
 
+// SetCounters replaces counter fields that still hold their old values
+func (r *DepartmentRepository) SetCounters(ctx context.Context, id primitive.ObjectID, oldValues, newValues map[string]int64) (bool, error) {
+	ctx, span := tracing.Start(ctx, "DepartmentRepository.SetCounters")
+	defer span.End()
+	defer metrics.ObserveMongo("department", "SetCounters")()
+
+	updated, err := setCounters(ctx, r.db.Collection(database.CollectionDepartments), id, oldValues, newValues)
+	if err != nil {
+		return false, err
+	}
+
+	r.cache.Delete(ctx, id.Hex())
+	return updated, nil
+}
+
+// ListTenantIDs lists tenants that have departments
+func (r *DepartmentRepository) ListTenantIDs(ctx context.Context) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "DepartmentRepository.ListTenantIDs")
+	defer span.End()
+	defer metrics.ObserveMongo("department", "ListTenantIDs")()
+
+	return distinctTenants(ctx, r.db.Collection(database.CollectionDepartments))
+}
+
+// NextAssignmentCursor atomically advances the round-robin cursor for key
+func (r *DepartmentRepository) NextAssignmentCursor(ctx context.Context, key string) (int64, error) {
+	ctx, span := tracing.Start(ctx, "DepartmentRepository.NextAssignmentCursor")
+	defer span.End()
+	defer metrics.ObserveMongo("department", "NextAssignmentCursor")()
+
+	filter := bson.M{"_id": key}
+	update := bson.M{"$inc": bson.M{"sequence": 1}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var cursor models.AssignmentCursor
+	err := r.db.Collection(database.CollectionAssignCursors).FindOneAndUpdate(ctx, filter, update, opts).Decode(&cursor)
+	if err != nil {
+		return 0, fmt.Errorf("failed to advance assignment cursor: %w", err)
+	}
+
+	return cursor.Sequence, nil
+}
+
 func (r *DepartmentRepository) generateSlug(name string) string {
 	slug := strings.ToLower(name)
 	slug = strings.ReplaceAll(slug, " ", "-")
@@ -200,7 +304,8 @@ func (r *DepartmentRepository) generateSlug(name string) string {
 
 // CategoryRepository handles category database operations
 type CategoryRepository struct {
-	db *database.MongoDB
+	db    *database.MongoDB
+	cache *cache.Cache
 }
 
 // NewCategoryRepository creates a new category repository
@@ -208,8 +313,18 @@ func NewCategoryRepository(db *database.MongoDB) *CategoryRepository {
 	return &CategoryRepository{db: db}
 }
 
+// WithCache puts a read-through cache in front of GetByID
+func (r *CategoryRepository) WithCache(c *cache.Cache) *CategoryRepository {
+	r.cache = c
+	return r
+}
+
 // Create creates a new category
 func (r *CategoryRepository) Create(ctx context.Context, category *models.Category) error {
+	ctx, span := tracing.Start(ctx, "CategoryRepository.Create")
+	defer span.End()
+	defer metrics.ObserveMongo("category", "Create")()
+
 	category.CreatedAt = time.Now()
 	category.UpdatedAt = time.Now()
 	category.Slug = r.generateSlug(category.Name)
@@ -225,7 +340,15 @@ func (r *CategoryRepository) Create(ctx context.Context, category *models.Catego
 
 // GetByID gets a category by ID
 func (r *CategoryRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Category, error) {
+	ctx, span := tracing.Start(ctx, "CategoryRepository.GetByID")
+	defer span.End()
+	defer metrics.ObserveMongo("category", "GetByID")()
+
 	var category models.Category
+	if r.cache.Get(ctx, id.Hex(), &category) {
+		return &category, nil
+	}
+
 	err := r.db.Collection(database.CollectionCategories).FindOne(ctx, bson.M{
 		"_id":        id,
 		"is_deleted": false,
@@ -238,11 +361,16 @@ func (r *CategoryRepository) GetByID(ctx context.Context, id primitive.ObjectID)
 		return nil, fmt.Errorf("failed to get category: %w", err)
 	}
 
+	r.cache.Set(ctx, id.Hex(), &category)
 	return &category, nil
 }
 
 // Update updates a category
 func (r *CategoryRepository) Update(ctx context.Context, category *models.Category) error {
+	ctx, span := tracing.Start(ctx, "CategoryRepository.Update")
+	defer span.End()
+	defer metrics.ObserveMongo("category", "Update")()
+
 	category.UpdatedAt = time.Now()
 
 	_, err := r.db.Collection(database.CollectionCategories).UpdateOne(
@@ -254,11 +382,16 @@ func (r *CategoryRepository) Update(ctx context.Context, category *models.Catego
 		return fmt.Errorf("failed to update category: %w", err)
 	}
 
+	r.cache.Delete(ctx, category.ID.Hex())
 	return nil
 }
 
 // Delete soft deletes a category
 func (r *CategoryRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, span := tracing.Start(ctx, "CategoryRepository.Delete")
+	defer span.End()
+	defer metrics.ObserveMongo("category", "Delete")()
+
 	now := time.Now()
 	_, err := r.db.Collection(database.CollectionCategories).UpdateOne(
 		ctx,
@@ -273,11 +406,16 @@ func (r *CategoryRepository) Delete(ctx context.Context, id primitive.ObjectID)
 		return fmt.Errorf("failed to delete category: %w", err)
 	}
 
+	r.cache.Delete(ctx, id.Hex())
 	return nil
 }
 
 // List lists categories
 func (r *CategoryRepository) List(ctx context.Context, tenantID string, publicOnly bool) ([]models.Category, error) {
+	ctx, span := tracing.Start(ctx, "CategoryRepository.List")
+	defer span.End()
+	defer metrics.ObserveMongo("category", "List")()
+
 	query := bson.M{
 		"tenant_id":  tenantID,
 		"is_deleted": false,
@@ -306,6 +444,10 @@ func (r *CategoryRepository) List(ctx context.Context, tenantID string, publicOn
 
 // GetByDepartmentID gets categories for a department
 func (r *CategoryRepository) GetByDepartmentID(ctx context.Context, tenantID string, departmentID primitive.ObjectID) ([]models.Category, error) {
+	ctx, span := tracing.Start(ctx, "CategoryRepository.GetByDepartmentID")
+	defer span.End()
+	defer metrics.ObserveMongo("category", "GetByDepartmentID")()
+
 	query := bson.M{
 		"tenant_id":     tenantID,
 		"department_id": departmentID,
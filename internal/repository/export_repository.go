@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/minisource/ticket/internal/database"
+	"github.com/minisource/ticket/internal/metrics"
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExportRepository handles export job database operations
+type ExportRepository struct {
+	db *database.MongoDB
+}
+
+// NewExportRepository creates a new export job repository
+func NewExportRepository(db *database.MongoDB) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+// Create creates a new export job
+func (r *ExportRepository) Create(ctx context.Context, job *models.ExportJob) error {
+	ctx, span := tracing.Start(ctx, "ExportRepository.Create")
+	defer span.End()
+	defer metrics.ObserveMongo("export", "Create")()
+
+	job.CreatedAt = time.Now()
+
+	result, err := r.db.Collection(database.CollectionExportJobs).InsertOne(ctx, job)
+	if err != nil {
+		return fmt.Errorf("failed to create export job: %w", err)
+	}
+
+	job.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// GetByID gets a tenant's export job by ID
+func (r *ExportRepository) GetByID(ctx context.Context, tenantID string, id primitive.ObjectID) (*models.ExportJob, error) {
+	ctx, span := tracing.Start(ctx, "ExportRepository.GetByID")
+	defer span.End()
+	defer metrics.ObserveMongo("export", "GetByID")()
+
+	var job models.ExportJob
+	err := r.db.Collection(database.CollectionExportJobs).FindOne(ctx, bson.M{
+		"_id":       id,
+		"tenant_id": tenantID,
+	}).Decode(&job)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get export job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// ClaimNext marks the oldest pending job as running and returns it, or nil
+// when none is pending. Jobs left running longer than stale, by a worker
+// that stopped, are claimed again.
+func (r *ExportRepository) ClaimNext(ctx context.Context, stale time.Duration) (*models.ExportJob, error) {
+	ctx, span := tracing.Start(ctx, "ExportRepository.ClaimNext")
+	defer span.End()
+	defer metrics.ObserveMongo("export", "ClaimNext")()
+
+	now := time.Now()
+	filter := bson.M{
+		"$or": []bson.M{
+			{"status": models.ExportPending},
+			{"status": models.ExportRunning, "started_at": bson.M{"$lt": now.Add(-stale)}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"status": models.ExportRunning, "started_at": now}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job models.ExportJob
+	err := r.db.Collection(database.CollectionExportJobs).FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim export job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// Finish records the outcome of a running job, as long as it is still this
+// claim's. It reports false when the job was claimed again after going stale
+// or deleted meanwhile.
+func (r *ExportRepository) Finish(ctx context.Context, job *models.ExportJob) (bool, error) {
+	ctx, span := tracing.Start(ctx, "ExportRepository.Finish")
+	defer span.End()
+	defer metrics.ObserveMongo("export", "Finish")()
+
+	now := time.Now()
+	job.FinishedAt = &now
+
+	filter := bson.M{"_id": job.ID, "status": models.ExportRunning, "started_at": job.StartedAt}
+	result, err := r.db.Collection(database.CollectionExportJobs).UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+		"status":      job.Status,
+		"rows":        job.Rows,
+		"file_name":   job.FileName,
+		"file_id":     job.FileID,
+		"size":        job.Size,
+		"error":       job.Error,
+		"finished_at": job.FinishedAt,
+	}})
+	if err != nil {
+		return false, fmt.Errorf("failed to finish export job: %w", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// ListExpired lists jobs that expired before a time. Running jobs are left
+// to finish, so their file isn't written after the job is gone.
+func (r *ExportRepository) ListExpired(ctx context.Context, before time.Time) ([]models.ExportJob, error) {
+	ctx, span := tracing.Start(ctx, "ExportRepository.ListExpired")
+	defer span.End()
+	defer metrics.ObserveMongo("export", "ListExpired")()
+
+	cursor, err := r.db.Collection(database.CollectionExportJobs).Find(ctx, bson.M{
+		"expires_at": bson.M{"$lt": before},
+		"status":     bson.M{"$ne": models.ExportRunning},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired export jobs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []models.ExportJob
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to decode export jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// Delete deletes an export job
+func (r *ExportRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, span := tracing.Start(ctx, "ExportRepository.Delete")
+	defer span.End()
+	defer metrics.ObserveMongo("export", "Delete")()
+
+	_, err := r.db.Collection(database.CollectionExportJobs).DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete export job: %w", err)
+	}
+	return nil
+}
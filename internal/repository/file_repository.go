@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/minisource/ticket/internal/database"
+	"github.com/minisource/ticket/internal/metrics"
+	"github.com/minisource/ticket/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FileRepository keeps files in a GridFS bucket, so any instance can read a
+// file another one wrote
+type FileRepository struct {
+	db     *database.MongoDB
+	bucket string
+}
+
+// NewFileRepository creates a new file repository over the named bucket
+func NewFileRepository(db *database.MongoDB, bucket string) *FileRepository {
+	return &FileRepository{db: db, bucket: bucket}
+}
+
+// Write stores what fn writes as a file named name and returns its ID and
+// size. If fn fails, the partly written file is discarded.
+func (r *FileRepository) Write(ctx context.Context, name string, fn func(io.Writer) error) (primitive.ObjectID, int64, error) {
+	ctx, span := tracing.Start(ctx, "FileRepository.Write")
+	defer span.End()
+	defer metrics.ObserveMongo("file", "Write")()
+
+	bucket, err := r.open()
+	if err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+
+	id := primitive.NewObjectID()
+	stream, err := bucket.OpenUploadStreamWithID(id, name)
+	if err != nil {
+		return primitive.NilObjectID, 0, fmt.Errorf("failed to create file: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = stream.SetWriteDeadline(deadline)
+	}
+
+	w := &countingWriter{w: stream}
+	if err := fn(w); err != nil {
+		_ = stream.Abort()
+		return primitive.NilObjectID, 0, err
+	}
+	if err := stream.Close(); err != nil {
+		return primitive.NilObjectID, 0, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return id, w.n, nil
+}
+
+// Open opens a file for reading, or returns nil if there is none. The caller
+// closes it.
+func (r *FileRepository) Open(ctx context.Context, id primitive.ObjectID) (*gridfs.DownloadStream, error) {
+	ctx, span := tracing.Start(ctx, "FileRepository.Open")
+	defer span.End()
+	defer metrics.ObserveMongo("file", "Open")()
+
+	bucket, err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = bucket.SetReadDeadline(deadline)
+	}
+
+	stream, err := bucket.OpenDownloadStream(id)
+	if err != nil {
+		if errors.Is(err, gridfs.ErrFileNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	return stream, nil
+}
+
+// Delete deletes a file. Files that are already gone are ignored.
+func (r *FileRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, span := tracing.Start(ctx, "FileRepository.Delete")
+	defer span.End()
+	defer metrics.ObserveMongo("file", "Delete")()
+
+	bucket, err := r.open()
+	if err != nil {
+		return err
+	}
+
+	if err := bucket.DeleteContext(ctx, id); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// open opens the bucket; it is cheap and creates nothing until the first
+// write
+func (r *FileRepository) open() (*gridfs.Bucket, error) {
+	bucket, err := gridfs.NewBucket(r.db.Database, options.GridFSBucket().SetName(r.bucket))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s bucket: %w", r.bucket, err)
+	}
+	return bucket, nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/minisource/ticket/internal/database"
+	"github.com/minisource/ticket/internal/metrics"
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ImportRepository handles import job database operations
+type ImportRepository struct {
+	db *database.MongoDB
+}
+
+// NewImportRepository creates a new import job repository
+func NewImportRepository(db *database.MongoDB) *ImportRepository {
+	return &ImportRepository{db: db}
+}
+
+// Create creates a new import job
+func (r *ImportRepository) Create(ctx context.Context, job *models.ImportJob) error {
+	ctx, span := tracing.Start(ctx, "ImportRepository.Create")
+	defer span.End()
+	defer metrics.ObserveMongo("import", "Create")()
+
+	job.CreatedAt = time.Now()
+	if job.ID.IsZero() {
+		job.ID = primitive.NewObjectID()
+	}
+
+	if _, err := r.db.Collection(database.CollectionImportJobs).InsertOne(ctx, job); err != nil {
+		return fmt.Errorf("failed to create import job: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID gets a tenant's import job by ID
+func (r *ImportRepository) GetByID(ctx context.Context, tenantID string, id primitive.ObjectID) (*models.ImportJob, error) {
+	ctx, span := tracing.Start(ctx, "ImportRepository.GetByID")
+	defer span.End()
+	defer metrics.ObserveMongo("import", "GetByID")()
+
+	var job models.ImportJob
+	err := r.db.Collection(database.CollectionImportJobs).FindOne(ctx, bson.M{
+		"_id":       id,
+		"tenant_id": tenantID,
+	}).Decode(&job)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get import job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// List lists a tenant's import jobs, newest first, without their issues
+func (r *ImportRepository) List(ctx context.Context, tenantID string, limit int64) ([]models.ImportJob, error) {
+	ctx, span := tracing.Start(ctx, "ImportRepository.List")
+	defer span.End()
+	defer metrics.ObserveMongo("import", "List")()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(limit).
+		SetProjection(bson.M{"issues": 0})
+
+	cursor, err := r.db.Collection(database.CollectionImportJobs).Find(ctx, bson.M{"tenant_id": tenantID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list import jobs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []models.ImportJob
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to decode import jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// ClaimNext marks the oldest pending job as running and returns it, or nil
+// when none is pending. Running jobs that saved no progress for longer than
+// stale, because their worker stopped, are claimed again and resume.
+func (r *ImportRepository) ClaimNext(ctx context.Context, stale time.Duration) (*models.ImportJob, error) {
+	ctx, span := tracing.Start(ctx, "ImportRepository.ClaimNext")
+	defer span.End()
+	defer metrics.ObserveMongo("import", "ClaimNext")()
+
+	now := time.Now()
+	filter := bson.M{
+		"$or": []bson.M{
+			{"status": models.ImportPending},
+			{"status": models.ImportRunning, "heartbeat_at": bson.M{"$lt": now.Add(-stale)}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"status": models.ImportRunning, "started_at": now, "heartbeat_at": now}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job models.ImportJob
+	err := r.db.Collection(database.CollectionImportJobs).FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim import job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// SaveProgress saves a running job's counts and issues, and marks it alive.
+// It reports false when the job is no longer this claim's, because it went
+// stale and another worker claimed it.
+func (r *ImportRepository) SaveProgress(ctx context.Context, job *models.ImportJob) (bool, error) {
+	ctx, span := tracing.Start(ctx, "ImportRepository.SaveProgress")
+	defer span.End()
+	defer metrics.ObserveMongo("import", "SaveProgress")()
+
+	now := time.Now()
+	job.HeartbeatAt = &now
+
+	result, err := r.db.Collection(database.CollectionImportJobs).UpdateOne(ctx, importClaim(job), bson.M{"$set": importProgress(job)})
+	if err != nil {
+		return false, fmt.Errorf("failed to save import progress: %w", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// Finish records the outcome of a running job. Like SaveProgress, it reports
+// false when the job is no longer this claim's.
+func (r *ImportRepository) Finish(ctx context.Context, job *models.ImportJob) (bool, error) {
+	ctx, span := tracing.Start(ctx, "ImportRepository.Finish")
+	defer span.End()
+	defer metrics.ObserveMongo("import", "Finish")()
+
+	now := time.Now()
+	job.HeartbeatAt = &now
+	job.FinishedAt = &now
+
+	fields := importProgress(job)
+	fields["status"] = job.Status
+	fields["error"] = job.Error
+	fields["finished_at"] = job.FinishedAt
+
+	result, err := r.db.Collection(database.CollectionImportJobs).UpdateOne(ctx, importClaim(job), bson.M{"$set": fields})
+	if err != nil {
+		return false, fmt.Errorf("failed to finish import job: %w", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// Requeue puts a finished or failed job back in the queue. It returns false
+// when the job was in another state.
+func (r *ImportRepository) Requeue(ctx context.Context, job *models.ImportJob, from models.ImportStatus) (bool, error) {
+	ctx, span := tracing.Start(ctx, "ImportRepository.Requeue")
+	defer span.End()
+	defer metrics.ObserveMongo("import", "Requeue")()
+
+	fields := importProgress(job)
+	fields["status"] = models.ImportPending
+	fields["dry_run"] = job.DryRun
+	fields["error"] = ""
+
+	result, err := r.db.Collection(database.CollectionImportJobs).UpdateOne(ctx,
+		bson.M{"_id": job.ID, "status": from},
+		bson.M{"$set": fields, "$unset": bson.M{"finished_at": ""}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to requeue import job: %w", err)
+	}
+
+	job.Status = models.ImportPending
+	job.Error = ""
+	job.FinishedAt = nil
+	return result.MatchedCount > 0, nil
+}
+
+// importClaim matches a job while it is running under the claim it was
+// returned with; every claim sets a new started_at
+func importClaim(job *models.ImportJob) bson.M {
+	return bson.M{"_id": job.ID, "status": models.ImportRunning, "started_at": job.StartedAt}
+}
+
+// importProgress is the progress part of a job as fields to set
+func importProgress(job *models.ImportJob) bson.M {
+	return bson.M{
+		"records":      job.Records,
+		"imported":     job.Imported,
+		"skipped":      job.Skipped,
+		"invalid":      job.Invalid,
+		"warnings":     job.Warnings,
+		"issues":       job.Issues,
+		"heartbeat_at": job.HeartbeatAt,
+	}
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/minisource/ticket/internal/database"
+	"github.com/minisource/ticket/internal/metrics"
+	"github.com/minisource/ticket/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LeaseRepository lets instances agree on which of them runs a scheduled job
+type LeaseRepository struct {
+	db *database.MongoDB
+}
+
+// NewLeaseRepository creates a new lease repository
+func NewLeaseRepository(db *database.MongoDB) *LeaseRepository {
+	return &LeaseRepository{db: db}
+}
+
+// Claim claims the run of the named job scheduled at runAt. Only the first
+// caller for a given runAt gets true; the rest, and callers for earlier
+// runs, get false.
+func (r *LeaseRepository) Claim(ctx context.Context, name string, runAt time.Time) (bool, error) {
+	ctx, span := tracing.Start(ctx, "LeaseRepository.Claim")
+	defer span.End()
+	defer metrics.ObserveMongo("lease", "Claim")()
+
+	filter := bson.M{
+		"_id":    name,
+		"run_at": bson.M{"$lt": runAt},
+	}
+	update := bson.M{"$set": bson.M{
+		"run_at":     runAt,
+		"claimed_at": time.Now(),
+	}}
+
+	// The upsert creates the lease on the first run; once it exists, a lease
+	// already at runAt fails the filter and the insert hits the duplicate _id
+	_, err := r.db.Collection(database.CollectionLeases).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim lease: %w", err)
+	}
+
+	return true, nil
+}
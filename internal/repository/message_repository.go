@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"github.com/minisource/ticket/internal/database"
+	"github.com/minisource/ticket/internal/metrics"
 	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/tracing"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -25,6 +27,10 @@ func NewMessageRepository(db *database.MongoDB) *MessageRepository {
 
 // Create creates a new message
 func (r *MessageRepository) Create(ctx context.Context, message *models.TicketMessage) error {
+	ctx, span := tracing.Start(ctx, "MessageRepository.Create")
+	defer span.End()
+	defer metrics.ObserveMongo("message", "Create")()
+
 	message.CreatedAt = time.Now()
 
 	result, err := r.db.Collection(database.CollectionMessages).InsertOne(ctx, message)
@@ -36,8 +42,49 @@ func (r *MessageRepository) Create(ctx context.Context, message *models.TicketMe
 	return nil
 }
 
+// Import inserts messages migrated from another helpdesk as they are,
+// keeping their timestamps
+func (r *MessageRepository) Import(ctx context.Context, messages []models.TicketMessage) error {
+	ctx, span := tracing.Start(ctx, "MessageRepository.Import")
+	defer span.End()
+	defer metrics.ObserveMongo("message", "Import")()
+
+	if len(messages) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(messages))
+	for i := range messages {
+		if messages[i].ID.IsZero() {
+			messages[i].ID = primitive.NewObjectID()
+		}
+		docs[i] = messages[i]
+	}
+
+	if _, err := r.db.Collection(database.CollectionMessages).InsertMany(ctx, docs); err != nil {
+		return fmt.Errorf("failed to import messages: %w", err)
+	}
+	return nil
+}
+
+// DeleteByTicketID permanently deletes a ticket's messages
+func (r *MessageRepository) DeleteByTicketID(ctx context.Context, ticketID primitive.ObjectID) error {
+	ctx, span := tracing.Start(ctx, "MessageRepository.DeleteByTicketID")
+	defer span.End()
+	defer metrics.ObserveMongo("message", "DeleteByTicketID")()
+
+	if _, err := r.db.Collection(database.CollectionMessages).DeleteMany(ctx, bson.M{"ticket_id": ticketID}); err != nil {
+		return fmt.Errorf("failed to delete messages: %w", err)
+	}
+	return nil
+}
+
 // GetByID gets a message by ID
 func (r *MessageRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.TicketMessage, error) {
+	ctx, span := tracing.Start(ctx, "MessageRepository.GetByID")
+	defer span.End()
+	defer metrics.ObserveMongo("message", "GetByID")()
+
 	var message models.TicketMessage
 	err := r.db.Collection(database.CollectionMessages).FindOne(ctx, bson.M{
 		"_id":        id,
@@ -56,6 +103,10 @@ func (r *MessageRepository) GetByID(ctx context.Context, id primitive.ObjectID)
 
 // Update updates a message
 func (r *MessageRepository) Update(ctx context.Context, message *models.TicketMessage) error {
+	ctx, span := tracing.Start(ctx, "MessageRepository.Update")
+	defer span.End()
+	defer metrics.ObserveMongo("message", "Update")()
+
 	_, err := r.db.Collection(database.CollectionMessages).UpdateOne(
 		ctx,
 		bson.M{"_id": message.ID},
@@ -70,6 +121,10 @@ func (r *MessageRepository) Update(ctx context.Context, message *models.TicketMe
 
 // Delete soft deletes a message
 func (r *MessageRepository) Delete(ctx context.Context, id primitive.ObjectID, deletedBy string) error {
+	ctx, span := tracing.Start(ctx, "MessageRepository.Delete")
+	defer span.End()
+	defer metrics.ObserveMongo("message", "Delete")()
+
 	now := time.Now()
 	_, err := r.db.Collection(database.CollectionMessages).UpdateOne(
 		ctx,
@@ -89,6 +144,10 @@ func (r *MessageRepository) Delete(ctx context.Context, id primitive.ObjectID, d
 
 // GetByTicketID gets messages for a ticket
 func (r *MessageRepository) GetByTicketID(ctx context.Context, ticketID primitive.ObjectID, includePrivate bool, page, perPage int) ([]models.TicketMessage, int64, error) {
+	ctx, span := tracing.Start(ctx, "MessageRepository.GetByTicketID")
+	defer span.End()
+	defer metrics.ObserveMongo("message", "GetByTicketID")()
+
 	query := bson.M{
 		"ticket_id":  ticketID,
 		"is_deleted": false,
@@ -132,8 +191,32 @@ func (r *MessageRepository) GetByTicketID(ctx context.Context, ticketID primitiv
 	return messages, total, nil
 }
 
+// ListAfter lists one page of a ticket's messages, oldest first, starting
+// after a keyset cursor
+func (r *MessageRepository) ListAfter(ctx context.Context, ticketID primitive.ObjectID, includePrivate bool, page models.CursorRequest) (*models.CursorPage[models.TicketMessage], error) {
+	ctx, span := tracing.Start(ctx, "MessageRepository.ListAfter")
+	defer span.End()
+	defer metrics.ObserveMongo("message", "ListAfter")()
+
+	query := bson.M{
+		"ticket_id":  ticketID,
+		"is_deleted": false,
+	}
+
+	if !includePrivate {
+		query["is_private"] = false
+	}
+
+	sort := bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}
+	return listAfter[models.TicketMessage](ctx, r.db.Collection(database.CollectionMessages), query, nil, sort, page)
+}
+
 // GetLatestByTicketID gets the latest messages for a ticket
 func (r *MessageRepository) GetLatestByTicketID(ctx context.Context, ticketID primitive.ObjectID, limit int) ([]models.TicketMessage, error) {
+	ctx, span := tracing.Start(ctx, "MessageRepository.GetLatestByTicketID")
+	defer span.End()
+	defer metrics.ObserveMongo("message", "GetLatestByTicketID")()
+
 	query := bson.M{
 		"ticket_id":  ticketID,
 		"is_deleted": false,
@@ -163,8 +246,152 @@ func (r *MessageRepository) GetLatestByTicketID(ctx context.Context, ticketID pr
 	return messages, nil
 }
 
+// HasNewer reports whether a ticket has messages created after since by
+// anyone other than excludeSenderID
+func (r *MessageRepository) HasNewer(ctx context.Context, ticketID primitive.ObjectID, since time.Time, excludeSenderID string, includePrivate bool) (bool, error) {
+	ctx, span := tracing.Start(ctx, "MessageRepository.HasNewer")
+	defer span.End()
+	defer metrics.ObserveMongo("message", "HasNewer")()
+
+	query := bson.M{
+		"ticket_id":  ticketID,
+		"is_deleted": false,
+		"created_at": bson.M{"$gt": since},
+		"sender_id":  bson.M{"$ne": excludeSenderID},
+	}
+
+	if !includePrivate {
+		query["is_private"] = false
+	}
+
+	count, err := r.db.Collection(database.CollectionMessages).CountDocuments(ctx, query, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("failed to check for newer messages: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// TextSearch finds messages whose content matches the query, best matches
+// first. customerID, when set, limits matches to that customer's tickets.
+func (r *MessageRepository) TextSearch(ctx context.Context, tenantID, query, customerID string, includePrivate bool, limit int) ([]models.MessageTextMatch, error) {
+	ctx, span := tracing.Start(ctx, "MessageRepository.TextSearch")
+	defer span.End()
+	defer metrics.ObserveMongo("message", "TextSearch")()
+
+	match := bson.M{
+		"$text":      bson.M{"$search": query},
+		"tenant_id":  tenantID,
+		"is_deleted": false,
+	}
+	if !includePrivate {
+		match["is_private"] = false
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$set", Value: bson.M{"score": bson.M{"$meta": "textScore"}}}},
+	}
+
+	// Filter on ownership before limiting so other customers' matches
+	// can't crowd out this customer's
+	if customerID != "" {
+		pipeline = append(pipeline,
+			bson.D{{Key: "$lookup", Value: bson.M{
+				"from":         database.CollectionTickets,
+				"localField":   "ticket_id",
+				"foreignField": "_id",
+				"as":           "ticket",
+			}}},
+			bson.D{{Key: "$match", Value: bson.M{
+				"ticket.customer_id": customerID,
+				"ticket.is_deleted":  false,
+			}}},
+			bson.D{{Key: "$unset", Value: "ticket"}},
+		)
+	}
+
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}, {Key: "created_at", Value: -1}}}},
+		bson.D{{Key: "$limit", Value: limit}},
+	)
+
+	cursor, err := r.db.Collection(database.CollectionMessages).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var matches []models.MessageTextMatch
+	if err := cursor.All(ctx, &matches); err != nil {
+		return nil, fmt.Errorf("failed to decode messages: %w", err)
+	}
+
+	return matches, nil
+}
+
+// GetByIDs gets a tenant's messages by ID
+func (r *MessageRepository) GetByIDs(ctx context.Context, tenantID string, ids []primitive.ObjectID) ([]models.TicketMessage, error) {
+	ctx, span := tracing.Start(ctx, "MessageRepository.GetByIDs")
+	defer span.End()
+	defer metrics.ObserveMongo("message", "GetByIDs")()
+
+	cursor, err := r.db.Collection(database.CollectionMessages).Find(ctx, bson.M{
+		"_id":        bson.M{"$in": ids},
+		"tenant_id":  tenantID,
+		"is_deleted": false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var messages []models.TicketMessage
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, fmt.Errorf("failed to decode messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// ForEach calls fn with every tenant message that is not deleted, stopping at
+// the first error
+func (r *MessageRepository) ForEach(ctx context.Context, tenantID string, fn func(*models.TicketMessage) error) error {
+	ctx, span := tracing.Start(ctx, "MessageRepository.ForEach")
+	defer span.End()
+	defer metrics.ObserveMongo("message", "ForEach")()
+
+	cursor, err := r.db.Collection(database.CollectionMessages).Find(ctx, bson.M{
+		"tenant_id":  tenantID,
+		"is_deleted": false,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list messages: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var message models.TicketMessage
+		if err := cursor.Decode(&message); err != nil {
+			return fmt.Errorf("failed to decode message: %w", err)
+		}
+		if err := fn(&message); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to iterate messages: %w", err)
+	}
+	return nil
+}
+
 // CountByTicketID counts messages for a ticket
 func (r *MessageRepository) CountByTicketID(ctx context.Context, ticketID primitive.ObjectID) (int64, error) {
+	ctx, span := tracing.Start(ctx, "MessageRepository.CountByTicketID")
+	defer span.End()
+	defer metrics.ObserveMongo("message", "CountByTicketID")()
+
 	return r.db.Collection(database.CollectionMessages).CountDocuments(ctx, bson.M{
 		"ticket_id":  ticketID,
 		"is_deleted": false,
@@ -183,6 +410,10 @@ func NewHistoryRepository(db *database.MongoDB) *HistoryRepository {
 
 // Create creates a new history entry
 func (r *HistoryRepository) Create(ctx context.Context, history *models.TicketHistory) error {
+	ctx, span := tracing.Start(ctx, "HistoryRepository.Create")
+	defer span.End()
+	defer metrics.ObserveMongo("history", "Create")()
+
 	history.CreatedAt = time.Now()
 
 	result, err := r.db.Collection(database.CollectionTicketHistory).InsertOne(ctx, history)
@@ -196,6 +427,10 @@ func (r *HistoryRepository) Create(ctx context.Context, history *models.TicketHi
 
 // GetByTicketID gets history for a ticket
 func (r *HistoryRepository) GetByTicketID(ctx context.Context, ticketID primitive.ObjectID, page, perPage int) ([]models.TicketHistory, int64, error) {
+	ctx, span := tracing.Start(ctx, "HistoryRepository.GetByTicketID")
+	defer span.End()
+	defer metrics.ObserveMongo("history", "GetByTicketID")()
+
 	query := bson.M{"ticket_id": ticketID}
 
 	total, err := r.db.Collection(database.CollectionTicketHistory).CountDocuments(ctx, query)
@@ -229,3 +464,14 @@ func (r *HistoryRepository) GetByTicketID(ctx context.Context, ticketID primitiv
 
 	return history, total, nil
 }
+
+// ListAfter lists one page of a ticket's history, newest first, starting
+// after a keyset cursor
+func (r *HistoryRepository) ListAfter(ctx context.Context, ticketID primitive.ObjectID, page models.CursorRequest) (*models.CursorPage[models.TicketHistory], error) {
+	ctx, span := tracing.Start(ctx, "HistoryRepository.ListAfter")
+	defer span.End()
+	defer metrics.ObserveMongo("history", "ListAfter")()
+
+	sort := bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}
+	return listAfter[models.TicketHistory](ctx, r.db.Collection(database.CollectionTicketHistory), bson.M{"ticket_id": ticketID}, nil, sort, page)
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/pagination"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Page size when a cursor request doesn't set one
+const defaultCursorLimit = 20
+
+// listAfter reads one page of a keyset-paginated list. stages run between
+// matching and sorting, e.g. to compute a sort key; the sort must end with _id.
+func listAfter[T any](ctx context.Context, coll *mongo.Collection, query bson.M, stages mongo.Pipeline, sort bson.D, page models.CursorRequest) (*models.CursorPage[T], error) {
+	after, err := pagination.After(page.Cursor, sort)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := page.Limit
+	if limit <= 0 {
+		limit = defaultCursorLimit
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: query}}}
+	pipeline = append(pipeline, stages...)
+	if len(after) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: after}})
+	}
+	// One extra item tells whether there is a next page
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: sort}},
+		bson.D{{Key: "$limit", Value: int64(limit + 1)}},
+	)
+
+	cursor, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", coll.Name(), err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.Raw
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", coll.Name(), err)
+	}
+
+	result := &models.CursorPage[T]{}
+	if len(docs) > limit {
+		docs = docs[:limit]
+		if result.NextCursor, err = pagination.Encode(sort, docs[limit-1]); err != nil {
+			return nil, err
+		}
+	}
+
+	result.Items = make([]T, len(docs))
+	for n, doc := range docs {
+		if err := bson.Unmarshal(doc, &result.Items[n]); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", coll.Name(), err)
+		}
+	}
+
+	if page.IncludeTotal {
+		total, err := coll.CountDocuments(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", coll.Name(), err)
+		}
+		result.Total = &total
+	}
+
+	return result, nil
+}
@@ -0,0 +1,590 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/minisource/ticket/internal/database"
+	"github.com/minisource/ticket/internal/metrics"
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/report"
+	"github.com/minisource/ticket/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Key of the series for tickets without a value for the grouped field
+const noGroupKey = "none"
+
+// Ratings at or above this count as satisfied for CSAT
+const satisfiedRating = 4
+
+// reportPercentiles are the percentiles reported for durations
+var reportPercentiles = bson.A{0.5, 0.9, 0.95}
+
+// ReportRepository runs reporting aggregations over tickets
+type ReportRepository struct {
+	db *database.MongoDB
+}
+
+// NewReportRepository creates a new report repository
+func NewReportRepository(db *database.MongoDB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+// trendRow is one period of one group from a trend facet
+type trendRow struct {
+	ID struct {
+		Period time.Time   `bson:"p"`
+		Key    interface{} `bson:"k"`
+	} `bson:"_id"`
+	Name      string     `bson:"name"`
+	Count     int64      `bson:"count"`
+	Timed     int64      `bson:"timed"`
+	Durations []*float64 `bson:"durations"`
+	Breached  int64      `bson:"breached"`
+	Rating    *float64   `bson:"rating"`
+	Satisfied int64      `bson:"satisfied"`
+}
+
+// TicketTrends computes per-period ticket metrics in one aggregation. Each
+// metric is bucketed by the timestamp it's about: creation for volume and
+// first response, resolution for resolution time and SLA compliance, rating
+// for CSAT. The backlog is rebuilt from current timestamps, so tickets count
+// as open until they were last resolved, closed or cancelled.
+func (r *ReportRepository) TicketTrends(ctx context.Context, tenantID string, req models.ReportRequest) (*models.TicketTrendReport, error) {
+	ctx, span := tracing.Start(ctx, "ReportRepository.TicketTrends")
+	defer span.End()
+	defer metrics.ObserveMongo("report", "TicketTrends")()
+
+	match := bson.M{
+		"tenant_id":  tenantID,
+		"is_deleted": false,
+		"created_at": bson.M{"$lt": req.To},
+	}
+	if req.DepartmentID != "" {
+		deptID, err := primitive.ObjectIDFromHex(req.DepartmentID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid department ID: %w", err)
+		}
+		match["department_id"] = deptID
+	}
+	if req.AgentID != "" {
+		match["assigned_to_id"] = req.AgentID
+	}
+
+	key, name := reportGroup(req.GroupBy)
+	inRange := bson.M{"$gte": req.From, "$lt": req.To}
+	period := func(field string) bson.M {
+		return bson.M{"$dateTrunc": bson.M{
+			"date":        field,
+			"unit":        string(req.Interval),
+			"timezone":    req.Location.String(),
+			"startOfWeek": "monday",
+		}}
+	}
+	group := func(field string, accumulators bson.M) bson.M {
+		accumulators["_id"] = bson.M{"p": period(field), "k": "$report_key"}
+		accumulators["name"] = bson.M{"$max": "$report_name"}
+		accumulators["count"] = bson.M{"$sum": 1}
+		return bson.M{"$group": accumulators}
+	}
+	durations := func(since string) bson.M {
+		return bson.M{"$percentile": bson.M{
+			"input":  bson.M{"$subtract": bson.A{since, "$created_at"}},
+			"p":      reportPercentiles,
+			"method": "approximate",
+		}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$set", Value: bson.M{
+			"report_key":  key,
+			"report_name": name,
+			// Cancelled tickets get no timestamp of their own
+			"done_at": bson.M{"$ifNull": bson.A{
+				"$resolved_at",
+				"$closed_at",
+				bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", models.StatusCancelled}}, "$updated_at", nil}},
+			}},
+		}}},
+		{{Key: "$facet", Value: bson.M{
+			"created": bson.A{
+				bson.M{"$match": bson.M{"created_at": inRange}},
+				group("$created_at", bson.M{
+					"timed":     bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$ifNull": bson.A{"$first_responsed_at", false}}, 1, 0}}},
+					"durations": durations("$first_responsed_at"),
+				}),
+			},
+			"resolved": bson.A{
+				bson.M{"$match": bson.M{"resolved_at": inRange}},
+				group("$resolved_at", bson.M{
+					"durations": durations("$resolved_at"),
+					"breached":  bson.M{"$sum": bson.M{"$cond": bson.A{"$sla_breached", 1, 0}}},
+				}),
+			},
+			"rated": bson.A{
+				bson.M{"$match": bson.M{"rated_at": inRange, "satisfaction_rating": bson.M{"$ne": nil}}},
+				group("$rated_at", bson.M{
+					"rating":    bson.M{"$avg": "$satisfaction_rating"},
+					"satisfied": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gte": bson.A{"$satisfaction_rating", satisfiedRating}}, 1, 0}}},
+				}),
+			},
+			"done": bson.A{
+				bson.M{"$match": bson.M{"done_at": inRange}},
+				group("$done_at", bson.M{}),
+			},
+			// Open when the report starts
+			"backlog": bson.A{
+				bson.M{"$match": bson.M{
+					"created_at": bson.M{"$lt": req.From},
+					"$or":        bson.A{bson.M{"done_at": nil}, bson.M{"done_at": bson.M{"$gte": req.From}}},
+				}},
+				bson.M{"$group": bson.M{
+					"_id":   bson.M{"k": "$report_key"},
+					"name":  bson.M{"$max": "$report_name"},
+					"count": bson.M{"$sum": 1},
+				}},
+			},
+		}}},
+	}
+
+	cursor, err := r.db.Collection(database.CollectionTickets).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate ticket trends: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Created  []trendRow `bson:"created"`
+		Resolved []trendRow `bson:"resolved"`
+		Rated    []trendRow `bson:"rated"`
+		Done     []trendRow `bson:"done"`
+		Backlog  []trendRow `bson:"backlog"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode ticket trends: %w", err)
+	}
+
+	trends := newTrendBuilder(req)
+	if len(results) > 0 {
+		result := results[0]
+		for _, row := range result.Created {
+			if p := trends.period(row); p != nil {
+				p.Created = row.Count
+				p.FirstResponse = percentiles(row.Timed, row.Durations)
+			}
+		}
+		for _, row := range result.Resolved {
+			if p := trends.period(row); p != nil {
+				p.Resolved = row.Count
+				p.Resolution = percentiles(row.Count, row.Durations)
+				p.SLACompliance = ratio(row.Count-row.Breached, row.Count)
+			}
+		}
+		for _, row := range result.Rated {
+			if p := trends.period(row); p != nil {
+				p.Ratings = row.Count
+				p.AvgRating = row.Rating
+				p.CSAT = ratio(row.Satisfied, row.Count)
+			}
+		}
+		for _, row := range result.Done {
+			if p := trends.period(row); p != nil {
+				// Held in Backlog until the running total is computed
+				p.Backlog -= row.Count
+			}
+		}
+		for _, row := range result.Backlog {
+			if s := trends.series(row); len(s.Periods) > 0 {
+				s.Periods[0].Backlog += row.Count
+			}
+		}
+	}
+
+	return trends.report(), nil
+}
+
+// reportGroup returns the expressions for a ticket's group key and display
+// name. Tickets without a value get a null key.
+func reportGroup(groupBy models.ReportGroupBy) (key, name interface{}) {
+	switch groupBy {
+	case models.GroupByDepartment:
+		return "$department_id", "$department_name"
+	case models.GroupByAgent:
+		return bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$assigned_to_id", ""}}, ""}},
+			nil,
+			"$assigned_to_id",
+		}}, "$assigned_to_name"
+	case models.GroupByCategory:
+		return "$category_id", "$category_name"
+	case models.GroupByChannel:
+		return "$source", nil
+	case models.GroupByPriority:
+		return "$priority", nil
+	default:
+		return nil, nil
+	}
+}
+
+// trendBuilder collects facet rows into series with one entry per period
+type trendBuilder struct {
+	req     models.ReportRequest
+	periods []time.Time
+	index   map[int64]int
+	byKey   map[string]*models.ReportSeries
+}
+
+func newTrendBuilder(req models.ReportRequest) *trendBuilder {
+	b := &trendBuilder{
+		req:     req,
+		periods: report.Periods(req.From, req.To, req.Interval, req.Location),
+		index:   make(map[int64]int),
+		byKey:   make(map[string]*models.ReportSeries),
+	}
+	for n, p := range b.periods {
+		b.index[p.UnixMilli()] = n
+	}
+	return b
+}
+
+// series returns the series for a row's group, creating it on first use
+func (b *trendBuilder) series(row trendRow) *models.ReportSeries {
+	key := ""
+	if b.req.GroupBy != models.GroupByNone {
+		switch k := row.ID.Key.(type) {
+		case primitive.ObjectID:
+			key = k.Hex()
+		case string:
+			key = k
+		default:
+			key = noGroupKey
+		}
+	}
+
+	s := b.seriesFor(key)
+	if s.Name == "" {
+		s.Name = row.Name
+	}
+	return s
+}
+
+// seriesFor returns the series for a group key, creating it on first use
+func (b *trendBuilder) seriesFor(key string) *models.ReportSeries {
+	s, ok := b.byKey[key]
+	if !ok {
+		s = &models.ReportSeries{Key: key, Periods: make([]models.ReportPeriod, len(b.periods))}
+		for n, p := range b.periods {
+			s.Periods[n].Start = p
+		}
+		b.byKey[key] = s
+	}
+	return s
+}
+
+// period returns the entry for a row's group and period
+func (b *trendBuilder) period(row trendRow) *models.ReportPeriod {
+	n, ok := b.index[row.ID.Period.UnixMilli()]
+	if !ok {
+		return nil
+	}
+	return &b.series(row).Periods[n]
+}
+
+// report turns the opening backlog and per-period changes into running
+// backlog totals and orders the series by key, tickets without a group last
+func (b *trendBuilder) report() *models.TicketTrendReport {
+	// An ungrouped report always has its one series, even without tickets
+	if b.req.GroupBy == models.GroupByNone {
+		b.seriesFor("")
+	}
+
+	result := &models.TicketTrendReport{
+		From:     b.req.From,
+		To:       b.req.To,
+		Interval: b.req.Interval,
+		GroupBy:  b.req.GroupBy,
+		Timezone: b.req.Location.String(),
+		Series:   make([]models.ReportSeries, 0, len(b.byKey)),
+	}
+
+	for _, s := range b.byKey {
+		var open int64
+		for n := range s.Periods {
+			open += s.Periods[n].Created + s.Periods[n].Backlog
+			s.Periods[n].Backlog = open
+		}
+		result.Series = append(result.Series, *s)
+	}
+
+	sort.Slice(result.Series, func(i, j int) bool {
+		a, b := result.Series[i].Key, result.Series[j].Key
+		if (a == noGroupKey) != (b == noGroupKey) {
+			return b == noGroupKey
+		}
+		return a < b
+	})
+
+	return result
+}
+
+// percentiles converts millisecond percentiles to minutes
+func percentiles(count int64, ms []*float64) models.DurationPercentiles {
+	result := models.DurationPercentiles{Count: count}
+	at := func(n int) *float64 {
+		if n >= len(ms) || ms[n] == nil {
+			return nil
+		}
+		m := minutes(*ms[n])
+		return &m
+	}
+	result.P50, result.P90, result.P95 = at(0), at(1), at(2)
+	return result
+}
+
+// minutes converts milliseconds to minutes, rounded to a tenth
+func minutes(ms float64) float64 {
+	return math.Round(ms/60000*10) / 10
+}
+
+// ratio returns part/whole, or nil when whole is zero
+func ratio(part, whole int64) *float64 {
+	if whole == 0 {
+		return nil
+	}
+	r := float64(part) / float64(whole)
+	return &r
+}
+
+// AgentScorecards computes scorecards for the agents with the given user IDs
+// over [from, to). Agents without activity get empty scorecards.
+func (r *ReportRepository) AgentScorecards(ctx context.Context, tenantID string, from, to time.Time, userIDs []string) (map[string]*models.AgentScorecard, error) {
+	ctx, span := tracing.Start(ctx, "ReportRepository.AgentScorecards")
+	defer span.End()
+	defer metrics.ObserveMongo("report", "AgentScorecards")()
+
+	cards := make(map[string]*models.AgentScorecard, len(userIDs))
+	for _, userID := range userIDs {
+		cards[userID] = &models.AgentScorecard{UserID: userID}
+	}
+	if len(userIDs) == 0 {
+		return cards, nil
+	}
+
+	if err := r.scoreMessages(ctx, tenantID, from, to, userIDs, cards); err != nil {
+		return nil, err
+	}
+	if err := r.scoreResolutions(ctx, tenantID, from, to, userIDs, cards); err != nil {
+		return nil, err
+	}
+	if err := r.scoreTickets(ctx, tenantID, from, to, userIDs, cards); err != nil {
+		return nil, err
+	}
+
+	return cards, nil
+}
+
+// scoreMessages counts the agents' replies, internal notes and the tickets
+// they replied to
+func (r *ReportRepository) scoreMessages(ctx context.Context, tenantID string, from, to time.Time, userIDs []string, cards map[string]*models.AgentScorecard) error {
+	isReply := bson.M{"$eq": bson.A{"$type", models.MessageTypeReply}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"tenant_id":   tenantID,
+			"sender_type": models.SenderAgent,
+			"sender_id":   bson.M{"$in": userIDs},
+			"type":        bson.M{"$in": bson.A{models.MessageTypeReply, models.MessageTypeInternalNote}},
+			"created_at":  bson.M{"$gte": from, "$lt": to},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     "$sender_id",
+			"replies": bson.M{"$sum": bson.M{"$cond": bson.A{isReply, 1, 0}}},
+			"notes":   bson.M{"$sum": bson.M{"$cond": bson.A{isReply, 0, 1}}},
+			"tickets": bson.M{"$addToSet": bson.M{"$cond": bson.A{isReply, "$ticket_id", "$$REMOVE"}}},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"replies": 1,
+			"notes":   1,
+			"handled": bson.M{"$size": "$tickets"},
+		}}},
+	}
+
+	cursor, err := r.db.Collection(database.CollectionMessages).Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to aggregate agent messages: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		UserID  string `bson:"_id"`
+		Replies int64  `bson:"replies"`
+		Notes   int64  `bson:"notes"`
+		Handled int64  `bson:"handled"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return fmt.Errorf("failed to decode agent messages: %w", err)
+	}
+
+	for _, row := range rows {
+		if card, ok := cards[row.UserID]; ok {
+			card.Replies = row.Replies
+			card.InternalNotes = row.Notes
+			card.Handled = row.Handled
+			card.InternalNoteRatio = ratio(row.Notes, row.Replies+row.Notes)
+		}
+	}
+	return nil
+}
+
+// scoreResolutions counts the agents' resolutions from ticket history and how
+// many of those tickets were reopened afterwards
+func (r *ReportRepository) scoreResolutions(ctx context.Context, tenantID string, from, to time.Time, userIDs []string, cards map[string]*models.AgentScorecard) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"tenant_id":  tenantID,
+			"action":     "status_changed",
+			"new_value":  models.StatusResolved,
+			"changed_by": bson.M{"$in": userIDs},
+			"created_at": bson.M{"$gte": from, "$lt": to},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": database.CollectionTicketHistory,
+			"let":  bson.M{"ticket": "$ticket_id", "resolved": "$created_at"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$ticket_id", "$$ticket"}},
+					bson.M{"$eq": bson.A{"$action", "status_changed"}},
+					bson.M{"$eq": bson.A{"$new_value", models.StatusReopened}},
+					bson.M{"$gt": bson.A{"$created_at", "$$resolved"}},
+				}}}},
+				bson.M{"$limit": 1},
+			},
+			"as": "reopens",
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$changed_by",
+			"resolutions": bson.M{"$sum": 1},
+			"reopened":    bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{bson.M{"$size": "$reopens"}, 0}}, 1, 0}}},
+		}}},
+	}
+
+	cursor, err := r.db.Collection(database.CollectionTicketHistory).Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to aggregate agent resolutions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		UserID      string `bson:"_id"`
+		Resolutions int64  `bson:"resolutions"`
+		Reopened    int64  `bson:"reopened"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return fmt.Errorf("failed to decode agent resolutions: %w", err)
+	}
+
+	for _, row := range rows {
+		if card, ok := cards[row.UserID]; ok {
+			card.Resolutions = row.Resolutions
+			card.Reopened = row.Reopened
+			card.ReopenRate = ratio(row.Reopened, row.Resolutions)
+		}
+	}
+	return nil
+}
+
+// scoreTickets computes resolution, SLA, first response and rating metrics
+// of the tickets assigned to the agents
+func (r *ReportRepository) scoreTickets(ctx context.Context, tenantID string, from, to time.Time, userIDs []string, cards map[string]*models.AgentScorecard) error {
+	inRange := bson.M{"$gte": from, "$lt": to}
+	within := func(field string) bson.M {
+		return bson.M{"$and": bson.A{
+			bson.M{"$gte": bson.A{field, from}},
+			bson.M{"$lt": bson.A{field, to}},
+		}}
+	}
+	count := func(cond interface{}) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{cond, 1, 0}}}
+	}
+
+	rated := within("$rated_at")
+	group := bson.M{
+		"_id":      "$assigned_to_id",
+		"resolved": count(within("$resolved_at")),
+		"breached": count(bson.M{"$and": bson.A{within("$resolved_at"), "$sla_breached"}}),
+		"first_response_ms": bson.M{"$median": bson.M{
+			"input":  bson.M{"$cond": bson.A{within("$created_at"), bson.M{"$subtract": bson.A{"$first_responsed_at", "$created_at"}}, nil}},
+			"method": "approximate",
+		}},
+		"rating": bson.M{"$avg": bson.M{"$cond": bson.A{rated, "$satisfaction_rating", nil}}},
+	}
+	for stars := 1; stars <= 5; stars++ {
+		group[fmt.Sprintf("stars_%d", stars)] = count(bson.M{"$and": bson.A{rated, bson.M{"$eq": bson.A{"$satisfaction_rating", stars}}}})
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"tenant_id":      tenantID,
+			"is_deleted":     false,
+			"assigned_to_id": bson.M{"$in": userIDs},
+			"$or": bson.A{
+				bson.M{"created_at": inRange},
+				bson.M{"resolved_at": inRange},
+				bson.M{"rated_at": inRange},
+			},
+		}}},
+		{{Key: "$group", Value: group}},
+	}
+
+	cursor, err := r.db.Collection(database.CollectionTickets).Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to aggregate agent tickets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		UserID          string   `bson:"_id"`
+		Resolved        int64    `bson:"resolved"`
+		Breached        int64    `bson:"breached"`
+		FirstResponseMs *float64 `bson:"first_response_ms"`
+		Rating          *float64 `bson:"rating"`
+		Stars1          int64    `bson:"stars_1"`
+		Stars2          int64    `bson:"stars_2"`
+		Stars3          int64    `bson:"stars_3"`
+		Stars4          int64    `bson:"stars_4"`
+		Stars5          int64    `bson:"stars_5"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return fmt.Errorf("failed to decode agent tickets: %w", err)
+	}
+
+	for _, row := range rows {
+		card, ok := cards[row.UserID]
+		if !ok {
+			continue
+		}
+		card.Resolved = row.Resolved
+		card.SLACompliance = ratio(row.Resolved-row.Breached, row.Resolved)
+		if row.FirstResponseMs != nil {
+			m := minutes(*row.FirstResponseMs)
+			card.FirstResponseMedian = &m
+		}
+		card.AvgRating = row.Rating
+		card.Ratings = [5]int64{row.Stars1, row.Stars2, row.Stars3, row.Stars4, row.Stars5}
+		var ratings, satisfied int64
+		for n, c := range card.Ratings {
+			ratings += c
+			if n+1 >= satisfiedRating {
+				satisfied += c
+			}
+		}
+		card.CSAT = ratio(satisfied, ratings)
+	}
+	return nil
+}
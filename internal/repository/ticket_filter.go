@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidSort is returned for sorts on fields outside ticketSortFields
+var ErrInvalidSort = errors.New("invalid sort")
+
+// Most fields a ticket list can be sorted by at once
+const maxSortFields = 3
+
+// ticketSortFields are the fields ticket lists may be sorted by. Sorting on
+// anything else could use an unindexed or nonexistent path.
+var ticketSortFields = map[string]bool{
+	"created_at":          true,
+	"updated_at":          true,
+	"last_activity_at":    true,
+	"resolved_at":         true,
+	"closed_at":           true,
+	"first_response_due":  true,
+	"resolution_due":      true,
+	"priority":            true,
+	"status":              true,
+	"ticket_number":       true,
+	"subject":             true,
+	"customer_name":       true,
+	"assigned_to_name":    true,
+	"message_count":       true,
+	"satisfaction_rating": true,
+}
+
+// Computed field holding priorityRank during a sort, since priorities are
+// stored as names
+const priorityRankField = "priority_rank"
+
+// ParseTicketSort builds a sort from a comma-separated list of fields, each
+// optionally suffixed with :asc or :desc. Fields without a direction use
+// sortOrder, which defaults to descending. The ticket ID is always the final
+// tie-breaker so pages are stable.
+func ParseTicketSort(sortBy, sortOrder string) (bson.D, error) {
+	defaultDir := -1
+	switch strings.ToLower(sortOrder) {
+	case "", "desc":
+	case "asc":
+		defaultDir = 1
+	default:
+		return nil, fmt.Errorf("%w: unknown sort order %q", ErrInvalidSort, sortOrder)
+	}
+
+	if strings.TrimSpace(sortBy) == "" {
+		sortBy = "created_at"
+	}
+
+	var sort bson.D
+	seen := map[string]bool{}
+	lastDir := defaultDir
+	for _, item := range strings.Split(sortBy, ",") {
+		field, dirName, _ := strings.Cut(strings.TrimSpace(item), ":")
+		if !ticketSortFields[field] {
+			return nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalidSort, field)
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("%w: %q is listed twice", ErrInvalidSort, field)
+		}
+		seen[field] = true
+
+		dir := defaultDir
+		switch strings.ToLower(dirName) {
+		case "":
+		case "asc":
+			dir = 1
+		case "desc":
+			dir = -1
+		default:
+			return nil, fmt.Errorf("%w: unknown direction %q for %q", ErrInvalidSort, dirName, field)
+		}
+
+		key := field
+		if field == "priority" {
+			key = priorityRankField
+		}
+		sort = append(sort, bson.E{Key: key, Value: dir})
+		lastDir = dir
+	}
+
+	if len(sort) > maxSortFields {
+		return nil, fmt.Errorf("%w: at most %d sort fields", ErrInvalidSort, maxSortFields)
+	}
+
+	return append(sort, bson.E{Key: "_id", Value: lastDir}), nil
+}
+
+// sortsByPriority reports whether a sort needs the computed priority rank
+func sortsByPriority(sort bson.D) bool {
+	for _, e := range sort {
+		if e.Key == priorityRankField {
+			return true
+		}
+	}
+	return false
+}
+
+// priorityRankStage computes the field that priority sorts compare
+func priorityRankStage() bson.D {
+	return bson.D{{Key: "$addFields", Value: bson.M{priorityRankField: priorityRank}}}
+}
+
+// ticketListQuery combines a ticket filter with an extra query, such as one
+// compiled from the ticket query language
+func ticketListQuery(filter models.TicketFilter, where bson.M) (bson.M, error) {
+	query, err := ticketFilterQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(where) > 0 {
+		and, _ := query["$and"].(bson.A)
+		query["$and"] = append(and, where)
+	}
+	return query, nil
+}
+
+// ticketFilterQuery builds the MongoDB query for a ticket filter
+func ticketFilterQuery(filter models.TicketFilter) (bson.M, error) {
+	query := bson.M{"is_deleted": false}
+	var and bson.A
+
+	if filter.TenantID != "" {
+		query["tenant_id"] = filter.TenantID
+	}
+
+	if len(filter.Status) > 0 {
+		query["status"] = bson.M{"$in": filter.Status}
+	}
+
+	if len(filter.Priority) > 0 {
+		query["priority"] = bson.M{"$in": filter.Priority}
+	}
+
+	if len(filter.Type) > 0 {
+		query["type"] = bson.M{"$in": filter.Type}
+	}
+
+	if len(filter.Source) > 0 {
+		query["source"] = bson.M{"$in": filter.Source}
+	}
+
+	if filter.DepartmentID != "" {
+		deptID, err := primitive.ObjectIDFromHex(filter.DepartmentID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid department ID: %w", err)
+		}
+		query["department_id"] = deptID
+	}
+
+	if filter.CategoryID != "" {
+		catID, err := primitive.ObjectIDFromHex(filter.CategoryID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid category ID: %w", err)
+		}
+		query["category_id"] = catID
+	}
+
+	if filter.AssignedToID != "" {
+		query["assigned_to_id"] = filter.AssignedToID
+	}
+
+	if filter.CustomerID != "" {
+		query["customer_id"] = filter.CustomerID
+	}
+
+	if len(filter.Tags) > 0 {
+		query["tags"] = bson.M{"$in": filter.Tags}
+	}
+
+	if filter.SLABreached != nil {
+		if *filter.SLABreached {
+			query["sla_breached"] = true
+		} else {
+			query["sla_breached"] = bson.M{"$ne": true}
+		}
+	}
+
+	// Unassigned tickets may hold an empty assignee or none at all
+	if filter.Unassigned != nil {
+		if *filter.Unassigned {
+			query["assigned_to_id"] = bson.M{"$in": bson.A{"", nil}}
+		} else if filter.AssignedToID == "" {
+			query["assigned_to_id"] = bson.M{"$nin": bson.A{"", nil}}
+		}
+	}
+
+	if filter.HasRating != nil {
+		query["satisfaction_rating"] = bson.M{"$exists": *filter.HasRating}
+	}
+
+	if filter.Search != "" {
+		query["$text"] = bson.M{"$search": filter.Search}
+	}
+
+	if r := timeRange(filter.CreatedFrom, filter.CreatedTo); r != nil {
+		query["created_at"] = r
+	}
+
+	if r := timeRange(filter.UpdatedFrom, filter.UpdatedTo); r != nil {
+		query["updated_at"] = r
+	}
+
+	if filter.DueBefore != nil {
+		query["resolution_due"] = bson.M{"$lt": *filter.DueBefore}
+	}
+
+	for key, value := range filter.CustomFields {
+		if key == "" || strings.ContainsAny(key, ".$") {
+			return nil, fmt.Errorf("invalid custom field name %q", key)
+		}
+		and = append(and, bson.M{"custom_fields." + key: bson.M{"$in": customFieldValues(value)}})
+	}
+
+	if len(and) > 0 {
+		query["$and"] = and
+	}
+
+	return query, nil
+}
+
+// timeRange builds an inclusive range condition, or nil when both ends are open
+func timeRange(from, to *time.Time) bson.M {
+	r := bson.M{}
+	if from != nil {
+		r["$gte"] = *from
+	}
+	if to != nil {
+		r["$lte"] = *to
+	}
+	if len(r) == 0 {
+		return nil
+	}
+	return r
+}
+
+// customFieldValues lists the stored values a custom field filter matches.
+// Custom fields keep whatever JSON type they were submitted with, so "5"
+// matches both the string and the number, and "true" the boolean.
+func customFieldValues(value string) bson.A {
+	values := bson.A{value}
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		values = append(values, n)
+	}
+	switch value {
+	case "true":
+		values = append(values, true)
+	case "false":
+		values = append(values, false)
+	}
+	return values
+}
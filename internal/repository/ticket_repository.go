@@ -2,17 +2,25 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/minisource/ticket/internal/database"
+	"github.com/minisource/ticket/internal/metrics"
 	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/tracing"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrVersionConflict is returned when a conditional update finds the ticket
+// at a different version than expected
+var ErrVersionConflict = errors.New("ticket was modified by someone else")
+
 // TicketRepository handles ticket database operations
 type TicketRepository struct {
 	db *database.MongoDB
@@ -25,9 +33,14 @@ func NewTicketRepository(db *database.MongoDB) *TicketRepository {
 
 // Create creates a new ticket
 func (r *TicketRepository) Create(ctx context.Context, ticket *models.Ticket) error {
+	ctx, span := tracing.Start(ctx, "TicketRepository.Create")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "Create")()
+
 	ticket.CreatedAt = time.Now()
 	ticket.UpdatedAt = time.Now()
 	ticket.LastActivityAt = time.Now()
+	ticket.Version = 1
 
 	result, err := r.db.Collection(database.CollectionTickets).InsertOne(ctx, ticket)
 	if err != nil {
@@ -38,8 +51,48 @@ func (r *TicketRepository) Create(ctx context.Context, ticket *models.Ticket) er
 	return nil
 }
 
+// Import inserts a ticket migrated from another helpdesk as it is, keeping
+// its ID, number and timestamps
+func (r *TicketRepository) Import(ctx context.Context, ticket *models.Ticket) error {
+	ctx, span := tracing.Start(ctx, "TicketRepository.Import")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "Import")()
+
+	if _, err := r.db.Collection(database.CollectionTickets).InsertOne(ctx, ticket); err != nil {
+		return fmt.Errorf("failed to import ticket: %w", err)
+	}
+	return nil
+}
+
+// GetImported gets a tenant's ticket by its ID in the helpdesk it was
+// imported from
+func (r *TicketRepository) GetImported(ctx context.Context, tenantID, externalID string) (*models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.GetImported")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "GetImported")()
+
+	var ticket models.Ticket
+	err := r.db.Collection(database.CollectionTickets).FindOne(ctx, bson.M{
+		"tenant_id":          tenantID,
+		"import.external_id": externalID,
+	}).Decode(&ticket)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get imported ticket: %w", err)
+	}
+
+	return &ticket, nil
+}
+
 // GetByID gets a ticket by ID
 func (r *TicketRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.GetByID")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "GetByID")()
+
 	var ticket models.Ticket
 	err := r.db.Collection(database.CollectionTickets).FindOne(ctx, bson.M{
 		"_id":        id,
@@ -58,6 +111,10 @@ func (r *TicketRepository) GetByID(ctx context.Context, id primitive.ObjectID) (
 
 // GetByTicketNumber gets a ticket by ticket number
 func (r *TicketRepository) GetByTicketNumber(ctx context.Context, tenantID, ticketNumber string) (*models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.GetByTicketNumber")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "GetByTicketNumber")()
+
 	var ticket models.Ticket
 	err := r.db.Collection(database.CollectionTickets).FindOne(ctx, bson.M{
 		"tenant_id":     tenantID,
@@ -75,40 +132,81 @@ func (r *TicketRepository) GetByTicketNumber(ctx context.Context, tenantID, tick
 	return &ticket, nil
 }
 
-// Update updates a ticket
-func (r *TicketRepository) Update(ctx context.Context, ticket *models.Ticket) error {
-	ticket.UpdatedAt = time.Now()
+// UpdateFields sets specific fields of a ticket and bumps its version
+func (r *TicketRepository) UpdateFields(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	ctx, span := tracing.Start(ctx, "TicketRepository.UpdateFields")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "UpdateFields")()
+
+	fields["updated_at"] = time.Now()
 
 	_, err := r.db.Collection(database.CollectionTickets).UpdateOne(
 		ctx,
-		bson.M{"_id": ticket.ID},
-		bson.M{"$set": ticket},
+		bson.M{"_id": id},
+		bson.M{"$set": fields, "$inc": bson.M{"version": 1}},
 	)
 	if err != nil {
-		return fmt.Errorf("failed to update ticket: %w", err)
+		return fmt.Errorf("failed to update ticket fields: %w", err)
 	}
 
 	return nil
 }
 
-// UpdateFields updates specific fields of a ticket
-func (r *TicketRepository) UpdateFields(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+// UpdateFieldsIfVersion sets specific fields of a ticket only if it is still
+// at version, and bumps the version. It returns ErrVersionConflict otherwise.
+func (r *TicketRepository) UpdateFieldsIfVersion(ctx context.Context, id primitive.ObjectID, version int64, fields bson.M) error {
+	ctx, span := tracing.Start(ctx, "TicketRepository.UpdateFieldsIfVersion")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "UpdateFieldsIfVersion")()
+
 	fields["updated_at"] = time.Now()
 
-	_, err := r.db.Collection(database.CollectionTickets).UpdateOne(
+	filter := bson.M{"_id": id, "is_deleted": false, "version": version}
+	if version == 0 {
+		// Tickets created before versioning have no version field
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	result, err := r.db.Collection(database.CollectionTickets).UpdateOne(
 		ctx,
-		bson.M{"_id": id},
-		bson.M{"$set": fields},
+		filter,
+		bson.M{"$set": fields, "$inc": bson.M{"version": 1}},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update ticket fields: %w", err)
 	}
+	if result.MatchedCount == 0 {
+		return ErrVersionConflict
+	}
 
 	return nil
 }
 
+// TransitionStatus moves a ticket from one status to another only if it is
+// still in from, and bumps the version. It reports whether the ticket moved.
+func (r *TicketRepository) TransitionStatus(ctx context.Context, id primitive.ObjectID, from, to models.TicketStatus) (bool, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.TransitionStatus")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "TransitionStatus")()
+
+	result, err := r.db.Collection(database.CollectionTickets).UpdateOne(
+		ctx,
+		bson.M{"_id": id, "is_deleted": false, "status": from},
+		bson.M{"$set": bson.M{"status": to, "updated_at": time.Now()}, "$inc": bson.M{"version": 1}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to change ticket status: %w", err)
+	}
+
+	return result.ModifiedCount > 0, nil
+}
+
 // Delete soft deletes a ticket
 func (r *TicketRepository) Delete(ctx context.Context, id primitive.ObjectID, deletedBy string) error {
+	ctx, span := tracing.Start(ctx, "TicketRepository.Delete")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "Delete")()
+
 	now := time.Now()
 	_, err := r.db.Collection(database.CollectionTickets).UpdateOne(
 		ctx,
@@ -129,120 +227,248 @@ func (r *TicketRepository) Delete(ctx context.Context, id primitive.ObjectID, de
 
 // List lists tickets with filters
 func (r *TicketRepository) List(ctx context.Context, filter models.TicketFilter) ([]models.Ticket, int64, error) {
-	query := bson.M{"is_deleted": false}
+	return r.ListWhere(ctx, filter, nil)
+}
 
-	if filter.TenantID != "" {
-		query["tenant_id"] = filter.TenantID
+// ListWhere lists tickets matching both the filter and an extra query, such
+// as one compiled from the ticket query language
+func (r *TicketRepository) ListWhere(ctx context.Context, filter models.TicketFilter, where bson.M) ([]models.Ticket, int64, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.ListWhere")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "ListWhere")()
+
+	query, err := ticketListQuery(filter, where)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	if len(filter.Status) > 0 {
-		query["status"] = bson.M{"$in": filter.Status}
+	sort, err := ParseTicketSort(filter.SortBy, filter.SortOrder)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	if len(filter.Priority) > 0 {
-		query["priority"] = bson.M{"$in": filter.Priority}
+	// Count total
+	total, err := r.db.Collection(database.CollectionTickets).CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count tickets: %w", err)
 	}
 
-	if len(filter.Type) > 0 {
-		query["type"] = bson.M{"$in": filter.Type}
+	// Pagination
+	page := 1
+	perPage := 20
+	if filter.Page > 0 {
+		page = filter.Page
+	}
+	if filter.PerPage > 0 {
+		perPage = filter.PerPage
 	}
+	skip := int64((page - 1) * perPage)
 
-	if filter.DepartmentID != "" {
-		deptID, _ := primitive.ObjectIDFromHex(filter.DepartmentID)
-		query["department_id"] = deptID
+	var cursor *mongo.Cursor
+	if sortsByPriority(sort) {
+		// Rank priorities by urgency rather than by name
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: query}},
+			priorityRankStage(),
+			{{Key: "$sort", Value: sort}},
+			{{Key: "$skip", Value: skip}},
+			{{Key: "$limit", Value: int64(perPage)}},
+			{{Key: "$project", Value: bson.M{priorityRankField: 0}}},
+		}
+		cursor, err = r.db.Collection(database.CollectionTickets).Aggregate(ctx, pipeline)
+	} else {
+		opts := options.Find().
+			SetSort(sort).
+			SetSkip(skip).
+			SetLimit(int64(perPage))
+		cursor, err = r.db.Collection(database.CollectionTickets).Find(ctx, query, opts)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list tickets: %w", err)
 	}
+	defer cursor.Close(ctx)
 
-	if filter.CategoryID != "" {
-		catID, _ := primitive.ObjectIDFromHex(filter.CategoryID)
-		query["category_id"] = catID
+	var tickets []models.Ticket
+	if err := cursor.All(ctx, &tickets); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode tickets: %w", err)
 	}
 
-	if filter.AssignedToID != "" {
-		query["assigned_to_id"] = filter.AssignedToID
+	return tickets, total, nil
+}
+
+// ListWhereAfter lists one page of tickets matching the filter and an extra
+// query, starting after a keyset cursor. Unlike ListWhere it doesn't count
+// every match unless asked to, and pages stay stable as tickets change.
+func (r *TicketRepository) ListWhereAfter(ctx context.Context, filter models.TicketFilter, where bson.M, page models.CursorRequest) (*models.CursorPage[models.Ticket], error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.ListWhereAfter")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "ListWhereAfter")()
+
+	query, err := ticketListQuery(filter, where)
+	if err != nil {
+		return nil, err
 	}
 
-	if filter.CustomerID != "" {
-		query["customer_id"] = filter.CustomerID
+	sort, err := ParseTicketSort(filter.SortBy, filter.SortOrder)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(filter.Tags) > 0 {
-		query["tags"] = bson.M{"$in": filter.Tags}
+	var stages mongo.Pipeline
+	if sortsByPriority(sort) {
+		stages = append(stages, priorityRankStage())
 	}
 
-	if filter.SLABreached != nil && *filter.SLABreached {
-		query["sla_breached"] = true
+	return listAfter[models.Ticket](ctx, r.db.Collection(database.CollectionTickets), query, stages, sort, page)
+}
+
+// CountWhere counts a tenant's tickets matching a query, stopping at limit
+// when it is positive
+func (r *TicketRepository) CountWhere(ctx context.Context, tenantID string, where bson.M, limit int64) (int64, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.CountWhere")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "CountWhere")()
+
+	query := bson.M{"tenant_id": tenantID, "is_deleted": false}
+	if len(where) > 0 {
+		query["$and"] = bson.A{where}
 	}
 
-	if filter.Unassigned != nil && *filter.Unassigned {
-		query["assigned_to_id"] = ""
+	opts := options.Count()
+	if limit > 0 {
+		opts.SetLimit(limit)
 	}
 
-	if filter.Search != "" {
-		query["$text"] = bson.M{"$search": filter.Search}
+	count, err := r.db.Collection(database.CollectionTickets).CountDocuments(ctx, query, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tickets: %w", err)
 	}
 
-	if filter.CreatedFrom != nil {
-		if _, ok := query["created_at"]; !ok {
-			query["created_at"] = bson.M{}
-		}
-		query["created_at"].(bson.M)["$gte"] = *filter.CreatedFrom
+	return count, nil
+}
+
+// CountMatching counts tickets matching a filter and an extra query, stopping
+// at limit when it is positive
+func (r *TicketRepository) CountMatching(ctx context.Context, filter models.TicketFilter, where bson.M, limit int64) (int64, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.CountMatching")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "CountMatching")()
+
+	query, err := ticketListQuery(filter, where)
+	if err != nil {
+		return 0, err
 	}
 
-	if filter.CreatedTo != nil {
-		if _, ok := query["created_at"]; !ok {
-			query["created_at"] = bson.M{}
-		}
-		query["created_at"].(bson.M)["$lte"] = *filter.CreatedTo
+	opts := options.Count()
+	if limit > 0 {
+		opts.SetLimit(limit)
 	}
 
-	// Count total
-	total, err := r.db.Collection(database.CollectionTickets).CountDocuments(ctx, query)
+	count, err := r.db.Collection(database.CollectionTickets).CountDocuments(ctx, query, opts)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count tickets: %w", err)
+		return 0, fmt.Errorf("failed to count tickets: %w", err)
 	}
 
-	// Sort
-	sortField := "created_at"
-	sortOrder := -1
-	if filter.SortBy != "" {
-		sortField = filter.SortBy
+	return count, nil
+}
+
+// CustomFieldKeys lists the custom field keys used by tickets matching a
+// filter and an extra query, sorted by name
+func (r *TicketRepository) CustomFieldKeys(ctx context.Context, filter models.TicketFilter, where bson.M) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.CustomFieldKeys")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "CustomFieldKeys")()
+
+	query, err := ticketListQuery(filter, where)
+	if err != nil {
+		return nil, err
 	}
-	if filter.SortOrder == "asc" {
-		sortOrder = 1
+	query["custom_fields"] = bson.M{"$type": "object"}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: query}},
+		{{Key: "$project", Value: bson.M{"field": bson.M{"$objectToArray": "$custom_fields"}}}},
+		{{Key: "$unwind", Value: "$field"}},
+		{{Key: "$group", Value: bson.M{"_id": "$field.k"}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
 	}
 
-	// Pagination
-	page := 1
-	perPage := 20
-	if filter.Page > 0 {
-		page = filter.Page
+	cursor, err := r.db.Collection(database.CollectionTickets).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list custom field keys: %w", err)
 	}
-	if filter.PerPage > 0 {
-		perPage = filter.PerPage
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Key string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode custom field keys: %w", err)
 	}
-	skip := int64((page - 1) * perPage)
 
-	opts := options.Find().
-		SetSort(bson.D{{Key: sortField, Value: sortOrder}}).
-		SetSkip(skip).
-		SetLimit(int64(perPage))
+	keys := make([]string, len(results))
+	for i, result := range results {
+		keys[i] = result.Key
+	}
+	return keys, nil
+}
+
+// EachWhere calls fn with every ticket matching a filter and an extra query,
+// in the filter's sort order, stopping at the first error. Paging is ignored.
+func (r *TicketRepository) EachWhere(ctx context.Context, filter models.TicketFilter, where bson.M, fn func(*models.Ticket) error) error {
+	ctx, span := tracing.Start(ctx, "TicketRepository.EachWhere")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "EachWhere")()
 
-	cursor, err := r.db.Collection(database.CollectionTickets).Find(ctx, query, opts)
+	query, err := ticketListQuery(filter, where)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to list tickets: %w", err)
+		return err
+	}
+
+	sort, err := ParseTicketSort(filter.SortBy, filter.SortOrder)
+	if err != nil {
+		return err
+	}
+
+	var cursor *mongo.Cursor
+	if sortsByPriority(sort) {
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: query}},
+			priorityRankStage(),
+			{{Key: "$sort", Value: sort}},
+			{{Key: "$project", Value: bson.M{priorityRankField: 0}}},
+		}
+		cursor, err = r.db.Collection(database.CollectionTickets).Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	} else {
+		cursor, err = r.db.Collection(database.CollectionTickets).Find(ctx, query, options.Find().SetSort(sort).SetAllowDiskUse(true))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list tickets: %w", err)
 	}
 	defer cursor.Close(ctx)
 
-	var tickets []models.Ticket
-	if err := cursor.All(ctx, &tickets); err != nil {
-		return nil, 0, fmt.Errorf("failed to decode tickets: %w", err)
+	for cursor.Next(ctx) {
+		var ticket models.Ticket
+		if err := cursor.Decode(&ticket); err != nil {
+			return fmt.Errorf("failed to decode ticket: %w", err)
+		}
+		if err := fn(&ticket); err != nil {
+			return err
+		}
 	}
 
-	return tickets, total, nil
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to iterate tickets: %w", err)
+	}
+	return nil
 }
 
 // GetByCustomerID gets tickets for a customer
 func (r *TicketRepository) GetByCustomerID(ctx context.Context, tenantID, customerID string, page, perPage int) ([]models.Ticket, int64, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.GetByCustomerID")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "GetByCustomerID")()
+
 	filter := models.TicketFilter{
 		TenantID:   tenantID,
 		CustomerID: customerID,
@@ -256,6 +482,10 @@ func (r *TicketRepository) GetByCustomerID(ctx context.Context, tenantID, custom
 
 // GetByAssigneeID gets tickets assigned to an agent
 func (r *TicketRepository) GetByAssigneeID(ctx context.Context, tenantID, assigneeID string, page, perPage int) ([]models.Ticket, int64, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.GetByAssigneeID")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "GetByAssigneeID")()
+
 	filter := models.TicketFilter{
 		TenantID:     tenantID,
 		AssignedToID: assigneeID,
@@ -269,6 +499,10 @@ func (r *TicketRepository) GetByAssigneeID(ctx context.Context, tenantID, assign
 
 // GetByDepartmentID gets tickets for a department
 func (r *TicketRepository) GetByDepartmentID(ctx context.Context, tenantID, departmentID string, page, perPage int) ([]models.Ticket, int64, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.GetByDepartmentID")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "GetByDepartmentID")()
+
 	filter := models.TicketFilter{
 		TenantID:     tenantID,
 		DepartmentID: departmentID,
@@ -282,6 +516,10 @@ func (r *TicketRepository) GetByDepartmentID(ctx context.Context, tenantID, depa
 
 // GetUnassigned gets unassigned tickets
 func (r *TicketRepository) GetUnassigned(ctx context.Context, tenantID string, departmentID *string, page, perPage int) ([]models.Ticket, int64, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.GetUnassigned")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "GetUnassigned")()
+
 	unassigned := true
 	filter := models.TicketFilter{
 		TenantID:   tenantID,
@@ -297,8 +535,135 @@ func (r *TicketRepository) GetUnassigned(ctx context.Context, tenantID string, d
 	return r.List(ctx, filter)
 }
 
+// ListOpenByAssignee lists an agent's tickets that are not yet done
+func (r *TicketRepository) ListOpenByAssignee(ctx context.Context, tenantID, assigneeID string) ([]models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.ListOpenByAssignee")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "ListOpenByAssignee")()
+
+	cursor, err := r.db.Collection(database.CollectionTickets).Find(ctx, bson.M{
+		"tenant_id":      tenantID,
+		"assigned_to_id": assigneeID,
+		"is_deleted":     false,
+		"status":         bson.M{"$nin": doneStatuses},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list assigned tickets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var tickets []models.Ticket
+	if err := cursor.All(ctx, &tickets); err != nil {
+		return nil, fmt.Errorf("failed to decode tickets: %w", err)
+	}
+
+	return tickets, nil
+}
+
+// TextSearch finds tickets whose subject or description match the query,
+// best matches first. customerID, when set, limits matches to that customer.
+func (r *TicketRepository) TextSearch(ctx context.Context, tenantID, query, customerID string, limit int) ([]models.TicketTextMatch, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.TextSearch")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "TextSearch")()
+
+	filter := bson.M{
+		"$text":      bson.M{"$search": query},
+		"tenant_id":  tenantID,
+		"is_deleted": false,
+	}
+	if customerID != "" {
+		filter["customer_id"] = customerID
+	}
+
+	score := bson.M{"score": bson.M{"$meta": "textScore"}}
+	opts := options.Find().
+		SetProjection(score).
+		SetSort(score).
+		SetLimit(int64(limit))
+
+	cursor, err := r.db.Collection(database.CollectionTickets).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search tickets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var matches []models.TicketTextMatch
+	if err := cursor.All(ctx, &matches); err != nil {
+		return nil, fmt.Errorf("failed to decode tickets: %w", err)
+	}
+
+	return matches, nil
+}
+
+// GetByIDs gets a tenant's tickets by ID. customerID, when set, limits the
+// result to that customer's tickets.
+func (r *TicketRepository) GetByIDs(ctx context.Context, tenantID, customerID string, ids []primitive.ObjectID) ([]models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.GetByIDs")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "GetByIDs")()
+
+	filter := bson.M{
+		"_id":        bson.M{"$in": ids},
+		"tenant_id":  tenantID,
+		"is_deleted": false,
+	}
+	if customerID != "" {
+		filter["customer_id"] = customerID
+	}
+
+	cursor, err := r.db.Collection(database.CollectionTickets).Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tickets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var tickets []models.Ticket
+	if err := cursor.All(ctx, &tickets); err != nil {
+		return nil, fmt.Errorf("failed to decode tickets: %w", err)
+	}
+
+	return tickets, nil
+}
+
+// ForEach calls fn with every tenant ticket that is not deleted, stopping at
+// the first error
+func (r *TicketRepository) ForEach(ctx context.Context, tenantID string, fn func(*models.Ticket) error) error {
+	ctx, span := tracing.Start(ctx, "TicketRepository.ForEach")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "ForEach")()
+
+	cursor, err := r.db.Collection(database.CollectionTickets).Find(ctx, bson.M{
+		"tenant_id":  tenantID,
+		"is_deleted": false,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list tickets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var ticket models.Ticket
+		if err := cursor.Decode(&ticket); err != nil {
+			return fmt.Errorf("failed to decode ticket: %w", err)
+		}
+		if err := fn(&ticket); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to iterate tickets: %w", err)
+	}
+	return nil
+}
+
 // GetSLABreached gets tickets with breached SLA
 func (r *TicketRepository) GetSLABreached(ctx context.Context, tenantID string, page, perPage int) ([]models.Ticket, int64, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.GetSLABreached")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "GetSLABreached")()
+
 	slaBreached := true
 	filter := models.TicketFilter{
 		TenantID:    tenantID,
@@ -313,6 +678,10 @@ func (r *TicketRepository) GetSLABreached(ctx context.Context, tenantID string,
 
 // GetDueSoon gets tickets with SLA due soon
 func (r *TicketRepository) GetDueSoon(ctx context.Context, tenantID string, hours int) ([]models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.GetDueSoon")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "GetDueSoon")()
+
 	now := time.Now()
 	deadline := now.Add(time.Duration(hours) * time.Hour)
 
@@ -353,6 +722,10 @@ func (r *TicketRepository) GetDueSoon(ctx context.Context, tenantID string, hour
 
 // IncrementMessageCount increments the message count
 func (r *TicketRepository) IncrementMessageCount(ctx context.Context, id primitive.ObjectID, isInternal bool) error {
+	ctx, span := tracing.Start(ctx, "TicketRepository.IncrementMessageCount")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "IncrementMessageCount")()
+
 	update := bson.M{
 		"$inc": bson.M{"message_count": 1},
 		"$set": bson.M{
@@ -371,6 +744,10 @@ func (r *TicketRepository) IncrementMessageCount(ctx context.Context, id primiti
 
 // GetNextTicketNumber gets the next ticket number for a tenant
 func (r *TicketRepository) GetNextTicketNumber(ctx context.Context, tenantID string) (string, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.GetNextTicketNumber")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "GetNextTicketNumber")()
+
 	filter := bson.M{"_id": tenantID}
 	update := bson.M{"$inc": bson.M{"sequence": 1}}
 	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
@@ -384,46 +761,495 @@ func (r *TicketRepository) GetNextTicketNumber(ctx context.Context, tenantID str
 	return fmt.Sprintf("TKT-%06d", counter.Sequence), nil
 }
 
-// GetStats gets ticket statistics
-func (r *TicketRepository) GetStats(ctx context.Context, tenantID string) (*models.TicketStats, error) {
+// EnsureTicketSequence moves a tenant's ticket number sequence to at least
+// sequence, so numbers kept by an import aren't allocated again
+func (r *TicketRepository) EnsureTicketSequence(ctx context.Context, tenantID string, sequence int64) error {
+	ctx, span := tracing.Start(ctx, "TicketRepository.EnsureTicketSequence")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "EnsureTicketSequence")()
+
+	_, err := r.db.Collection(database.CollectionTicketCounters).UpdateOne(ctx,
+		bson.M{"_id": tenantID},
+		bson.M{"$max": bson.M{"sequence": sequence}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update ticket number sequence: %w", err)
+	}
+	return nil
+}
+
+// GetStats computes dashboard statistics for a tenant's tickets in a single
+// aggregation, narrowed by creation date, department, assignee and customer
+func (r *TicketRepository) GetStats(ctx context.Context, tenantID string, filter models.StatsFilter) (*models.TicketStats, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.GetStats")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "GetStats")()
+
+	match := bson.M{"tenant_id": tenantID, "is_deleted": false}
+	if created := timeRange(filter.From, filter.To); created != nil {
+		match["created_at"] = created
+	}
+	if filter.DepartmentID != "" {
+		deptID, err := primitive.ObjectIDFromHex(filter.DepartmentID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid department ID: %w", err)
+		}
+		match["department_id"] = deptID
+	}
+	if filter.AgentID != "" {
+		match["assigned_to_id"] = filter.AgentID
+	}
+	if filter.CustomerID != "" {
+		match["customer_id"] = filter.CustomerID
+	}
+
+	countBy := func(field string) bson.A {
+		return bson.A{bson.M{"$group": bson.M{"_id": field, "count": bson.M{"$sum": 1}}}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$facet", Value: bson.M{
+			"status":     countBy("$status"),
+			"priority":   countBy("$priority"),
+			"type":       countBy("$type"),
+			"department": countBy("$department_id"),
+			"totals": bson.A{bson.M{"$group": bson.M{
+				"_id":   nil,
+				"total": bson.M{"$sum": 1},
+				"unassigned": bson.M{"$sum": bson.M{"$cond": bson.A{
+					bson.M{"$in": bson.A{bson.M{"$ifNull": bson.A{"$assigned_to_id", ""}}, bson.A{""}}}, 1, 0,
+				}}},
+				"sla_breached":      bson.M{"$sum": bson.M{"$cond": bson.A{"$sla_breached", 1, 0}}},
+				"avg_response_ms":   avgResponseMs(),
+				"avg_resolution_ms": bson.M{"$avg": bson.M{"$subtract": bson.A{"$resolved_at", "$created_at"}}},
+				"avg_satisfaction":  bson.M{"$avg": "$satisfaction_rating"},
+			}}},
+		}}},
+	}
+
+	cursor, err := r.db.Collection(database.CollectionTickets).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate ticket stats: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	type keyCount struct {
+		Key   string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	var results []struct {
+		Status     []keyCount `bson:"status"`
+		Priority   []keyCount `bson:"priority"`
+		Type       []keyCount `bson:"type"`
+		Department []struct {
+			Key   *primitive.ObjectID `bson:"_id"`
+			Count int64               `bson:"count"`
+		} `bson:"department"`
+		Totals []struct {
+			Total           int64    `bson:"total"`
+			Unassigned      int64    `bson:"unassigned"`
+			SLABreached     int64    `bson:"sla_breached"`
+			AvgResponseMs   *float64 `bson:"avg_response_ms"`
+			AvgResolutionMs *float64 `bson:"avg_resolution_ms"`
+			AvgSatisfaction *float64 `bson:"avg_satisfaction"`
+		} `bson:"totals"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode ticket stats: %w", err)
+	}
+
 	stats := &models.TicketStats{
 		ByPriority:   make(map[string]int64),
 		ByDepartment: make(map[string]int64),
 		ByType:       make(map[string]int64),
 	}
+	if len(results) == 0 {
+		return stats, nil
+	}
+	result := results[0]
+
+	for _, c := range result.Status {
+		switch models.TicketStatus(c.Key) {
+		case models.StatusOpen:
+			stats.OpenTickets = c.Count
+		case models.StatusPending:
+			stats.PendingTickets = c.Count
+		case models.StatusResolved:
+			stats.ResolvedTickets = c.Count
+		case models.StatusClosed:
+			stats.ClosedTickets = c.Count
+		}
+	}
+	for _, c := range result.Priority {
+		stats.ByPriority[c.Key] = c.Count
+	}
+	for _, c := range result.Type {
+		stats.ByType[c.Key] = c.Count
+	}
+	for _, c := range result.Department {
+		// Tickets without a department are counted under "none"
+		key := "none"
+		if c.Key != nil {
+			key = c.Key.Hex()
+		}
+		stats.ByDepartment[key] = c.Count
+	}
+
+	if len(result.Totals) > 0 {
+		totals := result.Totals[0]
+		stats.TotalTickets = totals.Total
+		stats.UnassignedTickets = totals.Unassigned
+		stats.SLABreached = totals.SLABreached
+		if totals.AvgResponseMs != nil {
+			stats.AvgResponseTime = int64(*totals.AvgResponseMs / 60000)
+		}
+		if totals.AvgResolutionMs != nil {
+			stats.AvgResolutionTime = int64(*totals.AvgResolutionMs / 60000)
+		}
+		if totals.AvgSatisfaction != nil {
+			stats.AvgSatisfaction = *totals.AvgSatisfaction
+		}
+	}
 
-	baseQuery := bson.M{"tenant_id": tenantID, "is_deleted": false}
+	return stats, nil
+}
 
-	// Total tickets
-	total, err := r.db.Collection(database.CollectionTickets).CountDocuments(ctx, baseQuery)
+// AvgRating averages the satisfaction ratings of the tickets assigned to a
+// user, or returns 0 if none are rated
+func (r *TicketRepository) AvgRating(ctx context.Context, tenantID, userID string) (float64, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.AvgRating")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "AvgRating")()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"tenant_id":           tenantID,
+			"is_deleted":          false,
+			"assigned_to_id":      userID,
+			"satisfaction_rating": bson.M{"$ne": nil},
+		}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "avg": bson.M{"$avg": "$satisfaction_rating"}}}},
+	}
+
+	cursor, err := r.db.Collection(database.CollectionTickets).Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
+		return 0, fmt.Errorf("failed to average ratings: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Avg float64 `bson:"avg"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return 0, fmt.Errorf("failed to decode average rating: %w", err)
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+	return results[0].Avg, nil
+}
+
+// doneStatuses are the statuses that no longer count as open work
+var doneStatuses = []models.TicketStatus{models.StatusResolved, models.StatusClosed, models.StatusCancelled}
+
+// CountOpenByDepartment counts open tickets per tenant and department across all tenants
+func (r *TicketRepository) CountOpenByDepartment(ctx context.Context) ([]models.TicketCount, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.CountOpenByDepartment")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "CountOpenByDepartment")()
+
+	return r.countByTenant(ctx, bson.M{}, "$department_id")
+}
+
+// CountSLABreached counts open tickets with a breached SLA per tenant
+func (r *TicketRepository) CountSLABreached(ctx context.Context) ([]models.TicketCount, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.CountSLABreached")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "CountSLABreached")()
+
+	return r.countByTenant(ctx, bson.M{"sla_breached": true}, nil)
+}
+
+// CountUnassigned counts open tickets without an assignee per tenant
+func (r *TicketRepository) CountUnassigned(ctx context.Context) ([]models.TicketCount, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.CountUnassigned")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "CountUnassigned")()
+
+	return r.countByTenant(ctx, bson.M{"assigned_to_id": bson.M{"$in": bson.A{"", nil}}}, nil)
+}
+
+// countByTenant counts open tickets matching match, grouped by tenant and an optional second key
+func (r *TicketRepository) countByTenant(ctx context.Context, match bson.M, groupBy interface{}) ([]models.TicketCount, error) {
+	match["is_deleted"] = false
+	match["status"] = bson.M{"$nin": doneStatuses}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"tenant_id": "$tenant_id", "key": groupBy},
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := r.db.Collection(database.CollectionTickets).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tickets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		ID struct {
+			TenantID string              `bson:"tenant_id"`
+			Key      *primitive.ObjectID `bson:"key"`
+		} `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode ticket counts: %w", err)
+	}
+
+	counts := make([]models.TicketCount, 0, len(results))
+	for _, result := range results {
+		count := models.TicketCount{TenantID: result.ID.TenantID, Count: result.Count}
+		if result.ID.Key != nil {
+			count.DepartmentID = result.ID.Key.Hex()
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, nil
+}
+
+// AgentStats recomputes per-assignee counters for a tenant. Rolling windows
+// count tickets assigned since the given start times.
+func (r *TicketRepository) AgentStats(ctx context.Context, tenantID string, dayStart, weekStart, monthStart time.Time) ([]models.AgentTicketStats, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.AgentStats")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "AgentStats")()
+
+	assignedSince := func(start time.Time) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gte": bson.A{"$assigned_at", start}}, 1, 0}}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"tenant_id":      tenantID,
+			"is_deleted":     false,
+			"assigned_to_id": bson.M{"$nin": bson.A{"", nil}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":                "$assigned_to_id",
+			"current_tickets":    openCount(),
+			"tickets_today":      assignedSince(dayStart),
+			"tickets_this_week":  assignedSince(weekStart),
+			"tickets_this_month": assignedSince(monthStart),
+			"avg_response_ms":    avgResponseMs(),
+		}}},
+		{{Key: "$set", Value: bson.M{"avg_response_time": responseMinutes()}}},
 	}
-	stats.TotalTickets = total
 
-	// Open tickets
-	openQuery := bson.M{"tenant_id": tenantID, "is_deleted": false, "status": models.StatusOpen}
-	stats.OpenTickets, _ = r.db.Collection(database.CollectionTickets).CountDocuments(ctx, openQuery)
+	cursor, err := r.db.Collection(database.CollectionTickets).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate agent stats: %w", err)
+	}
+	defer cursor.Close(ctx)
 
-	// Pending tickets
-	pendingQuery := bson.M{"tenant_id": tenantID, "is_deleted": false, "status": models.StatusPending}
-	stats.PendingTickets, _ = r.db.Collection(database.CollectionTickets).CountDocuments(ctx, pendingQuery)
+	var stats []models.AgentTicketStats
+	if err := cursor.All(ctx, &stats); err != nil {
+		return nil, fmt.Errorf("failed to decode agent stats: %w", err)
+	}
 
-	// Resolved tickets
-	resolvedQuery := bson.M{"tenant_id": tenantID, "is_deleted": false, "status": models.StatusResolved}
-	stats.ResolvedTickets, _ = r.db.Collection(database.CollectionTickets).CountDocuments(ctx, resolvedQuery)
+	return stats, nil
+}
 
-	// Closed tickets
-	closedQuery := bson.M{"tenant_id": tenantID, "is_deleted": false, "status": models.StatusClosed}
-	stats.ClosedTickets, _ = r.db.Collection(database.CollectionTickets).CountDocuments(ctx, closedQuery)
+// DepartmentStats recomputes per-department counters for a tenant
+func (r *TicketRepository) DepartmentStats(ctx context.Context, tenantID string) ([]models.DepartmentTicketStats, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.DepartmentStats")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "DepartmentStats")()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"tenant_id":     tenantID,
+			"is_deleted":    false,
+			"department_id": bson.M{"$ne": nil},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":             "$department_id",
+			"open_tickets":    openCount(),
+			"total_tickets":   bson.M{"$sum": 1},
+			"avg_response_ms": avgResponseMs(),
+		}}},
+		{{Key: "$set", Value: bson.M{"avg_response_time": responseMinutes()}}},
+	}
 
-	// Unassigned tickets
-	unassignedQuery := bson.M{"tenant_id": tenantID, "is_deleted": false, "assigned_to_id": ""}
-	stats.UnassignedTickets, _ = r.db.Collection(database.CollectionTickets).CountDocuments(ctx, unassignedQuery)
+	cursor, err := r.db.Collection(database.CollectionTickets).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate department stats: %w", err)
+	}
+	defer cursor.Close(ctx)
 
-	// SLA breached
-	slaQuery := bson.M{"tenant_id": tenantID, "is_deleted": false, "sla_breached": true}
-	stats.SLABreached, _ = r.db.Collection(database.CollectionTickets).CountDocuments(ctx, slaQuery)
+	var stats []models.DepartmentTicketStats
+	if err := cursor.All(ctx, &stats); err != nil {
+		return nil, fmt.Errorf("failed to decode department stats: %w", err)
+	}
 
 	return stats, nil
 }
+
+// openCount is a $group accumulator counting tickets not in a done status
+func openCount() bson.M {
+	return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$in": bson.A{"$status", doneStatuses}}, 0, 1}}}
+}
+
+// avgResponseMs is a $group accumulator averaging time to first response;
+// tickets without a response subtract to null and are skipped by $avg
+func avgResponseMs() bson.M {
+	return bson.M{"$avg": bson.M{"$subtract": bson.A{"$first_responsed_at", "$created_at"}}}
+}
+
+// responseMinutes converts avg_response_ms to whole minutes
+func responseMinutes() bson.M {
+	return bson.M{"$toLong": bson.M{"$divide": bson.A{"$avg_response_ms", 60000}}}
+}
+
+// queueBatchSize is how many ranked candidates ClaimNext tries per round
+const queueBatchSize = 10
+
+// priorityRank orders priorities for the queue and list sorts; higher is more urgent
+var priorityRank = bson.M{"$switch": bson.M{
+	"branches": bson.A{
+		bson.M{"case": bson.M{"$eq": bson.A{"$priority", models.PriorityCritical}}, "then": 5},
+		bson.M{"case": bson.M{"$eq": bson.A{"$priority", models.PriorityUrgent}}, "then": 4},
+		bson.M{"case": bson.M{"$eq": bson.A{"$priority", models.PriorityHigh}}, "then": 3},
+		bson.M{"case": bson.M{"$eq": bson.A{"$priority", models.PriorityMedium}}, "then": 2},
+	},
+	"default": 1,
+}}
+
+// ClaimNext atomically assigns the best unassigned ticket to the agent.
+// Tickets are ranked by priority, then by how many of the agent's skills
+// match the ticket's tags and category, then by nearest SLA deadline and age.
+// Candidates are limited to the agent's departments and, when the agent lists
+// languages, to tickets in those languages. It returns nil when the queue is empty.
+func (r *TicketRepository) ClaimNext(ctx context.Context, agent *models.Agent) (*models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "TicketRepository.ClaimNext")
+	defer span.End()
+	defer metrics.ObserveMongo("ticket", "ClaimNext")()
+
+	unassigned := bson.M{
+		"tenant_id":      agent.TenantID,
+		"is_deleted":     false,
+		"assigned_to_id": bson.M{"$in": bson.A{"", nil}},
+		"status":         bson.M{"$nin": doneStatuses},
+	}
+
+	match := bson.M{}
+	for key, value := range unassigned {
+		match[key] = value
+	}
+	if len(agent.DepartmentIDs) > 0 {
+		match["department_id"] = bson.M{"$in": agent.DepartmentIDs}
+	}
+	if len(agent.Languages) > 0 {
+		languages := bson.A{"", nil}
+		for _, language := range agent.Languages {
+			languages = append(languages, language, strings.ToLower(language))
+		}
+		match["language"] = bson.M{"$in": languages}
+	}
+
+	skills := make(bson.A, 0, len(agent.Skills))
+	for _, skill := range agent.Skills {
+		skills = append(skills, strings.ToLower(skill))
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$addFields", Value: bson.M{
+			"queue_priority": priorityRank,
+			"queue_skill_score": bson.M{"$size": bson.M{"$setIntersection": bson.A{
+				skills,
+				bson.M{"$map": bson.M{
+					"input": bson.M{"$concatArrays": bson.A{
+						bson.M{"$ifNull": bson.A{"$tags", bson.A{}}},
+						bson.A{bson.M{"$ifNull": bson.A{"$category_name", ""}}},
+					}},
+					"as": "term",
+					"in": bson.M{"$toLower": "$$term"},
+				}},
+			}}},
+			// Tickets without an SLA deadline sort after those with one
+			"queue_due": bson.M{"$ifNull": bson.A{"$first_response_due", "$resolution_due", time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)}},
+		}}},
+		{{Key: "$sort", Value: bson.D{
+			{Key: "queue_priority", Value: -1},
+			{Key: "queue_skill_score", Value: -1},
+			{Key: "queue_due", Value: 1},
+			{Key: "created_at", Value: 1},
+		}}},
+		{{Key: "$limit", Value: queueBatchSize}},
+		{{Key: "$project", Value: bson.M{"_id": 1}}},
+	}
+
+	// Pipeline update so an open ticket moves to in progress in the same write
+	now := time.Now()
+	claim := bson.A{bson.M{
+		"$set": bson.M{
+			"assigned_to_id":    bson.M{"$literal": agent.UserID},
+			"assigned_to_name":  bson.M{"$literal": agent.Name},
+			"assigned_to_email": bson.M{"$literal": agent.Email},
+			"assigned_at":       now,
+			"assigned_by_id":    bson.M{"$literal": agent.UserID},
+			"last_activity_at":  now,
+			"updated_at":        now,
+			"version":           bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}},
+			"status": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$status", models.StatusOpen}},
+				models.StatusInProgress,
+				"$status",
+			}},
+		},
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	// Another agent may claim a candidate between ranking and claiming; the
+	// unassigned guard makes that claim fail, so move on and re-rank once the
+	// batch is used up. A failed claim means the ticket left the queue, so the
+	// queue shrinks every round and the loop ends when it is empty.
+	for {
+		cursor, err := r.db.Collection(database.CollectionTickets).Aggregate(ctx, pipeline)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rank queue: %w", err)
+		}
+
+		var candidates []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &candidates); err != nil {
+			return nil, fmt.Errorf("failed to decode queue: %w", err)
+		}
+		if len(candidates) == 0 {
+			return nil, nil
+		}
+
+		for _, candidate := range candidates {
+			filter := bson.M{"_id": candidate.ID}
+			for key, value := range unassigned {
+				filter[key] = value
+			}
+
+			var ticket models.Ticket
+			err := r.db.Collection(database.CollectionTickets).FindOneAndUpdate(ctx, filter, claim, opts).Decode(&ticket)
+			if err == nil {
+				return &ticket, nil
+			}
+			if err != mongo.ErrNoDocuments {
+				return nil, fmt.Errorf("failed to claim ticket: %w", err)
+			}
+		}
+	}
+}
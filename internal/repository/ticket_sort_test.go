@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TestParseTicketSort checks the ticket list sort whitelist and compound sorts
+func TestParseTicketSort(t *testing.T) {
+	sort, err := ParseTicketSort("", "")
+	require.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}, sort)
+
+	// Priorities sort by urgency through a computed rank
+	sort, err = ParseTicketSort("priority:desc, created_at", "asc")
+	require.NoError(t, err)
+	assert.Equal(t, bson.D{
+		{Key: "priority_rank", Value: -1},
+		{Key: "created_at", Value: 1},
+		{Key: "_id", Value: 1},
+	}, sort)
+
+	for _, bad := range [][2]string{
+		{"password_hash", ""},
+		{"created_at:sideways", ""},
+		{"created_at", "up"},
+		{"status,status", ""},
+		{"status,priority,created_at,updated_at", ""},
+		{"$where", ""},
+	} {
+		_, err := ParseTicketSort(bad[0], bad[1])
+		assert.True(t, errors.Is(err, ErrInvalidSort), bad[0])
+	}
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/minisource/ticket/internal/database"
+	"github.com/minisource/ticket/internal/metrics"
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SavedViewRepository handles saved view database operations
+type SavedViewRepository struct {
+	db *database.MongoDB
+}
+
+// NewSavedViewRepository creates a new saved view repository
+func NewSavedViewRepository(db *database.MongoDB) *SavedViewRepository {
+	return &SavedViewRepository{db: db}
+}
+
+// Create creates a new saved view
+func (r *SavedViewRepository) Create(ctx context.Context, view *models.SavedView) error {
+	ctx, span := tracing.Start(ctx, "SavedViewRepository.Create")
+	defer span.End()
+	defer metrics.ObserveMongo("saved_view", "Create")()
+
+	view.CreatedAt = time.Now()
+	view.UpdatedAt = time.Now()
+
+	result, err := r.db.Collection(database.CollectionSavedViews).InsertOne(ctx, view)
+	if err != nil {
+		return fmt.Errorf("failed to create saved view: %w", err)
+	}
+
+	view.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// GetByID gets a tenant's saved view by ID
+func (r *SavedViewRepository) GetByID(ctx context.Context, tenantID string, id primitive.ObjectID) (*models.SavedView, error) {
+	ctx, span := tracing.Start(ctx, "SavedViewRepository.GetByID")
+	defer span.End()
+	defer metrics.ObserveMongo("saved_view", "GetByID")()
+
+	var view models.SavedView
+	err := r.db.Collection(database.CollectionSavedViews).FindOne(ctx, bson.M{
+		"_id":       id,
+		"tenant_id": tenantID,
+	}).Decode(&view)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get saved view: %w", err)
+	}
+
+	return &view, nil
+}
+
+// Update updates a saved view
+func (r *SavedViewRepository) Update(ctx context.Context, view *models.SavedView) error {
+	ctx, span := tracing.Start(ctx, "SavedViewRepository.Update")
+	defer span.End()
+	defer metrics.ObserveMongo("saved_view", "Update")()
+
+	view.UpdatedAt = time.Now()
+
+	_, err := r.db.Collection(database.CollectionSavedViews).UpdateOne(
+		ctx,
+		bson.M{"_id": view.ID, "tenant_id": view.TenantID},
+		bson.M{"$set": view},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update saved view: %w", err)
+	}
+
+	return nil
+}
+
+// Delete deletes a saved view
+func (r *SavedViewRepository) Delete(ctx context.Context, tenantID string, id primitive.ObjectID) error {
+	ctx, span := tracing.Start(ctx, "SavedViewRepository.Delete")
+	defer span.End()
+	defer metrics.ObserveMongo("saved_view", "Delete")()
+
+	_, err := r.db.Collection(database.CollectionSavedViews).DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
+	if err != nil {
+		return fmt.Errorf("failed to delete saved view: %w", err)
+	}
+	return nil
+}
+
+// ListVisible lists the views a user owns plus those shared in the tenant
+func (r *SavedViewRepository) ListVisible(ctx context.Context, tenantID, userID string) ([]models.SavedView, error) {
+	ctx, span := tracing.Start(ctx, "SavedViewRepository.ListVisible")
+	defer span.End()
+	defer metrics.ObserveMongo("saved_view", "ListVisible")()
+
+	query := bson.M{
+		"tenant_id": tenantID,
+		"$or": []bson.M{
+			{"owner_id": userID},
+			{"shared": true},
+		},
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+
+	cursor, err := r.db.Collection(database.CollectionSavedViews).Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved views: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var views []models.SavedView
+	if err := cursor.All(ctx, &views); err != nil {
+		return nil, fmt.Errorf("failed to decode saved views: %w", err)
+	}
+
+	return views, nil
+}
@@ -0,0 +1,121 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+)
+
+// ActiveLeave returns the out-of-office period covering now, or nil
+func ActiveLeave(agent *models.Agent, now time.Time) *models.OutOfOffice {
+	for i := range agent.OutOfOffice {
+		period := &agent.OutOfOffice[i]
+		if !now.Before(period.Start) && now.Before(period.End) {
+			return period
+		}
+	}
+	return nil
+}
+
+// StatusAt returns the status the agent's leave and shifts call for at now:
+// out of office during leave, on break during a shift break, available on
+// shift and offline otherwise. Agents without an enabled schedule are
+// available outside leave.
+func StatusAt(agent *models.Agent, now time.Time) models.AgentStatus {
+	if ActiveLeave(agent, now) != nil {
+		return models.AgentStatusOutOfOffice
+	}
+	if !agent.ScheduleEnabled {
+		return models.AgentStatusAvailable
+	}
+
+	local := now.In(Location(agent.Preferences.Timezone))
+	minute := local.Hour()*60 + local.Minute()
+	today := int(local.Weekday())
+	yesterday := (today + 6) % 7
+
+	for _, shift := range agent.Shifts {
+		if !shift.IsWorkDay {
+			continue
+		}
+		start, errStart := parseClock(shift.StartTime)
+		end, errEnd := parseClock(shift.EndTime)
+		if errStart != nil || errEnd != nil {
+			continue
+		}
+
+		// Shifts ending at or before their start run past midnight
+		overnight := end <= start
+		startedToday := shift.Day == today && minute >= start && (overnight || minute < end)
+		startedYesterday := shift.Day == yesterday && overnight && minute < end
+		if !startedToday && !startedYesterday {
+			continue
+		}
+
+		for _, b := range shift.Breaks {
+			breakStart, errStart := parseClock(b.Start)
+			breakEnd, errEnd := parseClock(b.End)
+			if errStart == nil && errEnd == nil && within(minute, breakStart, breakEnd) {
+				return models.AgentStatusOnBreak
+			}
+		}
+		return models.AgentStatusAvailable
+	}
+
+	return models.AgentStatusOffline
+}
+
+// Location loads the named timezone, falling back to UTC
+func Location(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// ValidateShifts checks shift days and clock times
+func ValidateShifts(shifts []models.DaySchedule) error {
+	for _, shift := range shifts {
+		if shift.Day < 0 || shift.Day > 6 {
+			return fmt.Errorf("invalid shift day %d", shift.Day)
+		}
+		if _, err := parseClock(shift.StartTime); err != nil {
+			return err
+		}
+		if _, err := parseClock(shift.EndTime); err != nil {
+			return err
+		}
+		for _, b := range shift.Breaks {
+			if _, err := parseClock(b.Start); err != nil {
+				return err
+			}
+			if _, err := parseClock(b.End); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseClock parses "HH:MM" into minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, errors.New("invalid time " + value + ", expected HH:MM")
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// within reports whether minute falls in [start, end), wrapping past midnight
+func within(minute, start, end int) bool {
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
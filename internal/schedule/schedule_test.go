@@ -0,0 +1,107 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusAt(t *testing.T) {
+	tehran, err := time.LoadLocation("Asia/Tehran")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+
+	// 2024-05-06 is a Monday
+	day := models.DaySchedule{
+		Day:       int(time.Monday),
+		IsWorkDay: true,
+		StartTime: "09:00",
+		EndTime:   "17:00",
+		Breaks:    []models.TimeRange{{Start: "12:00", End: "12:30"}},
+	}
+	night := models.DaySchedule{
+		Day:       int(time.Monday),
+		IsWorkDay: true,
+		StartTime: "22:00",
+		EndTime:   "06:00",
+		Breaks:    []models.TimeRange{{Start: "23:30", End: "00:30"}},
+	}
+	leave := models.OutOfOffice{
+		Start: time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name     string
+		shifts   []models.DaySchedule
+		timezone string
+		leave    []models.OutOfOffice
+		disabled bool
+		at       time.Time
+		want     models.AgentStatus
+	}{
+		{name: "on shift", shifts: []models.DaySchedule{day}, at: time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC), want: models.AgentStatusAvailable},
+		{name: "on break", shifts: []models.DaySchedule{day}, at: time.Date(2024, 5, 6, 12, 15, 0, 0, time.UTC), want: models.AgentStatusOnBreak},
+		{name: "break end is exclusive", shifts: []models.DaySchedule{day}, at: time.Date(2024, 5, 6, 12, 30, 0, 0, time.UTC), want: models.AgentStatusAvailable},
+		{name: "before shift", shifts: []models.DaySchedule{day}, at: time.Date(2024, 5, 6, 8, 59, 0, 0, time.UTC), want: models.AgentStatusOffline},
+		{name: "shift end is exclusive", shifts: []models.DaySchedule{day}, at: time.Date(2024, 5, 6, 17, 0, 0, 0, time.UTC), want: models.AgentStatusOffline},
+		{name: "other day", shifts: []models.DaySchedule{day}, at: time.Date(2024, 5, 7, 10, 0, 0, 0, time.UTC), want: models.AgentStatusOffline},
+		{name: "day off", shifts: []models.DaySchedule{{Day: int(time.Monday), StartTime: "09:00", EndTime: "17:00"}}, at: time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC), want: models.AgentStatusOffline},
+		{name: "invalid shift skipped", shifts: []models.DaySchedule{{Day: int(time.Monday), IsWorkDay: true, StartTime: "9am", EndTime: "17:00"}, day}, at: time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC), want: models.AgentStatusAvailable},
+
+		{name: "overnight evening", shifts: []models.DaySchedule{night}, at: time.Date(2024, 5, 6, 23, 0, 0, 0, time.UTC), want: models.AgentStatusAvailable},
+		{name: "overnight after midnight", shifts: []models.DaySchedule{night}, at: time.Date(2024, 5, 7, 3, 0, 0, 0, time.UTC), want: models.AgentStatusAvailable},
+		{name: "overnight break across midnight", shifts: []models.DaySchedule{night}, at: time.Date(2024, 5, 7, 0, 15, 0, 0, time.UTC), want: models.AgentStatusOnBreak},
+		{name: "overnight ended", shifts: []models.DaySchedule{night}, at: time.Date(2024, 5, 7, 6, 0, 0, 0, time.UTC), want: models.AgentStatusOffline},
+		{name: "overnight not started the day before", shifts: []models.DaySchedule{night}, at: time.Date(2024, 5, 6, 3, 0, 0, 0, time.UTC), want: models.AgentStatusOffline},
+
+		// 09:00 in Tehran (+03:30) is 05:30 UTC
+		{name: "timezone on shift", shifts: []models.DaySchedule{day}, timezone: tehran.String(), at: time.Date(2024, 5, 6, 5, 30, 0, 0, time.UTC), want: models.AgentStatusAvailable},
+		{name: "timezone before shift", shifts: []models.DaySchedule{day}, timezone: tehran.String(), at: time.Date(2024, 5, 6, 5, 29, 0, 0, time.UTC), want: models.AgentStatusOffline},
+		{name: "timezone after shift", shifts: []models.DaySchedule{day}, timezone: tehran.String(), at: time.Date(2024, 5, 6, 14, 0, 0, 0, time.UTC), want: models.AgentStatusOffline},
+		{name: "timezone crosses midnight", shifts: []models.DaySchedule{night}, timezone: tehran.String(), at: time.Date(2024, 5, 6, 21, 0, 0, 0, time.UTC), want: models.AgentStatusAvailable},
+		{name: "unknown timezone is UTC", shifts: []models.DaySchedule{day}, timezone: "Mars/Olympus", at: time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC), want: models.AgentStatusAvailable},
+
+		{name: "leave beats shift", shifts: []models.DaySchedule{day}, leave: []models.OutOfOffice{leave}, at: time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC), want: models.AgentStatusOutOfOffice},
+		{name: "leave end is exclusive", shifts: []models.DaySchedule{day}, leave: []models.OutOfOffice{leave}, at: time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC), want: models.AgentStatusOffline},
+		{name: "no schedule is available", disabled: true, at: time.Date(2024, 5, 6, 3, 0, 0, 0, time.UTC), want: models.AgentStatusAvailable},
+		{name: "no schedule on leave", disabled: true, leave: []models.OutOfOffice{leave}, at: time.Date(2024, 5, 6, 3, 0, 0, 0, time.UTC), want: models.AgentStatusOutOfOffice},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := &models.Agent{
+				ScheduleEnabled: !tt.disabled,
+				Shifts:          tt.shifts,
+				OutOfOffice:     tt.leave,
+				Preferences:     models.AgentPreferences{Timezone: tt.timezone},
+			}
+			assert.Equal(t, tt.want, StatusAt(agent, tt.at))
+		})
+	}
+}
+
+func TestValidateShifts(t *testing.T) {
+	tests := []struct {
+		name    string
+		shifts  []models.DaySchedule
+		wantErr bool
+	}{
+		{name: "valid", shifts: []models.DaySchedule{{Day: 0, StartTime: "22:00", EndTime: "06:00", Breaks: []models.TimeRange{{Start: "01:00", End: "01:30"}}}}},
+		{name: "bad day", shifts: []models.DaySchedule{{Day: 7, StartTime: "09:00", EndTime: "17:00"}}, wantErr: true},
+		{name: "bad start", shifts: []models.DaySchedule{{Day: 1, StartTime: "25:00", EndTime: "17:00"}}, wantErr: true},
+		{name: "bad break", shifts: []models.DaySchedule{{Day: 1, StartTime: "09:00", EndTime: "17:00", Breaks: []models.TimeRange{{Start: "noon", End: "13:00"}}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateShifts(tt.shifts)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Analyzer turns a normalized token into the term stored in the index
+type Analyzer interface {
+	// Term returns the token's index term, or false for stop words
+	Term(token string) (string, bool)
+}
+
+// Analyzers by language code. Each token is analyzed by the language its
+// script belongs to, so tickets mixing languages index and match correctly.
+var analyzers = map[string]Analyzer{
+	"en": englishAnalyzer{},
+	"fa": persianAnalyzer{},
+}
+
+// Analyze splits text into index terms
+func Analyze(text string) []string {
+	var terms []string
+	for _, token := range tokenize(text) {
+		if term, ok := analyzerFor(token).Term(token); ok {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// analyzerFor picks the analyzer for a token's script
+func analyzerFor(token string) Analyzer {
+	for _, r := range token {
+		if unicode.Is(unicode.Arabic, r) {
+			return analyzers["fa"]
+		}
+	}
+	return analyzers["en"]
+}
+
+// tokenize splits text on anything but letters and digits, including the
+// zero-width non-joiner Persian uses between a word and its affixes, and
+// normalizes each token
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r)
+	})
+
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if token := normalize(field); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// normalize lowercases a token, folds Arabic letter and digit variants to
+// their Persian and ASCII forms, and drops diacritics and tatweel
+func normalize(token string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(token) {
+		switch {
+		case r == 'ي' || r == 'ى':
+			r = 'ی'
+		case r == 'ك':
+			r = 'ک'
+		case r == 'ة':
+			r = 'ه'
+		case r == 'أ' || r == 'إ':
+			r = 'ا'
+		case r >= '۰' && r <= '۹':
+			r = '0' + (r - '۰')
+		case r >= '٠' && r <= '٩':
+			r = '0' + (r - '٠')
+		case r == 'ـ' || unicode.Is(unicode.Mn, r):
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+type englishAnalyzer struct{}
+
+var englishStopWords = stopWords("a an and are as at be but by for if in into is it no not of on or such that the their then there these they this to was will with")
+
+// Term applies a light suffix-stripping stemmer
+func (englishAnalyzer) Term(token string) (string, bool) {
+	if englishStopWords[token] {
+		return "", false
+	}
+
+	switch {
+	case strings.HasSuffix(token, "sses"):
+		return strings.TrimSuffix(token, "es"), true
+	case strings.HasSuffix(token, "ies") && len(token) > 4:
+		return strings.TrimSuffix(token, "ies") + "y", true
+	case strings.HasSuffix(token, "ing") && len(token) > 5:
+		return strings.TrimSuffix(token, "ing"), true
+	case strings.HasSuffix(token, "ed") && len(token) > 4:
+		return strings.TrimSuffix(token, "ed"), true
+	case strings.HasSuffix(token, "s") && !strings.HasSuffix(token, "ss") && len(token) > 3:
+		return strings.TrimSuffix(token, "s"), true
+	}
+	return token, true
+}
+
+type persianAnalyzer struct{}
+
+var persianStopWords = stopWords("و در به از که این آن با را برای است تا یا هم می نمی ها های شود شد بود")
+
+// Plural and comparative suffixes, longest first
+var persianSuffixes = []string{"ترین", "های", "ها", "تر"}
+
+// Term strips common suffixes, keeping at least two letters of stem
+func (persianAnalyzer) Term(token string) (string, bool) {
+	if persianStopWords[token] {
+		return "", false
+	}
+
+	for _, suffix := range persianSuffixes {
+		stem := strings.TrimSuffix(token, suffix)
+		if stem != token && len([]rune(stem)) >= 2 {
+			return stem, true
+		}
+	}
+	return token, true
+}
+
+func stopWords(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
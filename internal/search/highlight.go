@@ -0,0 +1,159 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+const (
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+
+	// Shortest shared prefix that counts as a match, so stemmed forms
+	// ("printer", "printing") highlight the same word
+	minStemPrefix = 4
+)
+
+// Terms splits a text-search query into the words worth highlighting.
+// Negated words ("-refund") are dropped and phrases are split into words.
+func Terms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+
+	for _, field := range strings.Fields(query) {
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+		for _, w := range words(field) {
+			term := strings.ToLower(w.text)
+			if !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+
+	return terms
+}
+
+// Snippet returns an HTML-escaped excerpt of about width runes around the
+// first matched term, with every matched word wrapped in <mark> tags. It
+// reports false when no term matches.
+func Snippet(text string, terms []string, width int) (string, bool) {
+	runes := []rune(text)
+	found := words(text)
+
+	var hits []word
+	for _, w := range found {
+		if matches(strings.ToLower(w.text), terms) {
+			hits = append(hits, w)
+		}
+	}
+	if len(hits) == 0 {
+		return "", false
+	}
+
+	// Center the window on the first hit, then clamp it to the text
+	start := hits[0].start - width/3
+	if start < 0 {
+		start = 0
+	}
+	end := start + width
+	if end > len(runes) {
+		end = len(runes)
+		start = end - width
+		if start < 0 {
+			start = 0
+		}
+	}
+
+	// Don't cut words in half at either edge
+	for start > 0 && !unicode.IsSpace(runes[start-1]) {
+		start++
+		if start >= hits[0].start {
+			start = hits[0].start
+			break
+		}
+	}
+	for end < len(runes) && !unicode.IsSpace(runes[end]) {
+		end--
+		if end <= hits[0].end {
+			end = hits[0].end
+			break
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, hit := range hits {
+		if hit.start < start || hit.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:hit.start])))
+		b.WriteString(markOpen)
+		b.WriteString(html.EscapeString(string(runes[hit.start:hit.end])))
+		b.WriteString(markClose)
+		pos = hit.end
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+
+	return strings.TrimSpace(b.String()), true
+}
+
+// word is a run of letters or digits at rune offsets [start, end)
+type word struct {
+	text       string
+	start, end int
+}
+
+func words(text string) []word {
+	var found []word
+	runes := []rune(text)
+
+	start := -1
+	for i, r := range runes {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			found = append(found, word{text: string(runes[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		found = append(found, word{text: string(runes[start:]), start: start, end: len(runes)})
+	}
+
+	return found
+}
+
+// matches reports whether w is one of the terms or shares a stem with one
+func matches(w string, terms []string) bool {
+	for _, term := range terms {
+		if w == term {
+			return true
+		}
+		if commonPrefix([]rune(w), []rune(term)) >= minStemPrefix {
+			return true
+		}
+	}
+	return false
+}
+
+func commonPrefix(a, b []rune) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,29 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSearchSnippet checks term parsing and highlighted, escaped snippets
+func TestSearchSnippet(t *testing.T) {
+	terms := Terms(`"printer jam" -refund Printer`)
+	assert.Equal(t, []string{"printer", "jam"}, terms)
+
+	snippet, ok := Snippet("My <b>printing</b> stopped after a paper jam.", terms, 160)
+	assert.True(t, ok)
+	assert.Equal(t, "My &lt;b&gt;<mark>printing</mark>&lt;/b&gt; stopped after a paper <mark>jam</mark>.", snippet)
+
+	_, ok = Snippet("Nothing relevant here", terms, 160)
+	assert.False(t, ok)
+
+	// Long text is cut around the first match on word boundaries
+	long := strings.Repeat("lorem ipsum ", 50) + "the printer caught fire " + strings.Repeat("dolor sit ", 50)
+	snippet, ok = Snippet(long, terms, 60)
+	assert.True(t, ok)
+	assert.True(t, strings.HasPrefix(snippet, "…"))
+	assert.True(t, strings.HasSuffix(snippet, "…"))
+	assert.Contains(t, snippet, "<mark>printer</mark>")
+}
@@ -0,0 +1,158 @@
+package search
+
+import (
+	"context"
+	"sort"
+
+	"github.com/minisource/ticket/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Backend names
+const (
+	BackendMongo = "mongo"
+	BackendLocal = "local"
+)
+
+// Facet names
+const (
+	FacetStatus     = "status"
+	FacetPriority   = "priority"
+	FacetDepartment = "department"
+)
+
+// Index finds tickets by their subject, description and message content
+type Index interface {
+	// Name returns the backend name
+	Name() string
+	// Search returns one page of matching tickets, best first, and facet
+	// counts over every match
+	Search(ctx context.Context, query models.TicketSearchQuery) (*Result, error)
+	// IndexTicket adds or replaces a ticket's own fields
+	IndexTicket(ctx context.Context, ticket *models.Ticket) error
+	// IndexMessage adds or replaces a message, or removes it once deleted
+	IndexMessage(ctx context.Context, message *models.TicketMessage) error
+	// DeleteTicket removes a ticket and its messages
+	DeleteTicket(ctx context.Context, ticketID primitive.ObjectID) error
+	// Reset removes a tenant's tickets and messages, ahead of a rebuild
+	Reset(ctx context.Context, tenantID string) error
+	// Flush persists pending changes
+	Flush() error
+	// Close flushes and releases the index
+	Close() error
+}
+
+// Hit is a matching ticket and, if any, its best matching message
+type Hit struct {
+	TicketID  primitive.ObjectID
+	MessageID primitive.ObjectID // Zero when only the ticket's own fields matched
+	Score     float64
+}
+
+// Result is a page of hits
+type Result struct {
+	Hits   []Hit
+	Total  int64
+	Facets map[string][]models.FacetCount
+}
+
+// candidate is a matching ticket with the fields filters and facets need
+type candidate struct {
+	hit          Hit
+	status       string
+	priority     string
+	departmentID string
+	lastActivity int64
+}
+
+// filterAndPage applies the query's filters to candidates, counts facets
+// over what is left and returns the requested page
+func filterAndPage(candidates []candidate, query models.TicketSearchQuery) *Result {
+	matched := candidates[:0]
+	for _, c := range candidates {
+		if matchesAny(c.status, query.Status) &&
+			matchesAny(c.priority, query.Priority) &&
+			matchesAny(c.departmentID, query.DepartmentID) {
+			matched = append(matched, c)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].hit.Score != matched[j].hit.Score {
+			return matched[i].hit.Score > matched[j].hit.Score
+		}
+		return matched[i].lastActivity > matched[j].lastActivity
+	})
+
+	counts := map[string]map[string]int{
+		FacetStatus:     {},
+		FacetPriority:   {},
+		FacetDepartment: {},
+	}
+	for _, c := range matched {
+		counts[FacetStatus][c.status]++
+		counts[FacetPriority][c.priority]++
+		if c.departmentID != "" {
+			counts[FacetDepartment][c.departmentID]++
+		}
+	}
+
+	result := &Result{
+		Total:  int64(len(matched)),
+		Facets: make(map[string][]models.FacetCount, len(counts)),
+	}
+	for name, values := range counts {
+		result.Facets[name] = facetCounts(values)
+	}
+
+	page, perPage := query.Page, query.PerPage
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = 20
+	}
+	start := (page - 1) * perPage
+	if start > len(matched) {
+		start = len(matched)
+	}
+	end := start + perPage
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	result.Hits = make([]Hit, 0, end-start)
+	for _, c := range matched[start:end] {
+		result.Hits = append(result.Hits, c.hit)
+	}
+
+	return result
+}
+
+// matchesAny reports whether value is one of allowed, or allowed is empty
+func matchesAny[T ~string](value string, allowed []T) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if string(a) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// facetCounts orders a facet's values by count, then value
+func facetCounts(values map[string]int) []models.FacetCount {
+	facets := make([]models.FacetCount, 0, len(values))
+	for value, count := range values {
+		facets = append(facets, models.FacetCount{Value: value, Count: count})
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Value < facets[j].Value
+	})
+	return facets
+}
@@ -0,0 +1,626 @@
+package search
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// BM25 parameters
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// Weights of terms reached by expanding a query word, relative to an
+	// exact match
+	prefixWeight = 0.8
+	fuzzyWeight  = 0.6
+
+	// Most index terms a single prefix or fuzzy query word expands to
+	maxExpansions = 50
+
+	// Shortest prefix query ("ab*") that is expanded
+	minPrefixLength = 2
+
+	// Bumped when the snapshot layout changes; older snapshots are ignored
+	// and need a rebuild
+	snapshotVersion = 1
+)
+
+// LocalIndex is an embedded inverted index kept in memory and snapshotted to
+// a file. Tokens are analyzed per language, query words ending in * match by
+// prefix, and fuzzy queries also match words within one or two edits.
+type LocalIndex struct {
+	path    string
+	flushMu sync.Mutex // Serializes snapshot writes
+
+	mu       sync.RWMutex
+	changes  uint64 // Bumped on every change
+	flushed  uint64 // changes as of the last snapshot
+	tickets  map[string]*localTicket
+	units    map[string]*localUnit
+	postings map[string]map[string]map[string]int // Tenant, term, unit key → term frequency
+	stats    map[string]*tenantStats
+}
+
+// localTicket holds the ticket fields searches filter and facet on
+type localTicket struct {
+	TenantID     string
+	CustomerID   string
+	Status       string
+	Priority     string
+	DepartmentID string
+	LastActivity int64
+	Messages     []string // Message unit keys
+}
+
+// localUnit is an indexed piece of text: a ticket's subject and description,
+// or one message
+type localUnit struct {
+	TenantID  string
+	TicketID  string
+	MessageID string
+	Private   bool
+	Terms     map[string]int
+	Length    int
+}
+
+// tenantStats feed BM25's document frequency and length normalization
+type tenantStats struct {
+	units  int
+	length int
+}
+
+// snapshot is what is written to disk; postings and stats are derived on load
+type snapshot struct {
+	Version int
+	Tickets map[string]*localTicket
+	Units   map[string]*localUnit
+}
+
+// OpenLocalIndex loads the index snapshot at path, or starts an empty index
+// if there is none
+func OpenLocalIndex(path string) (*LocalIndex, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create search index directory: %w", err)
+	}
+
+	idx := &LocalIndex{path: path}
+	idx.reset()
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open search index: %w", err)
+	}
+	defer file.Close()
+
+	var snap snapshot
+	if err := gob.NewDecoder(file).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("failed to read search index: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return idx, nil
+	}
+
+	if snap.Tickets != nil {
+		idx.tickets = snap.Tickets
+	}
+	for key, unit := range snap.Units {
+		idx.addUnit(key, unit)
+	}
+	idx.flushed = idx.changes
+
+	return idx, nil
+}
+
+// Name returns the backend name
+func (i *LocalIndex) Name() string {
+	return BackendLocal
+}
+
+// IndexTicket adds or replaces a ticket's subject and description. Subject
+// words count twice.
+func (i *LocalIndex) IndexTicket(ctx context.Context, ticket *models.Ticket) error {
+	if ticket.IsDeleted {
+		return i.DeleteTicket(ctx, ticket.ID)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	id := ticket.ID.Hex()
+	doc, ok := i.tickets[id]
+	if !ok {
+		doc = &localTicket{}
+		i.tickets[id] = doc
+	}
+	doc.TenantID = ticket.TenantID
+	doc.CustomerID = ticket.CustomerID
+	doc.Status = string(ticket.Status)
+	doc.Priority = string(ticket.Priority)
+	doc.DepartmentID = ""
+	if ticket.DepartmentID != nil {
+		doc.DepartmentID = ticket.DepartmentID.Hex()
+	}
+	doc.LastActivity = ticket.LastActivityAt.UnixNano()
+
+	subject := Analyze(ticket.Subject)
+	terms := append(append(subject, subject...), Analyze(ticket.Description)...)
+	i.addUnit(ticketKey(id), newUnit(ticket.TenantID, id, "", false, terms))
+
+	return nil
+}
+
+// IndexMessage adds or replaces a message, or removes it once deleted
+func (i *LocalIndex) IndexMessage(ctx context.Context, message *models.TicketMessage) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	ticketID := message.TicketID.Hex()
+	key := messageKey(message.ID.Hex())
+
+	doc, ok := i.tickets[ticketID]
+	if message.IsDeleted {
+		i.removeUnit(key)
+		if ok {
+			doc.Messages = without(doc.Messages, key)
+		}
+		return nil
+	}
+
+	// The ticket's fields arrive with its own event; until then it is
+	// skipped by searches
+	if !ok {
+		doc = &localTicket{TenantID: message.TenantID}
+		i.tickets[ticketID] = doc
+	}
+	if _, exists := i.units[key]; !exists {
+		doc.Messages = append(doc.Messages, key)
+	}
+
+	unit := newUnit(message.TenantID, ticketID, message.ID.Hex(), message.IsPrivate, Analyze(message.Content))
+	i.addUnit(key, unit)
+
+	return nil
+}
+
+// DeleteTicket removes a ticket and its messages
+func (i *LocalIndex) DeleteTicket(ctx context.Context, ticketID primitive.ObjectID) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	id := ticketID.Hex()
+	if doc, ok := i.tickets[id]; ok {
+		for _, key := range doc.Messages {
+			i.removeUnit(key)
+		}
+		delete(i.tickets, id)
+	}
+	i.removeUnit(ticketKey(id))
+
+	return nil
+}
+
+// Reset removes a tenant's tickets and messages
+func (i *LocalIndex) Reset(ctx context.Context, tenantID string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for id, doc := range i.tickets {
+		if doc.TenantID != tenantID {
+			continue
+		}
+		for _, key := range doc.Messages {
+			i.removeUnit(key)
+		}
+		i.removeUnit(ticketKey(id))
+		delete(i.tickets, id)
+	}
+	return nil
+}
+
+// Flush writes a snapshot if anything changed since the last one. The
+// snapshot is copied under the read lock and written without it, so searches
+// and updates carry on meanwhile. The file is replaced atomically, so a crash
+// leaves the previous snapshot intact.
+func (i *LocalIndex) Flush() error {
+	i.flushMu.Lock()
+	defer i.flushMu.Unlock()
+
+	i.mu.RLock()
+	if i.changes == i.flushed {
+		i.mu.RUnlock()
+		return nil
+	}
+	snap := i.snapshot()
+	changes := i.changes
+	i.mu.RUnlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(i.path), filepath.Base(i.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create search index snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(snap); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write search index snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write search index snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), i.path); err != nil {
+		return fmt.Errorf("failed to replace search index snapshot: %w", err)
+	}
+
+	// Changes made while writing are left for the next flush
+	i.mu.Lock()
+	i.flushed = changes
+	i.mu.Unlock()
+	return nil
+}
+
+// snapshot copies what is persisted. Units are never modified once added, so
+// they are shared; tickets are updated in place, so they are copied. Callers
+// hold the read lock.
+func (i *LocalIndex) snapshot() *snapshot {
+	snap := &snapshot{
+		Version: snapshotVersion,
+		Tickets: make(map[string]*localTicket, len(i.tickets)),
+		Units:   make(map[string]*localUnit, len(i.units)),
+	}
+	for id, doc := range i.tickets {
+		copied := *doc
+		copied.Messages = append([]string(nil), doc.Messages...)
+		snap.Tickets[id] = &copied
+	}
+	for key, unit := range i.units {
+		snap.Units[key] = unit
+	}
+	return snap
+}
+
+// Close flushes the index
+func (i *LocalIndex) Close() error {
+	return i.Flush()
+}
+
+// Search scores messages and ticket fields with BM25 and ranks tickets by
+// their fields' score plus their best message's
+func (i *LocalIndex) Search(ctx context.Context, query models.TicketSearchQuery) (*Result, error) {
+	_, span := tracing.Start(ctx, "LocalIndex.Search")
+	defer span.End()
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	postings := i.postings[query.TenantID]
+	stats := i.stats[query.TenantID]
+	if postings == nil || stats == nil || stats.units == 0 {
+		return filterAndPage(nil, query), nil
+	}
+	avgLength := float64(stats.length) / float64(stats.units)
+
+	visible := func(unit *localUnit) bool {
+		return query.IncludePrivate || !unit.Private
+	}
+
+	// Score each unit by its best expansion of each query word
+	scores := make(map[string]float64)
+	excluded := make(map[string]bool)
+	for _, word := range parseQuery(query.Query) {
+		expansions := i.expand(postings, word, query.Fuzzy)
+
+		if word.negated {
+			for _, e := range expansions {
+				for key := range postings[e.term] {
+					if unit := i.units[key]; visible(unit) {
+						excluded[unit.TicketID] = true
+					}
+				}
+			}
+			continue
+		}
+
+		best := make(map[string]float64)
+		for _, e := range expansions {
+			units := postings[e.term]
+			idf := math.Log(1 + (float64(stats.units)-float64(len(units))+0.5)/(float64(len(units))+0.5))
+			for key, tf := range units {
+				unit := i.units[key]
+				if !visible(unit) {
+					continue
+				}
+				norm := float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*(1-bm25B+bm25B*float64(unit.Length)/avgLength))
+				if score := e.weight * idf * norm; score > best[key] {
+					best[key] = score
+				}
+			}
+		}
+		for key, score := range best {
+			scores[key] += score
+		}
+	}
+
+	// Fold units into tickets, keeping each ticket's best message
+	hits := make(map[string]*Hit)
+	bestMessage := make(map[string]float64)
+	for key, score := range scores {
+		unit := i.units[key]
+		if excluded[unit.TicketID] {
+			continue
+		}
+		hit, ok := hits[unit.TicketID]
+		if !ok {
+			id, _ := primitive.ObjectIDFromHex(unit.TicketID)
+			hit = &Hit{TicketID: id}
+			hits[unit.TicketID] = hit
+		}
+		if unit.MessageID == "" {
+			hit.Score += score
+			continue
+		}
+		if score > bestMessage[unit.TicketID] {
+			hit.Score += score - bestMessage[unit.TicketID]
+			bestMessage[unit.TicketID] = score
+			hit.MessageID, _ = primitive.ObjectIDFromHex(unit.MessageID)
+		}
+	}
+
+	candidates := make([]candidate, 0, len(hits))
+	for id, hit := range hits {
+		doc := i.tickets[id]
+		if doc == nil || doc.Status == "" {
+			continue // Ticket fields not indexed yet
+		}
+		if query.CustomerID != "" && doc.CustomerID != query.CustomerID {
+			continue
+		}
+		candidates = append(candidates, candidate{
+			hit:          *hit,
+			status:       doc.Status,
+			priority:     doc.Priority,
+			departmentID: doc.DepartmentID,
+			lastActivity: doc.LastActivity,
+		})
+	}
+
+	return filterAndPage(candidates, query), nil
+}
+
+// queryWord is one word of a search query
+type queryWord struct {
+	token   string // Normalized, unstemmed
+	term    string // Analyzed; empty for stop words
+	prefix  bool
+	negated bool
+}
+
+// parseQuery splits a query into words. A leading - excludes tickets with
+// the word and a trailing * matches it as a prefix. Quotes are ignored.
+func parseQuery(query string) []queryWord {
+	var words []queryWord
+	for _, field := range strings.Fields(query) {
+		negated := strings.HasPrefix(field, "-")
+		prefix := strings.HasSuffix(field, "*")
+
+		tokens := tokenize(field)
+		for n, token := range tokens {
+			word := queryWord{
+				token:   token,
+				negated: negated,
+				prefix:  prefix && n == len(tokens)-1,
+			}
+			if term, ok := analyzerFor(token).Term(token); ok {
+				word.term = term
+			}
+			if word.term != "" || word.prefix {
+				words = append(words, word)
+			}
+		}
+	}
+	return words
+}
+
+// expansion is an index term a query word matches, with its weight
+type expansion struct {
+	term   string
+	weight float64
+}
+
+// expand finds the index terms a query word matches
+func (i *LocalIndex) expand(postings map[string]map[string]int, word queryWord, fuzzy bool) []expansion {
+	var expansions []expansion
+	if _, ok := postings[word.term]; ok && word.term != "" {
+		expansions = append(expansions, expansion{term: word.term, weight: 1})
+	}
+
+	prefix := word.prefix && len([]rune(word.token)) >= minPrefixLength
+	edits := maxEdits(word.term)
+	if !prefix && (!fuzzy || edits == 0) {
+		return expansions
+	}
+
+	var extra []expansion
+	for term := range postings {
+		if term == word.term {
+			continue
+		}
+		switch {
+		case prefix && (strings.HasPrefix(term, word.token) || strings.HasPrefix(term, word.term) && word.term != ""):
+			extra = append(extra, expansion{term: term, weight: prefixWeight})
+		case fuzzy && edits > 0 && withinDistance(term, word.term, edits):
+			extra = append(extra, expansion{term: term, weight: fuzzyWeight})
+		}
+	}
+
+	// Prefer the closest and most common terms when capping
+	sort.Slice(extra, func(a, b int) bool {
+		if extra[a].weight != extra[b].weight {
+			return extra[a].weight > extra[b].weight
+		}
+		if na, nb := len(postings[extra[a].term]), len(postings[extra[b].term]); na != nb {
+			return na > nb
+		}
+		return extra[a].term < extra[b].term
+	})
+	if len(extra) > maxExpansions {
+		extra = extra[:maxExpansions]
+	}
+
+	return append(expansions, extra...)
+}
+
+// maxEdits is how many edits a fuzzy match may be from a term of this length
+func maxEdits(term string) int {
+	switch n := len([]rune(term)); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// withinDistance reports whether the Levenshtein distance between a and b
+// is at most max
+func withinDistance(a, b string, max int) bool {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return false
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for x := 1; x <= len(ra); x++ {
+		curr[0] = x
+		rowMin := curr[0]
+		for y := 1; y <= len(rb); y++ {
+			cost := 1
+			if ra[x-1] == rb[y-1] {
+				cost = 0
+			}
+			curr[y] = min(prev[y]+1, curr[y-1]+1, prev[y-1]+cost)
+			rowMin = min(rowMin, curr[y])
+		}
+		if rowMin > max {
+			return false
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)] <= max
+}
+
+func newUnit(tenantID, ticketID, messageID string, private bool, terms []string) *localUnit {
+	unit := &localUnit{
+		TenantID:  tenantID,
+		TicketID:  ticketID,
+		MessageID: messageID,
+		Private:   private,
+		Terms:     make(map[string]int),
+		Length:    len(terms),
+	}
+	for _, term := range terms {
+		unit.Terms[term]++
+	}
+	return unit
+}
+
+// addUnit indexes a unit, replacing any previous one under key. Callers
+// hold the write lock.
+func (i *LocalIndex) addUnit(key string, unit *localUnit) {
+	i.removeUnit(key)
+
+	i.units[key] = unit
+	postings, ok := i.postings[unit.TenantID]
+	if !ok {
+		postings = make(map[string]map[string]int)
+		i.postings[unit.TenantID] = postings
+	}
+	for term, tf := range unit.Terms {
+		if postings[term] == nil {
+			postings[term] = make(map[string]int)
+		}
+		postings[term][key] = tf
+	}
+
+	stats, ok := i.stats[unit.TenantID]
+	if !ok {
+		stats = &tenantStats{}
+		i.stats[unit.TenantID] = stats
+	}
+	stats.units++
+	stats.length += unit.Length
+
+	i.changes++
+}
+
+// removeUnit drops a unit from the index. Callers hold the write lock.
+func (i *LocalIndex) removeUnit(key string) {
+	unit, ok := i.units[key]
+	if !ok {
+		return
+	}
+	delete(i.units, key)
+
+	postings := i.postings[unit.TenantID]
+	for term := range unit.Terms {
+		delete(postings[term], key)
+		if len(postings[term]) == 0 {
+			delete(postings, term)
+		}
+	}
+
+	if stats := i.stats[unit.TenantID]; stats != nil {
+		stats.units--
+		stats.length -= unit.Length
+	}
+
+	i.changes++
+}
+
+func (i *LocalIndex) reset() {
+	i.tickets = make(map[string]*localTicket)
+	i.units = make(map[string]*localUnit)
+	i.postings = make(map[string]map[string]map[string]int)
+	i.stats = make(map[string]*tenantStats)
+	i.changes++
+}
+
+func ticketKey(id string) string {
+	return "t:" + id
+}
+
+func messageKey(id string) string {
+	return "m:" + id
+}
+
+// without returns keys minus key
+func without(keys []string, key string) []string {
+	result := keys[:0]
+	for _, k := range keys {
+		if k != key {
+			result = append(result, k)
+		}
+	}
+	return result
+}
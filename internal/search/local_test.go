@@ -0,0 +1,171 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestLocalIndex checks ranking, visibility, fuzzy and prefix matching,
+// Persian stemming, facets and persistence of the embedded index
+func TestLocalIndex(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "idx")
+
+	idx, err := OpenLocalIndex(path)
+	require.NoError(t, err)
+
+	dept := primitive.NewObjectID()
+	printer := &models.Ticket{
+		ID: primitive.NewObjectID(), TenantID: "t1", CustomerID: "alice",
+		Subject: "Printer jammed", Description: "The office printer keeps jamming",
+		Status: models.StatusOpen, Priority: models.PriorityHigh, DepartmentID: &dept,
+	}
+	invoice := &models.Ticket{
+		ID: primitive.NewObjectID(), TenantID: "t1", CustomerID: "bob",
+		Subject: "Invoice question", Description: "Where is my invoice?",
+		Status: models.StatusPending, Priority: models.PriorityLow,
+	}
+	persian := &models.Ticket{
+		ID: primitive.NewObjectID(), TenantID: "t1", CustomerID: "bob",
+		Subject: "مشکل پرداخت", Description: "فاکتورهای من نمایش داده نمی‌شوند",
+		Status: models.StatusOpen, Priority: models.PriorityLow,
+	}
+	other := &models.Ticket{
+		ID: primitive.NewObjectID(), TenantID: "t2", CustomerID: "carol",
+		Subject: "Printer offline", Description: "Printer is offline",
+		Status: models.StatusOpen, Priority: models.PriorityLow,
+	}
+	for _, ticket := range []*models.Ticket{printer, invoice, persian, other} {
+		require.NoError(t, idx.IndexTicket(ctx, ticket))
+	}
+
+	note := &models.TicketMessage{ID: primitive.NewObjectID(), TicketID: invoice.ID, TenantID: "t1", Content: "Customer mentioned the printer too", IsPrivate: true}
+	reply := &models.TicketMessage{ID: primitive.NewObjectID(), TicketID: invoice.ID, TenantID: "t1", Content: "Your refund was processed"}
+	require.NoError(t, idx.IndexMessage(ctx, note))
+	require.NoError(t, idx.IndexMessage(ctx, reply))
+
+	agent := func(q string) models.TicketSearchQuery {
+		return models.TicketSearchQuery{TenantID: "t1", Query: q, IncludePrivate: true}
+	}
+
+	// Ticket fields outrank an internal note; other tenants never match
+	result, err := idx.Search(ctx, agent("printer"))
+	require.NoError(t, err)
+	require.Len(t, result.Hits, 2)
+	assert.Equal(t, printer.ID, result.Hits[0].TicketID)
+	assert.Equal(t, invoice.ID, result.Hits[1].TicketID)
+	assert.Equal(t, note.ID, result.Hits[1].MessageID)
+	assert.Equal(t, []models.FacetCount{{Value: "high", Count: 1}, {Value: "low", Count: 1}}, result.Facets[FacetPriority])
+
+	// Customers don't match internal notes
+	result, err = idx.Search(ctx, models.TicketSearchQuery{TenantID: "t1", Query: "printer", CustomerID: "bob"})
+	require.NoError(t, err)
+	assert.Empty(t, result.Hits)
+
+	// Message content, stemming, prefix and fuzzy matching
+	result, err = idx.Search(ctx, agent("refunds"))
+	require.NoError(t, err)
+	require.Len(t, result.Hits, 1)
+	assert.Equal(t, reply.ID, result.Hits[0].MessageID)
+
+	result, err = idx.Search(ctx, agent("invo*"))
+	require.NoError(t, err)
+	assert.Len(t, result.Hits, 1)
+
+	query := agent("priner")
+	result, err = idx.Search(ctx, query)
+	require.NoError(t, err)
+	assert.Empty(t, result.Hits)
+	query.Fuzzy = true
+	result, err = idx.Search(ctx, query)
+	require.NoError(t, err)
+	assert.Len(t, result.Hits, 2)
+
+	// Persian plural suffixes and Arabic letter variants normalize away
+	result, err = idx.Search(ctx, agent("فاكتور"))
+	require.NoError(t, err)
+	require.Len(t, result.Hits, 1)
+	assert.Equal(t, persian.ID, result.Hits[0].TicketID)
+
+	// Filters and negation
+	query = agent("printer")
+	query.Status = []models.TicketStatus{models.StatusPending}
+	result, err = idx.Search(ctx, query)
+	require.NoError(t, err)
+	assert.Len(t, result.Hits, 1)
+
+	result, err = idx.Search(ctx, agent("printer -refund"))
+	require.NoError(t, err)
+	require.Len(t, result.Hits, 1)
+	assert.Equal(t, printer.ID, result.Hits[0].TicketID)
+
+	// The snapshot survives a reopen; deletes remove messages too
+	require.NoError(t, idx.Close())
+	idx, err = OpenLocalIndex(path)
+	require.NoError(t, err)
+
+	result, err = idx.Search(ctx, agent("refund"))
+	require.NoError(t, err)
+	assert.Len(t, result.Hits, 1)
+
+	require.NoError(t, idx.DeleteTicket(ctx, invoice.ID))
+	result, err = idx.Search(ctx, agent("refund"))
+	require.NoError(t, err)
+	assert.Empty(t, result.Hits)
+
+	// Reset only clears the given tenant
+	require.NoError(t, idx.Reset(ctx, "t1"))
+	result, err = idx.Search(ctx, agent("printer"))
+	require.NoError(t, err)
+	assert.Empty(t, result.Hits)
+	result, err = idx.Search(ctx, models.TicketSearchQuery{TenantID: "t2", Query: "printer"})
+	require.NoError(t, err)
+	require.Len(t, result.Hits, 1)
+	assert.Equal(t, other.ID, result.Hits[0].TicketID)
+}
+
+// TestLocalIndexFlushConcurrent checks that flushing while tickets are
+// indexed neither races nor loses the changes made meanwhile
+func TestLocalIndexFlushConcurrent(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "idx")
+
+	idx, err := OpenLocalIndex(path)
+	require.NoError(t, err)
+
+	const count = 200
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < count; n++ {
+			_ = idx.IndexTicket(ctx, &models.Ticket{
+				ID: primitive.NewObjectID(), TenantID: "t1", CustomerID: "alice",
+				Subject: fmt.Sprintf("Printer %d", n), Status: models.StatusOpen, Priority: models.PriorityLow,
+			})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 20; n++ {
+			assert.NoError(t, idx.Flush())
+		}
+	}()
+	wg.Wait()
+
+	require.NoError(t, idx.Close())
+	idx, err = OpenLocalIndex(path)
+	require.NoError(t, err)
+
+	result, err := idx.Search(ctx, models.TicketSearchQuery{TenantID: "t1", Query: "printer", IncludePrivate: true})
+	require.NoError(t, err)
+	assert.EqualValues(t, count, result.Total)
+}
@@ -0,0 +1,135 @@
+package search
+
+import (
+	"context"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/minisource/ticket/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Matches read from each collection before merging and ranking; results
+// beyond this are not reachable by paging
+const mongoCandidates = 200
+
+// MongoIndex searches with MongoDB's text indexes. MongoDB maintains them on
+// every write, so indexing calls are no-ops. It has no fuzzy or prefix
+// matching and uses MongoDB's default language for stemming.
+type MongoIndex struct {
+	ticketRepo  *repository.TicketRepository
+	messageRepo *repository.MessageRepository
+}
+
+// NewMongoIndex creates a new MongoDB-backed index
+func NewMongoIndex(ticketRepo *repository.TicketRepository, messageRepo *repository.MessageRepository) *MongoIndex {
+	return &MongoIndex{
+		ticketRepo:  ticketRepo,
+		messageRepo: messageRepo,
+	}
+}
+
+// Name returns the backend name
+func (i *MongoIndex) Name() string {
+	return BackendMongo
+}
+
+// Search ranks tickets by their own text score plus their best matching
+// message's
+func (i *MongoIndex) Search(ctx context.Context, query models.TicketSearchQuery) (*Result, error) {
+	ctx, span := tracing.Start(ctx, "MongoIndex.Search")
+	defer span.End()
+
+	ticketMatches, err := i.ticketRepo.TextSearch(ctx, query.TenantID, query.Query, query.CustomerID, mongoCandidates)
+	if err != nil {
+		return nil, err
+	}
+	messageMatches, err := i.messageRepo.TextSearch(ctx, query.TenantID, query.Query, query.CustomerID, query.IncludePrivate, mongoCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make(map[primitive.ObjectID]*Hit)
+	tickets := make(map[primitive.ObjectID]*models.Ticket)
+	for n := range ticketMatches {
+		match := &ticketMatches[n]
+		hits[match.ID] = &Hit{TicketID: match.ID, Score: match.Score}
+		tickets[match.ID] = &match.Ticket
+	}
+
+	// Keep each ticket's best message; matches arrive best first
+	var missing []primitive.ObjectID
+	for _, match := range messageMatches {
+		hit, ok := hits[match.TicketID]
+		if !ok {
+			hit = &Hit{TicketID: match.TicketID}
+			hits[match.TicketID] = hit
+			missing = append(missing, match.TicketID)
+		}
+		if hit.MessageID.IsZero() {
+			hit.MessageID = match.ID
+			hit.Score += match.Score
+		}
+	}
+
+	// Load tickets found only through their messages
+	if len(missing) > 0 {
+		found, err := i.ticketRepo.GetByIDs(ctx, query.TenantID, query.CustomerID, missing)
+		if err != nil {
+			return nil, err
+		}
+		for n := range found {
+			tickets[found[n].ID] = &found[n]
+		}
+	}
+
+	candidates := make([]candidate, 0, len(hits))
+	for id, hit := range hits {
+		ticket, ok := tickets[id]
+		if !ok {
+			continue // Deleted or not visible
+		}
+		c := candidate{
+			hit:          *hit,
+			status:       string(ticket.Status),
+			priority:     string(ticket.Priority),
+			lastActivity: ticket.LastActivityAt.UnixNano(),
+		}
+		if ticket.DepartmentID != nil {
+			c.departmentID = ticket.DepartmentID.Hex()
+		}
+		candidates = append(candidates, c)
+	}
+
+	return filterAndPage(candidates, query), nil
+}
+
+// IndexTicket is a no-op; MongoDB indexes tickets as they are written
+func (i *MongoIndex) IndexTicket(ctx context.Context, ticket *models.Ticket) error {
+	return nil
+}
+
+// IndexMessage is a no-op; MongoDB indexes messages as they are written
+func (i *MongoIndex) IndexMessage(ctx context.Context, message *models.TicketMessage) error {
+	return nil
+}
+
+// DeleteTicket is a no-op; searches skip deleted tickets
+func (i *MongoIndex) DeleteTicket(ctx context.Context, ticketID primitive.ObjectID) error {
+	return nil
+}
+
+// Reset is a no-op
+func (i *MongoIndex) Reset(ctx context.Context, tenantID string) error {
+	return nil
+}
+
+// Flush is a no-op
+func (i *MongoIndex) Flush() error {
+	return nil
+}
+
+// Close is a no-op
+func (i *MongoIndex) Close() error {
+	return nil
+}
@@ -6,8 +6,11 @@ import (
 	"time"
 
 	"github.com/minisource/ticket/config"
+	"github.com/minisource/ticket/internal/metrics"
 	"github.com/minisource/ticket/internal/models"
 	"github.com/minisource/ticket/internal/repository"
+	"github.com/minisource/ticket/internal/search"
+	"github.com/minisource/ticket/internal/tracing"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -21,6 +24,7 @@ type AdminUsecase struct {
 	agentRepo      *repository.AgentRepository
 	slaRepo        *repository.SLAPolicyRepository
 	cannedRepo     *repository.CannedResponseRepository
+	index          search.Index
 	config         *config.Config
 }
 
@@ -34,6 +38,7 @@ func NewAdminUsecase(
 	agentRepo *repository.AgentRepository,
 	slaRepo *repository.SLAPolicyRepository,
 	cannedRepo *repository.CannedResponseRepository,
+	index search.Index,
 	cfg *config.Config,
 ) *AdminUsecase {
 	return &AdminUsecase{
@@ -45,6 +50,7 @@ func NewAdminUsecase(
 		agentRepo:      agentRepo,
 		slaRepo:        slaRepo,
 		cannedRepo:     cannedRepo,
+		index:          index,
 		config:         cfg,
 	}
 }
@@ -53,6 +59,9 @@ func NewAdminUsecase(
 
 // CreateAgent creates a new agent
 func (u *AdminUsecase) CreateAgent(ctx context.Context, tenantID string, req models.CreateAgentRequest) (*models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.CreateAgent")
+	defer span.End()
+
 	if req.UserID == "" || req.Name == "" || req.Email == "" {
 		return nil, errors.New("user_id, name, and email are required")
 	}
@@ -111,6 +120,9 @@ func (u *AdminUsecase) CreateAgent(ctx context.Context, tenantID string, req mod
 
 // GetAgent gets an agent by ID
 func (u *AdminUsecase) GetAgent(ctx context.Context, id string) (*models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.GetAgent")
+	defer span.End()
+
 	agentID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, errors.New("invalid agent ID")
@@ -129,6 +141,9 @@ func (u *AdminUsecase) GetAgent(ctx context.Context, id string) (*models.Agent,
 
 // GetAgentByUserID gets an agent by user ID
 func (u *AdminUsecase) GetAgentByUserID(ctx context.Context, tenantID, userID string) (*models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.GetAgentByUserID")
+	defer span.End()
+
 	agent, err := u.agentRepo.GetByUserID(ctx, tenantID, userID)
 	if err != nil {
 		return nil, err
@@ -142,6 +157,9 @@ func (u *AdminUsecase) GetAgentByUserID(ctx context.Context, tenantID, userID st
 
 // UpdateAgent updates an agent
 func (u *AdminUsecase) UpdateAgent(ctx context.Context, id string, req models.UpdateAgentRequest) (*models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.UpdateAgent")
+	defer span.End()
+
 	agent, err := u.GetAgent(ctx, id)
 	if err != nil {
 		return nil, err
@@ -196,6 +214,9 @@ func (u *AdminUsecase) UpdateAgent(ctx context.Context, id string, req models.Up
 
 // DeleteAgent deletes an agent
 func (u *AdminUsecase) DeleteAgent(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.DeleteAgent")
+	defer span.End()
+
 	agent, err := u.GetAgent(ctx, id)
 	if err != nil {
 		return err
@@ -216,11 +237,17 @@ func (u *AdminUsecase) DeleteAgent(ctx context.Context, id string) error {
 
 // ListAgents lists agents
 func (u *AdminUsecase) ListAgents(ctx context.Context, tenantID string, activeOnly bool) ([]models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.ListAgents")
+	defer span.End()
+
 	return u.agentRepo.List(ctx, tenantID, activeOnly)
 }
 
 // UpdateAgentStatus updates an agent's status
 func (u *AdminUsecase) UpdateAgentStatus(ctx context.Context, tenantID, userID string, status models.AgentStatus) error {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.UpdateAgentStatus")
+	defer span.End()
+
 	agent, err := u.agentRepo.GetByUserID(ctx, tenantID, userID)
 	if err != nil || agent == nil {
 		return errors.New("agent not found")
@@ -233,6 +260,9 @@ func (u *AdminUsecase) UpdateAgentStatus(ctx context.Context, tenantID, userID s
 
 // CreateSLAPolicy creates a new SLA policy
 func (u *AdminUsecase) CreateSLAPolicy(ctx context.Context, tenantID string, req models.CreateSLAPolicyRequest) (*models.SLAPolicy, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.CreateSLAPolicy")
+	defer span.End()
+
 	if req.Name == "" {
 		return nil, errors.New("name is required")
 	}
@@ -255,6 +285,9 @@ func (u *AdminUsecase) CreateSLAPolicy(ctx context.Context, tenantID string, req
 
 // GetSLAPolicy gets an SLA policy by ID
 func (u *AdminUsecase) GetSLAPolicy(ctx context.Context, id string) (*models.SLAPolicy, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.GetSLAPolicy")
+	defer span.End()
+
 	slaID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, errors.New("invalid SLA policy ID")
@@ -273,6 +306,9 @@ func (u *AdminUsecase) GetSLAPolicy(ctx context.Context, id string) (*models.SLA
 
 // UpdateSLAPolicy updates an SLA policy
 func (u *AdminUsecase) UpdateSLAPolicy(ctx context.Context, id string, req models.UpdateSLAPolicyRequest) (*models.SLAPolicy, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.UpdateSLAPolicy")
+	defer span.End()
+
 	policy, err := u.GetSLAPolicy(ctx, id)
 	if err != nil {
 		return nil, err
@@ -303,6 +339,9 @@ func (u *AdminUsecase) UpdateSLAPolicy(ctx context.Context, id string, req model
 
 // DeleteSLAPolicy deletes an SLA policy
 func (u *AdminUsecase) DeleteSLAPolicy(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.DeleteSLAPolicy")
+	defer span.End()
+
 	policy, err := u.GetSLAPolicy(ctx, id)
 	if err != nil {
 		return err
@@ -317,6 +356,9 @@ func (u *AdminUsecase) DeleteSLAPolicy(ctx context.Context, id string) error {
 
 // ListSLAPolicies lists SLA policies
 func (u *AdminUsecase) ListSLAPolicies(ctx context.Context, tenantID string) ([]models.SLAPolicy, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.ListSLAPolicies")
+	defer span.End()
+
 	return u.slaRepo.List(ctx, tenantID)
 }
 
@@ -324,6 +366,9 @@ func (u *AdminUsecase) ListSLAPolicies(ctx context.Context, tenantID string) ([]
 
 // CreateCannedResponse creates a new canned response
 func (u *AdminUsecase) CreateCannedResponse(ctx context.Context, tenantID, createdBy string, req models.CreateCannedResponseRequest) (*models.CannedResponse, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.CreateCannedResponse")
+	defer span.End()
+
 	if req.Title == "" || req.Content == "" {
 		return nil, errors.New("title and content are required")
 	}
@@ -357,6 +402,9 @@ func (u *AdminUsecase) CreateCannedResponse(ctx context.Context, tenantID, creat
 
 // GetCannedResponse gets a canned response by ID
 func (u *AdminUsecase) GetCannedResponse(ctx context.Context, id string) (*models.CannedResponse, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.GetCannedResponse")
+	defer span.End()
+
 	cannedID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, errors.New("invalid canned response ID")
@@ -375,6 +423,9 @@ func (u *AdminUsecase) GetCannedResponse(ctx context.Context, id string) (*model
 
 // UpdateCannedResponse updates a canned response
 func (u *AdminUsecase) UpdateCannedResponse(ctx context.Context, id string, req models.UpdateCannedResponseRequest) (*models.CannedResponse, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.UpdateCannedResponse")
+	defer span.End()
+
 	cannedResp, err := u.GetCannedResponse(ctx, id)
 	if err != nil {
 		return nil, err
@@ -411,6 +462,9 @@ func (u *AdminUsecase) UpdateCannedResponse(ctx context.Context, id string, req
 
 // DeleteCannedResponse deletes a canned response
 func (u *AdminUsecase) DeleteCannedResponse(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.DeleteCannedResponse")
+	defer span.End()
+
 	cannedResp, err := u.GetCannedResponse(ctx, id)
 	if err != nil {
 		return err
@@ -421,6 +475,9 @@ func (u *AdminUsecase) DeleteCannedResponse(ctx context.Context, id string) erro
 
 // ListCannedResponses lists canned responses
 func (u *AdminUsecase) ListCannedResponses(ctx context.Context, tenantID string, departmentID *primitive.ObjectID, globalOnly bool) ([]models.CannedResponse, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.ListCannedResponses")
+	defer span.End()
+
 	return u.cannedRepo.List(ctx, tenantID, departmentID, globalOnly)
 }
 
@@ -428,6 +485,9 @@ func (u *AdminUsecase) ListCannedResponses(ctx context.Context, tenantID string,
 
 // BulkAssignTickets assigns multiple tickets to an agent
 func (u *AdminUsecase) BulkAssignTickets(ctx context.Context, tenantID string, ticketIDs []string, agentID, changedBy, changedByName string) (int, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.BulkAssignTickets")
+	defer span.End()
+
 	agent, err := u.agentRepo.GetByUserID(ctx, tenantID, agentID)
 	if err != nil || agent == nil {
 		return 0, errors.New("agent not found")
@@ -448,6 +508,15 @@ func (u *AdminUsecase) BulkAssignTickets(ctx context.Context, tenantID string, t
 		}
 
 		oldAssignee := ticket.AssignedToID
+		if ticket.TenantID != tenantID || oldAssignee == agent.UserID {
+			continue
+		}
+
+		// Reserve capacity first; once the agent is full no other ticket fits
+		reserved, err := u.agentRepo.ReserveTicketSlot(ctx, agent.ID)
+		if err != nil || reserved == nil {
+			break
+		}
 
 		// Update ticket
 		updates := map[string]interface{}{
@@ -461,10 +530,21 @@ func (u *AdminUsecase) BulkAssignTickets(ctx context.Context, tenantID string, t
 			updates["status"] = models.StatusInProgress
 		}
 
-		if err := u.ticketRepo.UpdateFields(ctx, ticketID, updates); err != nil {
+		// Only over the version we read, so a concurrent reassignment can't
+		// have the old assignee decremented twice; on a conflict skip the ticket
+		if err := u.ticketRepo.UpdateFieldsIfVersion(ctx, ticketID, ticket.Version, updates); err != nil {
+			_ = u.agentRepo.ReleaseTicketSlot(ctx, agent.ID)
 			continue
 		}
 
+		// Free the previous assignee's slot now that the write has landed
+		if oldAssignee != "" {
+			previous, _ := u.agentRepo.GetByUserID(ctx, tenantID, oldAssignee)
+			if previous != nil {
+				_ = u.agentRepo.DecrementTicketCount(ctx, previous.ID)
+			}
+		}
+
 		// Create history
 		history := &models.TicketHistory{
 			TenantID:      ticket.TenantID,
@@ -477,9 +557,7 @@ func (u *AdminUsecase) BulkAssignTickets(ctx context.Context, tenantID string, t
 			CreatedAt:     now,
 		}
 		_ = u.historyRepo.Create(ctx, history)
-
-		// Update agent stats
-		_ = u.agentRepo.IncrementTicketCount(ctx, agent.ID)
+		u.reindex(ctx, ticketID)
 
 		successCount++
 	}
@@ -489,6 +567,9 @@ func (u *AdminUsecase) BulkAssignTickets(ctx context.Context, tenantID string, t
 
 // BulkChangeStatus changes status of multiple tickets
 func (u *AdminUsecase) BulkChangeStatus(ctx context.Context, tenantID string, ticketIDs []string, status models.TicketStatus, changedBy, changedByName string) (int, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.BulkChangeStatus")
+	defer span.End()
+
 	successCount := 0
 	now := time.Now()
 
@@ -518,6 +599,9 @@ func (u *AdminUsecase) BulkChangeStatus(ctx context.Context, tenantID string, ti
 		if err := u.ticketRepo.UpdateFields(ctx, ticketID, updates); err != nil {
 			continue
 		}
+		if status == models.StatusResolved && oldStatus != models.StatusResolved {
+			metrics.TicketsResolved.WithLabelValues(ticket.TenantID).Inc()
+		}
 
 		// Create history
 		history := &models.TicketHistory{
@@ -531,6 +615,7 @@ func (u *AdminUsecase) BulkChangeStatus(ctx context.Context, tenantID string, ti
 			CreatedAt:     now,
 		}
 		_ = u.historyRepo.Create(ctx, history)
+		u.reindex(ctx, ticketID)
 
 		successCount++
 	}
@@ -540,6 +625,9 @@ func (u *AdminUsecase) BulkChangeStatus(ctx context.Context, tenantID string, ti
 
 // BulkChangePriority changes priority of multiple tickets
 func (u *AdminUsecase) BulkChangePriority(ctx context.Context, tenantID string, ticketIDs []string, priority models.TicketPriority, changedBy, changedByName string) (int, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.BulkChangePriority")
+	defer span.End()
+
 	successCount := 0
 	now := time.Now()
 
@@ -578,6 +666,7 @@ func (u *AdminUsecase) BulkChangePriority(ctx context.Context, tenantID string,
 			CreatedAt:     now,
 		}
 		_ = u.historyRepo.Create(ctx, history)
+		u.reindex(ctx, ticketID)
 
 		successCount++
 	}
@@ -587,6 +676,9 @@ func (u *AdminUsecase) BulkChangePriority(ctx context.Context, tenantID string,
 
 // BulkTransferDepartment transfers multiple tickets to a department
 func (u *AdminUsecase) BulkTransferDepartment(ctx context.Context, tenantID string, ticketIDs []string, departmentID, changedBy, changedByName string) (int, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.BulkTransferDepartment")
+	defer span.End()
+
 	deptID, err := primitive.ObjectIDFromHex(departmentID)
 	if err != nil {
 		return 0, errors.New("invalid department ID")
@@ -645,6 +737,7 @@ func (u *AdminUsecase) BulkTransferDepartment(ctx context.Context, tenantID stri
 			CreatedAt:     now,
 		}
 		_ = u.historyRepo.Create(ctx, history)
+		u.reindex(ctx, ticketID)
 
 		successCount++
 	}
@@ -654,6 +747,9 @@ func (u *AdminUsecase) BulkTransferDepartment(ctx context.Context, tenantID stri
 
 // BulkDeleteTickets deletes multiple tickets
 func (u *AdminUsecase) BulkDeleteTickets(ctx context.Context, tenantID string, ticketIDs []string) (int, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.BulkDeleteTickets")
+	defer span.End()
+
 	successCount := 0
 
 	for _, ticketIDStr := range ticketIDs {
@@ -675,6 +771,7 @@ func (u *AdminUsecase) BulkDeleteTickets(ctx context.Context, tenantID string, t
 		if err := u.ticketRepo.Delete(ctx, ticketID, ""); err != nil {
 			continue
 		}
+		_ = u.index.DeleteTicket(ctx, ticketID)
 
 		// Update department counts
 		if ticket.DepartmentID != nil {
@@ -695,20 +792,81 @@ func (u *AdminUsecase) BulkDeleteTickets(ctx context.Context, tenantID string, t
 	return successCount, nil
 }
 
+// reindex refreshes a ticket in the search index after a bulk change
+func (u *AdminUsecase) reindex(ctx context.Context, ticketID primitive.ObjectID) {
+	ticket, err := u.ticketRepo.GetByID(ctx, ticketID)
+	if err != nil || ticket == nil {
+		return
+	}
+	_ = u.index.IndexTicket(ctx, ticket)
+}
+
+// ===== Search =====
+
+// RebuildSearchIndex clears a tenant's entries from the search index and
+// reindexes its tickets and messages from MongoDB. Changes made while it runs
+// are indexed as usual.
+func (u *AdminUsecase) RebuildSearchIndex(ctx context.Context, tenantID string) (*models.SearchRebuildReport, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.RebuildSearchIndex")
+	defer span.End()
+
+	report := &models.SearchRebuildReport{
+		Backend:   u.index.Name(),
+		StartedAt: time.Now(),
+	}
+
+	if err := u.index.Reset(ctx, tenantID); err != nil {
+		return nil, err
+	}
+
+	// Tickets first, so their messages land on indexed tickets
+	err := u.ticketRepo.ForEach(ctx, tenantID, func(ticket *models.Ticket) error {
+		report.Tickets++
+		return u.index.IndexTicket(ctx, ticket)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.messageRepo.ForEach(ctx, tenantID, func(message *models.TicketMessage) error {
+		report.Messages++
+		return u.index.IndexMessage(ctx, message)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.index.Flush(); err != nil {
+		return nil, err
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
 // ===== Dashboard & Statistics =====
 
 // GetDashboardStats gets dashboard statistics
-func (u *AdminUsecase) GetDashboardStats(ctx context.Context, tenantID string) (*models.TicketStats, error) {
-	return u.ticketRepo.GetStats(ctx, tenantID)
+func (u *AdminUsecase) GetDashboardStats(ctx context.Context, tenantID string, filter models.StatsFilter) (*models.TicketStats, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.GetDashboardStats")
+	defer span.End()
+
+	return u.ticketRepo.GetStats(ctx, tenantID, filter)
 }
 
 // GetAgentStats gets agent statistics
 func (u *AdminUsecase) GetAgentStats(ctx context.Context, tenantID, agentID string) (*models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.GetAgentStats")
+	defer span.End()
+
 	return u.agentRepo.GetByUserID(ctx, tenantID, agentID)
 }
 
 // GetDepartmentStats gets department statistics
 func (u *AdminUsecase) GetDepartmentStats(ctx context.Context, id string) (*models.Department, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.GetDepartmentStats")
+	defer span.End()
+
 	deptID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, errors.New("invalid department ID")
@@ -719,15 +877,24 @@ func (u *AdminUsecase) GetDepartmentStats(ctx context.Context, id string) (*mode
 
 // GetSLABreachedTickets gets tickets with breached SLA
 func (u *AdminUsecase) GetSLABreachedTickets(ctx context.Context, tenantID string, page, perPage int) ([]models.Ticket, int64, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.GetSLABreachedTickets")
+	defer span.End()
+
 	return u.ticketRepo.GetSLABreached(ctx, tenantID, page, perPage)
 }
 
 // GetTicketsDueSoon gets tickets due soon
 func (u *AdminUsecase) GetTicketsDueSoon(ctx context.Context, tenantID string, hours int) ([]models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.GetTicketsDueSoon")
+	defer span.End()
+
 	return u.ticketRepo.GetDueSoon(ctx, tenantID, hours)
 }
 
 // GetUnassignedTickets gets unassigned tickets
 func (u *AdminUsecase) GetUnassignedTickets(ctx context.Context, tenantID string, page, perPage int) ([]models.Ticket, int64, error) {
+	ctx, span := tracing.Start(ctx, "AdminUsecase.GetUnassignedTickets")
+	defer span.End()
+
 	return u.ticketRepo.GetUnassigned(ctx, tenantID, nil, page, perPage)
 }
@@ -5,8 +5,10 @@ import (
 	"errors"
 
 	"github.com/minisource/ticket/config"
+	"github.com/minisource/ticket/internal/assignment"
 	"github.com/minisource/ticket/internal/models"
 	"github.com/minisource/ticket/internal/repository"
+	"github.com/minisource/ticket/internal/tracing"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -38,9 +40,15 @@ func NewDepartmentUsecase(
 
 // CreateDepartment creates a new department
 func (u *DepartmentUsecase) CreateDepartment(ctx context.Context, tenantID string, req models.CreateDepartmentRequest) (*models.Department, error) {
+	ctx, span := tracing.Start(ctx, "DepartmentUsecase.CreateDepartment")
+	defer span.End()
+
 	if req.Name == "" {
 		return nil, errors.New("name is required")
 	}
+	if req.AutoAssignType != "" && !assignment.IsValidStrategy(req.AutoAssignType) {
+		return nil, errors.New("invalid auto-assign type")
+	}
 
 	department := &models.Department{
 		TenantID:        tenantID,
@@ -103,6 +111,9 @@ func (u *DepartmentUsecase) CreateDepartment(ctx context.Context, tenantID strin
 
 // GetDepartment gets a department by ID
 func (u *DepartmentUsecase) GetDepartment(ctx context.Context, id string) (*models.Department, error) {
+	ctx, span := tracing.Start(ctx, "DepartmentUsecase.GetDepartment")
+	defer span.End()
+
 	deptID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, errors.New("invalid department ID")
@@ -121,6 +132,9 @@ func (u *DepartmentUsecase) GetDepartment(ctx context.Context, id string) (*mode
 
 // UpdateDepartment updates a department
 func (u *DepartmentUsecase) UpdateDepartment(ctx context.Context, id string, req models.UpdateDepartmentRequest) (*models.Department, error) {
+	ctx, span := tracing.Start(ctx, "DepartmentUsecase.UpdateDepartment")
+	defer span.End()
+
 	department, err := u.GetDepartment(ctx, id)
 	if err != nil {
 		return nil, err
@@ -139,6 +153,9 @@ func (u *DepartmentUsecase) UpdateDepartment(ctx context.Context, id string, req
 		department.AutoAssign = *req.AutoAssign
 	}
 	if req.AutoAssignType != nil {
+		if *req.AutoAssignType != "" && !assignment.IsValidStrategy(*req.AutoAssignType) {
+			return nil, errors.New("invalid auto-assign type")
+		}
 		department.AutoAssignType = *req.AutoAssignType
 	}
 	if req.DefaultPriority != nil {
@@ -195,6 +212,9 @@ func (u *DepartmentUsecase) UpdateDepartment(ctx context.Context, id string, req
 
 // DeleteDepartment deletes a department
 func (u *DepartmentUsecase) DeleteDepartment(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "DepartmentUsecase.DeleteDepartment")
+	defer span.End()
+
 	department, err := u.GetDepartment(ctx, id)
 	if err != nil {
 		return err
@@ -209,11 +229,17 @@ func (u *DepartmentUsecase) DeleteDepartment(ctx context.Context, id string) err
 
 // ListDepartments lists departments
 func (u *DepartmentUsecase) ListDepartments(ctx context.Context, tenantID string, activeOnly bool) ([]models.Department, error) {
+	ctx, span := tracing.Start(ctx, "DepartmentUsecase.ListDepartments")
+	defer span.End()
+
 	return u.departmentRepo.List(ctx, tenantID, activeOnly)
 }
 
 // AddAgentToDepartment adds an agent to a department
 func (u *DepartmentUsecase) AddAgentToDepartment(ctx context.Context, departmentID, agentID string) error {
+	ctx, span := tracing.Start(ctx, "DepartmentUsecase.AddAgentToDepartment")
+	defer span.End()
+
 	deptID, err := primitive.ObjectIDFromHex(departmentID)
 	if err != nil {
 		return errors.New("invalid department ID")
@@ -250,6 +276,9 @@ func (u *DepartmentUsecase) AddAgentToDepartment(ctx context.Context, department
 
 // RemoveAgentFromDepartment removes an agent from a department
 func (u *DepartmentUsecase) RemoveAgentFromDepartment(ctx context.Context, departmentID, agentID string) error {
+	ctx, span := tracing.Start(ctx, "DepartmentUsecase.RemoveAgentFromDepartment")
+	defer span.End()
+
 	deptID, err := primitive.ObjectIDFromHex(departmentID)
 	if err != nil {
 		return errors.New("invalid department ID")
@@ -283,6 +312,9 @@ func (u *DepartmentUsecase) RemoveAgentFromDepartment(ctx context.Context, depar
 
 // GetDepartmentAgents gets agents for a department
 func (u *DepartmentUsecase) GetDepartmentAgents(ctx context.Context, tenantID, departmentID string) ([]models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "DepartmentUsecase.GetDepartmentAgents")
+	defer span.End()
+
 	deptID, err := primitive.ObjectIDFromHex(departmentID)
 	if err != nil {
 		return nil, errors.New("invalid department ID")
@@ -310,6 +342,9 @@ func NewCategoryUsecase(
 
 // CreateCategory creates a new category
 func (u *CategoryUsecase) CreateCategory(ctx context.Context, tenantID string, req models.CreateCategoryRequest) (*models.Category, error) {
+	ctx, span := tracing.Start(ctx, "CategoryUsecase.CreateCategory")
+	defer span.End()
+
 	if req.Name == "" {
 		return nil, errors.New("name is required")
 	}
@@ -359,6 +394,9 @@ func (u *CategoryUsecase) CreateCategory(ctx context.Context, tenantID string, r
 
 // GetCategory gets a category by ID
 func (u *CategoryUsecase) GetCategory(ctx context.Context, id string) (*models.Category, error) {
+	ctx, span := tracing.Start(ctx, "CategoryUsecase.GetCategory")
+	defer span.End()
+
 	catID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, errors.New("invalid category ID")
@@ -377,6 +415,9 @@ func (u *CategoryUsecase) GetCategory(ctx context.Context, id string) (*models.C
 
 // UpdateCategory updates a category
 func (u *CategoryUsecase) UpdateCategory(ctx context.Context, id string, req models.UpdateCategoryRequest) (*models.Category, error) {
+	ctx, span := tracing.Start(ctx, "CategoryUsecase.UpdateCategory")
+	defer span.End()
+
 	category, err := u.GetCategory(ctx, id)
 	if err != nil {
 		return nil, err
@@ -416,6 +457,9 @@ func (u *CategoryUsecase) UpdateCategory(ctx context.Context, id string, req mod
 
 // DeleteCategory deletes a category
 func (u *CategoryUsecase) DeleteCategory(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "CategoryUsecase.DeleteCategory")
+	defer span.End()
+
 	category, err := u.GetCategory(ctx, id)
 	if err != nil {
 		return err
@@ -426,11 +470,17 @@ func (u *CategoryUsecase) DeleteCategory(ctx context.Context, id string) error {
 
 // ListCategories lists categories
 func (u *CategoryUsecase) ListCategories(ctx context.Context, tenantID string, publicOnly bool) ([]models.Category, error) {
+	ctx, span := tracing.Start(ctx, "CategoryUsecase.ListCategories")
+	defer span.End()
+
 	return u.categoryRepo.List(ctx, tenantID, publicOnly)
 }
 
 // GetCategoriesByDepartment gets categories for a department
 func (u *CategoryUsecase) GetCategoriesByDepartment(ctx context.Context, tenantID, departmentID string) ([]models.Category, error) {
+	ctx, span := tracing.Start(ctx, "CategoryUsecase.GetCategoriesByDepartment")
+	defer span.End()
+
 	deptID, err := primitive.ObjectIDFromHex(departmentID)
 	if err != nil {
 		return nil, errors.New("invalid department ID")
@@ -0,0 +1,273 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/minisource/ticket/config"
+	"github.com/minisource/ticket/internal/export"
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/minisource/ticket/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrExportNotFound is returned for export jobs that don't exist, or
+	// whose file has expired
+	ErrExportNotFound = errors.New("export not found")
+	// ErrExportNotReady is returned when downloading a job that hasn't
+	// finished
+	ErrExportNotReady = errors.New("export not finished")
+	// ErrExportTooLarge is returned for exports that need the background
+	// worker while it is disabled
+	ErrExportTooLarge = errors.New("too many tickets to export in the request")
+)
+
+// exportStaleAfter is how long a job may run before another worker takes it
+// over, assuming the first one stopped
+const exportStaleAfter = time.Hour
+
+// ExportUsecase handles ticket exports. Small exports stream straight to the
+// client; larger ones are queued and written to GridFS by the export worker,
+// so any instance can serve the download.
+type ExportUsecase struct {
+	ticketRepo *repository.TicketRepository
+	exportRepo *repository.ExportRepository
+	fileRepo   *repository.FileRepository
+	cfg        config.ExportConfig
+}
+
+// NewExportUsecase creates a new export usecase
+func NewExportUsecase(ticketRepo *repository.TicketRepository, exportRepo *repository.ExportRepository, fileRepo *repository.FileRepository, cfg config.ExportConfig) *ExportUsecase {
+	return &ExportUsecase{
+		ticketRepo: ticketRepo,
+		exportRepo: exportRepo,
+		fileRepo:   fileRepo,
+		cfg:        cfg,
+	}
+}
+
+// NeedsBackground checks the filter and reports whether it matches too many
+// tickets to stream in the request
+func (u *ExportUsecase) NeedsBackground(ctx context.Context, filter models.TicketFilter, userID string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "ExportUsecase.NeedsBackground")
+	defer span.End()
+
+	filter, where, err := compileTicketQuery(filter, userID)
+	if err != nil {
+		return false, err
+	}
+	if _, err := repository.ParseTicketSort(filter.SortBy, filter.SortOrder); err != nil {
+		return false, err
+	}
+
+	limit := int64(u.cfg.SyncLimit)
+	count, err := u.ticketRepo.CountMatching(ctx, filter, where, limit+1)
+	if err != nil {
+		return false, err
+	}
+
+	return count > limit, nil
+}
+
+// WriteTickets writes every ticket matching the filter, with a column per
+// custom field in use, and returns the number of tickets written
+func (u *ExportUsecase) WriteTickets(ctx context.Context, filter models.TicketFilter, userID string, format export.Format, loc *time.Location, w io.Writer) (int64, error) {
+	ctx, span := tracing.Start(ctx, "ExportUsecase.WriteTickets")
+	defer span.End()
+
+	filter, where, err := compileTicketQuery(filter, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	keys, err := u.ticketRepo.CustomFieldKeys(ctx, filter, where)
+	if err != nil {
+		return 0, err
+	}
+
+	out, err := export.NewWriter(w, format, loc)
+	if err != nil {
+		return 0, err
+	}
+	if err := out.Write(export.TicketHeader(keys)); err != nil {
+		return 0, err
+	}
+
+	var rows int64
+	err = u.ticketRepo.EachWhere(ctx, filter, where, func(ticket *models.Ticket) error {
+		rows++
+		return out.Write(export.TicketRow(ticket, keys))
+	})
+	if err != nil {
+		return rows, err
+	}
+
+	return rows, out.Close()
+}
+
+// QueueTicketExport queues a background export of the tickets matching the
+// filter
+func (u *ExportUsecase) QueueTicketExport(ctx context.Context, filter models.TicketFilter, userID string, format export.Format, loc *time.Location) (*models.ExportJob, error) {
+	ctx, span := tracing.Start(ctx, "ExportUsecase.QueueTicketExport")
+	defer span.End()
+
+	if !u.cfg.Enabled {
+		return nil, ErrExportTooLarge
+	}
+
+	filter.Page, filter.PerPage = 0, 0
+	job := &models.ExportJob{
+		TenantID:    filter.TenantID,
+		RequestedBy: userID,
+		Kind:        models.ExportTickets,
+		Format:      string(format),
+		Filter:      filter,
+		Timezone:    loc.String(),
+		Status:      models.ExportPending,
+		ExpiresAt:   time.Now().Add(u.cfg.Retention),
+	}
+
+	if err := u.exportRepo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// RunNext runs the oldest queued export, if any, and reports whether there
+// was one. Failures are recorded on the job.
+func (u *ExportUsecase) RunNext(ctx context.Context) (bool, error) {
+	ctx, span := tracing.Start(ctx, "ExportUsecase.RunNext")
+	defer span.End()
+
+	job, err := u.exportRepo.ClaimNext(ctx, exportStaleAfter)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	job.FileName = fmt.Sprintf("tickets-%s.%s", job.CreatedAt.Format("20060102-150405"), job.Format)
+	if err := u.writeJob(ctx, job); err != nil {
+		job.Status = models.ExportFailed
+		job.Error = err.Error()
+		job.FileName = ""
+	} else {
+		job.Status = models.ExportDone
+	}
+
+	finished, err := u.exportRepo.Finish(ctx, job)
+	if err != nil || !finished {
+		// Another worker took the job over or it was deleted; its file is
+		// not ours to keep
+		if !job.FileID.IsZero() {
+			_ = u.fileRepo.Delete(ctx, job.FileID)
+		}
+		return true, err
+	}
+
+	return true, nil
+}
+
+// writeJob writes a job's file and records its ID, size and rows. Nothing
+// is kept if writing fails.
+func (u *ExportUsecase) writeJob(ctx context.Context, job *models.ExportJob) error {
+	format, err := export.ParseFormat(job.Format)
+	if err != nil {
+		return err
+	}
+	loc, err := time.LoadLocation(job.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	var rows int64
+	id, size, err := u.fileRepo.Write(ctx, job.FileName, func(w io.Writer) error {
+		n, err := u.WriteTickets(ctx, job.Filter, job.RequestedBy, format, loc, w)
+		rows = n
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	job.FileID = id
+	job.Size = size
+	job.Rows = rows
+	return nil
+}
+
+// GetJob gets a tenant's export job
+func (u *ExportUsecase) GetJob(ctx context.Context, tenantID, id string) (*models.ExportJob, error) {
+	ctx, span := tracing.Start(ctx, "ExportUsecase.GetJob")
+	defer span.End()
+
+	jobID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrExportNotFound
+	}
+
+	job, err := u.exportRepo.GetByID(ctx, tenantID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrExportNotFound
+	}
+
+	return job, nil
+}
+
+// OpenDownload opens the file of a finished export job. The caller closes it.
+func (u *ExportUsecase) OpenDownload(ctx context.Context, tenantID, id string) (*models.ExportJob, io.ReadCloser, error) {
+	ctx, span := tracing.Start(ctx, "ExportUsecase.OpenDownload")
+	defer span.End()
+
+	job, err := u.GetJob(ctx, tenantID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Status != models.ExportDone {
+		return job, nil, ErrExportNotReady
+	}
+
+	file, err := u.fileRepo.Open(ctx, job.FileID)
+	if err != nil {
+		return job, nil, err
+	}
+	if file == nil {
+		return job, nil, ErrExportNotFound
+	}
+
+	return job, file, nil
+}
+
+// Cleanup deletes expired export jobs and their files, returning how many
+// were deleted
+func (u *ExportUsecase) Cleanup(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "ExportUsecase.Cleanup")
+	defer span.End()
+
+	jobs, err := u.exportRepo.ListExpired(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for i := range jobs {
+		if !jobs[i].FileID.IsZero() {
+			if err := u.fileRepo.Delete(ctx, jobs[i].FileID); err != nil {
+				return deleted, err
+			}
+		}
+		if err := u.exportRepo.Delete(ctx, jobs[i].ID); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/minisource/ticket/config"
+	"github.com/minisource/ticket/internal/guest"
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/notifier"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/minisource/ticket/internal/tracing"
+)
+
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)`)
+
+// GuestUsecase handles tickets submitted by customers without an account
+type GuestUsecase struct {
+	ticketUsecase *TicketUsecase
+	ticketRepo    *repository.TicketRepository
+	tokens        *guest.TokenManager
+	notifier      *notifier.Client
+	config        *config.Config
+}
+
+// NewGuestUsecase creates a new guest usecase
+func NewGuestUsecase(
+	ticketUsecase *TicketUsecase,
+	ticketRepo *repository.TicketRepository,
+	tokens *guest.TokenManager,
+	notifierClient *notifier.Client,
+	cfg *config.Config,
+) *GuestUsecase {
+	return &GuestUsecase{
+		ticketUsecase: ticketUsecase,
+		ticketRepo:    ticketRepo,
+		tokens:        tokens,
+		notifier:      notifierClient,
+		config:        cfg,
+	}
+}
+
+// CreateTicket creates a guest ticket and emails the customer an access link.
+// The access token is only ever sent by email, so submitting with someone
+// else's address doesn't grant access to the ticket.
+func (u *GuestUsecase) CreateTicket(ctx context.Context, req models.CreateGuestTicketRequest, ip, userAgent string) (*models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "GuestUsecase.CreateTicket")
+	defer span.End()
+
+	if req.TenantID == "" {
+		return nil, errors.New("tenant ID is required")
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, errors.New("name is required")
+	}
+
+	address, err := mail.ParseAddress(req.Email)
+	if err != nil {
+		return nil, errors.New("invalid email address")
+	}
+	email := strings.ToLower(address.Address)
+
+	if u.isSpam(req) {
+		return nil, errors.New("submission rejected")
+	}
+
+	ticketReq := models.CreateTicketRequest{
+		TenantID:     req.TenantID,
+		Subject:      req.Subject,
+		Description:  req.Description,
+		Type:         req.Type,
+		Source:       models.SourceWeb,
+		DepartmentID: req.DepartmentID,
+		CategoryID:   req.CategoryID,
+		Attachments:  req.Attachments,
+	}
+
+	ticket, err := u.ticketUsecase.createTicket(ctx, ticketReq, ticketCustomer{
+		ID:      guestCustomerID(email),
+		Name:    strings.TrimSpace(req.Name),
+		Email:   email,
+		Phone:   req.Phone,
+		IsGuest: true,
+	}, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	token, _, err := u.tokens.Issue(ticket.ID.Hex(), ticket.TenantID, email)
+	if err != nil {
+		return nil, err
+	}
+
+	// The ticket exists even if the email fails; the customer can ask for
+	// the link again
+	_ = u.sendAccessLink(ctx, ticket, token)
+
+	return ticket, nil
+}
+
+// GetTicket gets the ticket a guest token was issued for
+func (u *GuestUsecase) GetTicket(ctx context.Context, claims *guest.Claims) (*models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "GuestUsecase.GetTicket")
+	defer span.End()
+
+	ticket, err := u.ticketUsecase.GetTicket(ctx, claims.TicketID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The token must still match the ticket's tenant and customer
+	if ticket.TenantID != claims.TenantID || !strings.EqualFold(ticket.CustomerEmail, claims.Email) {
+		return nil, errors.New("ticket not found")
+	}
+
+	return ticket, nil
+}
+
+// GetMessages gets public messages on a guest ticket
+func (u *GuestUsecase) GetMessages(ctx context.Context, claims *guest.Claims, page, perPage int) ([]models.TicketMessage, int64, error) {
+	ctx, span := tracing.Start(ctx, "GuestUsecase.GetMessages")
+	defer span.End()
+
+	ticket, err := u.GetTicket(ctx, claims)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return u.ticketUsecase.GetTicketMessages(ctx, ticket.ID.Hex(), false, page, perPage)
+}
+
+// GetMessagesAfter gets one page of public messages on a guest ticket after a cursor
+func (u *GuestUsecase) GetMessagesAfter(ctx context.Context, claims *guest.Claims, page models.CursorRequest) (*models.CursorPage[models.TicketMessage], error) {
+	ctx, span := tracing.Start(ctx, "GuestUsecase.GetMessagesAfter")
+	defer span.End()
+
+	ticket, err := u.GetTicket(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.ticketUsecase.GetTicketMessagesAfter(ctx, ticket.ID.Hex(), false, page)
+}
+
+// AddReply adds a customer reply to a guest ticket
+func (u *GuestUsecase) AddReply(ctx context.Context, claims *guest.Claims, req models.CreateMessageRequest, ip, userAgent string) (*models.TicketMessage, error) {
+	ctx, span := tracing.Start(ctx, "GuestUsecase.AddReply")
+	defer span.End()
+
+	ticket, err := u.GetTicket(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if ticket.Status == models.StatusClosed || ticket.Status == models.StatusCancelled {
+		return nil, errors.New("ticket is closed")
+	}
+
+	// Guests can only post public replies
+	req.Type = models.MessageTypeReply
+	req.IsPrivate = false
+
+	return u.ticketUsecase.AddReply(ctx, ticket.ID.Hex(), req, ticket.CustomerID, ticket.CustomerName, ticket.CustomerEmail, models.SenderCustomer, ip, userAgent)
+}
+
+// ResendAccessLink emails a fresh access link for a guest ticket.
+// It reports success for unknown tickets so callers cannot probe for them.
+func (u *GuestUsecase) ResendAccessLink(ctx context.Context, req models.ResendGuestLinkRequest) error {
+	ctx, span := tracing.Start(ctx, "GuestUsecase.ResendAccessLink")
+	defer span.End()
+
+	if req.TenantID == "" {
+		return errors.New("tenant ID is required")
+	}
+
+	ticket, err := u.ticketRepo.GetByTicketNumber(ctx, req.TenantID, req.TicketNumber)
+	if err != nil {
+		return err
+	}
+	if ticket == nil || !ticket.IsGuest || !strings.EqualFold(ticket.CustomerEmail, strings.TrimSpace(req.Email)) {
+		return nil
+	}
+
+	token, _, err := u.tokens.Issue(ticket.ID.Hex(), ticket.TenantID, ticket.CustomerEmail)
+	if err != nil {
+		return err
+	}
+
+	return u.sendAccessLink(ctx, ticket, token)
+}
+
+// Helper functions
+
+func (u *GuestUsecase) isSpam(req models.CreateGuestTicketRequest) bool {
+	// Bots tend to fill every field, including the hidden honeypot
+	if req.Website != "" {
+		return true
+	}
+
+	maxLinks := u.config.Guest.MaxLinks
+	if maxLinks > 0 && len(linkPattern.FindAllString(req.Subject+" "+req.Description, -1)) > maxLinks {
+		return true
+	}
+
+	return false
+}
+
+func (u *GuestUsecase) sendAccessLink(ctx context.Context, ticket *models.Ticket, token string) error {
+	ctx, span := tracing.Start(ctx, "GuestUsecase.sendAccessLink")
+	defer span.End()
+
+	// The token goes in the fragment, which browsers don't send to servers or
+	// in Referer headers; the portal moves it into the X-Guest-Token header
+	link := fmt.Sprintf("%s/%s#token=%s", strings.TrimRight(u.config.Guest.PortalURL, "/"), ticket.ID.Hex(), token)
+
+	return u.notifier.SendEmail(ctx, notifier.EmailRequest{
+		TenantID: ticket.TenantID,
+		To:       []string{ticket.CustomerEmail},
+		Subject:  fmt.Sprintf("Your support ticket #%s", ticket.TicketNumber),
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nWe received your request \"%s\".\n\nYou can view and reply to your ticket here:\n%s\n\nThis link expires in %d days.",
+			ticket.CustomerName, ticket.Subject, link, int(u.config.Guest.TokenTTL.Hours()/24),
+		),
+		Template: "ticket_guest_access",
+		Data: map[string]interface{}{
+			"ticket_number": ticket.TicketNumber,
+			"customer_name": ticket.CustomerName,
+			"subject":       ticket.Subject,
+			"link":          link,
+		},
+	})
+}
+
+func guestCustomerID(email string) string {
+	return "guest:" + email
+}
@@ -0,0 +1,786 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/minisource/ticket/config"
+	"github.com/minisource/ticket/internal/importer"
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/minisource/ticket/internal/search"
+	"github.com/minisource/ticket/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrImportNotFound is returned for import jobs that don't exist
+	ErrImportNotFound = errors.New("import not found")
+	// ErrImportInvalidState is returned when running or resuming a job
+	// that isn't in a state to be run or resumed
+	ErrImportInvalidState = errors.New("import can't be run in its current state")
+	// ErrImportDisabled is returned for uploads while the import worker is
+	// disabled
+	ErrImportDisabled = errors.New("imports are disabled")
+	// ErrInvalidImport wraps problems with an uploaded file or its options
+	ErrInvalidImport = errors.New("invalid import")
+
+	// errImportTakenOver stops a worker whose job went stale and was
+	// claimed by another one
+	errImportTakenOver = errors.New("import job was claimed by another worker")
+)
+
+const (
+	// importStaleAfter is how long a running job may go without saving
+	// progress before another worker resumes it, assuming the first stopped
+	importStaleAfter = 10 * time.Minute
+	// importBatch is how many records are read between progress saves
+	importBatch = 100
+	// importListLimit caps the jobs listed
+	importListLimit = 50
+)
+
+// importedNumber matches ticket numbers in this service's own format
+var importedNumber = regexp.MustCompile(`^TKT-(\d+)$`)
+
+// ImportUsecase handles ticket imports from other helpdesks. Uploaded files
+// are kept in GridFS, so whichever worker claims the job can read them, and
+// imported by the import worker.
+type ImportUsecase struct {
+	ticketRepo       *repository.TicketRepository
+	messageRepo      *repository.MessageRepository
+	historyRepo      *repository.HistoryRepository
+	agentRepo        *repository.AgentRepository
+	departmentRepo   *repository.DepartmentRepository
+	categoryRepo     *repository.CategoryRepository
+	importRepo       *repository.ImportRepository
+	fileRepo         *repository.FileRepository
+	reconcileUsecase *ReconcileUsecase
+	index            search.Index
+	cfg              config.ImportConfig
+}
+
+// NewImportUsecase creates a new import usecase
+func NewImportUsecase(
+	ticketRepo *repository.TicketRepository,
+	messageRepo *repository.MessageRepository,
+	historyRepo *repository.HistoryRepository,
+	agentRepo *repository.AgentRepository,
+	departmentRepo *repository.DepartmentRepository,
+	categoryRepo *repository.CategoryRepository,
+	importRepo *repository.ImportRepository,
+	fileRepo *repository.FileRepository,
+	reconcileUsecase *ReconcileUsecase,
+	index search.Index,
+	cfg config.ImportConfig,
+) *ImportUsecase {
+	return &ImportUsecase{
+		ticketRepo:       ticketRepo,
+		messageRepo:      messageRepo,
+		historyRepo:      historyRepo,
+		agentRepo:        agentRepo,
+		departmentRepo:   departmentRepo,
+		categoryRepo:     categoryRepo,
+		importRepo:       importRepo,
+		fileRepo:         fileRepo,
+		reconcileUsecase: reconcileUsecase,
+		index:            index,
+		cfg:              cfg,
+	}
+}
+
+// ParseImportFormat reads an import format by name, or from the file name's
+// extension when name is empty
+func ParseImportFormat(name, fileName string) (models.ImportFormat, error) {
+	if name == "" {
+		name = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileName)), ".")
+	}
+	switch format := models.ImportFormat(strings.ToLower(name)); format {
+	case models.ImportCSV, models.ImportJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("%w: unknown format %q; use csv or json", ErrInvalidImport, name)
+}
+
+// Create saves an uploaded file and queues its import. The job's tenant,
+// requester, file name, format and options are set by the caller.
+func (u *ImportUsecase) Create(ctx context.Context, job *models.ImportJob, file io.Reader) (*models.ImportJob, error) {
+	ctx, span := tracing.Start(ctx, "ImportUsecase.Create")
+	defer span.End()
+
+	if !u.cfg.Enabled {
+		return nil, ErrImportDisabled
+	}
+	if _, err := time.LoadLocation(job.Timezone); err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidImport, job.Timezone)
+	}
+
+	job.ID = primitive.NewObjectID()
+	job.Status = models.ImportPending
+	if err := u.saveFile(ctx, job, file); err != nil {
+		return nil, err
+	}
+
+	if err := u.importRepo.Create(ctx, job); err != nil {
+		_ = u.fileRepo.Delete(ctx, job.FileID)
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// saveFile stores an upload and checks that it can be read, so a wrong
+// format or missing columns fail the upload
+func (u *ImportUsecase) saveFile(ctx context.Context, job *models.ImportJob, file io.Reader) error {
+	limited := io.LimitReader(file, u.cfg.MaxSize+1)
+
+	id, size, err := u.fileRepo.Write(ctx, job.FileName, func(w io.Writer) error {
+		// The first record is read on the way through; whatever the reader
+		// buffered is stored with the rest
+		reader, err := importer.NewReader(io.TeeReader(limited, w), job.Format)
+		if err == nil {
+			_, err = reader.Next()
+		}
+		var recErr *importer.RecordError
+		if err != nil && err != io.EOF && !errors.As(err, &recErr) {
+			return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+
+		if _, err := io.Copy(w, limited); err != nil {
+			return fmt.Errorf("failed to save import file: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if size > u.cfg.MaxSize {
+		_ = u.fileRepo.Delete(ctx, id)
+		return fmt.Errorf("%w: file is larger than %d MB", ErrInvalidImport, u.cfg.MaxSize>>20)
+	}
+
+	job.FileID = id
+	return nil
+}
+
+// GetJob gets a tenant's import job
+func (u *ImportUsecase) GetJob(ctx context.Context, tenantID, id string) (*models.ImportJob, error) {
+	ctx, span := tracing.Start(ctx, "ImportUsecase.GetJob")
+	defer span.End()
+
+	jobID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrImportNotFound
+	}
+
+	job, err := u.importRepo.GetByID(ctx, tenantID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrImportNotFound
+	}
+
+	return job, nil
+}
+
+// ListJobs lists a tenant's latest import jobs
+func (u *ImportUsecase) ListJobs(ctx context.Context, tenantID string) ([]models.ImportJob, error) {
+	ctx, span := tracing.Start(ctx, "ImportUsecase.ListJobs")
+	defer span.End()
+
+	jobs, err := u.importRepo.List(ctx, tenantID, importListLimit)
+	if err != nil {
+		return nil, err
+	}
+	if jobs == nil {
+		jobs = []models.ImportJob{}
+	}
+
+	return jobs, nil
+}
+
+// Run queues a finished dry run to import for real, from the start
+func (u *ImportUsecase) Run(ctx context.Context, tenantID, id string) (*models.ImportJob, error) {
+	ctx, span := tracing.Start(ctx, "ImportUsecase.Run")
+	defer span.End()
+
+	job, err := u.GetJob(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !job.DryRun || job.Status != models.ImportDone {
+		return nil, ErrImportInvalidState
+	}
+
+	job.DryRun = false
+	job.Records, job.Imported, job.Skipped, job.Invalid, job.Warnings = 0, 0, 0, 0, 0
+	job.Issues = nil
+	job.HeartbeatAt = nil
+
+	return u.requeue(ctx, job, models.ImportDone)
+}
+
+// Resume queues a failed job to carry on after the last record it saved
+func (u *ImportUsecase) Resume(ctx context.Context, tenantID, id string) (*models.ImportJob, error) {
+	ctx, span := tracing.Start(ctx, "ImportUsecase.Resume")
+	defer span.End()
+
+	job, err := u.GetJob(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != models.ImportFailed {
+		return nil, ErrImportInvalidState
+	}
+
+	return u.requeue(ctx, job, models.ImportFailed)
+}
+
+func (u *ImportUsecase) requeue(ctx context.Context, job *models.ImportJob, from models.ImportStatus) (*models.ImportJob, error) {
+	if !u.cfg.Enabled {
+		return nil, ErrImportDisabled
+	}
+	file, err := u.fileRepo.Open(ctx, job.FileID)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, ErrImportInvalidState
+	}
+	file.Close()
+
+	ok, err := u.importRepo.Requeue(ctx, job, from)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// Another request got there first
+		return nil, ErrImportInvalidState
+	}
+
+	return job, nil
+}
+
+// RunNext runs the oldest queued import, if any, and reports whether there
+// was one. Failures are recorded on the job, which can then be resumed.
+func (u *ImportUsecase) RunNext(ctx context.Context) (bool, error) {
+	ctx, span := tracing.Start(ctx, "ImportUsecase.RunNext")
+	defer span.End()
+
+	job, err := u.importRepo.ClaimNext(ctx, importStaleAfter)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	err = u.process(ctx, job)
+	if ctx.Err() != nil {
+		// Shutting down; the job is resumed once it goes stale
+		return true, ctx.Err()
+	}
+	if errors.Is(err, errImportTakenOver) {
+		// The worker that claimed it finishes the job
+		return true, nil
+	}
+	if err != nil {
+		job.Status = models.ImportFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = models.ImportDone
+		job.Error = ""
+	}
+
+	finished, err := u.importRepo.Finish(ctx, job)
+	if err != nil || !finished {
+		return true, err
+	}
+
+	if job.Status == models.ImportDone && !job.DryRun {
+		// The file is only kept to run or resume the job
+		_ = u.fileRepo.Delete(ctx, job.FileID)
+	}
+	if !job.DryRun && job.Imported > 0 {
+		// Imported tickets bypass the agent and department counters
+		if _, err := u.reconcileUsecase.ReconcileTenant(ctx, job.TenantID); err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+// process reads the job's file from the first record it hasn't saved and
+// imports, or in a dry run checks, each record
+func (u *ImportUsecase) process(ctx context.Context, job *models.ImportJob) error {
+	loc, err := time.LoadLocation(job.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	file, err := u.fileRepo.Open(ctx, job.FileID)
+	if err != nil {
+		return err
+	}
+	if file == nil {
+		return errors.New("import file is gone")
+	}
+	defer file.Close()
+
+	reader, err := importer.NewReader(file, job.Format)
+	if err != nil {
+		return err
+	}
+
+	run, err := u.newImportRun(ctx, job, loc)
+	if err != nil {
+		return err
+	}
+
+	var record int64
+	for {
+		rec, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		var recErr *importer.RecordError
+		if err != nil && !errors.As(err, &recErr) {
+			return fmt.Errorf("failed to read import file: %w", err)
+		}
+
+		record++
+		if record <= job.Records {
+			continue // Done before the job was resumed
+		}
+
+		if err != nil {
+			job.Invalid++
+			run.report(record, "", []models.ImportIssue{{Message: err.Error()}})
+		} else if err := run.importRecord(ctx, record, rec); err != nil {
+			return err
+		}
+
+		job.Records = record
+		if record%importBatch == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			saved, err := u.importRepo.SaveProgress(ctx, job)
+			if err != nil {
+				return err
+			}
+			if !saved {
+				return errImportTakenOver
+			}
+		}
+	}
+
+	return nil
+}
+
+// importRun is the state of one pass over an import file
+type importRun struct {
+	u           *ImportUsecase
+	job         *models.ImportJob
+	loc         *time.Location
+	agents      map[string]*models.Agent // By email; nil for emails that aren't agents
+	departments []models.Department
+	categories  []models.Category
+}
+
+func (u *ImportUsecase) newImportRun(ctx context.Context, job *models.ImportJob, loc *time.Location) (*importRun, error) {
+	departments, err := u.departmentRepo.List(ctx, job.TenantID, false)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := u.categoryRepo.List(ctx, job.TenantID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &importRun{
+		u:           u,
+		job:         job,
+		loc:         loc,
+		agents:      map[string]*models.Agent{},
+		departments: departments,
+		categories:  categories,
+	}, nil
+}
+
+// importRecord imports one record, counting it on the job. Only failures
+// to read or write the database are returned.
+func (r *importRun) importRecord(ctx context.Context, record int64, rec *importer.Record) error {
+	job := r.job
+
+	t, issues := importer.Normalize(rec, r.loc)
+	externalID := rec.ID
+	if externalID == "" {
+		externalID = rec.TicketNumber
+	}
+	if t == nil {
+		job.Invalid++
+		r.report(record, externalID, issues)
+		return nil
+	}
+	if externalID == "" {
+		// Without an ID only this job recognizes the record when resumed
+		externalID = fmt.Sprintf("%s:%d", job.ID.Hex(), record)
+	}
+
+	existing, err := r.u.ticketRepo.GetImported(ctx, job.TenantID, externalID)
+	if err != nil {
+		return err
+	}
+	// A ticket this job wrote for this record before it stopped is redone
+	redo := existing != nil && existing.Import.JobID == job.ID && existing.Import.Record == record
+	if existing != nil && !redo {
+		job.Skipped++
+		return nil
+	}
+
+	id := primitive.NewObjectID()
+	if redo {
+		id = existing.ID
+	}
+	ticket, messages, err := r.build(ctx, id, t, &issues)
+	if err != nil {
+		return err
+	}
+
+	if err := r.number(ctx, ticket, t.TicketNumber, existing, &issues); err != nil {
+		return err
+	}
+	if hasImportErrors(issues) {
+		job.Invalid++
+		r.report(record, externalID, issues)
+		return nil
+	}
+
+	if !job.DryRun {
+		ticket.Import = &models.TicketImport{
+			JobID:      job.ID,
+			ExternalID: externalID,
+			Record:     record,
+			ImportedAt: time.Now(),
+		}
+		imported, err := r.write(ctx, ticket, messages, existing)
+		if err != nil {
+			return err
+		}
+		if !imported {
+			issues = append(issues, models.ImportIssue{Field: "ticket_number", Message: fmt.Sprintf("ticket number %s is taken", ticket.TicketNumber)})
+			job.Invalid++
+			r.report(record, externalID, issues)
+			return nil
+		}
+	}
+
+	job.Imported++
+	r.report(record, externalID, issues)
+	return nil
+}
+
+// build makes the ticket and its messages, mapping people, departments and
+// categories to this tenant's
+func (r *importRun) build(ctx context.Context, id primitive.ObjectID, t *importer.Ticket, issues *[]models.ImportIssue) (*models.Ticket, []models.TicketMessage, error) {
+	ticket := &models.Ticket{
+		ID:             id,
+		TenantID:       r.job.TenantID,
+		Version:        1,
+		Subject:        t.Subject,
+		Description:    t.Description,
+		Type:           t.Type,
+		Status:         t.Status,
+		Priority:       t.Priority,
+		Source:         t.Source,
+		CustomerID:     t.CustomerID,
+		CustomerName:   t.CustomerName,
+		CustomerEmail:  t.CustomerEmail,
+		IsGuest:        t.CustomerID == "",
+		Tags:           t.Tags,
+		CustomFields:   t.CustomFields,
+		Attachments:    importAttachments(t.Attachments, t.CustomerID, t.CreatedAt),
+		CreatedAt:      t.CreatedAt,
+		UpdatedAt:      t.UpdatedAt,
+		ResolvedAt:     t.ResolvedAt,
+		ClosedAt:       t.ClosedAt,
+		LastActivityAt: t.UpdatedAt,
+	}
+
+	if t.Rating != nil {
+		ticket.SatisfactionRating = t.Rating
+		ticket.RatedAt = &t.UpdatedAt
+		if t.ClosedAt != nil {
+			ticket.RatedAt = t.ClosedAt
+		}
+		if t.ResolvedAt != nil {
+			ticket.RatedAt = t.ResolvedAt
+		}
+	}
+
+	if t.AssigneeEmail != "" {
+		agent, err := r.agent(ctx, t.AssigneeEmail)
+		if err != nil {
+			return nil, nil, err
+		}
+		if agent != nil {
+			ticket.AssignedToID = agent.UserID
+			ticket.AssignedToName = agent.Name
+			ticket.AssignedToEmail = agent.Email
+		} else {
+			*issues = append(*issues, models.ImportIssue{Field: "assignee_email", Message: fmt.Sprintf("no agent has the email %s; left unassigned", t.AssigneeEmail), Warning: true})
+		}
+	}
+
+	if t.Department != "" {
+		if dept := r.department(t.Department); dept != nil {
+			ticket.DepartmentID = &dept.ID
+			ticket.DepartmentName = dept.Name
+		} else {
+			*issues = append(*issues, models.ImportIssue{Field: "department", Message: fmt.Sprintf("no department %q; left without one", t.Department), Warning: true})
+		}
+	}
+	if t.Category != "" {
+		if cat := r.category(t.Category); cat != nil {
+			ticket.CategoryID = &cat.ID
+			ticket.CategoryName = cat.Name
+		} else {
+			*issues = append(*issues, models.ImportIssue{Field: "category", Message: fmt.Sprintf("no category %q; left without one", t.Category), Warning: true})
+		}
+	}
+
+	messages := make([]models.TicketMessage, 0, len(t.Messages))
+	for _, m := range t.Messages {
+		message := models.TicketMessage{
+			TicketID:    ticket.ID,
+			TenantID:    ticket.TenantID,
+			Type:        models.MessageTypeReply,
+			Content:     m.Body,
+			SenderType:  m.AuthorType,
+			SenderName:  m.AuthorName,
+			SenderEmail: m.AuthorEmail,
+			IsPrivate:   m.Internal,
+			CreatedAt:   m.CreatedAt,
+		}
+		if m.Internal {
+			message.Type = models.MessageTypeInternalNote
+		}
+
+		// Authors are agents when their email is an agent's, unless the
+		// file says otherwise
+		var agent *models.Agent
+		if m.AuthorEmail != "" && m.AuthorType != models.SenderCustomer && m.AuthorType != models.SenderSystem {
+			var err error
+			if agent, err = r.agent(ctx, m.AuthorEmail); err != nil {
+				return nil, nil, err
+			}
+		}
+		switch {
+		case agent != nil:
+			message.SenderType = models.SenderAgent
+			message.SenderID = agent.UserID
+			if message.SenderName == "" {
+				message.SenderName = agent.Name
+			}
+		case message.SenderType == "" || message.SenderType == models.SenderCustomer:
+			message.SenderType = models.SenderCustomer
+			if m.AuthorEmail == "" || m.AuthorEmail == ticket.CustomerEmail {
+				message.SenderID = ticket.CustomerID
+				message.SenderEmail = ticket.CustomerEmail
+				if message.SenderName == "" {
+					message.SenderName = ticket.CustomerName
+				}
+			}
+		}
+		if message.SenderName == "" {
+			message.SenderName = message.SenderEmail
+		}
+		message.Attachments = importAttachments(m.Attachments, message.SenderID, m.CreatedAt)
+
+		if message.IsPrivate {
+			ticket.InternalNotes++
+		} else {
+			ticket.MessageCount++
+		}
+		switch {
+		case message.SenderType == models.SenderCustomer:
+			ticket.LastCustomerReplyAt = &message.CreatedAt
+		case message.SenderType == models.SenderAgent && !message.IsPrivate:
+			ticket.LastAgentReplyAt = &message.CreatedAt
+			if ticket.FirstResponsedAt == nil {
+				ticket.FirstResponsedAt = &message.CreatedAt
+			}
+		}
+
+		messages = append(messages, message)
+	}
+
+	return ticket, messages, nil
+}
+
+// number gives the ticket its number: the file's when keeping numbers, or
+// the next one of the tenant's sequence. Dry runs check numbers without
+// allocating any.
+func (r *importRun) number(ctx context.Context, ticket *models.Ticket, number string, existing *models.Ticket, issues *[]models.ImportIssue) error {
+	if existing != nil {
+		ticket.TicketNumber = existing.TicketNumber
+		return nil
+	}
+
+	if !r.job.KeepNumbers || number == "" {
+		if r.job.DryRun {
+			return nil
+		}
+		next, err := r.u.ticketRepo.GetNextTicketNumber(ctx, r.job.TenantID)
+		if err != nil {
+			return err
+		}
+		ticket.TicketNumber = next
+		return nil
+	}
+
+	taken, err := r.u.ticketRepo.GetByTicketNumber(ctx, r.job.TenantID, number)
+	if err != nil {
+		return err
+	}
+	if taken != nil {
+		*issues = append(*issues, models.ImportIssue{Field: "ticket_number", Message: fmt.Sprintf("ticket number %s is taken", number)})
+		return nil
+	}
+	ticket.TicketNumber = number
+
+	// Numbers in this service's format move the sequence past them, so
+	// tickets created later don't collide
+	if match := importedNumber.FindStringSubmatch(number); match != nil && !r.job.DryRun {
+		sequence, err := strconv.ParseInt(match[1], 10, 64)
+		if err == nil {
+			if err := r.u.ticketRepo.EnsureTicketSequence(ctx, r.job.TenantID, sequence); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// write stores a ticket with its messages and indexes them for search. It
+// returns false when the ticket number turned out to be taken.
+func (r *importRun) write(ctx context.Context, ticket *models.Ticket, messages []models.TicketMessage, existing *models.Ticket) (bool, error) {
+	u := r.u
+
+	if existing != nil {
+		// Messages may have been written only in part
+		if err := u.messageRepo.DeleteByTicketID(ctx, existing.ID); err != nil {
+			return false, err
+		}
+	} else {
+		if err := u.ticketRepo.Import(ctx, ticket); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		_ = u.historyRepo.Create(ctx, &models.TicketHistory{
+			TicketID:      ticket.ID,
+			TenantID:      ticket.TenantID,
+			Action:        "imported",
+			NewValue:      ticket.Import.ExternalID,
+			ChangedBy:     r.job.RequestedBy,
+			ChangedByName: r.job.RequestedName,
+			Comment:       "Imported from " + r.job.FileName,
+		})
+	}
+
+	if err := u.messageRepo.Import(ctx, messages); err != nil {
+		return false, err
+	}
+
+	_ = u.index.IndexTicket(ctx, ticket)
+	for i := range messages {
+		_ = u.index.IndexMessage(ctx, &messages[i])
+	}
+
+	return true, nil
+}
+
+// report counts a record's warnings and keeps its issues, up to the cap
+func (r *importRun) report(record int64, externalID string, issues []models.ImportIssue) {
+	for _, issue := range issues {
+		if issue.Warning {
+			r.job.Warnings++
+		}
+		if len(r.job.Issues) < models.MaxImportIssues {
+			issue.Record = record
+			issue.ExternalID = externalID
+			r.job.Issues = append(r.job.Issues, issue)
+		}
+	}
+}
+
+// agent finds the tenant's agent with an email, or nil
+func (r *importRun) agent(ctx context.Context, email string) (*models.Agent, error) {
+	if agent, ok := r.agents[email]; ok {
+		return agent, nil
+	}
+	agent, err := r.u.agentRepo.GetByEmail(ctx, r.job.TenantID, email)
+	if err != nil {
+		return nil, err
+	}
+	r.agents[email] = agent
+	return agent, nil
+}
+
+// department finds a department by name or slug, ignoring case
+func (r *importRun) department(name string) *models.Department {
+	for i := range r.departments {
+		if strings.EqualFold(r.departments[i].Name, name) || strings.EqualFold(r.departments[i].Slug, name) {
+			return &r.departments[i]
+		}
+	}
+	return nil
+}
+
+// category finds a category by name or slug, ignoring case
+func (r *importRun) category(name string) *models.Category {
+	for i := range r.categories {
+		if strings.EqualFold(r.categories[i].Name, name) || strings.EqualFold(r.categories[i].Slug, name) {
+			return &r.categories[i]
+		}
+	}
+	return nil
+}
+
+// hasImportErrors reports whether any of a record's issues stop it from
+// being imported
+func hasImportErrors(issues []models.ImportIssue) bool {
+	for _, issue := range issues {
+		if !issue.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+// importAttachments links to attachments where the source helpdesk keeps
+// them
+func importAttachments(in []models.Attachment, uploadedBy string, at time.Time) []models.Attachment {
+	out := make([]models.Attachment, 0, len(in))
+	for _, att := range in {
+		att.ID = uuid.New().String()
+		att.UploadedBy = uploadedBy
+		att.UploadedAt = at
+		out = append(out, att)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/minisource/ticket/internal/tracing"
+)
+
+// PresenceUsecase tracks agent heartbeats and moves idle agents to away,
+// then offline
+type PresenceUsecase struct {
+	agentRepo    *repository.AgentRepository
+	awayAfter    time.Duration
+	offlineAfter time.Duration
+}
+
+// NewPresenceUsecase creates a new presence usecase
+func NewPresenceUsecase(agentRepo *repository.AgentRepository, awayAfter, offlineAfter time.Duration) *PresenceUsecase {
+	return &PresenceUsecase{
+		agentRepo:    agentRepo,
+		awayAfter:    awayAfter,
+		offlineAfter: offlineAfter,
+	}
+}
+
+// Heartbeat records that the agent is active. It returns nil if the user
+// is not an agent in the tenant.
+func (u *PresenceUsecase) Heartbeat(ctx context.Context, tenantID, userID string) (*models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "PresenceUsecase.Heartbeat")
+	defer span.End()
+
+	return u.agentRepo.Heartbeat(ctx, tenantID, userID)
+}
+
+// Sweep marks agents idle past the away threshold as away and those idle
+// past the offline threshold as offline. Breaks, leave and manual away are
+// left alone until the offline threshold. It returns the number of agents
+// changed.
+func (u *PresenceUsecase) Sweep(ctx context.Context, now time.Time) (int64, error) {
+	ctx, span := tracing.Start(ctx, "PresenceUsecase.Sweep")
+	defer span.End()
+
+	offline, err := u.agentRepo.MarkIdle(ctx, now.Add(-u.offlineAfter), []models.AgentStatus{
+		models.AgentStatusAvailable,
+		models.AgentStatusBusy,
+		models.AgentStatusAway,
+		models.AgentStatusOnBreak,
+	}, models.AgentStatusOffline)
+	if err != nil {
+		return 0, err
+	}
+
+	away, err := u.agentRepo.MarkIdle(ctx, now.Add(-u.awayAfter), []models.AgentStatus{
+		models.AgentStatusAvailable,
+		models.AgentStatusBusy,
+	}, models.AgentStatusAway)
+	if err != nil {
+		return offline, err
+	}
+
+	return offline + away, nil
+}
@@ -0,0 +1,263 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/minisource/ticket/internal/tracing"
+)
+
+// ReconcileUsecase recomputes denormalized agent and department counters
+// from the tickets collection. The counters are maintained by best-effort
+// increments elsewhere, so they drift; this corrects them.
+type ReconcileUsecase struct {
+	ticketRepo     *repository.TicketRepository
+	departmentRepo *repository.DepartmentRepository
+	agentRepo      *repository.AgentRepository
+	leaseRepo      *repository.LeaseRepository
+}
+
+// NewReconcileUsecase creates a new reconcile usecase
+func NewReconcileUsecase(
+	ticketRepo *repository.TicketRepository,
+	departmentRepo *repository.DepartmentRepository,
+	agentRepo *repository.AgentRepository,
+	leaseRepo *repository.LeaseRepository,
+) *ReconcileUsecase {
+	return &ReconcileUsecase{
+		ticketRepo:     ticketRepo,
+		departmentRepo: departmentRepo,
+		agentRepo:      agentRepo,
+		leaseRepo:      leaseRepo,
+	}
+}
+
+// ReconcileTenant recomputes counters for one tenant and reports what changed
+func (u *ReconcileUsecase) ReconcileTenant(ctx context.Context, tenantID string) (*models.ReconcileReport, error) {
+	ctx, span := tracing.Start(ctx, "ReconcileUsecase.ReconcileTenant")
+	defer span.End()
+
+	report := &models.ReconcileReport{
+		TenantID:    tenantID,
+		Corrections: []models.StatCorrection{},
+		StartedAt:   time.Now(),
+	}
+
+	if err := u.reconcileAgents(ctx, report); err != nil {
+		return nil, err
+	}
+	if err := u.reconcileDepartments(ctx, report); err != nil {
+		return nil, err
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// ReconcileAll reconciles every tenant that has agents or departments
+func (u *ReconcileUsecase) ReconcileAll(ctx context.Context) ([]models.ReconcileReport, error) {
+	ctx, span := tracing.Start(ctx, "ReconcileUsecase.ReconcileAll")
+	defer span.End()
+
+	agentTenants, err := u.agentRepo.ListTenantIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	departmentTenants, err := u.departmentRepo.ListTenantIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var reports []models.ReconcileReport
+	for _, tenantID := range append(agentTenants, departmentTenants...) {
+		if seen[tenantID] {
+			continue
+		}
+		seen[tenantID] = true
+
+		report, err := u.ReconcileTenant(ctx, tenantID)
+		if err != nil {
+			return reports, err
+		}
+		reports = append(reports, *report)
+	}
+
+	return reports, nil
+}
+
+// ResetRollingWindows zeroes the agent counters whose window starts at now:
+// daily always, weekly on Monday and monthly on the first. Windows follow UTC
+// for every tenant, whatever its timezone, matching how reconciliation counts
+// them. Every instance calls this at midnight; a lease lets only one reset.
+func (u *ReconcileUsecase) ResetRollingWindows(ctx context.Context, now time.Time) error {
+	ctx, span := tracing.Start(ctx, "ReconcileUsecase.ResetRollingWindows")
+	defer span.End()
+
+	now = now.UTC()
+	dayStart, _, _ := windowStarts(now)
+	claimed, err := u.leaseRepo.Claim(ctx, "reset_rolling_windows", dayStart)
+	if err != nil || !claimed {
+		return err
+	}
+
+	fields := []string{"tickets_today"}
+	if now.Weekday() == time.Monday {
+		fields = append(fields, "tickets_this_week")
+	}
+	if now.Day() == 1 {
+		fields = append(fields, "tickets_this_month")
+	}
+
+	return u.agentRepo.ResetCounters(ctx, fields...)
+}
+
+// reconcileAgents reads agents before aggregating tickets, so an assignment
+// landing in between changes the stored counter and the guarded write skips it
+func (u *ReconcileUsecase) reconcileAgents(ctx context.Context, report *models.ReconcileReport) error {
+	agents, err := u.agentRepo.List(ctx, report.TenantID, false)
+	if err != nil {
+		return err
+	}
+
+	dayStart, weekStart, monthStart := windowStarts(report.StartedAt)
+	stats, err := u.ticketRepo.AgentStats(ctx, report.TenantID, dayStart, weekStart, monthStart)
+	if err != nil {
+		return err
+	}
+
+	byUser := make(map[string]models.AgentTicketStats, len(stats))
+	for _, s := range stats {
+		byUser[s.UserID] = s
+	}
+
+	for _, agent := range agents {
+		report.AgentsChecked++
+
+		s := byUser[agent.UserID]
+		current := map[string]int64{
+			"current_tickets":    int64(agent.CurrentTickets),
+			"tickets_today":      int64(agent.TicketsToday),
+			"tickets_this_week":  int64(agent.TicketsThisWeek),
+			"tickets_this_month": int64(agent.TicketsThisMonth),
+			"avg_response_time":  agent.AvgResponseTime,
+		}
+		actual := map[string]int64{
+			"current_tickets":    int64(s.CurrentTickets),
+			"tickets_today":      int64(s.TicketsToday),
+			"tickets_this_week":  int64(s.TicketsThisWeek),
+			"tickets_this_month": int64(s.TicketsThisMonth),
+			"avg_response_time":  s.AvgResponseTime,
+		}
+
+		oldValues, newValues := counterDiff(current, actual)
+		if len(newValues) == 0 {
+			continue
+		}
+
+		updated, err := u.agentRepo.SetCounters(ctx, agent.ID, oldValues, newValues)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			report.Skipped++
+			continue
+		}
+
+		report.Corrections = append(report.Corrections, corrections("agent", agent.ID.Hex(), agent.Name, oldValues, newValues)...)
+	}
+
+	return nil
+}
+
+func (u *ReconcileUsecase) reconcileDepartments(ctx context.Context, report *models.ReconcileReport) error {
+	departments, err := u.departmentRepo.List(ctx, report.TenantID, false)
+	if err != nil {
+		return err
+	}
+
+	stats, err := u.ticketRepo.DepartmentStats(ctx, report.TenantID)
+	if err != nil {
+		return err
+	}
+
+	byDepartment := make(map[string]models.DepartmentTicketStats, len(stats))
+	for _, s := range stats {
+		byDepartment[s.DepartmentID.Hex()] = s
+	}
+
+	for _, department := range departments {
+		report.DepartmentsChecked++
+
+		s := byDepartment[department.ID.Hex()]
+		current := map[string]int64{
+			"open_tickets":      int64(department.OpenTickets),
+			"total_tickets":     int64(department.TotalTickets),
+			"avg_response_time": department.AvgResponseTime,
+		}
+		actual := map[string]int64{
+			"open_tickets":      int64(s.OpenTickets),
+			"total_tickets":     int64(s.TotalTickets),
+			"avg_response_time": s.AvgResponseTime,
+		}
+
+		oldValues, newValues := counterDiff(current, actual)
+		if len(newValues) == 0 {
+			continue
+		}
+
+		updated, err := u.departmentRepo.SetCounters(ctx, department.ID, oldValues, newValues)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			report.Skipped++
+			continue
+		}
+
+		report.Corrections = append(report.Corrections, corrections("department", department.ID.Hex(), department.Name, oldValues, newValues)...)
+	}
+
+	return nil
+}
+
+// windowStarts returns the UTC start of the day, ISO week and month containing t
+func windowStarts(t time.Time) (day, week, month time.Time) {
+	t = t.UTC()
+	day = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	week = day.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+	month = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return day, week, month
+}
+
+// counterDiff returns the stored and recomputed values of fields that differ
+func counterDiff(current, actual map[string]int64) (oldValues, newValues map[string]int64) {
+	oldValues = make(map[string]int64)
+	newValues = make(map[string]int64)
+	for field, value := range actual {
+		if current[field] != value {
+			oldValues[field] = current[field]
+			newValues[field] = value
+		}
+	}
+	return oldValues, newValues
+}
+
+func corrections(entity, id, name string, oldValues, newValues map[string]int64) []models.StatCorrection {
+	result := make([]models.StatCorrection, 0, len(newValues))
+	for field, value := range newValues {
+		result = append(result, models.StatCorrection{
+			Entity:   entity,
+			ID:       id,
+			Name:     name,
+			Field:    field,
+			OldValue: oldValues[field],
+			NewValue: value,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Field < result[j].Field })
+	return result
+}
@@ -0,0 +1,230 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/report"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/minisource/ticket/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrInvalidReport is returned for report requests that can't be run
+	ErrInvalidReport = errors.New("invalid report")
+	// ErrAgentNotFound is returned for scorecards of an unknown agent
+	ErrAgentNotFound = errors.New("agent not found")
+)
+
+// A report may span at most this many periods, e.g. a year of days
+const maxReportPeriods = 366
+
+// defaultReportPeriods is how far back a report reaches without a start
+var defaultReportPeriods = map[models.ReportInterval]int{
+	models.IntervalDay:   30,
+	models.IntervalWeek:  12,
+	models.IntervalMonth: 12,
+}
+
+// Scorecards cover 30 days by default and a year at most
+const (
+	defaultScorecardPeriod = 30 * 24 * time.Hour
+	maxScorecardPeriod     = 366 * 24 * time.Hour
+)
+
+// ReportUsecase handles ticket reports
+type ReportUsecase struct {
+	reportRepo *repository.ReportRepository
+	agentRepo  *repository.AgentRepository
+}
+
+// NewReportUsecase creates a new report usecase
+func NewReportUsecase(
+	reportRepo *repository.ReportRepository,
+	agentRepo *repository.AgentRepository,
+) *ReportUsecase {
+	return &ReportUsecase{
+		reportRepo: reportRepo,
+		agentRepo:  agentRepo,
+	}
+}
+
+// TicketTrends reports ticket metrics per period. Without an end the report
+// runs until now, and without a start it covers the last 30 days, 12 weeks or
+// 12 months, depending on the interval.
+func (u *ReportUsecase) TicketTrends(ctx context.Context, tenantID string, req models.ReportRequest) (*models.TicketTrendReport, error) {
+	ctx, span := tracing.Start(ctx, "ReportUsecase.TicketTrends")
+	defer span.End()
+
+	if err := normalizeReport(&req, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return u.reportRepo.TicketTrends(ctx, tenantID, req)
+}
+
+// normalizeReport validates a report request and fills in defaults
+func normalizeReport(req *models.ReportRequest, now time.Time) error {
+	if req.Location == nil {
+		req.Location = time.UTC
+	}
+	if req.Interval == "" {
+		req.Interval = models.IntervalDay
+	}
+	periods, ok := defaultReportPeriods[req.Interval]
+	if !ok {
+		return fmt.Errorf("%w: interval must be day, week or month", ErrInvalidReport)
+	}
+
+	switch req.GroupBy {
+	case models.GroupByNone, models.GroupByDepartment, models.GroupByAgent,
+		models.GroupByCategory, models.GroupByChannel, models.GroupByPriority:
+	default:
+		return fmt.Errorf("%w: cannot group by %q", ErrInvalidReport, req.GroupBy)
+	}
+
+	if req.To.IsZero() {
+		req.To = now
+	}
+	if req.From.IsZero() {
+		req.From = report.Truncate(req.To, req.Interval, req.Location)
+		for n := 1; n < periods; n++ {
+			req.From = report.Truncate(req.From.Add(-time.Nanosecond), req.Interval, req.Location)
+		}
+	}
+	if !req.From.Before(req.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidReport)
+	}
+	p := report.Truncate(req.From, req.Interval, req.Location)
+	for n := 0; p.Before(req.To); n++ {
+		if n == maxReportPeriods {
+			return fmt.Errorf("%w: reports cover at most %d periods", ErrInvalidReport, maxReportPeriods)
+		}
+		p = report.Next(p, req.Interval, req.Location)
+	}
+
+	return nil
+}
+
+// AgentScorecards ranks agents' scorecards by req.SortBy, best first, for
+// the tenant's agents, a department's active agents or a single agent.
+// Without an end the period runs until now, and without a start it covers
+// 30 days.
+func (u *ReportUsecase) AgentScorecards(ctx context.Context, tenantID string, req models.ScorecardRequest) (*models.AgentScorecardReport, error) {
+	ctx, span := tracing.Start(ctx, "ReportUsecase.AgentScorecards")
+	defer span.End()
+
+	if req.SortBy == "" {
+		req.SortBy = models.ScorecardByResolved
+	}
+	better, ok := scorecardOrder[req.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalidReport, req.SortBy)
+	}
+	if req.To.IsZero() {
+		req.To = time.Now()
+	}
+	if req.From.IsZero() {
+		req.From = req.To.Add(-defaultScorecardPeriod)
+	}
+	if !req.From.Before(req.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidReport)
+	}
+	if req.To.Sub(req.From) > maxScorecardPeriod {
+		return nil, fmt.Errorf("%w: scorecards cover at most 366 days", ErrInvalidReport)
+	}
+
+	var agents []models.Agent
+	switch {
+	case req.AgentID != "":
+		agent, err := u.agentRepo.GetByUserID(ctx, tenantID, req.AgentID)
+		if err != nil {
+			return nil, err
+		}
+		if agent == nil {
+			return nil, ErrAgentNotFound
+		}
+		agents = []models.Agent{*agent}
+	case req.DepartmentID != "":
+		deptID, err := primitive.ObjectIDFromHex(req.DepartmentID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid department ID", ErrInvalidReport)
+		}
+		if agents, err = u.agentRepo.GetByDepartmentID(ctx, tenantID, deptID); err != nil {
+			return nil, err
+		}
+	default:
+		var err error
+		if agents, err = u.agentRepo.List(ctx, tenantID, false); err != nil {
+			return nil, err
+		}
+	}
+
+	userIDs := make([]string, len(agents))
+	for n, agent := range agents {
+		userIDs[n] = agent.UserID
+	}
+	cards, err := u.reportRepo.AgentScorecards(ctx, tenantID, req.From, req.To, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.AgentScorecardReport{
+		From:   req.From,
+		To:     req.To,
+		SortBy: req.SortBy,
+		Agents: make([]models.AgentScorecard, 0, len(agents)),
+	}
+	for _, agent := range agents {
+		card := cards[agent.UserID]
+		card.AgentID = agent.ID.Hex()
+		card.Name = agent.Name
+		report.Agents = append(report.Agents, *card)
+	}
+
+	// Agents are listed by name, which breaks ties
+	sort.SliceStable(report.Agents, func(i, j int) bool {
+		return better(&report.Agents[i], &report.Agents[j])
+	})
+	for n := range report.Agents {
+		report.Agents[n].Rank = n + 1
+	}
+	if req.Limit > 0 && len(report.Agents) > req.Limit {
+		report.Agents = report.Agents[:req.Limit]
+	}
+
+	return report, nil
+}
+
+// scorecardOrder reports whether a ranks above b for each leaderboard sort
+var scorecardOrder = map[models.ScorecardSort]func(a, b *models.AgentScorecard) bool{
+	models.ScorecardByResolved: func(a, b *models.AgentScorecard) bool { return a.Resolved > b.Resolved },
+	models.ScorecardByHandled:  func(a, b *models.AgentScorecard) bool { return a.Handled > b.Handled },
+	models.ScorecardByFirstResponse: func(a, b *models.AgentScorecard) bool {
+		return rankRatio(a.FirstResponseMedian, b.FirstResponseMedian, false)
+	},
+	models.ScorecardBySLACompliance: func(a, b *models.AgentScorecard) bool {
+		return rankRatio(a.SLACompliance, b.SLACompliance, true)
+	},
+	models.ScorecardByCSAT: func(a, b *models.AgentScorecard) bool { return rankRatio(a.CSAT, b.CSAT, true) },
+	models.ScorecardByReopenRate: func(a, b *models.AgentScorecard) bool {
+		return rankRatio(a.ReopenRate, b.ReopenRate, false)
+	},
+}
+
+// rankRatio reports whether a ranks above b, with missing values last
+func rankRatio(a, b *float64, higherIsBetter bool) bool {
+	switch {
+	case a == nil || b == nil:
+		return a != nil && b == nil
+	case higherIsBetter:
+		return *a > *b
+	default:
+		return *a < *b
+	}
+}
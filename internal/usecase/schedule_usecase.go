@@ -0,0 +1,267 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/minisource/ticket/internal/schedule"
+	"github.com/minisource/ticket/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ScheduleUsecase handles agent shifts and out-of-office periods
+type ScheduleUsecase struct {
+	agentRepo   *repository.AgentRepository
+	ticketRepo  *repository.TicketRepository
+	historyRepo *repository.HistoryRepository
+}
+
+// NewScheduleUsecase creates a new schedule usecase
+func NewScheduleUsecase(
+	agentRepo *repository.AgentRepository,
+	ticketRepo *repository.TicketRepository,
+	historyRepo *repository.HistoryRepository,
+) *ScheduleUsecase {
+	return &ScheduleUsecase{
+		agentRepo:   agentRepo,
+		ticketRepo:  ticketRepo,
+		historyRepo: historyRepo,
+	}
+}
+
+// UpdateSchedule sets an agent's weekly shifts
+func (u *ScheduleUsecase) UpdateSchedule(ctx context.Context, id string, req models.UpdateAgentScheduleRequest) (*models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "ScheduleUsecase.UpdateSchedule")
+	defer span.End()
+
+	agent, err := u.getAgent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := schedule.ValidateShifts(req.Shifts); err != nil {
+		return nil, err
+	}
+	if req.Timezone != nil {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil {
+			return nil, errors.New("invalid timezone")
+		}
+		agent.Preferences.Timezone = *req.Timezone
+	}
+
+	agent.ScheduleEnabled = req.Enabled
+	agent.Shifts = req.Shifts
+
+	if err := u.agentRepo.Update(ctx, agent); err != nil {
+		return nil, err
+	}
+
+	return agent, nil
+}
+
+// AddOutOfOffice schedules a leave period for an agent
+func (u *ScheduleUsecase) AddOutOfOffice(ctx context.Context, id string, req models.CreateOutOfOfficeRequest) (*models.Agent, error) {
+	ctx, span := tracing.Start(ctx, "ScheduleUsecase.AddOutOfOffice")
+	defer span.End()
+
+	agent, err := u.getAgent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.End.After(req.Start) {
+		return nil, errors.New("end must be after start")
+	}
+	if !req.End.After(time.Now()) {
+		return nil, errors.New("out-of-office period has already ended")
+	}
+
+	action := req.TicketAction
+	if action == "" {
+		action = models.LeaveTicketsHold
+	}
+	if action != models.LeaveTicketsHold && action != models.LeaveTicketsReassign {
+		return nil, errors.New("invalid ticket action")
+	}
+
+	period := models.OutOfOffice{
+		ID:           primitive.NewObjectID(),
+		Start:        req.Start,
+		End:          req.End,
+		Reason:       req.Reason,
+		TicketAction: action,
+	}
+
+	if req.DelegateID != "" {
+		if req.DelegateID == agent.UserID {
+			return nil, errors.New("agent cannot delegate to themselves")
+		}
+		delegate, err := u.agentRepo.GetByUserID(ctx, agent.TenantID, req.DelegateID)
+		if err != nil {
+			return nil, err
+		}
+		if delegate == nil {
+			return nil, errors.New("delegate not found")
+		}
+		period.DelegateID = delegate.UserID
+		period.DelegateName = delegate.Name
+	}
+
+	if err := u.agentRepo.AddOutOfOffice(ctx, agent.ID, period); err != nil {
+		return nil, err
+	}
+
+	agent.OutOfOffice = append(agent.OutOfOffice, period)
+	return agent, nil
+}
+
+// RemoveOutOfOffice cancels a leave period; an agent on that leave returns
+// on the scheduler's next run
+func (u *ScheduleUsecase) RemoveOutOfOffice(ctx context.Context, id, periodID string) error {
+	ctx, span := tracing.Start(ctx, "ScheduleUsecase.RemoveOutOfOffice")
+	defer span.End()
+
+	agent, err := u.getAgent(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	objID, err := primitive.ObjectIDFromHex(periodID)
+	if err != nil {
+		return errors.New("invalid out-of-office ID")
+	}
+
+	return u.agentRepo.RemoveOutOfOffice(ctx, agent.ID, objID)
+}
+
+// ApplySchedules moves agents to the status their shifts and leave call for
+// and runs the ticket action of leave periods that have started. It returns
+// the number of status transitions.
+func (u *ScheduleUsecase) ApplySchedules(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "ScheduleUsecase.ApplySchedules")
+	defer span.End()
+
+	agents, err := u.agentRepo.ListScheduled(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	transitions := 0
+	for i := range agents {
+		agent := &agents[i]
+
+		if leave := schedule.ActiveLeave(agent, now); leave != nil && !leave.Applied {
+			u.applyLeave(ctx, agent, leave)
+		}
+
+		// Only act on boundaries, so a manual status change holds until the next one
+		status := schedule.StatusAt(agent, now)
+		if status == agent.ScheduleStatus {
+			continue
+		}
+		// Agents without shifts are only moved into leave and back out of it
+		if !agent.ScheduleEnabled && status == models.AgentStatusAvailable && agent.ScheduleStatus == "" {
+			continue
+		}
+
+		applied, err := u.agentRepo.ApplyScheduleStatus(ctx, agent.ID, agent.ScheduleStatus, status)
+		if err != nil {
+			return transitions, err
+		}
+		if applied {
+			transitions++
+		}
+	}
+
+	return transitions, nil
+}
+
+// applyLeave runs a leave period's ticket action once across instances
+func (u *ScheduleUsecase) applyLeave(ctx context.Context, agent *models.Agent, leave *models.OutOfOffice) {
+	ctx, span := tracing.Start(ctx, "ScheduleUsecase.applyLeave")
+	defer span.End()
+
+	claimed, err := u.agentRepo.MarkLeaveApplied(ctx, agent.ID, leave.ID)
+	if err != nil || !claimed || leave.TicketAction != models.LeaveTicketsReassign {
+		return
+	}
+
+	tickets, err := u.ticketRepo.ListOpenByAssignee(ctx, agent.TenantID, agent.UserID)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+
+	var delegate *models.Agent
+	if leave.DelegateID != "" {
+		delegate, _ = u.agentRepo.GetByUserID(ctx, agent.TenantID, leave.DelegateID)
+		if delegate != nil && schedule.ActiveLeave(delegate, now) != nil {
+			delegate = nil
+		}
+	}
+
+	for _, ticket := range tickets {
+		// Hand over to the delegate while they have capacity, otherwise back to the queue
+		updates := bson.M{
+			"assigned_to_id":    "",
+			"assigned_to_name":  "",
+			"assigned_to_email": "",
+			"assigned_at":       nil,
+		}
+		newAssignee := ""
+		if delegate != nil {
+			if reserved, _ := u.agentRepo.ReserveTicketSlot(ctx, delegate.ID); reserved != nil {
+				updates = bson.M{
+					"assigned_to_id":    delegate.UserID,
+					"assigned_to_name":  delegate.Name,
+					"assigned_to_email": delegate.Email,
+					"assigned_at":       now,
+					"assigned_by_id":    "system",
+				}
+				newAssignee = delegate.Name
+			}
+		}
+
+		if err := u.ticketRepo.UpdateFields(ctx, ticket.ID, updates); err != nil {
+			if newAssignee != "" {
+				_ = u.agentRepo.ReleaseTicketSlot(ctx, delegate.ID)
+			}
+			continue
+		}
+		_ = u.agentRepo.DecrementTicketCount(ctx, agent.ID)
+
+		_ = u.historyRepo.Create(ctx, &models.TicketHistory{
+			TicketID:      ticket.ID,
+			TenantID:      ticket.TenantID,
+			Action:        "reassigned",
+			Field:         "assignee",
+			OldValue:      agent.Name,
+			NewValue:      newAssignee,
+			ChangedBy:     "system",
+			ChangedByName: "System",
+			Comment:       "Assignee is out of office",
+		})
+	}
+}
+
+func (u *ScheduleUsecase) getAgent(ctx context.Context, id string) (*models.Agent, error) {
+	agentID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid agent ID")
+	}
+
+	agent, err := u.agentRepo.GetByID(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if agent == nil {
+		return nil, errors.New("agent not found")
+	}
+
+	return agent, nil
+}
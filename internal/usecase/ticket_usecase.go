@@ -3,15 +3,39 @@ package usecase
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/minisource/ticket/config"
+	"github.com/minisource/ticket/internal/assignment"
+	"github.com/minisource/ticket/internal/collision"
+	"github.com/minisource/ticket/internal/metrics"
 	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/pagination"
+	"github.com/minisource/ticket/internal/query"
+	"github.com/minisource/ticket/internal/realtime"
 	"github.com/minisource/ticket/internal/repository"
+	"github.com/minisource/ticket/internal/search"
+	"github.com/minisource/ticket/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+var (
+	// ErrNewerMessages is returned when a reply is based on a stale view of the ticket
+	ErrNewerMessages = errors.New("ticket has newer messages than the last one seen")
+	// ErrConflict is returned when the ticket changed between reading and writing it
+	ErrConflict = repository.ErrVersionConflict
+	// ErrInvalidSort is returned for ticket list sorts on fields that can't be sorted by
+	ErrInvalidSort = repository.ErrInvalidSort
+	// ErrInvalidCursor is returned for list cursors that are malformed or from another sort
+	ErrInvalidCursor = pagination.ErrInvalidCursor
+)
+
+// Approximate length of a highlighted search snippet, in characters
+const snippetWidth = 160
+
 // TicketUsecase handles ticket business logic
 type TicketUsecase struct {
 	ticketRepo     *repository.TicketRepository
@@ -21,6 +45,10 @@ type TicketUsecase struct {
 	categoryRepo   *repository.CategoryRepository
 	agentRepo      *repository.AgentRepository
 	slaRepo        *repository.SLAPolicyRepository
+	assigner       *assignment.Assigner
+	hub            realtime.Hub
+	tracker        *collision.Tracker
+	index          search.Index
 	config         *config.Config
 }
 
@@ -33,6 +61,10 @@ func NewTicketUsecase(
 	categoryRepo *repository.CategoryRepository,
 	agentRepo *repository.AgentRepository,
 	slaRepo *repository.SLAPolicyRepository,
+	assigner *assignment.Assigner,
+	hub realtime.Hub,
+	tracker *collision.Tracker,
+	index search.Index,
 	cfg *config.Config,
 ) *TicketUsecase {
 	return &TicketUsecase{
@@ -43,12 +75,36 @@ func NewTicketUsecase(
 		categoryRepo:   categoryRepo,
 		agentRepo:      agentRepo,
 		slaRepo:        slaRepo,
+		assigner:       assigner,
+		hub:            hub,
+		tracker:        tracker,
+		index:          index,
 		config:         cfg,
 	}
 }
 
+// ticketCustomer describes who a new ticket is created for
+type ticketCustomer struct {
+	ID      string
+	Name    string
+	Email   string
+	Phone   string
+	IsGuest bool
+}
+
 // CreateTicket creates a new ticket
 func (u *TicketUsecase) CreateTicket(ctx context.Context, req models.CreateTicketRequest, customerID, customerName, customerEmail, ip, userAgent string) (*models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.CreateTicket")
+	defer span.End()
+
+	return u.createTicket(ctx, req, ticketCustomer{
+		ID:    customerID,
+		Name:  customerName,
+		Email: customerEmail,
+	}, ip, userAgent)
+}
+
+func (u *TicketUsecase) createTicket(ctx context.Context, req models.CreateTicketRequest, customer ticketCustomer, ip, userAgent string) (*models.Ticket, error) {
 	// Validate required fields
 	if req.Subject == "" {
 		return nil, errors.New("subject is required")
@@ -83,9 +139,12 @@ func (u *TicketUsecase) CreateTicket(ctx context.Context, req models.CreateTicke
 		Status:        models.StatusOpen,
 		Priority:      req.Priority,
 		Source:        req.Source,
-		CustomerID:    customerID,
-		CustomerName:  customerName,
-		CustomerEmail: customerEmail,
+		Language:      req.Language,
+		CustomerID:    customer.ID,
+		CustomerName:  customer.Name,
+		CustomerEmail: customer.Email,
+		CustomerPhone: customer.Phone,
+		IsGuest:       customer.IsGuest,
 		Tags:          req.Tags,
 		CustomFields:  req.CustomFields,
 		CCEmails:      req.CCEmails,
@@ -127,7 +186,7 @@ func (u *TicketUsecase) CreateTicket(ctx context.Context, req models.CreateTicke
 			URL:        att.URL,
 			Size:       att.Size,
 			MimeType:   att.MimeType,
-			UploadedBy: customerID,
+			UploadedBy: customer.ID,
 			UploadedAt: time.Now(),
 		})
 	}
@@ -139,6 +198,7 @@ func (u *TicketUsecase) CreateTicket(ctx context.Context, req models.CreateTicke
 	if err := u.ticketRepo.Create(ctx, ticket); err != nil {
 		return nil, err
 	}
+	metrics.TicketsCreated.WithLabelValues(ticket.TenantID, string(ticket.Source)).Inc()
 
 	// Update department stats
 	if ticket.DepartmentID != nil {
@@ -146,18 +206,23 @@ func (u *TicketUsecase) CreateTicket(ctx context.Context, req models.CreateTicke
 	}
 
 	// Create history entry
-	u.createHistory(ctx, ticket.ID, req.TenantID, "created", "", nil, nil, customerID, customerName, "")
+	u.createHistory(ctx, ticket.ID, req.TenantID, "created", "", nil, nil, customer.ID, customer.Name, "")
 
 	// Auto-assign if enabled
 	if u.config.Ticket.AutoAssignEnabled && ticket.DepartmentID != nil {
 		u.autoAssign(ctx, ticket)
 	}
 
+	u.publish(ctx, realtime.EventTicketCreated, ticket, customer.ID, customer.Name)
+
 	return ticket, nil
 }
 
 // GetTicket gets a ticket by ID
 func (u *TicketUsecase) GetTicket(ctx context.Context, id string) (*models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.GetTicket")
+	defer span.End()
+
 	ticketID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, errors.New("invalid ticket ID")
@@ -176,6 +241,9 @@ func (u *TicketUsecase) GetTicket(ctx context.Context, id string) (*models.Ticke
 
 // GetTicketByNumber gets a ticket by ticket number
 func (u *TicketUsecase) GetTicketByNumber(ctx context.Context, tenantID, ticketNumber string) (*models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.GetTicketByNumber")
+	defer span.End()
+
 	ticket, err := u.ticketRepo.GetByTicketNumber(ctx, tenantID, ticketNumber)
 	if err != nil {
 		return nil, err
@@ -189,6 +257,9 @@ func (u *TicketUsecase) GetTicketByNumber(ctx context.Context, tenantID, ticketN
 
 // UpdateTicket updates a ticket
 func (u *TicketUsecase) UpdateTicket(ctx context.Context, id string, req models.UpdateTicketRequest, userID, userName string, isAgent bool) (*models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.UpdateTicket")
+	defer span.End()
+
 	ticket, err := u.GetTicket(ctx, id)
 	if err != nil {
 		return nil, err
@@ -199,29 +270,41 @@ func (u *TicketUsecase) UpdateTicket(ctx context.Context, id string, req models.
 		return nil, errors.New("you can only update your own tickets")
 	}
 
+	// The client's version wins over ours so edits based on a stale read fail
+	version := ticket.Version
+	if req.Version != nil {
+		version = *req.Version
+	}
+
 	// Track changes for history
 	changes := make(map[string][2]interface{})
+	fields := bson.M{}
 
 	if req.Subject != nil && *req.Subject != ticket.Subject {
 		changes["subject"] = [2]interface{}{ticket.Subject, *req.Subject}
 		ticket.Subject = *req.Subject
+		fields["subject"] = ticket.Subject
 	}
 
 	if req.Description != nil && *req.Description != ticket.Description {
 		changes["description"] = [2]interface{}{ticket.Description, *req.Description}
 		ticket.Description = *req.Description
+		fields["description"] = ticket.Description
 	}
 
 	if req.Type != nil && *req.Type != ticket.Type {
 		changes["type"] = [2]interface{}{ticket.Type, *req.Type}
 		ticket.Type = *req.Type
+		fields["type"] = ticket.Type
 	}
 
 	if req.Priority != nil && *req.Priority != ticket.Priority {
 		changes["priority"] = [2]interface{}{ticket.Priority, *req.Priority}
 		ticket.Priority = *req.Priority
+		fields["priority"] = ticket.Priority
 		// Recalculate SLA if priority changed
 		u.calculateSLA(ctx, ticket)
+		setSLAFields(fields, ticket)
 	}
 
 	if req.DepartmentID != nil {
@@ -233,6 +316,8 @@ func (u *TicketUsecase) UpdateTicket(ctx context.Context, id string, req models.
 					changes["department"] = [2]interface{}{ticket.DepartmentName, dept.Name}
 					ticket.DepartmentID = &deptID
 					ticket.DepartmentName = dept.Name
+					fields["department_id"] = deptID
+					fields["department_name"] = dept.Name
 				}
 			}
 		}
@@ -246,37 +331,56 @@ func (u *TicketUsecase) UpdateTicket(ctx context.Context, id string, req models.
 				changes["category"] = [2]interface{}{ticket.CategoryName, cat.Name}
 				ticket.CategoryID = &catID
 				ticket.CategoryName = cat.Name
+				fields["category_id"] = catID
+				fields["category_name"] = cat.Name
 			}
 		}
 	}
 
 	if req.Tags != nil {
 		ticket.Tags = req.Tags
+		fields["tags"] = ticket.Tags
 	}
 
 	if req.CCEmails != nil {
 		ticket.CCEmails = req.CCEmails
+		fields["cc_emails"] = ticket.CCEmails
 	}
 
 	if req.CustomFields != nil {
 		ticket.CustomFields = req.CustomFields
+		fields["custom_fields"] = ticket.CustomFields
 	}
 
-	// Save changes
-	if err := u.ticketRepo.Update(ctx, ticket); err != nil {
+	// Nothing changed; still report a stale version so the client refreshes
+	if len(fields) == 0 {
+		if version != ticket.Version {
+			return nil, ErrConflict
+		}
+		return ticket, nil
+	}
+
+	// Save only the changed fields, and only over the version we read
+	if err := u.ticketRepo.UpdateFieldsIfVersion(ctx, ticket.ID, version, fields); err != nil {
 		return nil, err
 	}
+	ticket.Version = version + 1
 
 	// Create history for each change
 	for field, values := range changes {
 		u.createHistory(ctx, ticket.ID, ticket.TenantID, "updated", field, values[0], values[1], userID, userName, "")
 	}
 
+	u.publish(ctx, realtime.EventTicketUpdated, ticket, userID, userName)
+
 	return ticket, nil
 }
 
 // ChangeStatus changes the ticket status
 func (u *TicketUsecase) ChangeStatus(ctx context.Context, id string, req models.ChangeStatusRequest, userID, userName string, isAgent bool) (*models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.ChangeStatus")
+	defer span.End()
+
 	ticket, err := u.GetTicket(ctx, id)
 	if err != nil {
 		return nil, err
@@ -288,53 +392,75 @@ func (u *TicketUsecase) ChangeStatus(ctx context.Context, id string, req models.
 	}
 
 	oldStatus := ticket.Status
+	now := time.Now()
 	ticket.Status = req.Status
-	ticket.LastActivityAt = time.Now()
+	ticket.LastActivityAt = now
+	fields := bson.M{
+		"status":           ticket.Status,
+		"last_activity_at": now,
+	}
 
 	// Set timestamps based on status
-	now := time.Now()
 	switch req.Status {
 	case models.StatusResolved:
 		ticket.ResolvedAt = &now
+		fields["resolved_at"] = now
+	case models.StatusClosed:
+		ticket.ClosedAt = &now
+		fields["closed_at"] = now
+	case models.StatusReopened:
+		ticket.ResolvedAt = nil
+		ticket.ClosedAt = nil
+		ticket.ReopenCount++
+		fields["resolved_at"] = nil
+		fields["closed_at"] = nil
+		fields["reopen_count"] = ticket.ReopenCount
+	}
+
+	// A concurrent change may have moved the status already; counters only
+	// follow a transition that actually happened
+	if err := u.ticketRepo.UpdateFieldsIfVersion(ctx, ticket.ID, ticket.Version, fields); err != nil {
+		return nil, err
+	}
+	ticket.Version++
+
+	switch req.Status {
+	case models.StatusResolved:
+		metrics.TicketsResolved.WithLabelValues(ticket.TenantID).Inc()
 		if ticket.AssignedToID != "" {
-			agentID, _ := primitive.ObjectIDFromHex(ticket.AssignedToID)
-			_ = u.agentRepo.IncrementResolved(ctx, agentID)
-			_ = u.agentRepo.DecrementTicketCount(ctx, agentID)
+			agent, _ := u.agentRepo.GetByUserID(ctx, ticket.TenantID, ticket.AssignedToID)
+			if agent != nil {
+				_ = u.agentRepo.IncrementResolved(ctx, agent.ID)
+			}
 		}
+		u.releaseAssignee(ctx, ticket.TenantID, ticket.AssignedToID)
 		if ticket.DepartmentID != nil {
 			_ = u.departmentRepo.DecrementOpenTickets(ctx, *ticket.DepartmentID)
 		}
 	case models.StatusClosed:
-		ticket.ClosedAt = &now
 		if oldStatus != models.StatusResolved {
-			if ticket.AssignedToID != "" {
-				agentID, _ := primitive.ObjectIDFromHex(ticket.AssignedToID)
-				_ = u.agentRepo.DecrementTicketCount(ctx, agentID)
-			}
+			u.releaseAssignee(ctx, ticket.TenantID, ticket.AssignedToID)
 			if ticket.DepartmentID != nil {
 				_ = u.departmentRepo.DecrementOpenTickets(ctx, *ticket.DepartmentID)
 			}
 		}
 	case models.StatusReopened:
-		ticket.ResolvedAt = nil
-		ticket.ClosedAt = nil
-		ticket.ReopenCount++
 		if ticket.DepartmentID != nil {
 			_ = u.departmentRepo.IncrementTicketCount(ctx, *ticket.DepartmentID, true)
 		}
 	}
 
-	if err := u.ticketRepo.Update(ctx, ticket); err != nil {
-		return nil, err
-	}
-
 	u.createHistory(ctx, ticket.ID, ticket.TenantID, "status_changed", "status", oldStatus, req.Status, userID, userName, req.Comment)
+	u.publish(ctx, realtime.EventStatusChanged, ticket, userID, userName)
 
 	return ticket, nil
 }
 
 // AssignTicket assigns a ticket to an agent
 func (u *TicketUsecase) AssignTicket(ctx context.Context, id string, req models.AssignTicketRequest, assignedByID, assignedByName string) (*models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.AssignTicket")
+	defer span.End()
+
 	ticket, err := u.GetTicket(ctx, id)
 	if err != nil {
 		return nil, err
@@ -349,15 +475,18 @@ func (u *TicketUsecase) AssignTicket(ctx context.Context, id string, req models.
 		return nil, errors.New("agent not found")
 	}
 
-	// Check capacity
-	if agent.CurrentTickets >= agent.MaxTickets {
-		return nil, errors.New("agent has reached maximum capacity")
-	}
-
-	// Update old assignee
-	if ticket.AssignedToID != "" {
-		oldAgentID, _ := primitive.ObjectIDFromHex(ticket.AssignedToID)
-		_ = u.agentRepo.DecrementTicketCount(ctx, oldAgentID)
+	// Reserve capacity atomically so concurrent assignments cannot overshoot.
+	// Reassigning to the current assignee keeps the slot it already holds.
+	oldAssigneeID := ticket.AssignedToID
+	reassigned := oldAssigneeID != agent.UserID
+	if reassigned {
+		reserved, err := u.agentRepo.ReserveTicketSlot(ctx, agent.ID)
+		if err != nil {
+			return nil, err
+		}
+		if reserved == nil {
+			return nil, errors.New("agent has reached maximum capacity")
+		}
 	}
 
 	oldAssignee := ticket.AssignedToName
@@ -368,31 +497,88 @@ func (u *TicketUsecase) AssignTicket(ctx context.Context, id string, req models.
 	ticket.AssignedAt = &now
 	ticket.AssignedByID = assignedByID
 	ticket.LastActivityAt = now
+	fields := assignmentFields(ticket)
 
 	// Set first response due if not set
 	if ticket.FirstResponseDue == nil {
 		u.calculateSLA(ctx, ticket)
+		setSLAFields(fields, ticket)
 	}
 
 	// Update status if open
 	if ticket.Status == models.StatusOpen {
 		ticket.Status = models.StatusInProgress
+		fields["status"] = ticket.Status
 	}
 
-	if err := u.ticketRepo.Update(ctx, ticket); err != nil {
+	// The version guard keeps two assigners from both decrementing the old assignee
+	if err := u.ticketRepo.UpdateFieldsIfVersion(ctx, ticket.ID, ticket.Version, fields); err != nil {
+		if reassigned {
+			_ = u.agentRepo.ReleaseTicketSlot(ctx, agent.ID)
+		}
 		return nil, err
 	}
+	ticket.Version++
 
-	// Update agent stats
-	_ = u.agentRepo.IncrementTicketCount(ctx, agent.ID)
+	if reassigned {
+		u.releaseAssignee(ctx, ticket.TenantID, oldAssigneeID)
+	}
 
 	u.createHistory(ctx, ticket.ID, ticket.TenantID, "assigned", "assignee", oldAssignee, agent.Name, assignedByID, assignedByName, req.Comment)
+	u.publish(ctx, realtime.EventTicketAssigned, ticket, assignedByID, assignedByName)
+
+	return ticket, nil
+}
+
+// ClaimNextTicket assigns the best unassigned ticket from the agent's queue
+// to the agent. It returns nil when the queue is empty.
+func (u *TicketUsecase) ClaimNextTicket(ctx context.Context, tenantID, userID, userName string) (*models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.ClaimNextTicket")
+	defer span.End()
+
+	agent, err := u.agentRepo.GetByUserID(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if agent == nil || !agent.IsActive {
+		return nil, errors.New("agent not found")
+	}
+
+	// Take the capacity slot first so concurrent pulls cannot overshoot
+	reserved, err := u.agentRepo.ReserveTicketSlot(ctx, agent.ID)
+	if err != nil {
+		return nil, err
+	}
+	if reserved == nil {
+		return nil, errors.New("agent has reached maximum capacity")
+	}
+
+	ticket, err := u.ticketRepo.ClaimNext(ctx, reserved)
+	if err != nil || ticket == nil {
+		_ = u.agentRepo.ReleaseTicketSlot(ctx, agent.ID)
+		return nil, err
+	}
+
+	if ticket.FirstResponseDue == nil {
+		u.calculateSLA(ctx, ticket)
+		fields := bson.M{}
+		setSLAFields(fields, ticket)
+		if len(fields) > 0 && u.ticketRepo.UpdateFields(ctx, ticket.ID, fields) == nil {
+			ticket.Version++
+		}
+	}
+
+	u.createHistory(ctx, ticket.ID, ticket.TenantID, "assigned", "assignee", "", agent.Name, userID, userName, "Claimed from queue")
+	u.publish(ctx, realtime.EventTicketAssigned, ticket, userID, userName)
 
 	return ticket, nil
 }
 
 // TransferTicket transfers a ticket to another department
 func (u *TicketUsecase) TransferTicket(ctx context.Context, id string, req models.TransferTicketRequest, userID, userName string) (*models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.TransferTicket")
+	defer span.End()
+
 	ticket, err := u.GetTicket(ctx, id)
 	if err != nil {
 		return nil, err
@@ -409,17 +595,8 @@ func (u *TicketUsecase) TransferTicket(ctx context.Context, id string, req model
 	}
 
 	oldDept := ticket.DepartmentName
-
-	// Update old department stats
-	if ticket.DepartmentID != nil {
-		_ = u.departmentRepo.DecrementOpenTickets(ctx, *ticket.DepartmentID)
-	}
-
-	// Remove old assignee
-	if ticket.AssignedToID != "" {
-		oldAgentID, _ := primitive.ObjectIDFromHex(ticket.AssignedToID)
-		_ = u.agentRepo.DecrementTicketCount(ctx, oldAgentID)
-	}
+	oldDeptID := ticket.DepartmentID
+	oldAssigneeID := ticket.AssignedToID
 
 	ticket.DepartmentID = &deptID
 	ticket.DepartmentName = dept.Name
@@ -429,14 +606,24 @@ func (u *TicketUsecase) TransferTicket(ctx context.Context, id string, req model
 	ticket.AssignedAt = nil
 	ticket.LastActivityAt = time.Now()
 
-	// Update new department stats
-	_ = u.departmentRepo.IncrementTicketCount(ctx, deptID, true)
+	fields := assignmentFields(ticket)
+	fields["department_id"] = deptID
+	fields["department_name"] = dept.Name
 
-	if err := u.ticketRepo.Update(ctx, ticket); err != nil {
+	if err := u.ticketRepo.UpdateFieldsIfVersion(ctx, ticket.ID, ticket.Version, fields); err != nil {
 		return nil, err
 	}
+	ticket.Version++
+
+	// Move department stats and free the old assignee's slot
+	if oldDeptID != nil {
+		_ = u.departmentRepo.DecrementOpenTickets(ctx, *oldDeptID)
+	}
+	_ = u.departmentRepo.IncrementTicketCount(ctx, deptID, true)
+	u.releaseAssignee(ctx, ticket.TenantID, oldAssigneeID)
 
 	u.createHistory(ctx, ticket.ID, ticket.TenantID, "transferred", "department", oldDept, dept.Name, userID, userName, req.Comment)
+	u.publish(ctx, realtime.EventTicketTransferred, ticket, userID, userName)
 
 	// Auto-assign if requested
 	if req.AssigneeID != "" {
@@ -450,11 +637,20 @@ func (u *TicketUsecase) TransferTicket(ctx context.Context, id string, req model
 
 // AddReply adds a reply to a ticket
 func (u *TicketUsecase) AddReply(ctx context.Context, ticketID string, req models.CreateMessageRequest, senderID, senderName, senderEmail string, senderType models.SenderType, ip, userAgent string) (*models.TicketMessage, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.AddReply")
+	defer span.End()
+
 	ticket, err := u.GetTicket(ctx, ticketID)
 	if err != nil {
 		return nil, err
 	}
 
+	if req.LastSeenMessageID != "" {
+		if err := u.checkLastSeen(ctx, ticket, req.LastSeenMessageID, senderID, senderType == models.SenderAgent); err != nil {
+			return nil, err
+		}
+	}
+
 	// Determine message type
 	msgType := models.MessageTypeReply
 	if req.Type != "" {
@@ -505,10 +701,6 @@ func (u *TicketUsecase) AddReply(ctx context.Context, ticketID string, req model
 
 	if senderType == models.SenderCustomer {
 		updates["last_customer_reply_at"] = now
-		// If pending, set to open
-		if ticket.Status == models.StatusPending {
-			updates["status"] = models.StatusOpen
-		}
 	} else if senderType == models.SenderAgent && !req.IsPrivate {
 		updates["last_agent_reply_at"] = now
 		// Set first response time
@@ -518,12 +710,126 @@ func (u *TicketUsecase) AddReply(ctx context.Context, ticketID string, req model
 	}
 
 	_ = u.ticketRepo.UpdateFields(ctx, ticket.ID, updates)
+	ticket.LastActivityAt = now
+
+	// A customer reply reopens a pending ticket, unless an agent changed the
+	// status since it was read
+	if senderType == models.SenderCustomer && ticket.Status == models.StatusPending {
+		reopened, err := u.ticketRepo.TransitionStatus(ctx, ticket.ID, models.StatusPending, models.StatusOpen)
+		if err == nil && reopened {
+			ticket.Status = models.StatusOpen
+		} else if current, _ := u.ticketRepo.GetByID(ctx, ticket.ID); current != nil {
+			ticket = current
+		}
+	}
+
+	// Keep the search index's status and activity for this ticket current
+	_ = u.index.IndexTicket(ctx, ticket)
+
+	event := realtime.NewEvent(realtime.EventMessageCreated, ticket.TenantID, ticket.ID.Hex(), senderID, senderName, message)
+	event.Private = message.IsPrivate
+	_ = u.hub.Publish(ctx, event)
+	_ = u.index.IndexMessage(ctx, message)
+
+	// The reply is sent, so the agent is back to just viewing
+	if senderType == models.SenderAgent {
+		_, _ = u.UpdatePresence(ctx, ticket, models.ViewerViewing, senderID, senderName)
+	}
 
 	return message, nil
 }
 
+// UpdatePresence records an agent viewing, replying to or leaving a ticket,
+// pushes the change to other agents and returns who is on the ticket
+func (u *TicketUsecase) UpdatePresence(ctx context.Context, ticket *models.Ticket, activity models.ViewerActivity, userID, userName string) ([]models.TicketViewer, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.UpdatePresence")
+	defer span.End()
+
+	viewers, changed, err := u.tracker.Touch(ctx, ticket.TenantID, ticket.ID.Hex(), userID, userName, activity)
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		event := realtime.NewEvent(realtime.EventViewersChanged, ticket.TenantID, ticket.ID.Hex(), userID, userName, viewers)
+		event.Private = true
+		_ = u.hub.Publish(ctx, event)
+	}
+
+	return viewers, nil
+}
+
+// ListViewers returns the agents currently viewing or replying to a ticket
+func (u *TicketUsecase) ListViewers(ctx context.Context, ticket *models.Ticket) ([]models.TicketViewer, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.ListViewers")
+	defer span.End()
+
+	return u.tracker.Viewers(ctx, ticket.TenantID, ticket.ID.Hex())
+}
+
+// checkLastSeen rejects a reply if someone else has written on the ticket
+// since the message the sender last saw
+func (u *TicketUsecase) checkLastSeen(ctx context.Context, ticket *models.Ticket, lastSeenID, senderID string, isAgent bool) error {
+	messageID, err := primitive.ObjectIDFromHex(lastSeenID)
+	if err != nil {
+		return errors.New("invalid last seen message ID")
+	}
+
+	lastSeen, err := u.messageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return err
+	}
+	if lastSeen == nil || lastSeen.TicketID != ticket.ID {
+		return errors.New("last seen message not found")
+	}
+
+	newer, err := u.messageRepo.HasNewer(ctx, ticket.ID, lastSeen.CreatedAt, senderID, isAgent)
+	if err != nil {
+		return err
+	}
+	if newer {
+		return ErrNewerMessages
+	}
+
+	return nil
+}
+
+// AuthorizeTicketAccess returns the ticket if the user may follow it: agents
+// may follow any ticket in their tenant, customers only their own
+func (u *TicketUsecase) AuthorizeTicketAccess(ctx context.Context, id, tenantID, userID string, isAgent bool) (*models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.AuthorizeTicketAccess")
+	defer span.End()
+
+	ticket, err := u.GetTicket(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.TenantID != tenantID {
+		return nil, errors.New("ticket not found")
+	}
+	if !isAgent && ticket.CustomerID != userID {
+		return nil, errors.New("you can only follow your own tickets")
+	}
+
+	return ticket, nil
+}
+
+// SendTyping broadcasts a typing indicator on a ticket
+func (u *TicketUsecase) SendTyping(ctx context.Context, ticket *models.Ticket, req models.TypingRequest, userID, userName string, isAgent bool) error {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.SendTyping")
+	defer span.End()
+
+	event := realtime.NewEvent(realtime.EventTyping, ticket.TenantID, ticket.ID.Hex(), userID, userName, req)
+	// Agents drafting an internal note stay hidden from the customer
+	event.Private = isAgent && req.Private
+	return u.hub.Publish(ctx, event)
+}
+
 // RateTicket adds a satisfaction rating
 func (u *TicketUsecase) RateTicket(ctx context.Context, id string, req models.RateTicketRequest, userID string) (*models.Ticket, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.RateTicket")
+	defer span.End()
+
 	ticket, err := u.GetTicket(ctx, id)
 	if err != nil {
 		return nil, err
@@ -542,42 +848,191 @@ func (u *TicketUsecase) RateTicket(ctx context.Context, id string, req models.Ra
 	ticket.SatisfactionComment = req.Comment
 	ticket.RatedAt = &now
 
-	if err := u.ticketRepo.Update(ctx, ticket); err != nil {
+	err = u.ticketRepo.UpdateFieldsIfVersion(ctx, ticket.ID, ticket.Version, bson.M{
+		"satisfaction_rating":  req.Rating,
+		"satisfaction_comment": req.Comment,
+		"rated_at":             now,
+	})
+	if err != nil {
 		return nil, err
 	}
+	ticket.Version++
 
-	// Update agent rating
+	// Recompute the assignee's average over all their rated tickets, so
+	// unrated resolutions and changed ratings don't skew it
 	if ticket.AssignedToID != "" {
-		// Calculate new average rating (simplified - in production, use proper aggregation)
-		agentID, _ := primitive.ObjectIDFromHex(ticket.AssignedToID)
-		agent, _ := u.agentRepo.GetByID(ctx, agentID)
+		agent, _ := u.agentRepo.GetByUserID(ctx, ticket.TenantID, ticket.AssignedToID)
 		if agent != nil {
-			newAvg := (agent.AvgRating*float64(agent.TotalResolved-1) + float64(req.Rating)) / float64(agent.TotalResolved)
-			agent.AvgRating = newAvg
-			_ = u.agentRepo.Update(ctx, agent)
+			if avg, err := u.ticketRepo.AvgRating(ctx, ticket.TenantID, ticket.AssignedToID); err == nil {
+				_ = u.agentRepo.SetAvgRating(ctx, agent.ID, avg)
+			}
 		}
 	}
 
 	return ticket, nil
 }
 
-// ListTickets lists tickets with filters
-func (u *TicketUsecase) ListTickets(ctx context.Context, filter models.TicketFilter) ([]models.Ticket, int64, error) {
-	return u.ticketRepo.List(ctx, filter)
+// ListTickets lists tickets with filters. filter.Query is compiled from the
+// ticket query language, with "me" meaning userID; it carries its own free
+// text, so filter.Search is ignored alongside it.
+func (u *TicketUsecase) ListTickets(ctx context.Context, filter models.TicketFilter, userID string) ([]models.Ticket, int64, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.ListTickets")
+	defer span.End()
+
+	if filter.Query == "" {
+		return u.ticketRepo.List(ctx, filter)
+	}
+
+	filter, where, err := compileTicketQuery(filter, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return u.ticketRepo.ListWhere(ctx, filter, where)
+}
+
+// ListTicketsAfter lists one page of tickets after a keyset cursor, with the
+// same filters as ListTickets
+func (u *TicketUsecase) ListTicketsAfter(ctx context.Context, filter models.TicketFilter, userID string, page models.CursorRequest) (*models.CursorPage[models.Ticket], error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.ListTicketsAfter")
+	defer span.End()
+
+	filter, where, err := compileTicketQuery(filter, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.ticketRepo.ListWhereAfter(ctx, filter, where, page)
+}
+
+// compileTicketQuery compiles filter.Query, which replaces filter.Search
+func compileTicketQuery(filter models.TicketFilter, userID string) (models.TicketFilter, bson.M, error) {
+	if filter.Query == "" {
+		return filter, nil, nil
+	}
+
+	where, err := query.Parse(filter.Query, query.Context{UserID: userID})
+	if err != nil {
+		return filter, nil, err
+	}
+	filter.Search = ""
+
+	return filter, where, nil
+}
+
+// SearchTickets searches ticket subjects, descriptions and message content
+// together. Results are ranked by relevance, carry highlighted snippets of
+// what matched, and come with facet counts over every match.
+func (u *TicketUsecase) SearchTickets(ctx context.Context, q models.TicketSearchQuery) (*models.TicketSearchResponse, int64, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.SearchTickets")
+	defer span.End()
+
+	q.Query = strings.TrimSpace(q.Query)
+	if q.Query == "" {
+		return nil, 0, errors.New("search query is required")
+	}
+
+	result, err := u.index.Search(ctx, q)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ticketIDs := make([]primitive.ObjectID, 0, len(result.Hits))
+	var messageIDs []primitive.ObjectID
+	for _, hit := range result.Hits {
+		ticketIDs = append(ticketIDs, hit.TicketID)
+		if !hit.MessageID.IsZero() {
+			messageIDs = append(messageIDs, hit.MessageID)
+		}
+	}
+
+	// Load the page from MongoDB; it has the final say on visibility
+	tickets := make(map[primitive.ObjectID]*models.Ticket)
+	if len(ticketIDs) > 0 {
+		found, err := u.ticketRepo.GetByIDs(ctx, q.TenantID, q.CustomerID, ticketIDs)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i := range found {
+			tickets[found[i].ID] = &found[i]
+		}
+	}
+	messages := make(map[primitive.ObjectID]*models.TicketMessage)
+	if len(messageIDs) > 0 {
+		found, err := u.messageRepo.GetByIDs(ctx, q.TenantID, messageIDs)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i := range found {
+			if q.IncludePrivate || !found[i].IsPrivate {
+				messages[found[i].ID] = &found[i]
+			}
+		}
+	}
+
+	terms := search.Terms(q.Query)
+	response := &models.TicketSearchResponse{
+		Results: make([]models.TicketSearchResult, 0, len(result.Hits)),
+		Facets:  result.Facets,
+	}
+	for _, hit := range result.Hits {
+		ticket, ok := tickets[hit.TicketID]
+		if !ok {
+			continue
+		}
+		res := models.TicketSearchResult{
+			Ticket:  ticket,
+			Score:   hit.Score,
+			Message: messages[hit.MessageID],
+		}
+		res.Highlights = highlights(&res, terms)
+		response.Results = append(response.Results, res)
+	}
+
+	return response, result.Total, nil
+}
+
+// highlights builds snippets for each field of a result that matched
+func highlights(result *models.TicketSearchResult, terms []string) []models.SearchHighlight {
+	fields := [][2]string{
+		{"subject", result.Ticket.Subject},
+		{"description", result.Ticket.Description},
+	}
+	if result.Message != nil {
+		fields = append(fields, [2]string{"message", result.Message.Content})
+	}
+
+	highlights := []models.SearchHighlight{}
+	for _, field := range fields {
+		if snippet, ok := search.Snippet(field[1], terms, snippetWidth); ok {
+			highlights = append(highlights, models.SearchHighlight{Field: field[0], Snippet: snippet})
+		}
+	}
+
+	return highlights
 }
 
 // GetCustomerTickets gets tickets for a customer
 func (u *TicketUsecase) GetCustomerTickets(ctx context.Context, tenantID, customerID string, page, perPage int) ([]models.Ticket, int64, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.GetCustomerTickets")
+	defer span.End()
+
 	return u.ticketRepo.GetByCustomerID(ctx, tenantID, customerID, page, perPage)
 }
 
 // GetAgentTickets gets tickets assigned to an agent
 func (u *TicketUsecase) GetAgentTickets(ctx context.Context, tenantID, agentID string, page, perPage int) ([]models.Ticket, int64, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.GetAgentTickets")
+	defer span.End()
+
 	return u.ticketRepo.GetByAssigneeID(ctx, tenantID, agentID, page, perPage)
 }
 
 // GetTicketMessages gets messages for a ticket
 func (u *TicketUsecase) GetTicketMessages(ctx context.Context, ticketID string, includePrivate bool, page, perPage int) ([]models.TicketMessage, int64, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.GetTicketMessages")
+	defer span.End()
+
 	id, err := primitive.ObjectIDFromHex(ticketID)
 	if err != nil {
 		return nil, 0, errors.New("invalid ticket ID")
@@ -587,6 +1042,9 @@ func (u *TicketUsecase) GetTicketMessages(ctx context.Context, ticketID string,
 
 // GetTicketHistory gets history for a ticket
 func (u *TicketUsecase) GetTicketHistory(ctx context.Context, ticketID string, page, perPage int) ([]models.TicketHistory, int64, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.GetTicketHistory")
+	defer span.End()
+
 	id, err := primitive.ObjectIDFromHex(ticketID)
 	if err != nil {
 		return nil, 0, errors.New("invalid ticket ID")
@@ -594,35 +1052,70 @@ func (u *TicketUsecase) GetTicketHistory(ctx context.Context, ticketID string, p
 	return u.historyRepo.GetByTicketID(ctx, id, page, perPage)
 }
 
+// GetTicketMessagesAfter gets one page of a ticket's messages after a cursor
+func (u *TicketUsecase) GetTicketMessagesAfter(ctx context.Context, ticketID string, includePrivate bool, page models.CursorRequest) (*models.CursorPage[models.TicketMessage], error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.GetTicketMessagesAfter")
+	defer span.End()
+
+	id, err := primitive.ObjectIDFromHex(ticketID)
+	if err != nil {
+		return nil, errors.New("invalid ticket ID")
+	}
+	return u.messageRepo.ListAfter(ctx, id, includePrivate, page)
+}
+
+// GetTicketHistoryAfter gets one page of a ticket's history after a cursor
+func (u *TicketUsecase) GetTicketHistoryAfter(ctx context.Context, ticketID string, page models.CursorRequest) (*models.CursorPage[models.TicketHistory], error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.GetTicketHistoryAfter")
+	defer span.End()
+
+	id, err := primitive.ObjectIDFromHex(ticketID)
+	if err != nil {
+		return nil, errors.New("invalid ticket ID")
+	}
+	return u.historyRepo.ListAfter(ctx, id, page)
+}
+
 // GetStats gets ticket statistics
-func (u *TicketUsecase) GetStats(ctx context.Context, tenantID string) (*models.TicketStats, error) {
-	return u.ticketRepo.GetStats(ctx, tenantID)
+func (u *TicketUsecase) GetStats(ctx context.Context, tenantID string, filter models.StatsFilter) (*models.TicketStats, error) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.GetStats")
+	defer span.End()
+
+	return u.ticketRepo.GetStats(ctx, tenantID, filter)
 }
 
 // DeleteTicket soft deletes a ticket
 func (u *TicketUsecase) DeleteTicket(ctx context.Context, id string, deletedBy string) error {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.DeleteTicket")
+	defer span.End()
+
 	ticket, err := u.GetTicket(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	// Update agent stats
-	if ticket.AssignedToID != "" {
-		agentID, _ := primitive.ObjectIDFromHex(ticket.AssignedToID)
-		_ = u.agentRepo.DecrementTicketCount(ctx, agentID)
+	if err := u.ticketRepo.Delete(ctx, ticket.ID, deletedBy); err != nil {
+		return err
 	}
 
-	// Update department stats
-	if ticket.DepartmentID != nil && ticket.Status != models.StatusClosed && ticket.Status != models.StatusResolved {
-		_ = u.departmentRepo.DecrementOpenTickets(ctx, *ticket.DepartmentID)
+	// Resolved and closed tickets already gave back their agent slot and open count
+	if ticket.Status != models.StatusClosed && ticket.Status != models.StatusResolved {
+		u.releaseAssignee(ctx, ticket.TenantID, ticket.AssignedToID)
+		if ticket.DepartmentID != nil {
+			_ = u.departmentRepo.DecrementOpenTickets(ctx, *ticket.DepartmentID)
+		}
 	}
 
-	return u.ticketRepo.Delete(ctx, ticket.ID, deletedBy)
+	_ = u.index.DeleteTicket(ctx, ticket.ID)
+	return nil
 }
 
 // Helper functions
 
 func (u *TicketUsecase) calculateSLA(ctx context.Context, ticket *models.Ticket) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.calculateSLA")
+	defer span.End()
+
 	if !u.config.SLA.Enabled {
 		return
 	}
@@ -667,23 +1160,105 @@ func (u *TicketUsecase) calculateSLA(ctx context.Context, ticket *models.Ticket)
 }
 
 func (u *TicketUsecase) autoAssign(ctx context.Context, ticket *models.Ticket) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.autoAssign")
+	defer span.End()
+
 	agents, err := u.agentRepo.GetAvailable(ctx, ticket.TenantID, ticket.DepartmentID)
 	if err != nil || len(agents) == 0 {
 		return
 	}
 
-	// Use least busy agent (already sorted)
-	agent := agents[0]
+	var dept *models.Department
+	if ticket.DepartmentID != nil {
+		dept, _ = u.departmentRepo.GetByID(ctx, *ticket.DepartmentID)
+	}
+
+	// Another assignment may fill the picked agent first; drop them and pick again
+	var agent *models.Agent
+	for len(agents) > 0 {
+		picked, err := u.assigner.Pick(ctx, dept, ticket, agents)
+		if err != nil || picked == nil {
+			return
+		}
+
+		reserved, err := u.agentRepo.ReserveTicketSlot(ctx, picked.ID)
+		if err != nil {
+			return
+		}
+		if reserved != nil {
+			agent = reserved
+			break
+		}
+
+		agents = withoutAgent(agents, picked.ID)
+	}
+	if agent == nil {
+		return
+	}
+
 	now := time.Now()
 	ticket.AssignedToID = agent.UserID
 	ticket.AssignedToName = agent.Name
 	ticket.AssignedToEmail = agent.Email
 	ticket.AssignedAt = &now
 
-	_ = u.ticketRepo.Update(ctx, ticket)
-	_ = u.agentRepo.IncrementTicketCount(ctx, agent.ID)
+	if err := u.ticketRepo.UpdateFieldsIfVersion(ctx, ticket.ID, ticket.Version, assignmentFields(ticket)); err != nil {
+		_ = u.agentRepo.ReleaseTicketSlot(ctx, agent.ID)
+		ticket.AssignedToID = ""
+		ticket.AssignedToName = ""
+		ticket.AssignedToEmail = ""
+		ticket.AssignedAt = nil
+		return
+	}
+	ticket.Version++
 
 	u.createHistory(ctx, ticket.ID, ticket.TenantID, "auto_assigned", "assignee", "", agent.Name, "system", "System", "")
+	u.publish(ctx, realtime.EventTicketAssigned, ticket, "system", "System")
+}
+
+// assignmentFields returns the ticket's assignment as fields to set
+func assignmentFields(ticket *models.Ticket) bson.M {
+	fields := bson.M{
+		"assigned_to_id":    ticket.AssignedToID,
+		"assigned_to_name":  ticket.AssignedToName,
+		"assigned_to_email": ticket.AssignedToEmail,
+		"assigned_at":       ticket.AssignedAt,
+		"last_activity_at":  ticket.LastActivityAt,
+	}
+	if ticket.AssignedByID != "" {
+		fields["assigned_by_id"] = ticket.AssignedByID
+	}
+	return fields
+}
+
+// setSLAFields adds the deadlines calculateSLA set on the ticket to fields
+func setSLAFields(fields bson.M, ticket *models.Ticket) {
+	if ticket.FirstResponseDue != nil {
+		fields["first_response_due"] = ticket.FirstResponseDue
+	}
+	if ticket.ResolutionDue != nil {
+		fields["resolution_due"] = ticket.ResolutionDue
+	}
+	if ticket.SLAPolicyID != nil {
+		fields["sla_policy_id"] = ticket.SLAPolicyID
+	}
+}
+
+// publish broadcasts a ticket change; delivery is best-effort
+func (u *TicketUsecase) publish(ctx context.Context, eventType realtime.EventType, ticket *models.Ticket, actorID, actorName string) {
+	_ = u.hub.Publish(ctx, realtime.NewEvent(eventType, ticket.TenantID, ticket.ID.Hex(), actorID, actorName, ticket))
+	_ = u.index.IndexTicket(ctx, ticket)
+}
+
+// withoutAgent returns agents minus the one with the given ID
+func withoutAgent(agents []models.Agent, id primitive.ObjectID) []models.Agent {
+	result := make([]models.Agent, 0, len(agents))
+	for _, a := range agents {
+		if a.ID != id {
+			result = append(result, a)
+		}
+	}
+	return result
 }
 
 func (u *TicketUsecase) isValidStatusTransition(from, to models.TicketStatus, isAgent bool) bool {
@@ -705,7 +1280,23 @@ func (u *TicketUsecase) isValidStatusTransition(from, to models.TicketStatus, is
 	return false
 }
 
+// releaseAssignee frees the capacity slot held by the agent with the given
+// user ID; tickets store the agent's user ID, not the agent document ID
+func (u *TicketUsecase) releaseAssignee(ctx context.Context, tenantID, userID string) {
+	if userID == "" {
+		return
+	}
+
+	agent, _ := u.agentRepo.GetByUserID(ctx, tenantID, userID)
+	if agent != nil {
+		_ = u.agentRepo.DecrementTicketCount(ctx, agent.ID)
+	}
+}
+
 func (u *TicketUsecase) createHistory(ctx context.Context, ticketID primitive.ObjectID, tenantID, action, field string, oldValue, newValue interface{}, changedBy, changedByName, comment string) {
+	ctx, span := tracing.Start(ctx, "TicketUsecase.createHistory")
+	defer span.End()
+
 	history := &models.TicketHistory{
 		TicketID:      ticketID,
 		TenantID:      tenantID,
@@ -0,0 +1,265 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/query"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/minisource/ticket/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrViewNotFound is returned for views that don't exist or aren't visible to the user
+	ErrViewNotFound = errors.New("saved view not found")
+	// ErrViewForbidden is returned when a user may see a view but not change it
+	ErrViewForbidden = errors.New("not allowed to change this view")
+)
+
+// View badge counts stop here, so a huge view can't make the list slow
+const viewCountLimit = 1000
+
+// ViewUsecase handles saved ticket views
+type ViewUsecase struct {
+	viewRepo   *repository.SavedViewRepository
+	ticketRepo *repository.TicketRepository
+}
+
+// NewViewUsecase creates a new view usecase
+func NewViewUsecase(
+	viewRepo *repository.SavedViewRepository,
+	ticketRepo *repository.TicketRepository,
+) *ViewUsecase {
+	return &ViewUsecase{
+		viewRepo:   viewRepo,
+		ticketRepo: ticketRepo,
+	}
+}
+
+// CreateView saves a view for a user. Only users who can share views may
+// create shared ones.
+func (u *ViewUsecase) CreateView(ctx context.Context, tenantID, userID, userName string, canShare bool, req models.CreateSavedViewRequest) (*models.SavedView, error) {
+	ctx, span := tracing.Start(ctx, "ViewUsecase.CreateView")
+	defer span.End()
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, errors.New("view name is required")
+	}
+	if req.Shared && !canShare {
+		return nil, ErrViewForbidden
+	}
+	if _, err := query.Parse(req.Query, query.Context{UserID: userID}); err != nil {
+		return nil, err
+	}
+	if _, err := repository.ParseTicketSort(req.SortBy, req.SortOrder); err != nil {
+		return nil, err
+	}
+
+	view := &models.SavedView{
+		TenantID:  tenantID,
+		OwnerID:   userID,
+		OwnerName: userName,
+		Name:      req.Name,
+		Query:     req.Query,
+		Columns:   req.Columns,
+		SortBy:    req.SortBy,
+		SortOrder: req.SortOrder,
+		Shared:    req.Shared,
+	}
+
+	if err := u.viewRepo.Create(ctx, view); err != nil {
+		return nil, err
+	}
+
+	return view, nil
+}
+
+// GetView gets a view the user owns or that is shared with them
+func (u *ViewUsecase) GetView(ctx context.Context, tenantID, userID, id string) (*models.SavedView, error) {
+	ctx, span := tracing.Start(ctx, "ViewUsecase.GetView")
+	defer span.End()
+
+	viewID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrViewNotFound
+	}
+
+	view, err := u.viewRepo.GetByID(ctx, tenantID, viewID)
+	if err != nil {
+		return nil, err
+	}
+	if view == nil || (view.OwnerID != userID && !view.Shared) {
+		return nil, ErrViewNotFound
+	}
+
+	return view, nil
+}
+
+// UpdateView updates a view. Owners may change their own views; shared views
+// and sharing itself also need canShare.
+func (u *ViewUsecase) UpdateView(ctx context.Context, tenantID, userID string, canShare bool, id string, req models.UpdateSavedViewRequest) (*models.SavedView, error) {
+	ctx, span := tracing.Start(ctx, "ViewUsecase.UpdateView")
+	defer span.End()
+
+	view, err := u.GetView(ctx, tenantID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !canEditView(view, userID, canShare) || (req.Shared != nil && *req.Shared && !canShare) {
+		return nil, ErrViewForbidden
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, errors.New("view name is required")
+		}
+		view.Name = name
+	}
+	if req.Query != nil {
+		if _, err := query.Parse(*req.Query, query.Context{UserID: userID}); err != nil {
+			return nil, err
+		}
+		view.Query = *req.Query
+	}
+	if req.Columns != nil {
+		view.Columns = req.Columns
+	}
+	if req.SortBy != nil {
+		view.SortBy = *req.SortBy
+	}
+	if req.SortOrder != nil {
+		view.SortOrder = *req.SortOrder
+	}
+	if req.Shared != nil {
+		view.Shared = *req.Shared
+	}
+	if _, err := repository.ParseTicketSort(view.SortBy, view.SortOrder); err != nil {
+		return nil, err
+	}
+
+	if err := u.viewRepo.Update(ctx, view); err != nil {
+		return nil, err
+	}
+
+	return view, nil
+}
+
+// DeleteView deletes a view, under the same rules as UpdateView
+func (u *ViewUsecase) DeleteView(ctx context.Context, tenantID, userID string, canShare bool, id string) error {
+	ctx, span := tracing.Start(ctx, "ViewUsecase.DeleteView")
+	defer span.End()
+
+	view, err := u.GetView(ctx, tenantID, userID, id)
+	if err != nil {
+		return err
+	}
+	if !canEditView(view, userID, canShare) {
+		return ErrViewForbidden
+	}
+
+	return u.viewRepo.Delete(ctx, tenantID, view.ID)
+}
+
+// ListViews lists the user's own views and the tenant's shared views
+func (u *ViewUsecase) ListViews(ctx context.Context, tenantID, userID string) ([]models.SavedView, error) {
+	ctx, span := tracing.Start(ctx, "ViewUsecase.ListViews")
+	defer span.End()
+
+	return u.viewRepo.ListVisible(ctx, tenantID, userID)
+}
+
+// ListViewTickets lists the tickets in a view, using the view's sort unless
+// the filter sets one. "me" in the view's query is the user running it.
+func (u *ViewUsecase) ListViewTickets(ctx context.Context, tenantID, userID, id string, filter models.TicketFilter) ([]models.Ticket, int64, error) {
+	ctx, span := tracing.Start(ctx, "ViewUsecase.ListViewTickets")
+	defer span.End()
+
+	view, err := u.GetView(ctx, tenantID, userID, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	where, err := query.Parse(view.Query, query.Context{UserID: userID})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filter.TenantID = tenantID
+	if filter.SortBy == "" {
+		filter.SortBy = view.SortBy
+		filter.SortOrder = view.SortOrder
+	}
+
+	return u.ticketRepo.ListWhere(ctx, filter, where)
+}
+
+// ListViewTicketsAfter lists one page of a view's tickets after a keyset cursor
+func (u *ViewUsecase) ListViewTicketsAfter(ctx context.Context, tenantID, userID, id string, filter models.TicketFilter, page models.CursorRequest) (*models.CursorPage[models.Ticket], error) {
+	ctx, span := tracing.Start(ctx, "ViewUsecase.ListViewTicketsAfter")
+	defer span.End()
+
+	view, err := u.GetView(ctx, tenantID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	where, err := query.Parse(view.Query, query.Context{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	filter.TenantID = tenantID
+	if filter.SortBy == "" {
+		filter.SortBy = view.SortBy
+		filter.SortOrder = view.SortOrder
+	}
+
+	return u.ticketRepo.ListWhereAfter(ctx, filter, where, page)
+}
+
+// CountViews counts the tickets in each of the user's visible views. A view
+// whose query no longer compiles is skipped rather than failing the rest.
+func (u *ViewUsecase) CountViews(ctx context.Context, tenantID, userID string) ([]models.ViewCount, error) {
+	ctx, span := tracing.Start(ctx, "ViewUsecase.CountViews")
+	defer span.End()
+
+	views, err := u.viewRepo.ListVisible(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]models.ViewCount, 0, len(views))
+	for _, view := range views {
+		where, err := query.Parse(view.Query, query.Context{UserID: userID})
+		if err != nil {
+			continue
+		}
+
+		count, err := u.ticketRepo.CountWhere(ctx, tenantID, where, viewCountLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		counts = append(counts, models.ViewCount{
+			ViewID: view.ID,
+			Name:   view.Name,
+			Count:  count,
+			Capped: count >= viewCountLimit,
+		})
+	}
+
+	return counts, nil
+}
+
+// canEditView reports whether a user may change or delete a view
+func canEditView(view *models.SavedView, userID string, canShare bool) bool {
+	if view.Shared {
+		return canShare
+	}
+	return view.OwnerID == userID
+}
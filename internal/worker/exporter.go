@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/minisource/go-common/logging"
+	"github.com/minisource/ticket/internal/usecase"
+)
+
+// Exporter runs queued ticket exports one at a time and deletes expired ones
+type Exporter struct {
+	usecase  *usecase.ExportUsecase
+	interval time.Duration
+	logger   logging.Logger
+}
+
+// NewExporter creates a new export worker
+func NewExporter(exportUsecase *usecase.ExportUsecase, interval time.Duration, logger logging.Logger) *Exporter {
+	return &Exporter{
+		usecase:  exportUsecase,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start runs the exporter until ctx is cancelled
+func (w *Exporter) Start(ctx context.Context) {
+	go w.run(ctx)
+}
+
+func (w *Exporter) run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.drain(ctx)
+			w.cleanup(ctx, now)
+		}
+	}
+}
+
+// drain runs queued exports until none are left
+func (w *Exporter) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		ran, err := w.usecase.RunNext(ctx)
+		if err != nil {
+			w.logger.Error(logging.General, logging.Startup, "Ticket export failed", map[logging.ExtraKey]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		if !ran {
+			return
+		}
+	}
+}
+
+func (w *Exporter) cleanup(ctx context.Context, now time.Time) {
+	deleted, err := w.usecase.Cleanup(ctx, now)
+	if err != nil {
+		w.logger.Error(logging.General, logging.Startup, "Failed to delete expired exports", map[logging.ExtraKey]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if deleted > 0 {
+		w.logger.Info(logging.General, logging.Startup, "Deleted expired exports", map[logging.ExtraKey]interface{}{
+			"deleted": deleted,
+		})
+	}
+}
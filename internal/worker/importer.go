@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/minisource/go-common/logging"
+	"github.com/minisource/ticket/internal/usecase"
+)
+
+// Importer runs queued ticket imports one at a time
+type Importer struct {
+	usecase  *usecase.ImportUsecase
+	interval time.Duration
+	logger   logging.Logger
+}
+
+// NewImporter creates a new import worker
+func NewImporter(importUsecase *usecase.ImportUsecase, interval time.Duration, logger logging.Logger) *Importer {
+	return &Importer{
+		usecase:  importUsecase,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start runs the importer until ctx is cancelled
+func (w *Importer) Start(ctx context.Context) {
+	go w.run(ctx)
+}
+
+func (w *Importer) run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.drain(ctx)
+		}
+	}
+}
+
+// drain runs queued imports until none are left
+func (w *Importer) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		ran, err := w.usecase.RunNext(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				w.logger.Error(logging.General, logging.Startup, "Ticket import failed", map[logging.ExtraKey]interface{}{
+					"error": err.Error(),
+				})
+			}
+			return
+		}
+		if !ran {
+			return
+		}
+	}
+}
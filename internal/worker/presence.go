@@ -0,0 +1,63 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/minisource/go-common/logging"
+	"github.com/minisource/ticket/internal/usecase"
+)
+
+// PresenceSweeper marks agents that stopped sending heartbeats away, then offline
+type PresenceSweeper struct {
+	usecase  *usecase.PresenceUsecase
+	interval time.Duration
+	logger   logging.Logger
+}
+
+// NewPresenceSweeper creates a new presence sweeper
+func NewPresenceSweeper(presenceUsecase *usecase.PresenceUsecase, interval time.Duration, logger logging.Logger) *PresenceSweeper {
+	return &PresenceSweeper{
+		usecase:  presenceUsecase,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start runs the sweeper until ctx is cancelled
+func (w *PresenceSweeper) Start(ctx context.Context) {
+	go w.run(ctx)
+}
+
+func (w *PresenceSweeper) run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.sweep(ctx, now)
+		}
+	}
+}
+
+func (w *PresenceSweeper) sweep(ctx context.Context, now time.Time) {
+	ctx, cancel := context.WithTimeout(ctx, w.interval)
+	defer cancel()
+
+	changed, err := w.usecase.Sweep(ctx, now)
+	if err != nil {
+		w.logger.Error(logging.General, logging.Startup, "Failed to sweep idle agents", map[logging.ExtraKey]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if changed > 0 {
+		w.logger.Info(logging.General, logging.Startup, "Marked idle agents away or offline", map[logging.ExtraKey]interface{}{
+			"agents": changed,
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/minisource/go-common/logging"
+	"github.com/minisource/ticket/internal/usecase"
+)
+
+// Reconciler periodically corrects drifted agent and department counters
+// and resets the rolling ticket windows at each UTC midnight, whatever the
+// tenant's timezone
+type Reconciler struct {
+	usecase  *usecase.ReconcileUsecase
+	interval time.Duration
+	timeout  time.Duration
+	logger   logging.Logger
+}
+
+// NewReconciler creates a new reconciler
+func NewReconciler(reconcileUsecase *usecase.ReconcileUsecase, interval, timeout time.Duration, logger logging.Logger) *Reconciler {
+	return &Reconciler{
+		usecase:  reconcileUsecase,
+		interval: interval,
+		timeout:  timeout,
+		logger:   logger,
+	}
+}
+
+// Start runs the reconciler until ctx is cancelled
+func (w *Reconciler) Start(ctx context.Context) {
+	go w.run(ctx)
+}
+
+func (w *Reconciler) run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	midnight := time.NewTimer(time.Until(nextMidnight(time.Now())))
+	defer midnight.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.reconcile(ctx)
+		case now := <-midnight.C:
+			w.resetWindows(ctx, now)
+			midnight.Reset(time.Until(nextMidnight(now)))
+		}
+	}
+}
+
+func (w *Reconciler) reconcile(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	reports, err := w.usecase.ReconcileAll(ctx)
+	if err != nil {
+		w.logger.Error(logging.General, logging.Startup, "Counter reconciliation failed", map[logging.ExtraKey]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	for _, report := range reports {
+		if len(report.Corrections) == 0 {
+			continue
+		}
+		w.logger.Info(logging.General, logging.Startup, "Corrected drifted counters", map[logging.ExtraKey]interface{}{
+			"tenant_id":   report.TenantID,
+			"corrections": len(report.Corrections),
+			"skipped":     report.Skipped,
+		})
+	}
+}
+
+func (w *Reconciler) resetWindows(ctx context.Context, now time.Time) {
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	if err := w.usecase.ResetRollingWindows(ctx, now); err != nil {
+		w.logger.Error(logging.General, logging.Startup, "Failed to reset rolling ticket counters", map[logging.ExtraKey]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+// nextMidnight returns the next UTC midnight after t
+func nextMidnight(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,63 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/minisource/go-common/logging"
+	"github.com/minisource/ticket/internal/usecase"
+)
+
+// ShiftScheduler applies agent shift and out-of-office transitions
+type ShiftScheduler struct {
+	usecase  *usecase.ScheduleUsecase
+	interval time.Duration
+	logger   logging.Logger
+}
+
+// NewShiftScheduler creates a new shift scheduler
+func NewShiftScheduler(scheduleUsecase *usecase.ScheduleUsecase, interval time.Duration, logger logging.Logger) *ShiftScheduler {
+	return &ShiftScheduler{
+		usecase:  scheduleUsecase,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start runs the scheduler until ctx is cancelled
+func (w *ShiftScheduler) Start(ctx context.Context) {
+	go w.run(ctx)
+}
+
+func (w *ShiftScheduler) run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.apply(ctx, now)
+		}
+	}
+}
+
+func (w *ShiftScheduler) apply(ctx context.Context, now time.Time) {
+	ctx, cancel := context.WithTimeout(ctx, w.interval)
+	defer cancel()
+
+	transitions, err := w.usecase.ApplySchedules(ctx, now)
+	if err != nil {
+		w.logger.Error(logging.General, logging.Startup, "Failed to apply agent schedules", map[logging.ExtraKey]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if transitions > 0 {
+		w.logger.Info(logging.General, logging.Startup, "Applied agent schedule transitions", map[logging.ExtraKey]interface{}{
+			"transitions": transitions,
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/minisource/go-common/logging"
+	"github.com/minisource/ticket/internal/search"
+)
+
+// SearchFlusher periodically persists search index changes
+type SearchFlusher struct {
+	index    search.Index
+	interval time.Duration
+	logger   logging.Logger
+}
+
+// NewSearchFlusher creates a new search index flusher
+func NewSearchFlusher(index search.Index, interval time.Duration, logger logging.Logger) *SearchFlusher {
+	return &SearchFlusher{
+		index:    index,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start runs the flusher until ctx is cancelled
+func (w *SearchFlusher) Start(ctx context.Context) {
+	go w.run(ctx)
+}
+
+func (w *SearchFlusher) run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.index.Flush(); err != nil {
+				w.logger.Error(logging.General, logging.Startup, "Failed to flush search index", map[logging.ExtraKey]interface{}{
+					"error": err.Error(),
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,209 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestAgentRepository connects to the MongoDB at TEST_MONGODB_URI
+func newTestAgentRepository(t *testing.T) *repository.AgentRepository {
+	return repository.NewAgentRepository(newTestDB(t))
+}
+
+// TestAgentCapacityReservation hammers ReserveTicketSlot from many goroutines
+func TestAgentCapacityReservation(t *testing.T) {
+	repo := newTestAgentRepository(t)
+	ctx := context.Background()
+
+	const maxTickets = 5
+	const workers = 50
+
+	agent := &models.Agent{
+		TenantID:   "tenant-1",
+		UserID:     "agent-1",
+		Name:       "Agent One",
+		Status:     models.AgentStatusAvailable,
+		MaxTickets: maxTickets,
+	}
+	require.NoError(t, repo.Create(ctx, agent))
+
+	var reserved int64
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			got, err := repo.ReserveTicketSlot(ctx, agent.ID)
+			assert.NoError(t, err)
+			if got != nil {
+				atomic.AddInt64(&reserved, 1)
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	assert.Equal(t, int64(maxTickets), reserved)
+
+	stored, err := repo.GetByID(ctx, agent.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, maxTickets, stored.CurrentTickets)
+
+	t.Run("Release Frees Slot", func(t *testing.T) {
+		require.NoError(t, repo.ReleaseTicketSlot(ctx, agent.ID))
+
+		got, err := repo.ReserveTicketSlot(ctx, agent.ID)
+		require.NoError(t, err)
+		assert.NotNil(t, got)
+
+		got, err = repo.ReserveTicketSlot(ctx, agent.ID)
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Release After Daily Reset", func(t *testing.T) {
+		require.NoError(t, repo.ResetCounters(ctx, "tickets_today"))
+		require.NoError(t, repo.ReleaseTicketSlot(ctx, agent.ID))
+
+		stored, err := repo.GetByID(ctx, agent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, maxTickets-1, stored.CurrentTickets)
+		assert.Equal(t, 0, stored.TicketsToday)
+	})
+}
+
+// TestAssignTicketCapacity drives AssignTicket concurrently and checks that
+// agent ticket counts follow the tickets actually assigned
+func TestAssignTicketCapacity(t *testing.T) {
+	db := newTestDB(t)
+	agents := repository.NewAgentRepository(db)
+	tickets := repository.NewTicketRepository(db)
+	uc := newTestTicketUsecase(db)
+	ctx := context.Background()
+
+	const tenantID = "tenant-assign"
+	const maxTickets = 3
+
+	newAgent := func(userID string) *models.Agent {
+		agent := &models.Agent{
+			TenantID:   tenantID,
+			UserID:     userID,
+			Name:       userID,
+			Status:     models.AgentStatusAvailable,
+			MaxTickets: maxTickets,
+		}
+		require.NoError(t, agents.Create(ctx, agent))
+		return agent
+	}
+	newTicket := func(number string) *models.Ticket {
+		ticket := &models.Ticket{
+			TenantID:     tenantID,
+			TicketNumber: number,
+			Subject:      "Capacity " + number,
+			Status:       models.StatusOpen,
+			Priority:     models.PriorityMedium,
+		}
+		require.NoError(t, tickets.Create(ctx, ticket))
+		return ticket
+	}
+	currentTickets := func(agent *models.Agent) int {
+		stored, err := agents.GetByID(ctx, agent.ID)
+		require.NoError(t, err)
+		require.NotNil(t, stored)
+		return stored.CurrentTickets
+	}
+
+	t.Run("Concurrent Assignments Stop At Capacity", func(t *testing.T) {
+		agent := newAgent("agent-busy")
+
+		var assigned atomic.Int32
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i := 0; i < 20; i++ {
+			ticket := newTicket(fmt.Sprintf("TKT-BUSY-%d", i))
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+
+				_, err := uc.AssignTicket(ctx, ticket.ID.Hex(), models.AssignTicketRequest{AssigneeID: agent.UserID}, "admin", "Admin")
+				if err == nil {
+					assigned.Add(1)
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		assert.Equal(t, int32(maxTickets), assigned.Load())
+		assert.Equal(t, maxTickets, currentTickets(agent))
+	})
+
+	t.Run("Lost Update Rolls Back The Slot", func(t *testing.T) {
+		agent := newAgent("agent-racing")
+		ticket := newTicket("TKT-RACE")
+
+		// Every writer reserves before the version-guarded update; all but
+		// the winner must hand their slot back
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				_, _ = uc.AssignTicket(ctx, ticket.ID.Hex(), models.AssignTicketRequest{AssigneeID: agent.UserID}, "admin", "Admin")
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		stored, err := tickets.GetByID(ctx, ticket.ID)
+		require.NoError(t, err)
+		assert.Equal(t, agent.UserID, stored.AssignedToID)
+		assert.Equal(t, 1, currentTickets(agent))
+	})
+
+	t.Run("Reassignment Moves The Slot", func(t *testing.T) {
+		first := newAgent("agent-first")
+		second := newAgent("agent-second")
+		ticket := newTicket("TKT-MOVE")
+
+		_, err := uc.AssignTicket(ctx, ticket.ID.Hex(), models.AssignTicketRequest{AssigneeID: first.UserID}, "admin", "Admin")
+		require.NoError(t, err)
+		_, err = uc.AssignTicket(ctx, ticket.ID.Hex(), models.AssignTicketRequest{AssigneeID: first.UserID}, "admin", "Admin")
+		require.NoError(t, err)
+		assert.Equal(t, 1, currentTickets(first))
+
+		_, err = uc.AssignTicket(ctx, ticket.ID.Hex(), models.AssignTicketRequest{AssigneeID: second.UserID}, "admin", "Admin")
+		require.NoError(t, err)
+		assert.Equal(t, 0, currentTickets(first))
+		assert.Equal(t, 1, currentTickets(second))
+
+		_, err = uc.ChangeStatus(ctx, ticket.ID.Hex(), models.ChangeStatusRequest{Status: models.StatusResolved}, "admin", "Admin", true)
+		require.NoError(t, err)
+		assert.Equal(t, 0, currentTickets(second))
+
+		// The resolution is credited to the assignee, found by user ID
+		stored, err := agents.GetByID(ctx, second.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, stored.TotalResolved)
+	})
+}
@@ -0,0 +1,116 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/minisource/ticket/internal/cache"
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLookupCacheInvalidation checks that writes through the department,
+// category and SLA repositories drop their cached entries
+func TestLookupCacheInvalidation(t *testing.T) {
+	ctx := t.Context()
+	db := newTestDB(t)
+	store := cache.NewMemoryStore()
+
+	t.Run("department", func(t *testing.T) {
+		c := cache.New(store, "departments", time.Hour)
+		repo := repository.NewDepartmentRepository(db).WithCache(c)
+
+		department := &models.Department{TenantID: "tenant-cache", Name: "Billing"}
+		require.NoError(t, repo.Create(ctx, department))
+
+		_, err := repo.GetByID(ctx, department.ID)
+		require.NoError(t, err)
+		got, err := repo.GetByID(ctx, department.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Billing", got.Name)
+		assert.Equal(t, int64(1), c.Stats().Hits)
+
+		department.Name = "Payments"
+		require.NoError(t, repo.Update(ctx, department))
+		got, err = repo.GetByID(ctx, department.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Payments", got.Name)
+
+		require.NoError(t, repo.Delete(ctx, department.ID))
+		got, err = repo.GetByID(ctx, department.ID)
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("category", func(t *testing.T) {
+		c := cache.New(store, "categories", time.Hour)
+		repo := repository.NewCategoryRepository(db).WithCache(c)
+
+		category := &models.Category{TenantID: "tenant-cache", Name: "Refunds"}
+		require.NoError(t, repo.Create(ctx, category))
+
+		_, err := repo.GetByID(ctx, category.ID)
+		require.NoError(t, err)
+		got, err := repo.GetByID(ctx, category.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), c.Stats().Hits)
+
+		category.Name = "Chargebacks"
+		require.NoError(t, repo.Update(ctx, category))
+		got, err = repo.GetByID(ctx, category.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Chargebacks", got.Name)
+
+		require.NoError(t, repo.Delete(ctx, category.ID))
+		got, err = repo.GetByID(ctx, category.ID)
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("sla policy", func(t *testing.T) {
+		c := cache.New(store, "sla_policies", time.Hour)
+		repo := repository.NewSLAPolicyRepository(db).WithCache(c)
+
+		policy := &models.SLAPolicy{TenantID: "tenant-cache", Name: "Standard", IsDefault: true}
+		require.NoError(t, repo.Create(ctx, policy))
+
+		got, err := repo.GetDefault(ctx, "tenant-cache")
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		_, err = repo.GetByID(ctx, policy.ID)
+		require.NoError(t, err)
+
+		// Clearing the default flag must drop both the ID and the default entries
+		policy.Name = "Legacy"
+		policy.IsDefault = false
+		require.NoError(t, repo.Update(ctx, policy))
+
+		got, err = repo.GetByID(ctx, policy.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Legacy", got.Name)
+		got, err = repo.GetDefault(ctx, "tenant-cache")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+
+		// A new default is seen straight away
+		replacement := &models.SLAPolicy{TenantID: "tenant-cache", Name: "Premium", IsDefault: true}
+		require.NoError(t, repo.Create(ctx, replacement))
+		got, err = repo.GetDefault(ctx, "tenant-cache")
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, "Premium", got.Name)
+
+		require.NoError(t, repo.Delete(ctx, replacement.ID))
+		got, err = repo.GetByID(ctx, replacement.ID)
+		require.NoError(t, err)
+		assert.Nil(t, got)
+		got, err = repo.GetDefault(ctx, "tenant-cache")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+}
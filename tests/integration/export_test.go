@@ -0,0 +1,66 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExportFinishClaim checks that only the latest claim on an export job
+// can finish it, and that running jobs aren't cleaned up
+func TestExportFinishClaim(t *testing.T) {
+	repo := repository.NewExportRepository(newTestDB(t))
+	ctx := context.Background()
+
+	job := &models.ExportJob{
+		TenantID:  "tenant-export",
+		Kind:      models.ExportTickets,
+		Format:    "csv",
+		Status:    models.ExportPending,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}
+	require.NoError(t, repo.Create(ctx, job))
+
+	first, err := repo.ClaimNext(ctx, time.Hour)
+	require.NoError(t, err)
+	require.NotNil(t, first)
+
+	expired, err := repo.ListExpired(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+
+	// The first worker looks stopped, so a second one takes the job over
+	time.Sleep(5 * time.Millisecond)
+	second, err := repo.ClaimNext(ctx, 0)
+	require.NoError(t, err)
+	require.NotNil(t, second)
+	assert.Equal(t, first.ID, second.ID)
+
+	first.Status = models.ExportDone
+	finished, err := repo.Finish(ctx, first)
+	require.NoError(t, err)
+	assert.False(t, finished)
+
+	second.Status = models.ExportDone
+	finished, err = repo.Finish(ctx, second)
+	require.NoError(t, err)
+	assert.True(t, finished)
+
+	// Finishing twice matches nothing, as the job is no longer running
+	finished, err = repo.Finish(ctx, second)
+	require.NoError(t, err)
+	assert.False(t, finished)
+
+	expired, err = repo.ListExpired(ctx, time.Now())
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, job.ID, expired[0].ID)
+}
@@ -0,0 +1,67 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/minisource/ticket/internal/database"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestFileRepository checks that files written to GridFS read back whole and
+// that failed writes leave nothing behind
+func TestFileRepository(t *testing.T) {
+	db := newTestDB(t)
+	files := repository.NewFileRepository(db, database.BucketExports)
+	ctx := context.Background()
+
+	// Larger than a GridFS chunk
+	content := strings.Repeat("subject,status\nPrinter jammed,open\n", 20000)
+
+	id, size, err := files.Write(ctx, "tickets.csv", func(w io.Writer) error {
+		_, err := io.WriteString(w, content)
+		return err
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, len(content), size)
+
+	file, err := files.Open(ctx, id)
+	require.NoError(t, err)
+	require.NotNil(t, file)
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	assert.Equal(t, content, string(data))
+
+	require.NoError(t, files.Delete(ctx, id))
+	file, err = files.Open(ctx, id)
+	require.NoError(t, err)
+	assert.Nil(t, file)
+	require.NoError(t, files.Delete(ctx, id))
+
+	file, err = files.Open(ctx, primitive.NewObjectID())
+	require.NoError(t, err)
+	assert.Nil(t, file)
+
+	// A failed write is discarded, chunks included
+	failed := errors.New("export failed")
+	_, _, err = files.Write(ctx, "broken.csv", func(w io.Writer) error {
+		_, _ = io.WriteString(w, content)
+		return failed
+	})
+	assert.ErrorIs(t, err, failed)
+
+	chunks, err := db.Collection(database.BucketExports+".chunks").CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.Zero(t, chunks)
+}
@@ -0,0 +1,69 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/minisource/ticket/config"
+	"github.com/minisource/ticket/internal/assignment"
+	"github.com/minisource/ticket/internal/collision"
+	"github.com/minisource/ticket/internal/database"
+	"github.com/minisource/ticket/internal/realtime"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/minisource/ticket/internal/search"
+	"github.com/minisource/ticket/internal/usecase"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDB connects to the MongoDB at TEST_MONGODB_URI and drops the
+// test database when the test ends
+func newTestDB(t *testing.T) *database.MongoDB {
+	uri := os.Getenv("TEST_MONGODB_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGODB_URI not set")
+	}
+
+	db, err := database.NewMongoDB(config.MongoDBConfig{
+		URI:             uri,
+		Database:        "ticket_integration_test",
+		MaxPoolSize:     100,
+		MaxConnIdleTime: time.Minute,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		ctx := context.Background()
+		_ = db.Database.Drop(ctx)
+		_ = db.Close(ctx)
+	})
+
+	return db
+}
+
+// newTestTicketUsecase wires a ticket usecase to db with in-memory realtime
+// and collision stores and SLA tracking off
+func newTestTicketUsecase(db *database.MongoDB) *usecase.TicketUsecase {
+	ticketRepo := repository.NewTicketRepository(db)
+	messageRepo := repository.NewMessageRepository(db)
+	departmentRepo := repository.NewDepartmentRepository(db)
+
+	return usecase.NewTicketUsecase(
+		ticketRepo,
+		messageRepo,
+		repository.NewHistoryRepository(db),
+		departmentRepo,
+		repository.NewCategoryRepository(db),
+		repository.NewAgentRepository(db),
+		repository.NewSLAPolicyRepository(db),
+		assignment.NewAssigner(departmentRepo),
+		realtime.NewMemoryHub(16),
+		collision.NewTracker(collision.NewMemoryStore(), time.Minute),
+		search.NewMongoIndex(ticketRepo, messageRepo),
+		&config.Config{},
+	)
+}
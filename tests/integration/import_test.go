@@ -0,0 +1,68 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestImportProgressClaim checks that a worker whose import was taken over
+// can no longer save progress or finish it
+func TestImportProgressClaim(t *testing.T) {
+	repo := repository.NewImportRepository(newTestDB(t))
+	ctx := context.Background()
+
+	job := &models.ImportJob{
+		TenantID: "tenant-import",
+		FileName: "tickets.csv",
+		Format:   models.ImportCSV,
+		Status:   models.ImportPending,
+	}
+	require.NoError(t, repo.Create(ctx, job))
+
+	first, err := repo.ClaimNext(ctx, time.Hour)
+	require.NoError(t, err)
+	require.NotNil(t, first)
+
+	first.Records = 100
+	saved, err := repo.SaveProgress(ctx, first)
+	require.NoError(t, err)
+	assert.True(t, saved)
+
+	// The first worker went quiet, so a second one takes the job over
+	time.Sleep(5 * time.Millisecond)
+	second, err := repo.ClaimNext(ctx, 0)
+	require.NoError(t, err)
+	require.NotNil(t, second)
+	assert.Equal(t, first.ID, second.ID)
+	assert.EqualValues(t, 100, second.Records)
+
+	first.Records = 200
+	saved, err = repo.SaveProgress(ctx, first)
+	require.NoError(t, err)
+	assert.False(t, saved)
+
+	first.Status = models.ImportDone
+	finished, err := repo.Finish(ctx, first)
+	require.NoError(t, err)
+	assert.False(t, finished)
+
+	second.Status = models.ImportDone
+	finished, err = repo.Finish(ctx, second)
+	require.NoError(t, err)
+	assert.True(t, finished)
+
+	stored, err := repo.GetByID(ctx, job.TenantID, job.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, models.ImportDone, stored.Status)
+	assert.EqualValues(t, 100, stored.Records)
+}
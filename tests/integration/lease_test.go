@@ -0,0 +1,57 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLeaseClaim checks that exactly one caller claims each run of a job
+func TestLeaseClaim(t *testing.T) {
+	repo := repository.NewLeaseRepository(newTestDB(t))
+	ctx := context.Background()
+	today := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	var claims atomic.Int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			claimed, err := repo.Claim(ctx, "reset_rolling_windows", today)
+			assert.NoError(t, err)
+			if claimed {
+				claims.Add(1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	assert.Equal(t, int32(1), claims.Load())
+
+	// A late instance can't rerun an earlier day, but the next day is free
+	claimed, err := repo.Claim(ctx, "reset_rolling_windows", today.AddDate(0, 0, -1))
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	claimed, err = repo.Claim(ctx, "reset_rolling_windows", today.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	// Jobs are leased independently
+	claimed, err = repo.Claim(ctx, "other_job", today)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
@@ -0,0 +1,51 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGetAvailableSkipsStaleAgents checks that with presence on, only agents
+// with a recent heartbeat are offered for auto-assignment
+func TestGetAvailableSkipsStaleAgents(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewAgentRepository(db).WithStaleAfter(5 * time.Minute)
+	ctx := context.Background()
+
+	const tenantID = "tenant-presence"
+	create := func(userID string) *models.Agent {
+		agent := &models.Agent{
+			TenantID:   tenantID,
+			UserID:     userID,
+			Name:       userID,
+			Status:     models.AgentStatusAvailable,
+			MaxTickets: 5,
+		}
+		require.NoError(t, repo.Create(ctx, agent))
+		return agent
+	}
+
+	create("agent-silent")
+	create("agent-live")
+	_, err := repo.Heartbeat(ctx, tenantID, "agent-live")
+	require.NoError(t, err)
+
+	agents, err := repo.GetAvailable(ctx, tenantID, nil)
+	require.NoError(t, err)
+	require.Len(t, agents, 1)
+	assert.Equal(t, "agent-live", agents[0].UserID)
+
+	// Without presence every available agent is offered
+	agents, err = repository.NewAgentRepository(db).GetAvailable(ctx, tenantID, nil)
+	require.NoError(t, err)
+	assert.Len(t, agents, 2)
+}
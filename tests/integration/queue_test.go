@@ -0,0 +1,84 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestClaimNextConcurrent has more agents than tickets pull from the queue at
+// once, so most ranked batches are stale, and checks every ticket is claimed
+// exactly once
+func TestClaimNextConcurrent(t *testing.T) {
+	repo := repository.NewTicketRepository(newTestDB(t))
+	ctx := context.Background()
+
+	const tenantID = "tenant-queue"
+	const tickets = 25
+	const agents = 40
+
+	for i := 0; i < tickets; i++ {
+		require.NoError(t, repo.Create(ctx, &models.Ticket{
+			TenantID:     tenantID,
+			TicketNumber: fmt.Sprintf("TKT-Q-%d", i),
+			Subject:      "Queued",
+			Status:       models.StatusOpen,
+			Priority:     models.PriorityMedium,
+		}))
+	}
+
+	var mu sync.Mutex
+	claimedBy := map[primitive.ObjectID]string{}
+	empty := 0
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < agents; i++ {
+		agent := &models.Agent{TenantID: tenantID, UserID: fmt.Sprintf("agent-%d", i), Name: "Agent"}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			ticket, err := repo.ClaimNext(ctx, agent)
+			assert.NoError(t, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if ticket == nil {
+				empty++
+				return
+			}
+			if previous, ok := claimedBy[ticket.ID]; ok {
+				t.Errorf("ticket %s claimed by both %s and %s", ticket.TicketNumber, previous, agent.UserID)
+			}
+			claimedBy[ticket.ID] = agent.UserID
+			assert.Equal(t, models.StatusInProgress, ticket.Status)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	assert.Len(t, claimedBy, tickets)
+	assert.Equal(t, agents-tickets, empty)
+
+	for id, userID := range claimedBy {
+		stored, err := repo.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, userID, stored.AssignedToID)
+	}
+
+	ticket, err := repo.ClaimNext(ctx, &models.Agent{TenantID: tenantID, UserID: "agent-late"})
+	require.NoError(t, err)
+	assert.Nil(t, ticket)
+}
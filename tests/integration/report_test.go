@@ -0,0 +1,150 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/minisource/ticket/internal/database"
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/minisource/ticket/internal/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestAgentScorecards checks the scorecard aggregations over messages,
+// status history and assigned tickets, and the leaderboard ranking
+func TestAgentScorecards(t *testing.T) {
+	db := newTestDB(t)
+	agents := repository.NewAgentRepository(db)
+	tickets := repository.NewTicketRepository(db)
+	messages := repository.NewMessageRepository(db)
+	uc := usecase.NewReportUsecase(repository.NewReportRepository(db), agents)
+	ctx := context.Background()
+
+	const tenantID = "tenant-scorecards"
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	ago := func(minutes int) *time.Time {
+		v := now.Add(-time.Duration(minutes) * time.Minute)
+		return &v
+	}
+	rating := func(r int) *int { return &r }
+
+	for _, userID := range []string{"agent-a", "agent-b", "agent-c"} {
+		require.NoError(t, agents.Create(ctx, &models.Agent{
+			TenantID: tenantID, UserID: userID, Name: userID,
+			Status: models.AgentStatusAvailable, MaxTickets: 10,
+		}))
+	}
+
+	// agent-a resolves two tickets, one breached and rated 3, and one rated
+	// 5 that is reopened later; agent-b resolves one rated 5
+	first := &models.Ticket{
+		TenantID: tenantID, AssignedToID: "agent-a", Status: models.StatusReopened, Priority: models.PriorityLow,
+		CreatedAt: *ago(50), FirstResponsedAt: ago(40), ResolvedAt: ago(30),
+		SatisfactionRating: rating(5), RatedAt: ago(25),
+	}
+	second := &models.Ticket{
+		TenantID: tenantID, AssignedToID: "agent-a", Status: models.StatusResolved, Priority: models.PriorityLow,
+		CreatedAt: *ago(50), ResolvedAt: ago(20), SLABreached: true,
+		SatisfactionRating: rating(3), RatedAt: ago(15),
+	}
+	third := &models.Ticket{
+		TenantID: tenantID, AssignedToID: "agent-b", Status: models.StatusResolved, Priority: models.PriorityLow,
+		CreatedAt: *ago(50), ResolvedAt: ago(20), SatisfactionRating: rating(5), RatedAt: ago(10),
+	}
+	for _, ticket := range []*models.Ticket{first, second, third} {
+		ticket.ID = primitive.NewObjectID()
+		require.NoError(t, tickets.Import(ctx, ticket))
+	}
+
+	reply := func(ticket *models.Ticket, userID string, kind models.MessageType) {
+		require.NoError(t, messages.Create(ctx, &models.TicketMessage{
+			TicketID: ticket.ID, TenantID: tenantID, Type: kind, Content: "x",
+			SenderType: models.SenderAgent, SenderID: userID, SenderName: userID,
+		}))
+	}
+	reply(first, "agent-a", models.MessageTypeReply)
+	reply(first, "agent-a", models.MessageTypeReply)
+	reply(second, "agent-a", models.MessageTypeReply)
+	reply(second, "agent-a", models.MessageTypeInternalNote)
+	reply(third, "agent-b", models.MessageTypeReply)
+
+	// History is written directly to control the order of its entries
+	statusChange := func(ticket *models.Ticket, status models.TicketStatus, by string, at *time.Time) interface{} {
+		return models.TicketHistory{
+			ID: primitive.NewObjectID(), TicketID: ticket.ID, TenantID: tenantID,
+			Action: "status_changed", Field: "status", NewValue: status, ChangedBy: by, CreatedAt: *at,
+		}
+	}
+	_, err := db.Collection(database.CollectionTicketHistory).InsertMany(ctx, []interface{}{
+		statusChange(first, models.StatusResolved, "agent-a", ago(30)),
+		statusChange(first, models.StatusReopened, "customer", ago(5)),
+		statusChange(second, models.StatusResolved, "agent-a", ago(20)),
+		statusChange(third, models.StatusResolved, "agent-b", ago(20)),
+	})
+	require.NoError(t, err)
+
+	period := models.ScorecardRequest{From: now.Add(-time.Hour), To: now.Add(time.Minute)}
+
+	t.Run("Scorecard", func(t *testing.T) {
+		req := period
+		req.AgentID = "agent-a"
+		report, err := uc.AgentScorecards(ctx, tenantID, req)
+		require.NoError(t, err)
+		require.Len(t, report.Agents, 1)
+
+		card := report.Agents[0]
+		assert.EqualValues(t, 3, card.Replies)
+		assert.EqualValues(t, 1, card.InternalNotes)
+		assert.EqualValues(t, 2, card.Handled)
+		require.NotNil(t, card.InternalNoteRatio)
+		assert.InDelta(t, 0.25, *card.InternalNoteRatio, 0.001)
+
+		assert.EqualValues(t, 2, card.Resolutions)
+		assert.EqualValues(t, 1, card.Reopened)
+		require.NotNil(t, card.ReopenRate)
+		assert.InDelta(t, 0.5, *card.ReopenRate, 0.001)
+
+		assert.EqualValues(t, 2, card.Resolved)
+		require.NotNil(t, card.SLACompliance)
+		assert.InDelta(t, 0.5, *card.SLACompliance, 0.001)
+		require.NotNil(t, card.FirstResponseMedian)
+		assert.InDelta(t, 10, *card.FirstResponseMedian, 0.001)
+		assert.Equal(t, [5]int64{0, 0, 1, 0, 1}, card.Ratings)
+		require.NotNil(t, card.CSAT)
+		assert.InDelta(t, 0.5, *card.CSAT, 0.001)
+		require.NotNil(t, card.AvgRating)
+		assert.InDelta(t, 4, *card.AvgRating, 0.001)
+	})
+
+	t.Run("Leaderboard", func(t *testing.T) {
+		ranked := func(sortBy models.ScorecardSort, limit int) []string {
+			req := period
+			req.SortBy = sortBy
+			req.Limit = limit
+			report, err := uc.AgentScorecards(ctx, tenantID, req)
+			require.NoError(t, err)
+
+			var userIDs []string
+			for n, card := range report.Agents {
+				assert.Equal(t, n+1, card.Rank)
+				userIDs = append(userIDs, card.UserID)
+			}
+			return userIDs
+		}
+
+		assert.Equal(t, []string{"agent-a", "agent-b", "agent-c"}, ranked(models.ScorecardByResolved, 0))
+		// Agents without ratings come last
+		assert.Equal(t, []string{"agent-b", "agent-a", "agent-c"}, ranked(models.ScorecardByCSAT, 0))
+		assert.Equal(t, []string{"agent-b", "agent-a"}, ranked(models.ScorecardByReopenRate, 2))
+
+		_, err := uc.AgentScorecards(ctx, tenantID, models.ScorecardRequest{SortBy: "speed"})
+		assert.ErrorIs(t, err, usecase.ErrInvalidReport)
+	})
+}
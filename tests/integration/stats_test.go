@@ -0,0 +1,113 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestGetStats checks how the $facet results map onto ticket statistics and
+// that filters, customers included, scope them
+func TestGetStats(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewTicketRepository(db)
+	ctx := context.Background()
+
+	const tenantID = "tenant-stats"
+	created := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(minutes int) *time.Time {
+		v := created.Add(time.Duration(minutes) * time.Minute)
+		return &v
+	}
+	rating := func(r int) *int { return &r }
+	dept := primitive.NewObjectID()
+
+	tickets := []*models.Ticket{
+		{
+			TenantID: tenantID, CustomerID: "alice", Status: models.StatusOpen,
+			Priority: models.PriorityHigh, Type: models.TypeIncident, DepartmentID: &dept,
+			AssignedToID: "agent-1", FirstResponsedAt: at(10), SLABreached: true,
+		},
+		{
+			TenantID: tenantID, CustomerID: "alice", Status: models.StatusResolved,
+			Priority: models.PriorityLow, Type: models.TypeQuestion, DepartmentID: &dept,
+			AssignedToID: "agent-1", FirstResponsedAt: at(30), ResolvedAt: at(120), SatisfactionRating: rating(4),
+		},
+		{
+			TenantID: tenantID, CustomerID: "bob", Status: models.StatusPending,
+			Priority: models.PriorityLow, Type: models.TypeQuestion,
+		},
+		{
+			TenantID: tenantID, CustomerID: "bob", Status: models.StatusClosed,
+			Priority: models.PriorityLow, Type: models.TypeBug, DepartmentID: &dept,
+			AssignedToID: "agent-2", ResolvedAt: at(60), SatisfactionRating: rating(2),
+		},
+		// Deleted and other tenants' tickets are never counted
+		{TenantID: tenantID, CustomerID: "alice", Status: models.StatusOpen, Priority: models.PriorityLow, IsDeleted: true},
+		{TenantID: "tenant-other", CustomerID: "alice", Status: models.StatusOpen, Priority: models.PriorityLow},
+	}
+	for _, ticket := range tickets {
+		ticket.ID = primitive.NewObjectID()
+		ticket.CreatedAt = created
+		require.NoError(t, repo.Import(ctx, ticket))
+	}
+
+	t.Run("Whole Tenant", func(t *testing.T) {
+		stats, err := repo.GetStats(ctx, tenantID, models.StatsFilter{})
+		require.NoError(t, err)
+
+		assert.EqualValues(t, 4, stats.TotalTickets)
+		assert.EqualValues(t, 1, stats.OpenTickets)
+		assert.EqualValues(t, 1, stats.PendingTickets)
+		assert.EqualValues(t, 1, stats.ResolvedTickets)
+		assert.EqualValues(t, 1, stats.ClosedTickets)
+		assert.EqualValues(t, 1, stats.UnassignedTickets)
+		assert.EqualValues(t, 1, stats.SLABreached)
+		assert.Equal(t, map[string]int64{"high": 1, "low": 3}, stats.ByPriority)
+		assert.Equal(t, map[string]int64{"incident": 1, "question": 2, "bug": 1}, stats.ByType)
+		assert.Equal(t, map[string]int64{dept.Hex(): 3, "none": 1}, stats.ByDepartment)
+		assert.EqualValues(t, 20, stats.AvgResponseTime)
+		assert.EqualValues(t, 90, stats.AvgResolutionTime)
+		assert.InDelta(t, 3.0, stats.AvgSatisfaction, 0.001)
+	})
+
+	t.Run("Customer Scoped", func(t *testing.T) {
+		stats, err := repo.GetStats(ctx, tenantID, models.StatsFilter{CustomerID: "bob"})
+		require.NoError(t, err)
+
+		assert.EqualValues(t, 2, stats.TotalTickets)
+		assert.EqualValues(t, 0, stats.OpenTickets)
+		assert.EqualValues(t, 1, stats.PendingTickets)
+		assert.EqualValues(t, 1, stats.ClosedTickets)
+		assert.Equal(t, map[string]int64{"low": 2}, stats.ByPriority)
+		assert.EqualValues(t, 0, stats.AvgResponseTime)
+		assert.InDelta(t, 2.0, stats.AvgSatisfaction, 0.001)
+	})
+
+	t.Run("Agent Scoped", func(t *testing.T) {
+		stats, err := repo.GetStats(ctx, tenantID, models.StatsFilter{AgentID: "agent-1"})
+		require.NoError(t, err)
+
+		assert.EqualValues(t, 2, stats.TotalTickets)
+		assert.EqualValues(t, 0, stats.UnassignedTickets)
+		assert.Equal(t, map[string]int64{dept.Hex(): 2}, stats.ByDepartment)
+	})
+
+	t.Run("No Matches", func(t *testing.T) {
+		stats, err := repo.GetStats(ctx, tenantID, models.StatsFilter{CustomerID: "nobody"})
+		require.NoError(t, err)
+
+		assert.Zero(t, stats.TotalTickets)
+		assert.Empty(t, stats.ByPriority)
+		assert.NotNil(t, stats.ByDepartment)
+	})
+}
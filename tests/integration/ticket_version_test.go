@@ -0,0 +1,100 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/minisource/ticket/internal/models"
+	"github.com/minisource/ticket/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TestTicketVersionedUpdates checks that conditional updates reject stale versions
+func TestTicketVersionedUpdates(t *testing.T) {
+	repo := repository.NewTicketRepository(newTestDB(t))
+	ctx := context.Background()
+
+	ticket := &models.Ticket{
+		TenantID:     "tenant-version",
+		TicketNumber: "TKT-1",
+		Subject:      "Printer on fire",
+		Status:       models.StatusOpen,
+		Priority:     models.PriorityMedium,
+	}
+	require.NoError(t, repo.Create(ctx, ticket))
+	require.Equal(t, int64(1), ticket.Version)
+
+	t.Run("Only One Concurrent Writer Wins", func(t *testing.T) {
+		var wins, conflicts atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := repo.UpdateFieldsIfVersion(ctx, ticket.ID, 1, bson.M{"status": models.StatusInProgress})
+				switch err {
+				case nil:
+					wins.Add(1)
+				case repository.ErrVersionConflict:
+					conflicts.Add(1)
+				default:
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), wins.Load())
+		assert.Equal(t, int32(19), conflicts.Load())
+	})
+
+	t.Run("Unconditional Updates Bump Version", func(t *testing.T) {
+		require.NoError(t, repo.UpdateFields(ctx, ticket.ID, bson.M{"subject": "Printer still on fire"}))
+
+		stored, err := repo.GetByID(ctx, ticket.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), stored.Version)
+		assert.Equal(t, models.StatusInProgress, stored.Status)
+
+		err = repo.UpdateFieldsIfVersion(ctx, ticket.ID, 2, bson.M{"priority": models.PriorityHigh})
+		assert.ErrorIs(t, err, repository.ErrVersionConflict)
+	})
+
+	t.Run("Reopen Only From Pending", func(t *testing.T) {
+		pending := &models.Ticket{
+			TenantID:     "tenant-version",
+			TicketNumber: "TKT-2",
+			Subject:      "Waiting on customer",
+			Status:       models.StatusPending,
+			Priority:     models.PriorityMedium,
+		}
+		require.NoError(t, repo.Create(ctx, pending))
+
+		// An agent resolves the ticket after the customer's reply read it as pending
+		require.NoError(t, repo.UpdateFields(ctx, pending.ID, bson.M{"status": models.StatusResolved}))
+		moved, err := repo.TransitionStatus(ctx, pending.ID, models.StatusPending, models.StatusOpen)
+		require.NoError(t, err)
+		assert.False(t, moved)
+
+		stored, err := repo.GetByID(ctx, pending.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusResolved, stored.Status)
+
+		require.NoError(t, repo.UpdateFields(ctx, pending.ID, bson.M{"status": models.StatusPending}))
+		moved, err = repo.TransitionStatus(ctx, pending.ID, models.StatusPending, models.StatusOpen)
+		require.NoError(t, err)
+		assert.True(t, moved)
+
+		stored, err = repo.GetByID(ctx, pending.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusOpen, stored.Status)
+		assert.Equal(t, int64(4), stored.Version)
+	})
+}